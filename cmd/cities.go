@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"weather-app/config"
+	datacollector "weather-app/internal/data_collector"
+	"weather-app/internal/provider"
+	"weather-app/internal/pubsub"
+)
+
+const citiesUsage = "usage: cities [-config FILE] import [-p] FILE | cities [-config FILE] list"
+
+func runCities(args []string) error {
+	cfg, args, err := config.Load("cities", args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New(citiesUsage)
+	}
+	switch args[0] {
+	case "import":
+		return importCities(cfg, args[1:])
+	case "list":
+		if len(args) != 1 {
+			return errors.New(citiesUsage)
+		}
+		return listCities(cfg)
+	default:
+		return errors.New(citiesUsage)
+	}
+}
+
+func importCities(cfg *config.Config, args []string) error {
+	fs := flag.NewFlagSet("cities import", flag.ContinueOnError)
+	fs.BoolVar(&cfg.Collector.Parallel, "p", cfg.Collector.Parallel, "Enable parallel mode")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New(citiesUsage)
+	}
+	cfg.Collector.CitiesFile = fs.Arg(0)
+	if err := cfg.ValidateCollector(); err != nil {
+		return err
+	}
+
+	client := provider.NewClient(cfg.OpenWeather.Provider())
+	service, closeServices, err := newServices(cfg, client)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(closeServices)
+
+	dataCollector := datacollector.NewDataCollector(cfg.Collector, service, cfg.OpenWeather.APIKey, pubsub.NewBroker(), client)
+	ctx, stop := signalContext()
+	defer stop()
+	return dataCollector.ImportCities(ctx)
+}
+
+func listCities(cfg *config.Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	client := provider.NewClient(cfg.OpenWeather.Provider())
+	service, closeServices, err := newServices(cfg, client)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(closeServices)
+
+	cities, err := service.CityService.GetCities(context.Background())
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tCOUNTRY\tLATITUDE\tLONGITUDE")
+	for _, city := range cities {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%.4f\t%.4f\n", city.Id, city.Name, city.Country, city.Latitude, city.Longitude)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"weather-app/config"
+	datacollector "weather-app/internal/data_collector"
+	"weather-app/internal/lifecycle"
+	"weather-app/internal/provider"
+	"weather-app/internal/pubsub"
+)
+
+// runCollect runs the data collector without the HTTP server: collect keeps
+// updating forecasts until it is stopped, collect-once updates them a single
+// time and exits, which suits cron jobs.
+func runCollect(command string, args []string) error {
+	cfg, rest, err := config.Load(command, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected arguments %v", rest)
+	}
+	if err := cfg.ValidateCollector(); err != nil {
+		return err
+	}
+
+	tracer, err := tracingComponent(cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	client := provider.NewClient(cfg.OpenWeather.Provider())
+	service, closeServices, err := newServices(cfg, client)
+	if err != nil {
+		return err
+	}
+
+	// Nobody subscribes to the broker in a collector-only process. Servers
+	// find the stored updates by polling, and webhooks are queued in storage.
+	dataCollector := datacollector.NewDataCollector(cfg.Collector, service, cfg.OpenWeather.APIKey, pubsub.NewBroker(), client)
+	ctx, stop := signalContext()
+	defer stop()
+	if command == "collect-once" {
+		defer tracer.Stop(context.Background())
+		defer closeStorage(closeServices)
+		return dataCollector.RunOnce(ctx)
+	}
+
+	manager := lifecycle.NewManager(cfg.Server.ShutdownTimeout.Duration)
+	manager.Add(tracer)
+	manager.Add(storageComponent(closeServices))
+	manager.Add(lifecycle.Component{
+		Name:  "data collector",
+		Start: dataCollector.Start,
+		Stop:  dataCollector.Stop,
+	})
+	return manager.Run(ctx)
+}
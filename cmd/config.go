@@ -0,0 +1,33 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"weather-app/config"
+)
+
+const configUsage = "usage: config [-config FILE] print"
+
+// runConfig prints the effective configuration with secrets redacted. The
+// configuration is printed even if it is invalid, followed by the errors.
+func runConfig(args []string) error {
+	cfg, args, err := config.Load("config", args)
+	if err != nil {
+		return err
+	}
+	if len(args) != 1 || args[0] != "print" {
+		return errors.New(configUsage)
+	}
+
+	out, err := cfg.Redacted().YAML()
+	if err != nil {
+		return err
+	}
+	os.Stdout.Write(out)
+
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return nil
+}
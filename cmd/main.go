@@ -2,18 +2,30 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"weather-app/config"
-	datacollector "weather-app/internal/data_collector"
-	"weather-app/internal/handler"
+	"weather-app/internal/cache"
+	"weather-app/internal/lifecycle"
+	"weather-app/internal/metrics"
+	"weather-app/internal/provider"
+	"weather-app/internal/repository"
+	"weather-app/internal/repository/memory"
 	"weather-app/internal/repository/postgres"
+	"weather-app/internal/repository/sqlite"
 	"weather-app/internal/service"
+	alertservice "weather-app/internal/service/alert_service"
 	cityservice "weather-app/internal/service/city_service"
+	collectorservice "weather-app/internal/service/collector_service"
 	forecastservice "weather-app/internal/service/forecast_service"
+	healthservice "weather-app/internal/service/health_service"
 	userservice "weather-app/internal/service/user_service"
-	"weather-app/server"
+	webhookservice "weather-app/internal/service/webhook_service"
+	"weather-app/internal/tracing"
 
 	_ "weather-app/docs"
 
@@ -32,62 +44,182 @@ import (
 // @in header
 // @name Authorization
 
+const usage = `usage: app.exe [command] [flags]
+
+commands:
+  serve                  run the HTTP API (default)
+  collect                run the data collector only
+  collect-once           fetch forecasts once and exit
+  migrate                up|down|status|goto N
+  prune                  delete data past its retention once
+  cities import FILE     load cities listed in FILE
+  cities list            print stored cities
+  users create-admin     create an administrator account
+  config print           print the effective configuration
+
+every command accepts -config FILE (YAML or TOML) before its arguments`
+
 func main() {
-	pgCfg, err := config.LoadPGConfig()
-	if err != nil {
-		logrus.Fatalf("Failed to load PG config: %v", err)
+	logrus.AddHook(tracing.LogHook{})
+
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "serve":
+		err = runServe(args)
+	case "collect", "collect-once":
+		err = runCollect(command, args)
+	case "migrate":
+		err = runMigrate(args)
+	case "prune":
+		err = runPrune(args)
+	case "cities":
+		err = runCities(args)
+	case "users":
+		err = runUsers(args)
+	case "config":
+		err = runConfig(args)
+	case "help":
+		fmt.Println(usage)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		err = fmt.Errorf("unknown command %q", command)
 	}
-	db, err := postgres.NewPgConnection(pgCfg)
 	if err != nil {
-		logrus.Fatalf("Failed to connect to the database: %v", err)
+		fmt.Fprintf(os.Stderr, "%s: %v\n", command, err)
+		os.Exit(1)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			logrus.Fatalf("Failed to shut down the database: %v", err)
+}
+
+// newServices connects to the configured storage, migrating it first if
+// auto-migration is enabled, and builds the services on top of it. Services
+// reach the weather provider through client. The returned function closes the
+// storage.
+func newServices(cfg *config.Config, client *provider.Client) (*service.Service, func() error, error) {
+	dbCfg := cfg.Database
+	if dbCfg.AutoMigrate {
+		if err := migrateUp(dbCfg); err != nil {
+			return nil, nil, fmt.Errorf("failed to apply migrations: %w", err)
 		}
-	}()
+	}
+	repo, closeRepo, err := newRepository(dbCfg)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to the database: %w", err)
+	}
+
+	client.ShareQuota(repo.CollectorRunRepository)
+	cityServ := cityservice.NewCityService(repo.CityRepository, client)
+	forecastServ := forecastservice.NewForecastService(cityServ, repo.ForecastRepository, client, newCache(cfg.Cache))
+	userServ := userservice.NewUserService(cityServ, repo.UserRepository, cfg.Login.Policy())
+	alertServ := alertservice.NewAlertService(cityServ, repo.AlertRepository)
+	webhookServ := webhookservice.NewWebhookService(repo.WebhookRepository, cfg.Webhooks.Policy())
+	collectorServ := collectorservice.NewCollectorService(repo.CollectorRunRepository, client)
+	schemaVersion, err := latestMigration(dbCfg.Driver)
+	if err != nil {
+		closeRepo()
+		return nil, nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	healthServ := healthservice.NewHealthService(repo.HealthRepository, repo.CityRepository, repo.ForecastRepository, cfg.Health.Policy(schemaVersion))
+	return service.NewService(userServ, cityServ, forecastServ, alertServ, webhookServ, collectorServ, healthServ), closeRepo, nil
+}
+
+// registerDBMetrics exposes the connection pool stats of db. The metrics are
+// optional, so a failure is only logged.
+func registerDBMetrics(db *sql.DB, name string) {
+	if err := metrics.RegisterDB(db, name); err != nil {
+		logrus.Warnf("Failed to register database metrics: %v", err)
+	}
+}
 
-	cityRep := postgres.NewCityRepository(db)
-	forecastRep := postgres.NewForecastRepository(db)
-	userRep := postgres.NewUserRepository(db)
+// newCache returns the cache of forecast responses, or nil if it is disabled.
+func newCache(cfg config.CacheConfig) cache.Cache {
+	if cfg.Size == 0 {
+		return nil
+	}
+	return cache.NewLRU(cfg.Size, cfg.TTL.Duration)
+}
 
-	cityServ := cityservice.NewCityService(cityRep)
-	forecastServ := forecastservice.NewForecastService(cityServ, forecastRep)
-	userServ := userservice.NewUserService(cityServ, userRep)
-	service := service.NewService(userServ, cityServ, forecastServ)
+// signalContext returns a context that is canceled when the process receives
+// SIGINT or SIGTERM.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+}
 
-	collectorCfg, err := config.ParseCollectorFlags()
+// tracingComponent installs the tracer provider configured by cfg. Stopping
+// the component flushes the spans left, so it is registered first and stops
+// last.
+func tracingComponent(cfg config.TracingConfig) (lifecycle.Component, error) {
+	shutdown, err := tracing.Setup(context.Background(), cfg.Tracing())
 	if err != nil {
-		logrus.Fatalf("Failed to parse datacollector flags: %v", err)
+		return lifecycle.Component{}, fmt.Errorf("failed to set up tracing: %w", err)
 	}
+	return lifecycle.Component{Name: "tracing", Stop: shutdown}, nil
+}
 
-	if collectorCfg.ToStart {
-		apiKey, err := config.LoadOpenWeatherAPIKey()
-		if err != nil {
-			logrus.Fatalf("Failed to load apiKey: %v", err)
-		}
-		dataCollector := datacollector.NewDataCollector(collectorCfg, service, apiKey)
-		go dataCollector.Start()
+// storageComponent closes the storage once the components using it have
+// stopped.
+func storageComponent(close func() error) lifecycle.Component {
+	return lifecycle.Component{
+		Name: "database",
+		Stop: func(ctx context.Context) error {
+			return close()
+		},
 	}
+}
 
-	handler := handler.NewHandler(service)
-	srv := new(server.Server)
-	go func() {
-		if err := srv.Run(os.Getenv("SERVER_PORT"), handler.InitRoutes()); err != nil {
-			logrus.Fatalf("ERROR running server:%s", err.Error())
-		}
-	}()
-	defer func() {
-		if err := srv.Shutdown(context.Background()); err != nil {
-			logrus.Fatalf("ERROR shutting down server: %s", err.Error())
-		}
-	}()
+func closeStorage(close func() error) {
+	if err := close(); err != nil {
+		logrus.Errorf("Failed to shut down the database: %v", err)
+	}
+}
 
-	logrus.Print("WebApp Started")
+func newRepository(cfg config.DBConfig) (*repository.Repository, func() error, error) {
+	if cfg.Driver == config.DriverMemory {
+		storage := memory.NewStorage()
+		return repository.NewRepository(
+			memory.NewCityRepository(storage),
+			memory.NewForecastRepository(storage),
+			memory.NewUserRepository(storage),
+			memory.NewAlertRepository(storage),
+			memory.NewWebhookRepository(storage),
+			memory.NewCollectorRunRepository(storage),
+			memory.NewHealthRepository(storage),
+		), func() error { return nil }, nil
+	}
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
+	if cfg.Driver == config.DriverSqlite {
+		db, err := sqlite.NewSqliteConnection(cfg.Sqlite)
+		if err != nil {
+			return nil, nil, err
+		}
+		registerDBMetrics(db.DB, cfg.Driver)
+		return repository.NewRepository(
+			sqlite.NewCityRepository(db),
+			sqlite.NewForecastRepository(db),
+			sqlite.NewUserRepository(db),
+			sqlite.NewAlertRepository(db),
+			sqlite.NewWebhookRepository(db),
+			sqlite.NewCollectorRunRepository(db),
+			sqlite.NewHealthRepository(db),
+		), db.Close, nil
+	}
 
-	logrus.Println("WebApp Shutting Down")
+	db, err := postgres.NewPgConnection(cfg.Postgres)
+	if err != nil {
+		return nil, nil, err
+	}
+	registerDBMetrics(db.DB, cfg.Driver)
+	return repository.NewRepository(
+		postgres.NewCityRepository(db),
+		postgres.NewForecastRepository(db),
+		postgres.NewUserRepository(db),
+		postgres.NewAlertRepository(db),
+		postgres.NewWebhookRepository(db),
+		postgres.NewCollectorRunRepository(db),
+		postgres.NewHealthRepository(db),
+	), db.Close, nil
 }
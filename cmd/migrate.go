@@ -0,0 +1,132 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"weather-app/config"
+	"weather-app/internal/migrator"
+	"weather-app/internal/repository/postgres"
+	"weather-app/internal/repository/sqlite"
+
+	"github.com/sirupsen/logrus"
+)
+
+const migrateUsage = "usage: migrate [-config FILE] up|down|status|goto N"
+
+// runMigrate handles the migrate subcommand: up applies all pending
+// migrations, down rolls back the last one, goto moves to the given version
+// (forcing it if the schema is dirty) and status prints the applied version.
+func runMigrate(args []string) error {
+	cfg, args, err := config.Load("migrate", args)
+	if err != nil {
+		return err
+	}
+	if !validMigrateArgs(args) {
+		return errors.New(migrateUsage)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m, err := newMigrator(cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	switch args[0] {
+	case "up":
+		return m.Up()
+	case "down":
+		return m.Down()
+	case "goto":
+		version, err := strconv.ParseUint(args[1], 10, 32)
+		if err != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		return m.Goto(uint(version))
+	case "status":
+		status, err := m.Status()
+		if err != nil {
+			return err
+		}
+		printMigrationStatus(status)
+		return nil
+	}
+	return nil
+}
+
+func validMigrateArgs(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "up", "down", "status":
+		return len(args) == 1
+	case "goto":
+		return len(args) == 2
+	}
+	return false
+}
+
+func printMigrationStatus(status migrator.Status) {
+	fmt.Printf("version: %d, dirty: %t\n", status.Version, status.Dirty)
+	for _, version := range status.Migrations {
+		state := "pending"
+		if version <= status.Version {
+			state = "applied"
+		}
+		fmt.Printf("%06d %s\n", version, state)
+	}
+}
+
+func migrateUp(cfg config.DBConfig) error {
+	if cfg.Driver == config.DriverMemory {
+		return nil
+	}
+	m, err := newMigrator(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil {
+		return err
+	}
+	logrus.Print("Database migrations applied")
+	return nil
+}
+
+// newMigrator opens a dedicated connection, which the migrator closes.
+func newMigrator(cfg config.DBConfig) (*migrator.Migrator, error) {
+	switch cfg.Driver {
+	case config.DriverPostgres:
+		db, err := postgres.NewPgConnection(cfg.Postgres)
+		if err != nil {
+			return nil, err
+		}
+		return migrator.NewPostgresMigrator(db.DB)
+	case config.DriverSqlite:
+		db, err := sqlite.NewSqliteConnection(cfg.Sqlite)
+		if err != nil {
+			return nil, err
+		}
+		return migrator.NewSqliteMigrator(db.DB)
+	default:
+		return nil, fmt.Errorf("driver %q has no migrations", cfg.Driver)
+	}
+}
+
+// latestMigration returns the migration the storage of driver has to be at,
+// 0 for storage without migrations.
+func latestMigration(driver string) (uint, error) {
+	switch driver {
+	case config.DriverPostgres:
+		return migrator.LatestPostgres()
+	case config.DriverSqlite:
+		return migrator.LatestSqlite()
+	default:
+		return 0, nil
+	}
+}
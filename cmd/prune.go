@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"weather-app/config"
+	"weather-app/internal/provider"
+	"weather-app/internal/pruner"
+)
+
+// runPrune rolls up and deletes data past its retention a single time and
+// exits, for deployments that prune from cron rather than from the server.
+func runPrune(args []string) error {
+	cfg, rest, err := config.Load("prune", args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected arguments %v", rest)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	client := provider.NewClient(cfg.OpenWeather.Provider())
+	service, closeServices, err := newServices(cfg, client)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(closeServices)
+
+	ctx, stop := signalContext()
+	defer stop()
+	return pruner.NewPruner(cfg.Retention, service).PruneOnce(ctx)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"weather-app/config"
+	datacollector "weather-app/internal/data_collector"
+	"weather-app/internal/handler"
+	"weather-app/internal/lifecycle"
+	"weather-app/internal/metrics"
+	"weather-app/internal/provider"
+	"weather-app/internal/pruner"
+	"weather-app/internal/pubsub"
+	updatewatcher "weather-app/internal/update_watcher"
+	webhookdispatcher "weather-app/internal/webhook_dispatcher"
+	"weather-app/server"
+
+	"github.com/sirupsen/logrus"
+)
+
+// runServe starts the HTTP API and the webhook dispatcher. Stream and
+// WebSocket subscribers receive updates straight from a collector started in
+// the same process with -s, or else from the storage the collect command
+// writes to, polled every server.update_poll_interval.
+func runServe(args []string) error {
+	cfg, rest, err := config.Load("serve", args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return fmt.Errorf("unexpected arguments %v", rest)
+	}
+	if err := cfg.ValidateServer(); err != nil {
+		return err
+	}
+
+	tracer, err := tracingComponent(cfg.Tracing)
+	if err != nil {
+		return err
+	}
+	client := provider.NewClient(cfg.OpenWeather.Provider())
+	service, closeServices, err := newServices(cfg, client)
+	if err != nil {
+		return err
+	}
+
+	// Components stop in reverse order: the server drains first, then the
+	// database is closed and the last spans are exported.
+	manager := lifecycle.NewManager(cfg.Server.ShutdownTimeout.Duration)
+	manager.Add(tracer)
+	manager.Add(storageComponent(closeServices))
+
+	webhookDispatcher := webhookdispatcher.NewWebhookDispatcher(service, 10*time.Second, 50)
+	manager.Add(lifecycle.Component{
+		Name:  "webhook dispatcher",
+		Start: webhookDispatcher.Start,
+		Stop:  webhookDispatcher.Stop,
+	})
+
+	dataPruner := pruner.NewPruner(cfg.Retention, service)
+	manager.Add(lifecycle.Component{
+		Name:  "pruner",
+		Start: dataPruner.Start,
+		Stop:  dataPruner.Stop,
+	})
+
+	if err := metrics.RegisterFreshness(service.ForecastService.GetAllForecastFreshness); err != nil {
+		logrus.Warnf("Failed to register forecast freshness metrics: %v", err)
+	}
+
+	broker := pubsub.NewBroker()
+	if cfg.Collector.Enabled {
+		dataCollector := datacollector.NewDataCollector(cfg.Collector, service, cfg.OpenWeather.APIKey, broker, client)
+		manager.Add(lifecycle.Component{
+			Name:  "data collector",
+			Start: dataCollector.Start,
+			Stop:  dataCollector.Stop,
+		})
+	} else {
+		updateWatcher := updatewatcher.NewUpdateWatcher(service, broker, cfg.Server.UpdatePollInterval.Duration)
+		manager.Add(lifecycle.Component{
+			Name:  "update watcher",
+			Start: updateWatcher.Start,
+			Stop:  updateWatcher.Stop,
+		})
+	}
+
+	srv := server.NewServer(cfg.Server.Port, handler.NewHandler(service, broker, handler.Options{
+		Limits:         cfg.RateLimit.Limits(),
+		AllowedOrigins: cfg.Server.AllowedOrigins,
+		TrustedProxies: cfg.Server.TrustedProxies,
+	}).InitRoutes())
+	manager.Add(lifecycle.Component{
+		Name: "http server",
+		Start: func(ctx context.Context) error {
+			logrus.Print("WebApp Started")
+			return srv.Run()
+		},
+		Stop: func(ctx context.Context) error {
+			// Streams and WebSockets end with their subscriptions.
+			broker.Close()
+			return srv.Shutdown(ctx)
+		},
+	})
+
+	ctx, stop := signalContext()
+	defer stop()
+	err = manager.Run(ctx)
+	logrus.Println("WebApp Stopped")
+	return err
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"weather-app/config"
+	"weather-app/internal/models"
+	"weather-app/internal/provider"
+
+	"golang.org/x/term"
+)
+
+const usersUsage = `usage: users [-config FILE] create-admin -login LOGIN [-email EMAIL]
+
+the password is taken from ADMIN_PASSWORD, prompted for on a terminal or
+read from the first line of standard input`
+
+// adminPasswordEnv names the variable create-admin takes the password from.
+// The password is never a flag, which would show in the shell history and
+// the process list.
+const adminPasswordEnv = "ADMIN_PASSWORD"
+
+func runUsers(args []string) error {
+	cfg, args, err := config.Load("users", args)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 || args[0] != "create-admin" {
+		return errors.New(usersUsage)
+	}
+
+	fs := flag.NewFlagSet("users create-admin", flag.ContinueOnError)
+	login := fs.String("login", "", "Administrator login")
+	email := fs.String("email", "", "Administrator email")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *login == "" || fs.NArg() != 0 {
+		return errors.New(usersUsage)
+	}
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	client := provider.NewClient(cfg.OpenWeather.Provider())
+	service, closeServices, err := newServices(cfg, client)
+	if err != nil {
+		return err
+	}
+	defer closeStorage(closeServices)
+
+	id, err := service.UserService.CreateUser(context.Background(), models.User{
+		Login:    *login,
+		Password: password,
+		Email:    *email,
+		Role:     models.RoleAdmin,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Administrator %s created with id %d\n", *login, id)
+	return nil
+}
+
+// readPassword takes the administrator password from ADMIN_PASSWORD, a
+// prompt without echo when stdin is a terminal, or the first line of stdin
+// otherwise.
+func readPassword() (string, error) {
+	if password, ok := os.LookupEnv(adminPasswordEnv); ok {
+		if password == "" {
+			return "", fmt.Errorf("%s is empty", adminPasswordEnv)
+		}
+		return password, nil
+	}
+
+	stdin := int(os.Stdin.Fd())
+	if !term.IsTerminal(stdin) {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		password := strings.TrimRight(line, "\r\n")
+		if password == "" {
+			return "", fmt.Errorf("failed to read the password from stdin: %v", err)
+		}
+		return password, nil
+	}
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(stdin)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	repeated, err := term.ReadPassword(stdin)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	if len(password) == 0 {
+		return "", errors.New("the password is empty")
+	}
+	if string(password) != string(repeated) {
+		return "", errors.New("the passwords do not match")
+	}
+	return string(password), nil
+}
@@ -1,57 +1,693 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"flag"
+	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"weather-app/internal/provider"
+	"weather-app/internal/ratelimit"
 	"weather-app/internal/repository/postgres"
+	"weather-app/internal/repository/sqlite"
+	healthservice "weather-app/internal/service/health_service"
+	userservice "weather-app/internal/service/user_service"
+	webhookservice "weather-app/internal/service/webhook_service"
+	"weather-app/internal/tracing"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+	DriverSqlite   = "sqlite"
+
+	defaultSqlitePath = "weather.db"
+	redacted          = "******"
 )
 
-func LoadOpenWeatherAPIKey() (string, error) {
-	apiKey := os.Getenv("OPENWEATHER_API_KEY")
-	return apiKey, nil
+// Config is the effective configuration of the application. It is built from
+// defaults, an optional YAML or TOML file, environment variables and command
+// line flags, each overriding the previous one.
+type Config struct {
+	Server      ServerConfig      `yaml:"server" toml:"server"`
+	Database    DBConfig          `yaml:"database" toml:"database"`
+	OpenWeather OpenWeatherConfig `yaml:"openweather" toml:"openweather"`
+	Collector   CollectorConfig   `yaml:"collector" toml:"collector"`
+	Retention   RetentionConfig   `yaml:"retention" toml:"retention"`
+	Cache       CacheConfig       `yaml:"cache" toml:"cache"`
+	RateLimit   RateLimitConfig   `yaml:"rate_limit" toml:"rate_limit"`
+	Login       LoginConfig       `yaml:"login" toml:"login"`
+	Health      HealthConfig      `yaml:"health" toml:"health"`
+	Tracing     TracingConfig     `yaml:"tracing" toml:"tracing"`
+	Webhooks    WebhooksConfig    `yaml:"webhooks" toml:"webhooks"`
+}
+
+type ServerConfig struct {
+	Port string `yaml:"port" toml:"port"`
+	// AllowedOrigins are the web origins, such as https://dash.example.com,
+	// whose pages may open WebSockets to the server besides its own.
+	AllowedOrigins []string `yaml:"allowed_origins" toml:"allowed_origins"`
+	// TrustedProxies are the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For header gives the client address. Empty trusts
+	// none, and clients are known by the address they connect from.
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+	// ShutdownTimeout bounds the graceful shutdown: draining HTTP requests and
+	// finishing the collector's and dispatcher's work in flight.
+	ShutdownTimeout Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	// UpdatePollInterval is how often a server without its own collector
+	// looks for forecasts and alerts stored by a collector in another process
+	// to push them to streams and WebSockets.
+	UpdatePollInterval Duration `yaml:"update_poll_interval" toml:"update_poll_interval"`
+}
+
+type DBConfig struct {
+	Driver string `yaml:"driver" toml:"driver"`
+	// AutoMigrate applies pending database migrations before the server starts.
+	AutoMigrate bool                `yaml:"auto_migrate" toml:"auto_migrate"`
+	Postgres    postgres.PGConfig   `yaml:"postgres" toml:"postgres"`
+	Sqlite      sqlite.SqliteConfig `yaml:"sqlite" toml:"sqlite"`
+}
+
+type OpenWeatherConfig struct {
+	APIKey string `yaml:"api_key" toml:"api_key"`
+	// Timeout bounds each request to the provider.
+	Timeout Duration `yaml:"timeout" toml:"timeout"`
+	// Retries is how many times requests failing with 429, 5xx or a network
+	// error are repeated.
+	Retries int           `yaml:"retries" toml:"retries"`
+	Breaker BreakerConfig `yaml:"breaker" toml:"breaker"`
+	Limits  LimitsConfig  `yaml:"limits" toml:"limits"`
+}
+
+// BreakerConfig suspends requests for Cooldown after Failures failed requests
+// in a row. Zero failures disables it.
+type BreakerConfig struct {
+	Failures int      `yaml:"failures" toml:"failures"`
+	Cooldown Duration `yaml:"cooldown" toml:"cooldown"`
+}
+
+// LimitsConfig holds the limits of the API key. Zero values disable a limit.
+type LimitsConfig struct {
+	RequestsPerMinute int `yaml:"requests_per_minute" toml:"requests_per_minute"`
+	Burst             int `yaml:"burst" toml:"burst"`
+	DailyQuota        int `yaml:"daily_quota" toml:"daily_quota"`
+}
+
+// Provider returns the settings of the provider client.
+func (c OpenWeatherConfig) Provider() provider.Config {
+	return provider.Config{
+		Timeout:           c.Timeout.Duration,
+		Retries:           c.Retries,
+		BreakerFailures:   c.Breaker.Failures,
+		BreakerCooldown:   c.Breaker.Cooldown.Duration,
+		RequestsPerMinute: c.Limits.RequestsPerMinute,
+		Burst:             c.Limits.Burst,
+		DailyQuota:        c.Limits.DailyQuota,
+	}
+}
+
+// CollectorConfig holds the data collector settings. Each city is updated on
+// its own interval: the one an admin set for it, or one picked by the number of
+// users who have it in favorites.
+type CollectorConfig struct {
+	// Enabled starts the data collector inside the server process.
+	Enabled    bool   `yaml:"enabled" toml:"enabled"`
+	CitiesFile string `yaml:"cities_file" toml:"cities_file"`
+	// UpdateInterval applies to cities in favorites of at least one user.
+	UpdateInterval Duration `yaml:"update_interval" toml:"update_interval"`
+	// PopularInterval applies to cities in favorites of PopularFavorites or more users.
+	PopularInterval  Duration `yaml:"popular_interval" toml:"popular_interval"`
+	PopularFavorites int      `yaml:"popular_favorites" toml:"popular_favorites"`
+	// IdleInterval applies to cities nobody has in favorites.
+	IdleInterval Duration `yaml:"idle_interval" toml:"idle_interval"`
+	Parallel     bool     `yaml:"parallel" toml:"parallel"`
+	// Workers is the number of cities fetched at once in parallel mode.
+	Workers int `yaml:"workers" toml:"workers"`
+}
+
+// RetentionConfig controls how long stored data is kept. A zero period keeps
+// the data forever.
+type RetentionConfig struct {
+	// Forecasts is how long forecasts are kept before they are rolled up into
+	// daily aggregates and deleted.
+	Forecasts Duration `yaml:"forecasts" toml:"forecasts"`
+	// DailyForecasts is how long the daily aggregates are kept.
+	DailyForecasts Duration `yaml:"daily_forecasts" toml:"daily_forecasts"`
+	CollectorRuns  Duration `yaml:"collector_runs" toml:"collector_runs"`
+	SignInAttempts Duration `yaml:"sign_in_attempts" toml:"sign_in_attempts"`
+	// LoginFailures is how long the failed sign-ins of a login or an address
+	// are kept after the last one. They stop counting after login.lockout,
+	// which it must not be shorter than.
+	LoginFailures Duration `yaml:"login_failures" toml:"login_failures"`
+	// Interval is how often the server prunes expired data.
+	Interval Duration `yaml:"interval" toml:"interval"`
+	// BatchSize is the number of rows deleted per transaction, which keeps
+	// locks short.
+	BatchSize int `yaml:"batch_size" toml:"batch_size"`
+}
+
+// CacheConfig controls the in-process cache of forecast responses. Cached
+// forecasts of a city are dropped when new ones are stored by the same
+// process; TTL bounds how stale they get when another process collects them.
+type CacheConfig struct {
+	// Size is the number of responses kept. Zero disables the cache.
+	Size int      `yaml:"size" toml:"size"`
+	TTL  Duration `yaml:"ttl" toml:"ttl"`
+}
+
+// RateLimitConfig limits the requests of each client: a signed-in user, or an
+// IP address otherwise.
+type RateLimitConfig struct {
+	// Default applies across all routes without a limit of their own.
+	Default RouteLimit `yaml:"default" toml:"default"`
+	// Routes set limits of single routes, keyed by method and path as in
+	// "POST /auth/sign-in". Each route has a budget of its own.
+	Routes map[string]RouteLimit `yaml:"routes" toml:"routes"`
+}
+
+// RouteLimit allows RequestsPerMinute requests with bursts of up to Burst.
+// Zero requests per minute disables the limit.
+type RouteLimit struct {
+	RequestsPerMinute int `yaml:"requests_per_minute" toml:"requests_per_minute"`
+	Burst             int `yaml:"burst" toml:"burst"`
+}
+
+// Limits returns the request limits of the HTTP API.
+func (c RateLimitConfig) Limits() ratelimit.Limits {
+	limits := ratelimit.Limits{
+		Default: ratelimit.Rule(c.Default),
+		Routes:  make(map[string]ratelimit.Rule, len(c.Routes)),
+	}
+	for route, limit := range c.Routes {
+		limits.Routes[route] = ratelimit.Rule(limit)
+	}
+	return limits
+}
+
+// LoginConfig protects sign-in from password guessing. Failed sign-ins are
+// counted per login and per IP address and forgotten after Lockout.
+type LoginConfig struct {
+	// MaxFailures locks a login out for Lockout after as many failed sign-ins
+	// in a row. Zero disables the lockout. Neither the lockout nor the delays
+	// of a login apply to addresses it signed in from within
+	// retention.sign_in_attempts, so failures of others cannot lock a user
+	// out; guesses from those addresses are limited by MaxIPFailures only.
+	MaxFailures int `yaml:"max_failures" toml:"max_failures"`
+	// MaxIPFailures locks an IP address out the same way.
+	MaxIPFailures int      `yaml:"max_ip_failures" toml:"max_ip_failures"`
+	Lockout       Duration `yaml:"lockout" toml:"lockout"`
+	// Delay is the wait after a failed sign-in before the login may try
+	// again. It doubles with every further failure, up to MaxDelay. Zero
+	// disables the delays.
+	Delay    Duration `yaml:"delay" toml:"delay"`
+	MaxDelay Duration `yaml:"max_delay" toml:"max_delay"`
+}
+
+// Policy returns the sign-in protection of the user service.
+func (c LoginConfig) Policy() userservice.LoginPolicy {
+	return userservice.LoginPolicy{
+		MaxFailures:   c.MaxFailures,
+		MaxIPFailures: c.MaxIPFailures,
+		Lockout:       c.Lockout.Duration,
+		Delay:         c.Delay.Duration,
+		MaxDelay:      c.MaxDelay.Duration,
+	}
+}
+
+// HealthConfig controls the readiness checks of the server.
+type HealthConfig struct {
+	// MaxForecastAge is how old the newest stored forecasts may get before
+	// the server reports it is not ready. Zero disables the check.
+	MaxForecastAge Duration `yaml:"max_forecast_age" toml:"max_forecast_age"`
+	// Timeout bounds all readiness checks together.
+	Timeout Duration `yaml:"timeout" toml:"timeout"`
+}
+
+// Policy returns the readiness checks of storage migrated to schemaVersion.
+func (c HealthConfig) Policy(schemaVersion uint) healthservice.ReadinessPolicy {
+	return healthservice.ReadinessPolicy{
+		SchemaVersion:  schemaVersion,
+		MaxForecastAge: c.MaxForecastAge.Duration,
+		Timeout:        c.Timeout.Duration,
+	}
+}
+
+// TracingConfig controls where the spans of requests, queries and provider
+// calls are exported.
+type TracingConfig struct {
+	// Exporter is none, stdout or otlp. Trace ids are generated and shown in
+	// logs and error responses even with none.
+	Exporter    string `yaml:"exporter" toml:"exporter"`
+	ServiceName string `yaml:"service_name" toml:"service_name"`
+	// Endpoint is the host:port of the OTLP/HTTP collector. Empty uses
+	// OTEL_EXPORTER_OTLP_ENDPOINT, then localhost:4318.
+	Endpoint string `yaml:"endpoint" toml:"endpoint"`
+	// Insecure exports to the collector over plain HTTP.
+	Insecure bool `yaml:"insecure" toml:"insecure"`
+	// SampleRatio is the share of traces recorded, from 0 to 1.
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// Tracing returns the settings of the tracer provider.
+func (c TracingConfig) Tracing() tracing.Config {
+	return tracing.Config{
+		Exporter:    c.Exporter,
+		ServiceName: c.ServiceName,
+		Endpoint:    c.Endpoint,
+		Insecure:    c.Insecure,
+		SampleRatio: c.SampleRatio,
+	}
+}
+
+// WebhooksConfig controls the outbound webhooks of users.
+type WebhooksConfig struct {
+	// AllowPrivateNetworks lets webhooks call loopback, private and link-local
+	// addresses. Leave it off unless every user is trusted.
+	AllowPrivateNetworks bool `yaml:"allow_private_networks" toml:"allow_private_networks"`
 }
 
-type CollectorFlags struct {
-	ToStart    bool
-	Filename   string
-	UpdateTime time.Duration
-	Parallel   bool
+// Policy returns the delivery policy of the webhook service.
+func (c WebhooksConfig) Policy() webhookservice.DeliveryPolicy {
+	return webhookservice.DeliveryPolicy{AllowPrivateNetworks: c.AllowPrivateNetworks}
 }
 
-func ParseCollectorFlags() (CollectorFlags, error) {
-	var (
-		toStart    bool
-		filename   string
-		updateTime string
-		parallel   bool
-	)
-	flag.BoolVar(&toStart, "s", false, "Start data collector")
-	flag.StringVar(&filename, "f", "", "File containing list of cities")
-	flag.StringVar(&updateTime, "u", "1m", "Update interval")
-	flag.BoolVar(&parallel, "p", false, "Enable parallel mode")
-	flag.Parse()
+// Duration is a time.Duration written as "1m30s" in config files.
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
 
-	interval, err := time.ParseDuration(updateTime)
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
 	if err != nil {
-		return CollectorFlags{}, err
-	}
-
-	return CollectorFlags{
-		ToStart:    toStart,
-		Filename:   filename,
-		UpdateTime: interval,
-		Parallel:   parallel,
-	}, nil
-}
-
-func LoadPGConfig() (postgres.PGConfig, error) {
-	return postgres.PGConfig{
-		Host:     os.Getenv("DB_HOST"),
-		Port:     os.Getenv("DB_PORT"),
-		Username: os.Getenv("DB_USER"),
-		Password: os.Getenv("DB_PSWD"),
-		DBName:   os.Getenv("DB_NAME"),
-		SSLMode:  os.Getenv("DB_SSLMODE"),
-	}, nil
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
+
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{ShutdownTimeout: Duration{15 * time.Second}, UpdatePollInterval: Duration{30 * time.Second}},
+		Database: DBConfig{
+			Driver:   DriverPostgres,
+			Postgres: postgres.PGConfig{Port: "5432"},
+			Sqlite:   sqlite.SqliteConfig{Path: defaultSqlitePath},
+		},
+		// The limits of the free OpenWeather plan: 60 calls a minute and
+		// 1,000,000 a month.
+		OpenWeather: OpenWeatherConfig{
+			Timeout: Duration{10 * time.Second},
+			Retries: 3,
+			Breaker: BreakerConfig{Failures: 5, Cooldown: Duration{time.Minute}},
+			Limits:  LimitsConfig{RequestsPerMinute: 55, Burst: 5, DailyQuota: 30000},
+		},
+		Collector: CollectorConfig{
+			UpdateInterval:   Duration{30 * time.Minute},
+			PopularInterval:  Duration{10 * time.Minute},
+			PopularFavorites: 5,
+			IdleInterval:     Duration{time.Hour},
+			Workers:          10,
+		},
+		Retention: RetentionConfig{
+			Forecasts:      Duration{30 * 24 * time.Hour},
+			DailyForecasts: Duration{2 * 365 * 24 * time.Hour},
+			CollectorRuns:  Duration{90 * 24 * time.Hour},
+			SignInAttempts: Duration{90 * 24 * time.Hour},
+			LoginFailures:  Duration{24 * time.Hour},
+			Interval:       Duration{time.Hour},
+			BatchSize:      1000,
+		},
+		Cache: CacheConfig{Size: 1000, TTL: Duration{5 * time.Minute}},
+		// Signing in is limited much more strictly to slow down credential
+		// stuffing.
+		RateLimit: RateLimitConfig{
+			Default: RouteLimit{RequestsPerMinute: 120, Burst: 30},
+			Routes: map[string]RouteLimit{
+				"POST /auth/sign-in": {RequestsPerMinute: 5, Burst: 5},
+				"POST /auth/sign-up": {RequestsPerMinute: 10, Burst: 5},
+			},
+		},
+		Login: LoginConfig{
+			MaxFailures:   5,
+			MaxIPFailures: 20,
+			Lockout:       Duration{15 * time.Minute},
+			Delay:         Duration{time.Second},
+			MaxDelay:      Duration{30 * time.Second},
+		},
+		// Cities nobody follows are updated hourly, so the newest forecasts
+		// are at most an hour old while the collector runs.
+		Health:  HealthConfig{MaxForecastAge: Duration{2 * time.Hour}, Timeout: Duration{5 * time.Second}},
+		Tracing: TracingConfig{Exporter: tracing.ExporterNone, ServiceName: "weather-app", SampleRatio: 1},
+	}
+}
+
+// Load builds the configuration of a subcommand and returns the arguments
+// left after its flags. Every subcommand accepts -config (CONFIG_FILE by
+// default); serve, collect and collect-once also accept the collector flags.
+func Load(command string, args []string) (*Config, []string, error) {
+	fs := flag.NewFlagSet(command, flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "YAML or TOML configuration file")
+	switch command {
+	case "serve":
+		fs.Bool("s", false, "Start data collector")
+		fs.Bool("m", false, "Apply database migrations on start")
+		registerCollectorFlags(fs)
+	case "collect", "collect-once":
+		registerCollectorFlags(fs)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := Default()
+	if *configFile != "" {
+		if err := cfg.loadFile(*configFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, nil, err
+	}
+	if err := cfg.applyFlags(fs); err != nil {
+		return nil, nil, err
+	}
+	return cfg, fs.Args(), nil
+}
+
+func registerCollectorFlags(fs *flag.FlagSet) {
+	fs.String("f", "", "File containing list of cities")
+	fs.String("u", "30m", "Update interval of cities in favorites")
+	fs.Bool("p", false, "Enable parallel mode")
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		err = decoder.Decode(c)
+	case ".toml":
+		decoder := toml.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(c)
+	default:
+		return fmt.Errorf("config file %s: unsupported format, use .yaml, .yml or .toml", path)
+	}
+	if err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides the settings with the environment variables that are set.
+// Empty variables are ignored, as docker-compose passes unset ones that way.
+func (c *Config) loadEnv() error {
+	vars := map[string]*string{
+		"SERVER_PORT":         &c.Server.Port,
+		"DB_DRIVER":           &c.Database.Driver,
+		"DB_HOST":             &c.Database.Postgres.Host,
+		"DB_PORT":             &c.Database.Postgres.Port,
+		"DB_USER":             &c.Database.Postgres.Username,
+		"DB_PSWD":             &c.Database.Postgres.Password,
+		"DB_NAME":             &c.Database.Postgres.DBName,
+		"DB_SSLMODE":          &c.Database.Postgres.SSLMode,
+		"DB_PATH":             &c.Database.Sqlite.Path,
+		"OPENWEATHER_API_KEY": &c.OpenWeather.APIKey,
+		"TRACING_EXPORTER":    &c.Tracing.Exporter,
+	}
+	for name, value := range vars {
+		if env := os.Getenv(name); env != "" {
+			*value = env
+		}
+	}
+	if env := os.Getenv("DB_AUTO_MIGRATE"); env != "" {
+		autoMigrate, err := strconv.ParseBool(env)
+		if err != nil {
+			return fmt.Errorf("DB_AUTO_MIGRATE: %w", err)
+		}
+		c.Database.AutoMigrate = autoMigrate
+	}
+	return nil
+}
+
+// applyFlags overrides the settings with the flags given on the command line.
+func (c *Config) applyFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(f *flag.Flag) {
+		value := f.Value.String()
+		switch f.Name {
+		case "s":
+			c.Collector.Enabled = value == "true"
+		case "m":
+			c.Database.AutoMigrate = value == "true"
+		case "f":
+			c.Collector.CitiesFile = value
+		case "u":
+			if parseErr := c.Collector.UpdateInterval.UnmarshalText([]byte(value)); parseErr != nil {
+				err = fmt.Errorf("flag -u: %w", parseErr)
+			}
+		case "p":
+			c.Collector.Parallel = value == "true"
+		}
+	})
+	return err
+}
+
+// Validate checks the settings every command relies on: the storage backend
+// and the format of the values that are set.
+func (c *Config) Validate() error {
+	var errs []error
+	switch c.Database.Driver {
+	case DriverPostgres:
+		pg := c.Database.Postgres
+		if pg.Host == "" {
+			errs = append(errs, errors.New("database.postgres.host (DB_HOST) is required"))
+		}
+		if err := validatePort(pg.Port); err != nil {
+			errs = append(errs, fmt.Errorf("database.postgres.port (DB_PORT): %w", err))
+		}
+		if pg.Username == "" {
+			errs = append(errs, errors.New("database.postgres.username (DB_USER) is required"))
+		}
+		if pg.DBName == "" {
+			errs = append(errs, errors.New("database.postgres.dbname (DB_NAME) is required"))
+		}
+	case DriverSqlite:
+		if c.Database.Sqlite.Path == "" {
+			errs = append(errs, errors.New("database.sqlite.path (DB_PATH) is required"))
+		}
+	case DriverMemory:
+	default:
+		errs = append(errs, fmt.Errorf("database.driver (DB_DRIVER): unknown driver %q", c.Database.Driver))
+	}
+	if c.Server.Port != "" {
+		if err := validatePort(c.Server.Port); err != nil {
+			errs = append(errs, fmt.Errorf("server.port (SERVER_PORT): %w", err))
+		}
+	}
+	for _, origin := range c.Server.AllowedOrigins {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+			errs = append(errs, fmt.Errorf("server.allowed_origins: %q must be written as scheme://host[:port]", origin))
+		}
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				errs = append(errs, fmt.Errorf("server.trusted_proxies: %q is neither an IP address nor a CIDR range", proxy))
+			}
+		}
+	}
+	intervals := []struct {
+		name  string
+		value Duration
+	}{
+		{"collector.update_interval", c.Collector.UpdateInterval},
+		{"collector.popular_interval", c.Collector.PopularInterval},
+		{"collector.idle_interval", c.Collector.IdleInterval},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+		{"server.update_poll_interval", c.Server.UpdatePollInterval},
+		{"retention.interval", c.Retention.Interval},
+		{"health.timeout", c.Health.Timeout},
+	}
+	for _, interval := range intervals {
+		if interval.value.Duration <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive", interval.name))
+		}
+	}
+	if c.Collector.PopularFavorites < 1 {
+		errs = append(errs, errors.New("collector.popular_favorites must be at least 1"))
+	}
+	if c.Collector.Workers < 1 {
+		errs = append(errs, errors.New("collector.workers must be at least 1"))
+	}
+	periods := []struct {
+		name  string
+		value Duration
+	}{
+		{"retention.forecasts", c.Retention.Forecasts},
+		{"retention.daily_forecasts", c.Retention.DailyForecasts},
+		{"retention.collector_runs", c.Retention.CollectorRuns},
+		{"retention.sign_in_attempts", c.Retention.SignInAttempts},
+		{"retention.login_failures", c.Retention.LoginFailures},
+		{"login.delay", c.Login.Delay},
+		{"login.max_delay", c.Login.MaxDelay},
+		{"health.max_forecast_age", c.Health.MaxForecastAge},
+	}
+	for _, period := range periods {
+		if period.value.Duration < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", period.name))
+		}
+	}
+	if c.Retention.DailyForecasts.Duration > 0 && (c.Retention.Forecasts.Duration == 0 || c.Retention.DailyForecasts.Duration < c.Retention.Forecasts.Duration) {
+		errs = append(errs, errors.New("retention.daily_forecasts must not be shorter than retention.forecasts"))
+	}
+	if c.Retention.LoginFailures.Duration > 0 && c.Retention.LoginFailures.Duration < c.Login.Lockout.Duration {
+		errs = append(errs, errors.New("retention.login_failures must not be shorter than login.lockout"))
+	}
+	if c.Retention.BatchSize < 1 {
+		errs = append(errs, errors.New("retention.batch_size must be at least 1"))
+	}
+	if c.Cache.Size < 0 {
+		errs = append(errs, errors.New("cache.size must not be negative"))
+	}
+	if c.Cache.Size > 0 && c.Cache.TTL.Duration <= 0 {
+		errs = append(errs, errors.New("cache.ttl must be positive"))
+	}
+	if c.RateLimit.Default.RequestsPerMinute < 0 || c.RateLimit.Default.Burst < 0 {
+		errs = append(errs, errors.New("rate_limit.default must not be negative"))
+	}
+	// Routes are checked in order, so the errors are reported consistently.
+	routes := make([]string, 0, len(c.RateLimit.Routes))
+	for route := range c.RateLimit.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		limit := c.RateLimit.Routes[route]
+		method, path, ok := strings.Cut(route, " ")
+		if !ok || method == "" || !strings.HasPrefix(path, "/") {
+			errs = append(errs, fmt.Errorf(`rate_limit.routes: %q must be written as "METHOD /path"`, route))
+		}
+		if limit.RequestsPerMinute < 0 || limit.Burst < 0 {
+			errs = append(errs, fmt.Errorf("rate_limit.routes: limit of %q must not be negative", route))
+		}
+	}
+	limits := []struct {
+		name  string
+		value int
+	}{
+		{"openweather.retries", c.OpenWeather.Retries},
+		{"openweather.breaker.failures", c.OpenWeather.Breaker.Failures},
+		{"openweather.limits.requests_per_minute", c.OpenWeather.Limits.RequestsPerMinute},
+		{"openweather.limits.burst", c.OpenWeather.Limits.Burst},
+		{"openweather.limits.daily_quota", c.OpenWeather.Limits.DailyQuota},
+		{"login.max_failures", c.Login.MaxFailures},
+		{"login.max_ip_failures", c.Login.MaxIPFailures},
+	}
+	for _, limit := range limits {
+		if limit.value < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative", limit.name))
+		}
+	}
+	if c.OpenWeather.Timeout.Duration < 0 {
+		errs = append(errs, errors.New("openweather.timeout must not be negative"))
+	}
+	if c.OpenWeather.Breaker.Failures > 0 && c.OpenWeather.Breaker.Cooldown.Duration <= 0 {
+		errs = append(errs, errors.New("openweather.breaker.cooldown must be positive"))
+	}
+	// Failures are forgotten after the lockout period, so it is needed by the
+	// delays as well.
+	if (c.Login.MaxFailures > 0 || c.Login.MaxIPFailures > 0 || c.Login.Delay.Duration > 0) && c.Login.Lockout.Duration <= 0 {
+		errs = append(errs, errors.New("login.lockout must be positive"))
+	}
+	if c.Login.Delay.Duration > 0 && c.Login.MaxDelay.Duration < c.Login.Delay.Duration {
+		errs = append(errs, errors.New("login.max_delay must not be shorter than login.delay"))
+	}
+	switch c.Tracing.Exporter {
+	case tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOTLP:
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter (TRACING_EXPORTER): unknown exporter %q", c.Tracing.Exporter))
+	}
+	if c.Tracing.ServiceName == "" {
+		errs = append(errs, errors.New("tracing.service_name is required"))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateServer additionally requires the settings of the HTTP server and,
+// if it runs inside the server, of the data collector.
+func (c *Config) ValidateServer() error {
+	var errs []error
+	if err := c.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.Server.Port == "" {
+		errs = append(errs, errors.New("server.port (SERVER_PORT) is required"))
+	}
+	if c.Collector.Enabled && c.OpenWeather.APIKey == "" {
+		errs = append(errs, errors.New("openweather.api_key (OPENWEATHER_API_KEY) is required to run the collector"))
+	}
+	return errors.Join(errs...)
+}
+
+// ValidateCollector additionally requires the settings of the data collector.
+func (c *Config) ValidateCollector() error {
+	var errs []error
+	if err := c.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if c.OpenWeather.APIKey == "" {
+		errs = append(errs, errors.New("openweather.api_key (OPENWEATHER_API_KEY) is required to run the collector"))
+	}
+	return errors.Join(errs...)
+}
+
+func validatePort(port string) error {
+	if port == "" {
+		return errors.New("is required")
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q", port)
+	}
+	return nil
+}
+
+// Redacted returns a copy of the configuration with secrets hidden, suitable
+// for printing.
+func (c Config) Redacted() Config {
+	if c.Database.Postgres.Password != "" {
+		c.Database.Postgres.Password = redacted
+	}
+	if c.OpenWeather.APIKey != "" {
+		c.OpenWeather.APIKey = redacted
+	}
+	return c
+}
+
+// YAML renders the configuration in the format accepted by Load.
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(c)
 }
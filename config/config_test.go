@@ -0,0 +1,279 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"weather-app/internal/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ConfigTestSuite struct {
+	suite.Suite
+	dir string
+}
+
+func (suite *ConfigTestSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+	for _, name := range []string{
+		"CONFIG_FILE", "SERVER_PORT", "DB_DRIVER", "DB_HOST", "DB_PORT", "DB_USER", "DB_PSWD",
+		"DB_NAME", "DB_SSLMODE", "DB_PATH", "DB_AUTO_MIGRATE", "OPENWEATHER_API_KEY", "TRACING_EXPORTER",
+	} {
+		suite.T().Setenv(name, "")
+	}
+}
+
+func (suite *ConfigTestSuite) writeFile(name, content string) string {
+	path := filepath.Join(suite.dir, name)
+	suite.Require().NoError(os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func (suite *ConfigTestSuite) TestLoadDefaults() {
+	cfg, rest, err := Load("migrate", []string{"up"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []string{"up"}, rest)
+	assert.Equal(suite.T(), DriverPostgres, cfg.Database.Driver)
+	assert.Equal(suite.T(), "5432", cfg.Database.Postgres.Port)
+	assert.Equal(suite.T(), 30*time.Minute, cfg.Collector.UpdateInterval.Duration)
+	assert.Equal(suite.T(), 10*time.Minute, cfg.Collector.PopularInterval.Duration)
+	assert.Equal(suite.T(), time.Hour, cfg.Collector.IdleInterval.Duration)
+	assert.Equal(suite.T(), 10, cfg.Collector.Workers)
+	assert.Equal(suite.T(), 15*time.Second, cfg.Server.ShutdownTimeout.Duration)
+	assert.Equal(suite.T(), 30*time.Second, cfg.Server.UpdatePollInterval.Duration)
+	assert.Equal(suite.T(), 55, cfg.OpenWeather.Limits.RequestsPerMinute)
+	assert.Equal(suite.T(), 30000, cfg.OpenWeather.Limits.DailyQuota)
+	assert.Equal(suite.T(), 10*time.Second, cfg.OpenWeather.Timeout.Duration)
+	assert.Equal(suite.T(), 3, cfg.OpenWeather.Retries)
+	assert.Equal(suite.T(), 5, cfg.OpenWeather.Breaker.Failures)
+	assert.Equal(suite.T(), 30*24*time.Hour, cfg.Retention.Forecasts.Duration)
+	assert.Equal(suite.T(), 2*365*24*time.Hour, cfg.Retention.DailyForecasts.Duration)
+	assert.Equal(suite.T(), 1000, cfg.Retention.BatchSize)
+	assert.Equal(suite.T(), 1000, cfg.Cache.Size)
+	assert.Equal(suite.T(), 5*time.Minute, cfg.Cache.TTL.Duration)
+	assert.Equal(suite.T(), RouteLimit{RequestsPerMinute: 5, Burst: 5}, cfg.RateLimit.Routes["POST /auth/sign-in"])
+}
+
+func (suite *ConfigTestSuite) TestLoadPrecedence() {
+	path := suite.writeFile("config.yaml", `
+server:
+  port: "8000"
+database:
+  driver: sqlite
+  sqlite:
+    path: from-file.db
+collector:
+  update_interval: 5m
+  parallel: true
+`)
+	suite.T().Setenv("SERVER_PORT", "9000")
+	suite.T().Setenv("DB_PATH", "from-env.db")
+
+	cfg, _, err := Load("serve", []string{"-config", path, "-u", "30s", "-s"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "9000", cfg.Server.Port)
+	assert.Equal(suite.T(), DriverSqlite, cfg.Database.Driver)
+	assert.Equal(suite.T(), "from-env.db", cfg.Database.Sqlite.Path)
+	assert.Equal(suite.T(), 30*time.Second, cfg.Collector.UpdateInterval.Duration)
+	assert.True(suite.T(), cfg.Collector.Parallel)
+	assert.True(suite.T(), cfg.Collector.Enabled)
+}
+
+func (suite *ConfigTestSuite) TestLoadTOML() {
+	path := suite.writeFile("config.toml", `
+[database]
+driver = "memory"
+
+[openweather]
+api_key = "key"
+
+[collector]
+update_interval = "2m"
+`)
+	suite.T().Setenv("CONFIG_FILE", path)
+
+	cfg, _, err := Load("collect", nil)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), DriverMemory, cfg.Database.Driver)
+	assert.Equal(suite.T(), "key", cfg.OpenWeather.APIKey)
+	assert.Equal(suite.T(), 2*time.Minute, cfg.Collector.UpdateInterval.Duration)
+	assert.NoError(suite.T(), cfg.ValidateCollector())
+}
+
+func (suite *ConfigTestSuite) TestLoadRejectsUnknownFields() {
+	path := suite.writeFile("config.yaml", "server:\n  prot: 8000\n")
+
+	_, _, err := Load("serve", []string{"-config", path})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *ConfigTestSuite) TestLoadRejectsUnknownFormat() {
+	path := suite.writeFile("config.json", "{}")
+
+	_, _, err := Load("serve", []string{"-config", path})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *ConfigTestSuite) TestValidate() {
+	cfg := Default()
+	err := cfg.Validate()
+	assert.ErrorContains(suite.T(), err, "database.postgres.host")
+	assert.ErrorContains(suite.T(), err, "database.postgres.username")
+
+	cfg.Database.Driver = "mysql"
+	assert.ErrorContains(suite.T(), cfg.Validate(), `unknown driver "mysql"`)
+
+	cfg.Database.Driver = DriverMemory
+	assert.NoError(suite.T(), cfg.Validate())
+	assert.ErrorContains(suite.T(), cfg.ValidateServer(), "server.port")
+
+	cfg.Server.Port = "http"
+	assert.ErrorContains(suite.T(), cfg.Validate(), `invalid port "http"`)
+
+	cfg.Server.Port = "8000"
+	cfg.Collector.Enabled = true
+	assert.ErrorContains(suite.T(), cfg.ValidateServer(), "openweather.api_key")
+	cfg.OpenWeather.APIKey = "key"
+	assert.NoError(suite.T(), cfg.ValidateServer())
+}
+
+func (suite *ConfigTestSuite) TestValidateRetention() {
+	cfg := Default()
+	cfg.Database.Driver = DriverMemory
+	cfg.Retention.DailyForecasts.Duration = 24 * time.Hour
+	assert.ErrorContains(suite.T(), cfg.Validate(), "retention.daily_forecasts must not be shorter than retention.forecasts")
+
+	cfg.Retention.Forecasts.Duration = 0
+	assert.ErrorContains(suite.T(), cfg.Validate(), "retention.daily_forecasts must not be shorter than retention.forecasts")
+
+	cfg.Retention.DailyForecasts.Duration = 0
+	cfg.Retention.LoginFailures.Duration = time.Minute
+	assert.ErrorContains(suite.T(), cfg.Validate(), "retention.login_failures must not be shorter than login.lockout")
+
+	cfg.Retention.LoginFailures.Duration = 0
+	cfg.Retention.CollectorRuns.Duration = -time.Hour
+	cfg.Retention.BatchSize = 0
+	err := cfg.Validate()
+	assert.ErrorContains(suite.T(), err, "retention.collector_runs must not be negative")
+	assert.ErrorContains(suite.T(), err, "retention.batch_size must be at least 1")
+
+	cfg.Retention.CollectorRuns.Duration = 0
+	cfg.Retention.BatchSize = 100
+	assert.NoError(suite.T(), cfg.Validate())
+}
+
+func (suite *ConfigTestSuite) TestValidateCache() {
+	cfg := Default()
+	cfg.Database.Driver = DriverMemory
+	cfg.Cache.TTL.Duration = 0
+	assert.ErrorContains(suite.T(), cfg.Validate(), "cache.ttl must be positive")
+
+	cfg.Cache.Size = -1
+	assert.ErrorContains(suite.T(), cfg.Validate(), "cache.size must not be negative")
+
+	cfg.Cache.Size = 0
+	assert.NoError(suite.T(), cfg.Validate(), "a disabled cache needs no ttl")
+}
+
+func (suite *ConfigTestSuite) TestValidateRateLimit() {
+	cfg := Default()
+	cfg.Database.Driver = DriverMemory
+	cfg.RateLimit.Default.Burst = -1
+	cfg.RateLimit.Routes["/auth/sign-in"] = RouteLimit{RequestsPerMinute: 5}
+	cfg.RateLimit.Routes["GET /api/cities"] = RouteLimit{RequestsPerMinute: -5}
+	err := cfg.Validate()
+	assert.ErrorContains(suite.T(), err, "rate_limit.default must not be negative")
+	assert.ErrorContains(suite.T(), err, `"/auth/sign-in" must be written as "METHOD /path"`)
+	assert.ErrorContains(suite.T(), err, `limit of "GET /api/cities" must not be negative`)
+
+	cfg = Default()
+	cfg.Database.Driver = DriverMemory
+	cfg.RateLimit.Routes["GET /api/cities"] = RouteLimit{RequestsPerMinute: 30, Burst: 10}
+	assert.NoError(suite.T(), cfg.Validate())
+	assert.Equal(suite.T(), ratelimit.Rule{RequestsPerMinute: 30, Burst: 10}, cfg.RateLimit.Limits().Routes["GET /api/cities"])
+}
+
+func (suite *ConfigTestSuite) TestValidateLogin() {
+	cfg := Default()
+	cfg.Database.Driver = DriverMemory
+	cfg.Login.MaxFailures = -1
+	cfg.Login.Lockout.Duration = 0
+	cfg.Login.MaxDelay.Duration = 500 * time.Millisecond
+	err := cfg.Validate()
+	assert.ErrorContains(suite.T(), err, "login.max_failures must not be negative")
+	assert.ErrorContains(suite.T(), err, "login.lockout must be positive")
+	assert.ErrorContains(suite.T(), err, "login.max_delay must not be shorter than login.delay")
+
+	cfg = Default()
+	cfg.Database.Driver = DriverMemory
+	cfg.Login = LoginConfig{}
+	assert.NoError(suite.T(), cfg.Validate(), "disabled protection needs no lockout")
+}
+
+func (suite *ConfigTestSuite) TestValidateHealth() {
+	cfg := Default()
+	cfg.Database.Driver = DriverMemory
+	cfg.Health.MaxForecastAge.Duration = -time.Hour
+	cfg.Health.Timeout.Duration = 0
+	err := cfg.Validate()
+	assert.ErrorContains(suite.T(), err, "health.max_forecast_age must not be negative")
+	assert.ErrorContains(suite.T(), err, "health.timeout must be positive")
+
+	cfg.Health = HealthConfig{Timeout: Duration{time.Second}}
+	assert.NoError(suite.T(), cfg.Validate(), "zero age disables the forecast check")
+	assert.Equal(suite.T(), uint(7), cfg.Health.Policy(7).SchemaVersion)
+}
+
+func (suite *ConfigTestSuite) TestValidateAllowedOrigins() {
+	cfg := Default()
+	cfg.Database.Driver = DriverMemory
+	cfg.Server.AllowedOrigins = []string{"https://dash.example.com", "dash.example.com", "https://dash.example.com/app"}
+	err := cfg.Validate()
+	assert.NotContains(suite.T(), err.Error(), `"https://dash.example.com" must`)
+	assert.ErrorContains(suite.T(), err, `server.allowed_origins: "dash.example.com" must be written as scheme://host[:port]`)
+	assert.ErrorContains(suite.T(), err, `server.allowed_origins: "https://dash.example.com/app" must be written as scheme://host[:port]`)
+}
+
+func (suite *ConfigTestSuite) TestValidateTrustedProxies() {
+	cfg := Default()
+	cfg.Database.Driver = DriverMemory
+	cfg.Server.TrustedProxies = []string{"10.0.0.1", "10.0.0.0/8", "::1", "proxy.local"}
+	err := cfg.Validate()
+	assert.ErrorContains(suite.T(), err, `server.trusted_proxies: "proxy.local" is neither an IP address nor a CIDR range`)
+	assert.NotContains(suite.T(), err.Error(), `"10.0.0`)
+}
+
+func (suite *ConfigTestSuite) TestValidateTracing() {
+	cfg := Default()
+	cfg.Database.Driver = DriverMemory
+	cfg.Tracing.Exporter = "jaeger"
+	cfg.Tracing.SampleRatio = 1.5
+	err := cfg.Validate()
+	assert.ErrorContains(suite.T(), err, `tracing.exporter (TRACING_EXPORTER): unknown exporter "jaeger"`)
+	assert.ErrorContains(suite.T(), err, "tracing.sample_ratio must be between 0 and 1")
+
+	suite.T().Setenv("TRACING_EXPORTER", "otlp")
+	cfg, _, err = Load("serve", nil)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "otlp", cfg.Tracing.Tracing().Exporter)
+}
+
+func (suite *ConfigTestSuite) TestRedacted() {
+	cfg := Default()
+	cfg.Database.Postgres.Password = "password"
+	cfg.OpenWeather.APIKey = "key"
+
+	out, err := cfg.Redacted().YAML()
+	assert.NoError(suite.T(), err)
+	assert.NotContains(suite.T(), string(out), "password\n")
+	assert.NotContains(suite.T(), string(out), "key\n")
+	assert.Contains(suite.T(), string(out), "update_interval: 30m0s")
+	assert.Equal(suite.T(), "password", cfg.Database.Postgres.Password, "the original is not modified")
+}
+
+func TestConfigTestSuite(t *testing.T) {
+	suite.Run(t, new(ConfigTestSuite))
+}
@@ -0,0 +1,11 @@
+// Package db embeds the SQL migrations, so the binary can apply them without
+// the migrate container.
+package db
+
+import "embed"
+
+//go:embed migrations/*.sql
+var PostgresMigrations embed.FS
+
+//go:embed sqlite_migrations/*.sql
+var SqliteMigrations embed.FS
@@ -0,0 +1,22 @@
+create table if not exists alert_rules (
+    id serial,
+    user_id int,
+    city_id int,
+    metric varchar(32),
+    threshold double precision,
+    within_hours int,
+    primary key (id),
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (city_id) references cities(id) on delete cascade
+);
+
+create table if not exists triggered_alerts (
+    id serial,
+    rule_id int,
+    forecast_date timestamp,
+    value double precision,
+    triggered_at timestamp default now(),
+    primary key (id),
+    foreign key (rule_id) references alert_rules(id) on delete cascade,
+    unique (rule_id, forecast_date)
+);
\ No newline at end of file
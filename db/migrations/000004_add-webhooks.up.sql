@@ -0,0 +1,27 @@
+create table if not exists webhooks (
+    id serial,
+    user_id int,
+    url varchar(2048),
+    secret varchar(64),
+    created_at timestamp default now(),
+    primary key (id),
+    foreign key (user_id) references users(id) on delete cascade
+);
+
+create table if not exists webhook_deliveries (
+    id serial,
+    webhook_id int,
+    event varchar(64),
+    payload jsonb,
+    status varchar(16) default 'pending',
+    attempts int default 0,
+    response_status int default 0,
+    last_error text default '',
+    next_attempt_at timestamp default now(),
+    created_at timestamp default now(),
+    delivered_at timestamp,
+    primary key (id),
+    foreign key (webhook_id) references webhooks(id) on delete cascade
+);
+
+create index if not exists webhook_deliveries_pending_idx on webhook_deliveries (next_attempt_at) where status = 'pending';
\ No newline at end of file
@@ -0,0 +1,9 @@
+create table if not exists collector_dead_letters (
+    city_id int,
+    failures int default 0,
+    last_error text,
+    first_failed_at timestamp default now(),
+    last_failed_at timestamp default now(),
+    primary key (city_id),
+    foreign key (city_id) references cities(id) on delete cascade
+);
\ No newline at end of file
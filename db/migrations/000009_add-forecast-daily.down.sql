@@ -0,0 +1,2 @@
+drop index if exists forecasts_date_idx;
+drop table if exists forecast_daily;
\ No newline at end of file
@@ -0,0 +1,12 @@
+create table if not exists forecast_daily (
+    city_id int,
+    date date,
+    temp_min real,
+    temp_max real,
+    temp_avg real,
+    samples int,
+    primary key (city_id, date),
+    foreign key (city_id) references cities(id) on delete cascade
+);
+
+create index if not exists forecasts_date_idx on forecasts (date);
\ No newline at end of file
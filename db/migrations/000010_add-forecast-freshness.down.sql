@@ -0,0 +1,2 @@
+alter table cities drop column forecasts_next_update;
+alter table cities drop column forecasts_updated_at;
\ No newline at end of file
@@ -0,0 +1,20 @@
+create table if not exists login_failures (
+    subject varchar(320),
+    failures int default 0,
+    last_failed_at timestamp default now(),
+    locked_until timestamp,
+    primary key (subject)
+);
+
+create table if not exists sign_in_attempts (
+    id serial,
+    login varchar(255),
+    ip varchar(64),
+    user_id int,
+    outcome varchar(16),
+    attempted_at timestamp default now(),
+    primary key (id)
+);
+
+create index if not exists sign_in_attempts_login_idx on sign_in_attempts (login);
+create index if not exists sign_in_attempts_attempted_at_idx on sign_in_attempts (attempted_at);
\ No newline at end of file
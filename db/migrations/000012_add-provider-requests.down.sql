@@ -0,0 +1 @@
+drop table if exists provider_requests;
\ No newline at end of file
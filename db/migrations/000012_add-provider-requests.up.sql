@@ -0,0 +1,5 @@
+create table if not exists provider_requests (
+    day date,
+    requests int not null default 0,
+    primary key (day)
+);
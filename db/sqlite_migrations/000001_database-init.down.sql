@@ -0,0 +1,3 @@
+drop table if exists forecasts;
+
+drop table if exists cities;
\ No newline at end of file
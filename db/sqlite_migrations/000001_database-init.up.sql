@@ -0,0 +1,18 @@
+create table if not exists cities (
+    id integer primary key autoincrement,
+    name varchar(255),
+    country varchar(255),
+    latitude double precision,
+    longitude double precision,
+    unique (name, country)
+);
+
+create table if not exists forecasts (
+    id integer primary key autoincrement,
+    city_id int,
+    temp real,
+    date date,
+    forecast_json text,
+    unique (city_id, date),
+    foreign key (city_id) references cities(id) on delete cascade
+);
\ No newline at end of file
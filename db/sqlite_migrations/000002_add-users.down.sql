@@ -0,0 +1,3 @@
+drop table if exists users_cities;
+
+drop table if exists users;
\ No newline at end of file
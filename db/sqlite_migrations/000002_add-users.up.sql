@@ -0,0 +1,15 @@
+create table if not exists users (
+    id integer primary key autoincrement,
+    login varchar(255) unique,
+    password varchar(255),
+    email varchar(255)
+);
+
+create table if not exists users_cities (
+    id integer primary key autoincrement,
+    user_id int,
+    city_id int,
+    foreign key (user_id) references users(id) on delete cascade,
+    foreign key (city_id) references cities(id) on delete cascade,
+    unique (user_id, city_id)
+);
\ No newline at end of file
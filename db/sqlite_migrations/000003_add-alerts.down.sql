@@ -0,0 +1,3 @@
+drop table if exists triggered_alerts;
+
+drop table if exists alert_rules;
\ No newline at end of file
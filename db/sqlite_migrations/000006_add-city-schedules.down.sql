@@ -0,0 +1 @@
+alter table cities drop column update_interval;
\ No newline at end of file
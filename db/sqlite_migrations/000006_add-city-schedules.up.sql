@@ -0,0 +1 @@
+alter table cities add column update_interval int not null default 0;
\ No newline at end of file
@@ -0,0 +1 @@
+drop table if exists collector_runs;
\ No newline at end of file
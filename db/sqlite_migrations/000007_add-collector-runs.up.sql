@@ -0,0 +1,14 @@
+create table if not exists collector_runs (
+    id integer primary key autoincrement,
+    triggered_by varchar(16),
+    started_at timestamp default current_timestamp,
+    finished_at timestamp,
+    cities_attempted int default 0,
+    cities_succeeded int default 0,
+    cities_failed int default 0,
+    cities_skipped int default 0,
+    rows_written int default 0,
+    failures text default '[]'
+);
+
+create index if not exists collector_runs_started_at_idx on collector_runs (started_at);
\ No newline at end of file
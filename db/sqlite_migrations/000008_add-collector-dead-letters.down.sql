@@ -0,0 +1 @@
+drop table if exists collector_dead_letters;
\ No newline at end of file
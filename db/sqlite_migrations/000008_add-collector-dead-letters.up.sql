@@ -0,0 +1,8 @@
+create table if not exists collector_dead_letters (
+    city_id integer primary key,
+    failures int default 0,
+    last_error text,
+    first_failed_at timestamp default current_timestamp,
+    last_failed_at timestamp default current_timestamp,
+    foreign key (city_id) references cities(id) on delete cascade
+);
\ No newline at end of file
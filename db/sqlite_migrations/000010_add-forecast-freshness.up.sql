@@ -0,0 +1,2 @@
+alter table cities add column forecasts_updated_at timestamp;
+alter table cities add column forecasts_next_update timestamp;
\ No newline at end of file
@@ -0,0 +1,2 @@
+drop table if exists sign_in_attempts;
+drop table if exists login_failures;
\ No newline at end of file
@@ -0,0 +1,18 @@
+create table if not exists login_failures (
+    subject varchar(320) primary key,
+    failures int default 0,
+    last_failed_at timestamp default current_timestamp,
+    locked_until timestamp
+);
+
+create table if not exists sign_in_attempts (
+    id integer primary key autoincrement,
+    login varchar(255),
+    ip varchar(64),
+    user_id int,
+    outcome varchar(16),
+    attempted_at timestamp default current_timestamp
+);
+
+create index if not exists sign_in_attempts_login_idx on sign_in_attempts (login);
+create index if not exists sign_in_attempts_attempted_at_idx on sign_in_attempts (attempted_at);
\ No newline at end of file
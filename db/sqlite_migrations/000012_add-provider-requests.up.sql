@@ -0,0 +1,4 @@
+create table if not exists provider_requests (
+    day varchar(10) primary key,
+    requests int not null default 0
+);
@@ -15,21 +15,32 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/api/cities": {
+        "/api/admin/cities/schedules": {
             "get": {
-                "description": "Get the list of cities",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists cities with their admin-set update interval and number of favorites, most popular first. Cities with a zero interval are scheduled automatically by popularity",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "cities"
+                    "admin"
                 ],
-                "summary": "Get cities",
+                "summary": "Get city schedules",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_handler.GetCitiesResponse"
+                            "$ref": "#/definitions/internal_handler.GetCitySchedulesResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     },
                     "500": {
@@ -41,42 +52,91 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/forecast/detailed/{city_id}": {
-            "get": {
-                "description": "Get the detailed forecast for a specific city on a specific date",
+        "/api/admin/cities/{id}/schedule": {
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Sets how often the collector updates the city, in seconds (60 to 86400). 0 returns the city to the automatic schedule",
+                "consumes": [
+                    "application/json"
+                ],
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "forecast"
+                    "admin"
                 ],
-                "summary": "Get detailed forecast",
+                "summary": "Update city schedule",
                 "parameters": [
                     {
                         "type": "integer",
                         "description": "City ID",
-                        "name": "city_id",
+                        "name": "id",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "format": "date",
-                        "description": "Date",
-                        "name": "date",
-                        "in": "query",
-                        "required": true
+                        "description": "City schedule",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_dto.DTOCitySchedule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/collector/dead-letters": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
                     }
                 ],
+                "description": "Lists cities whose latest updates failed several times in a row, with the latest failure reason. A city leaves the list after its next successful update",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Get collector dead letters",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_handler.GetDetailedForecastResponse"
+                            "$ref": "#/definitions/internal_handler.GetDeadLettersResponse"
                         }
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
@@ -90,30 +150,34 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/forecast/short/{city_id}": {
+        "/api/admin/collector/runs": {
             "get": {
-                "description": "Get the short forecast for a specific city",
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lists the latest data collector runs, newest first, with the number of cities attempted, succeeded, failed and skipped, rows written and failure reasons",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "forecast"
+                    "admin"
                 ],
-                "summary": "Get short forecast",
+                "summary": "Get collector runs",
                 "parameters": [
                     {
                         "type": "integer",
-                        "description": "City ID",
-                        "name": "city_id",
-                        "in": "path",
-                        "required": true
+                        "description": "Number of runs, 20 by default and at most 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_handler.GetShortForecastResponse"
+                            "$ref": "#/definitions/internal_handler.GetCollectorRunsResponse"
                         }
                     },
                     "400": {
@@ -122,6 +186,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -131,26 +201,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/users/favorites": {
+        "/api/admin/collector/status": {
             "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Retrieves the list of favorite cities for the authenticated user",
+                "description": "Shows whether a collector runs in this process, the run in progress, the latest finished run and today's provider usage",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "favorites"
+                    "admin"
                 ],
-                "summary": "Get favorite cities",
+                "summary": "Get collector status",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/internal_handler.GetFavoritesResponse"
+                            "$ref": "#/definitions/weather-app_internal_models.CollectorStatus"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     },
                     "500": {
@@ -160,74 +236,77 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
+            }
+        },
+        "/api/admin/collector/trigger": {
             "post": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Adds a city to the user's list of favorite cities",
+                "description": "Asks the data collector to update all cities. The run starts in the background; triggers made while one is pending are merged",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "favorites"
-                ],
-                "summary": "Add favorite city",
-                "parameters": [
-                    {
-                        "type": "integer",
-                        "description": "City ID",
-                        "name": "cityId",
-                        "in": "query",
-                        "required": true
-                    }
+                    "admin"
                 ],
+                "summary": "Trigger collector run",
                 "responses": {
-                    "200": {
-                        "description": "OK"
+                    "202": {
+                        "description": "Accepted"
                     },
-                    "400": {
-                        "description": "Bad Request",
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
                             "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     },
-                    "500": {
-                        "description": "Internal Server Error",
+                    "409": {
+                        "description": "Conflict",
                         "schema": {
                             "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     }
                 }
-            },
-            "delete": {
+            }
+        },
+        "/api/admin/sign-in-attempts": {
+            "get": {
                 "security": [
                     {
                         "ApiKeyAuth": []
                     }
                 ],
-                "description": "Removes a city from the user's list of favorite cities",
+                "description": "Lists the latest sign-in attempts, newest first, with the address they came from and whether they succeeded, failed, or were refused as throttled or locked",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "favorites"
+                    "admin"
                 ],
-                "summary": "Delete favorite city",
+                "summary": "Get sign-in attempts",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Only attempts to sign in as this login",
+                        "name": "login",
+                        "in": "query"
+                    },
                     {
                         "type": "integer",
-                        "description": "City ID",
-                        "name": "cityId",
-                        "in": "query",
-                        "required": true
+                        "description": "Number of attempts, 20 by default and at most 100",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK"
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GetSignInAttemptsResponse"
+                        }
                     },
                     "400": {
                         "description": "Bad Request",
@@ -235,6 +314,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -244,36 +329,67 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/sign-in": {
-            "post": {
-                "description": "Authenticates a user and returns a JWT token",
-                "consumes": [
-                    "application/json"
+        "/api/admin/users/lockouts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Lists the logins (\"login:\u003clogin\u003e\") and addresses (\"ip:\u003caddress\u003e\") locked out after too many failed sign-ins, with the time the lockouts end",
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Sign in user",
-                "parameters": [
-                    {
-                        "description": "Sign in info",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
+                "summary": "Get sign-in lockouts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/weather-app_internal_dto.DTOSignIn"
+                            "$ref": "#/definitions/internal_handler.GetLockoutsResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     }
+                }
+            }
+        },
+        "/api/admin/users/lockouts/{subject}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Lets a login or an address, given as the subject listed by GET /api/admin/users/lockouts, sign in again right away by forgetting its failed sign-ins",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Delete sign-in lockout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Subject, such as login:alice or ip:203.0.113.7",
+                        "name": "subject",
+                        "in": "path",
+                        "required": true
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/internal_handler.SignInUserResponse"
-                        }
+                        "description": "OK"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -281,6 +397,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -290,36 +418,30 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/sign-up": {
+        "/api/admin/users/{id}/unlock": {
             "post": {
-                "description": "Registers a new user with login, password, and email",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
                 ],
+                "description": "Lets the user sign in again right away by forgetting the failed sign-ins of their login. Lockouts of addresses are lifted with DELETE /api/admin/users/lockouts/{subject}",
                 "tags": [
-                    "auth"
+                    "admin"
                 ],
-                "summary": "Sign up user",
+                "summary": "Unlock user",
                 "parameters": [
                     {
-                        "description": "Sign up info",
-                        "name": "input",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/weather-app_internal_dto.DTOSignUp"
-                        }
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
-                        "schema": {
-                            "$ref": "#/definitions/internal_handler.SignUpUserResponse"
-                        }
+                        "description": "OK"
                     },
                     "400": {
                         "description": "Bad Request",
@@ -327,6 +449,18 @@ const docTemplate = `{
                             "$ref": "#/definitions/internal_handler.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -335,179 +469,1849 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "internal_handler.ErrorResponse": {
-            "type": "object",
-            "properties": {
-                "message": {
-                    "type": "string"
-                }
-            }
         },
-        "internal_handler.GetCitiesResponse": {
-            "type": "object",
+        "/api/cities": {
+            "get": {
+                "description": "Get the list of cities",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cities"
+                ],
+                "summary": "Get cities",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GetCitiesResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/forecast/detailed/{city_id}": {
+            "get": {
+                "description": "Get the detailed forecast for a specific city on a specific date",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forecast"
+                ],
+                "summary": "Get detailed forecast",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "City ID",
+                        "name": "city_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "format": "date",
+                        "description": "Date",
+                        "name": "date",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified of the cached copy",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GetDetailedForecastResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/forecast/history/{city_id}": {
+            "get": {
+                "description": "Get the daily aggregates of forecasts that passed their retention for a specific city",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forecast"
+                ],
+                "summary": "Get forecast history",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "City ID",
+                        "name": "city_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GetForecastHistoryResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/forecast/short/{city_id}": {
+            "get": {
+                "description": "Get the short forecast for a specific city",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "forecast"
+                ],
+                "summary": "Get short forecast",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "City ID",
+                        "name": "city_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ETag of the cached copy",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Last-Modified of the cached copy",
+                        "name": "If-Modified-Since",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GetShortForecastResponse"
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/stream/favorites": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Server-Sent Events stream of forecast updates for the authenticated user's favorite cities at connection time",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "stream"
+                ],
+                "summary": "Stream favorite forecast updates",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_models.ForecastUpdate"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/stream/forecast": {
+            "get": {
+                "description": "Server-Sent Events stream that pushes a \"forecast\" event with models.ForecastUpdate whenever new forecasts are stored for one of the cities. city_id may be repeated or comma-separated",
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "stream"
+                ],
+                "summary": "Stream forecast updates",
+                "parameters": [
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "integer"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "City IDs",
+                        "name": "city_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_models.ForecastUpdate"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/alerts": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the list of alert rules for the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "alerts"
+                ],
+                "summary": "Get alert rules",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GetAlertRulesResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Creates an alert rule on a city. Metric is one of temp_below, temp_above, wind_gust_above, pop_above",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "alerts"
+                ],
+                "summary": "Create alert rule",
+                "parameters": [
+                    {
+                        "description": "Alert rule",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_dto.DTOAlertRule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.CreateAlertRuleResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/alerts/triggered": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves alerts triggered by the user's rules, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "alerts"
+                ],
+                "summary": "Get triggered alerts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GetTriggeredAlertsResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/alerts/{id}": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves an alert rule by ID",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "alerts"
+                ],
+                "summary": "Get alert rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Alert rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_models.AlertRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Replaces the city, metric, threshold and window of an alert rule",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "alerts"
+                ],
+                "summary": "Update alert rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Alert rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Alert rule",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_dto.DTOAlertRule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Deletes an alert rule together with its triggered alerts",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "alerts"
+                ],
+                "summary": "Delete alert rule",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Alert rule ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/favorites": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the list of favorite cities for the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "favorites"
+                ],
+                "summary": "Get favorite cities",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GetFavoritesResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Adds a city to the user's list of favorite cities",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "favorites"
+                ],
+                "summary": "Add favorite city",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "City ID",
+                        "name": "cityId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes a city from the user's list of favorite cities",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "favorites"
+                ],
+                "summary": "Delete favorite city",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "City ID",
+                        "name": "cityId",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the webhook endpoints registered by the authenticated user",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get webhooks",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GetWebhooksResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Registers an endpoint that receives alert.triggered and forecast.updated events. The returned secret is used to verify the X-Webhook-Signature header and is shown only once",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create webhook",
+                "parameters": [
+                    {
+                        "description": "Webhook",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_dto.DTOWebhook"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_models.Webhook"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/webhooks/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Removes a webhook endpoint together with its delivery log",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Delete webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/webhooks/{id}/deliveries": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Retrieves the latest deliveries of a webhook, newest first",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get webhook deliveries",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.GetDeliveriesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/users/webhooks/{id}/test": {
+            "post": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Synchronously sends a signed ping event to the webhook and returns the recorded delivery",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Test webhook",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_models.WebhookDelivery"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/ws": {
+            "get": {
+                "security": [
+                    {
+                        "ApiKeyAuth": []
+                    }
+                ],
+                "description": "Upgrades to a WebSocket. The client sends {\"action\":\"subscribe\"|\"unsubscribe\",\"channel\":\"forecast\"|\"alerts\",\"city_ids\":[...]} and receives forecast, alert and heartbeat messages. Browsers pass the token as the subprotocols \"bearer, \u003ctoken\u003e\". Pages from other origins than the server's and server.allowed_origins are refused",
+                "tags": [
+                    "stream"
+                ],
+                "summary": "WebSocket for live updates",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "bearer, \u003cJWT token\u003e",
+                        "name": "Sec-WebSocket-Protocol",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "Switching Protocols"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sign-in": {
+            "post": {
+                "description": "Authenticates a user and returns a JWT token. After failed sign-ins the login has to wait before trying again, and too many of them lock the login or the address out for a while",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign in user",
+                "parameters": [
+                    {
+                        "description": "Sign in info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_dto.DTOSignIn"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.SignInUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/auth/sign-up": {
+            "post": {
+                "description": "Registers a new user with login, password, and email",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "auth"
+                ],
+                "summary": "Sign up user",
+                "parameters": [
+                    {
+                        "description": "Sign up info",
+                        "name": "input",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_dto.DTOSignUp"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.SignUpUserResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Answers as long as the process serves requests, without checking its dependencies",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/internal_handler.HealthResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks that the database is reachable, migrated to the version this build expects, and that the newest forecasts are not older than health.max_forecast_age. The body lists each check with the reason it failed",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_models.Readiness"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/weather-app_internal_models.Readiness"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "internal_handler.CreateAlertRuleResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "internal_handler.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string"
+                },
+                "trace_id": {
+                    "description": "TraceId identifies the trace of the request, so that the failure can be\nfound in the logs and the tracing backend.",
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.GetAlertRulesResponse": {
+            "type": "object",
+            "properties": {
+                "rules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.AlertRule"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetCitiesResponse": {
+            "type": "object",
+            "properties": {
+                "cities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.City"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetCitySchedulesResponse": {
+            "type": "object",
+            "properties": {
+                "schedules": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.CitySchedule"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetCollectorRunsResponse": {
+            "type": "object",
+            "properties": {
+                "runs": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.CollectorRun"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetDeadLettersResponse": {
+            "type": "object",
+            "properties": {
+                "dead_letters": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.DeadLetter"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetDeliveriesResponse": {
+            "type": "object",
+            "properties": {
+                "deliveries": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.WebhookDelivery"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetDetailedForecastResponse": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "forecasts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.Forecast"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetFavoritesResponse": {
+            "type": "object",
+            "properties": {
+                "cities": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.City"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetForecastHistoryResponse": {
+            "type": "object",
+            "properties": {
+                "days": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.DailyForecast"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetLockoutsResponse": {
+            "type": "object",
+            "properties": {
+                "lockouts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.LoginFailure"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetShortForecastResponse": {
+            "type": "object",
+            "properties": {
+                "forecast": {
+                    "$ref": "#/definitions/weather-app_internal_models.ForecastSummary"
+                }
+            }
+        },
+        "internal_handler.GetSignInAttemptsResponse": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.SignInAttempt"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetTriggeredAlertsResponse": {
+            "type": "object",
+            "properties": {
+                "alerts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.TriggeredAlert"
+                    }
+                }
+            }
+        },
+        "internal_handler.GetWebhooksResponse": {
+            "type": "object",
+            "properties": {
+                "webhooks": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.Webhook"
+                    }
+                }
+            }
+        },
+        "internal_handler.HealthResponse": {
+            "type": "object",
+            "properties": {
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.SignInUserResponse": {
+            "type": "object",
+            "properties": {
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "internal_handler.SignUpUserResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "weather-app_internal_dto.DTOAlertRule": {
+            "type": "object",
+            "properties": {
+                "city_id": {
+                    "type": "integer"
+                },
+                "metric": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "type": "number"
+                },
+                "within_hours": {
+                    "type": "integer"
+                }
+            }
+        },
+        "weather-app_internal_dto.DTOCitySchedule": {
+            "type": "object",
+            "properties": {
+                "update_interval": {
+                    "type": "integer"
+                }
+            }
+        },
+        "weather-app_internal_dto.DTOSignIn": {
+            "type": "object",
+            "properties": {
+                "login": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "weather-app_internal_dto.DTOSignUp": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string"
+                },
+                "login": {
+                    "type": "string"
+                },
+                "password": {
+                    "type": "string"
+                }
+            }
+        },
+        "weather-app_internal_dto.DTOWebhook": {
+            "type": "object",
+            "properties": {
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "weather-app_internal_models.AlertRule": {
+            "description": "Weather alert rule model",
+            "type": "object",
+            "properties": {
+                "city_id": {
+                    "description": "@Description City ID",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "@Description Alert rule ID",
+                    "type": "integer"
+                },
+                "metric": {
+                    "description": "@Description One of temp_below, temp_above, wind_gust_above, pop_above",
+                    "type": "string"
+                },
+                "threshold": {
+                    "description": "@Description Threshold value (pop in percent)",
+                    "type": "number"
+                },
+                "user_id": {
+                    "description": "@Description Owner user ID",
+                    "type": "integer"
+                },
+                "within_hours": {
+                    "description": "@Description Look-ahead window in hours",
+                    "type": "integer"
+                }
+            }
+        },
+        "weather-app_internal_models.City": {
+            "description": "City model",
+            "type": "object",
+            "properties": {
+                "country": {
+                    "description": "@Description Country name",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "@Description City ID",
+                    "type": "integer"
+                },
+                "latitude": {
+                    "description": "@Description Latitude of the city",
+                    "type": "number"
+                },
+                "longitude": {
+                    "description": "@Description Longitude of the city",
+                    "type": "number"
+                },
+                "name": {
+                    "description": "@Description City name",
+                    "type": "string"
+                }
+            }
+        },
+        "weather-app_internal_models.CitySchedule": {
+            "description": "City update schedule model",
+            "type": "object",
+            "properties": {
+                "country": {
+                    "description": "@Description Country name",
+                    "type": "string"
+                },
+                "favorites": {
+                    "description": "@Description Number of users who have the city in favorites",
+                    "type": "integer"
+                },
+                "id": {
+                    "description": "@Description City ID",
+                    "type": "integer"
+                },
+                "latitude": {
+                    "description": "@Description Latitude of the city",
+                    "type": "number"
+                },
+                "longitude": {
+                    "description": "@Description Longitude of the city",
+                    "type": "number"
+                },
+                "name": {
+                    "description": "@Description City name",
+                    "type": "string"
+                },
+                "update_interval": {
+                    "description": "@Description Update interval in seconds set by an admin, 0 for automatic",
+                    "type": "integer"
+                }
+            }
+        },
+        "weather-app_internal_models.CollectorRun": {
+            "description": "Data collector run",
+            "type": "object",
             "properties": {
-                "cities": {
+                "cities_attempted": {
+                    "description": "@Description Cities requested from the provider",
+                    "type": "integer"
+                },
+                "cities_failed": {
+                    "description": "@Description Cities that failed",
+                    "type": "integer"
+                },
+                "cities_skipped": {
+                    "description": "@Description Cities skipped because the provider was unavailable or the collector stopped",
+                    "type": "integer"
+                },
+                "cities_succeeded": {
+                    "description": "@Description Cities whose forecasts were stored",
+                    "type": "integer"
+                },
+                "failures": {
+                    "description": "@Description Failed cities with reasons",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/weather-app_internal_models.City"
+                        "$ref": "#/definitions/weather-app_internal_models.RunFailure"
                     }
+                },
+                "finished_at": {
+                    "description": "@Description End time, null while the run is in progress",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "@Description Run ID",
+                    "type": "integer"
+                },
+                "rows_written": {
+                    "description": "@Description Forecast rows written",
+                    "type": "integer"
+                },
+                "started_at": {
+                    "description": "@Description Start time",
+                    "type": "string"
+                },
+                "triggered_by": {
+                    "description": "@Description One of full, scheduled, manual",
+                    "type": "string"
                 }
             }
         },
-        "internal_handler.GetDetailedForecastResponse": {
+        "weather-app_internal_models.CollectorStatus": {
+            "description": "Data collector status",
+            "type": "object",
+            "properties": {
+                "current_run": {
+                    "description": "@Description Run in progress",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/weather-app_internal_models.CollectorRun"
+                        }
+                    ]
+                },
+                "last_run": {
+                    "description": "@Description Latest finished run",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/weather-app_internal_models.CollectorRun"
+                        }
+                    ]
+                },
+                "provider_daily_quota": {
+                    "description": "@Description Daily provider quota, 0 if unlimited",
+                    "type": "integer"
+                },
+                "provider_requests": {
+                    "description": "@Description Provider requests made today by this process",
+                    "type": "integer"
+                },
+                "provider_unavailable": {
+                    "description": "@Description Why provider requests are refused, if they are",
+                    "type": "string"
+                },
+                "running": {
+                    "description": "@Description Whether a collector runs in this process and accepts triggers",
+                    "type": "boolean"
+                }
+            }
+        },
+        "weather-app_internal_models.DailyForecast": {
+            "description": "Daily forecast aggregate",
+            "type": "object",
+            "properties": {
+                "city_id": {
+                    "description": "@Description City ID",
+                    "type": "integer"
+                },
+                "date": {
+                    "description": "@Description Day",
+                    "type": "string"
+                },
+                "samples": {
+                    "description": "@Description Number of forecasts aggregated",
+                    "type": "integer"
+                },
+                "temp_avg": {
+                    "description": "@Description Average temperature",
+                    "type": "number"
+                },
+                "temp_max": {
+                    "description": "@Description Highest temperature",
+                    "type": "number"
+                },
+                "temp_min": {
+                    "description": "@Description Lowest temperature",
+                    "type": "number"
+                }
+            }
+        },
+        "weather-app_internal_models.DeadLetter": {
+            "description": "City the data collector keeps failing to update",
             "type": "object",
             "properties": {
                 "city": {
+                    "description": "@Description City name",
                     "type": "string"
                 },
-                "forecasts": {
+                "city_id": {
+                    "description": "@Description City ID",
+                    "type": "integer"
+                },
+                "failures": {
+                    "description": "@Description Failed updates in a row",
+                    "type": "integer"
+                },
+                "first_failed_at": {
+                    "description": "@Description First failure in the current streak",
+                    "type": "string"
+                },
+                "last_error": {
+                    "description": "@Description Latest failure reason",
+                    "type": "string"
+                },
+                "last_failed_at": {
+                    "description": "@Description Latest failure",
+                    "type": "string"
+                }
+            }
+        },
+        "weather-app_internal_models.Forecast": {
+            "description": "Weather forecast model",
+            "type": "object",
+            "properties": {
+                "city_id": {
+                    "description": "@Description City ID",
+                    "type": "integer"
+                },
+                "date": {
+                    "description": "@Description Date of the forecast",
+                    "type": "string"
+                },
+                "forecast_json": {
+                    "description": "@Description Raw JSON of the forecast details",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/weather-app_internal_models.Forecast"
+                        "type": "integer"
                     }
+                },
+                "id": {
+                    "description": "@Description Forecast ID",
+                    "type": "integer"
+                },
+                "temp": {
+                    "description": "@Description Temperature",
+                    "type": "number"
                 }
             }
         },
-        "internal_handler.GetFavoritesResponse": {
+        "weather-app_internal_models.ForecastSummary": {
+            "description": "Weather forecast summary",
             "type": "object",
             "properties": {
-                "cities": {
+                "available_dates": {
+                    "description": "@Description Available dates for forecasts",
                     "type": "array",
                     "items": {
-                        "$ref": "#/definitions/weather-app_internal_models.City"
+                        "type": "string"
                     }
+                },
+                "avg_temp": {
+                    "description": "@Description Average Temperature",
+                    "type": "number"
+                },
+                "city": {
+                    "description": "@Description City",
+                    "type": "string"
+                },
+                "country": {
+                    "description": "@Description Country",
+                    "type": "string"
                 }
             }
         },
-        "internal_handler.GetShortForecastResponse": {
+        "weather-app_internal_models.ForecastUpdate": {
+            "description": "Forecast update event",
             "type": "object",
             "properties": {
+                "city_id": {
+                    "description": "@Description City ID",
+                    "type": "integer"
+                },
                 "forecast": {
-                    "$ref": "#/definitions/weather-app_internal_models.ForecastSummary"
+                    "description": "@Description Updated short forecast",
+                    "allOf": [
+                        {
+                            "$ref": "#/definitions/weather-app_internal_models.ForecastSummary"
+                        }
+                    ]
+                },
+                "updated_at": {
+                    "description": "@Description Time the forecasts were stored",
+                    "type": "string"
                 }
             }
         },
-        "internal_handler.SignInUserResponse": {
+        "weather-app_internal_models.HealthCheck": {
+            "description": "Outcome of a readiness check",
             "type": "object",
             "properties": {
-                "token": {
+                "error": {
+                    "description": "@Description Why the check failed",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "@Description One of database, migrations, forecasts",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "@Description ok or failed",
                     "type": "string"
                 }
             }
         },
-        "internal_handler.SignUpUserResponse": {
+        "weather-app_internal_models.LoginFailure": {
+            "description": "Failed sign-ins of an account or an IP address",
             "type": "object",
             "properties": {
-                "id": {
+                "failures": {
+                    "description": "@Description Failed sign-ins in a row",
                     "type": "integer"
+                },
+                "last_failed_at": {
+                    "description": "@Description Latest failure",
+                    "type": "string"
+                },
+                "locked_until": {
+                    "description": "@Description End of the lockout, if locked",
+                    "type": "string"
+                },
+                "subject": {
+                    "description": "@Description \"login:\" followed by the login, or \"ip:\" followed by the address",
+                    "type": "string"
                 }
             }
         },
-        "weather-app_internal_dto.DTOSignIn": {
+        "weather-app_internal_models.Readiness": {
+            "description": "Outcome of the readiness checks",
             "type": "object",
             "properties": {
-                "login": {
-                    "type": "string"
+                "checks": {
+                    "description": "@Description Checks that were run",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/weather-app_internal_models.HealthCheck"
+                    }
                 },
-                "password": {
+                "status": {
+                    "description": "@Description ok, or unavailable if any check failed",
                     "type": "string"
                 }
             }
         },
-        "weather-app_internal_dto.DTOSignUp": {
+        "weather-app_internal_models.RunFailure": {
+            "description": "City that failed during a collector run",
             "type": "object",
             "properties": {
-                "email": {
+                "city": {
+                    "description": "@Description City name",
                     "type": "string"
                 },
-                "login": {
-                    "type": "string"
+                "city_id": {
+                    "description": "@Description City ID",
+                    "type": "integer"
                 },
-                "password": {
+                "error": {
+                    "description": "@Description Failure reason",
                     "type": "string"
                 }
             }
         },
-        "weather-app_internal_models.City": {
-            "description": "City model",
+        "weather-app_internal_models.SignInAttempt": {
+            "description": "Sign-in attempt",
             "type": "object",
             "properties": {
-                "country": {
-                    "description": "@Description Country name",
+                "attempted_at": {
+                    "description": "@Description Time of the attempt",
                     "type": "string"
                 },
                 "id": {
-                    "description": "@Description City ID",
+                    "description": "@Description Attempt ID",
                     "type": "integer"
                 },
-                "latitude": {
-                    "description": "@Description Latitude of the city",
-                    "type": "number"
+                "ip": {
+                    "description": "@Description Client IP address",
+                    "type": "string"
                 },
-                "longitude": {
-                    "description": "@Description Longitude of the city",
-                    "type": "number"
+                "login": {
+                    "description": "@Description Login given",
+                    "type": "string"
                 },
-                "name": {
-                    "description": "@Description City name",
+                "outcome": {
+                    "description": "@Description succeeded, failed, throttled or locked",
                     "type": "string"
+                },
+                "user_id": {
+                    "description": "@Description User ID, if the credentials were valid",
+                    "type": "integer"
                 }
             }
         },
-        "weather-app_internal_models.Forecast": {
-            "description": "Weather forecast model",
+        "weather-app_internal_models.TriggeredAlert": {
+            "description": "Triggered weather alert model",
             "type": "object",
             "properties": {
                 "city_id": {
                     "description": "@Description City ID",
                     "type": "integer"
                 },
-                "date": {
-                    "description": "@Description Date of the forecast",
+                "forecast_date": {
+                    "description": "@Description Date of the matching forecast",
                     "type": "string"
                 },
-                "forecast_json": {
-                    "description": "@Description Raw JSON of the forecast details",
-                    "type": "array",
-                    "items": {
-                        "type": "integer"
-                    }
-                },
                 "id": {
-                    "description": "@Description Forecast ID",
+                    "description": "@Description Triggered alert ID",
                     "type": "integer"
                 },
-                "temp": {
-                    "description": "@Description Temperature",
+                "metric": {
+                    "description": "@Description Rule metric",
+                    "type": "string"
+                },
+                "rule_id": {
+                    "description": "@Description Alert rule ID",
+                    "type": "integer"
+                },
+                "threshold": {
+                    "description": "@Description Rule threshold",
+                    "type": "number"
+                },
+                "triggered_at": {
+                    "description": "@Description Time the alert was recorded",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "@Description Owner user ID",
+                    "type": "integer"
+                },
+                "value": {
+                    "description": "@Description Forecast value that triggered the rule",
                     "type": "number"
                 }
             }
         },
-        "weather-app_internal_models.ForecastSummary": {
-            "description": "Weather forecast summary",
+        "weather-app_internal_models.Webhook": {
+            "description": "Webhook endpoint model",
             "type": "object",
             "properties": {
-                "available_dates": {
-                    "description": "@Description Available dates for forecasts",
+                "created_at": {
+                    "description": "@Description Creation time",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "@Description Webhook ID",
+                    "type": "integer"
+                },
+                "secret": {
+                    "description": "@Description HMAC secret, returned only on creation",
+                    "type": "string"
+                },
+                "url": {
+                    "description": "@Description Endpoint URL",
+                    "type": "string"
+                },
+                "user_id": {
+                    "description": "@Description Owner user ID",
+                    "type": "integer"
+                }
+            }
+        },
+        "weather-app_internal_models.WebhookDelivery": {
+            "description": "Webhook delivery log entry",
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "description": "@Description Number of delivery attempts",
+                    "type": "integer"
+                },
+                "created_at": {
+                    "description": "@Description Time the delivery was queued",
+                    "type": "string"
+                },
+                "delivered_at": {
+                    "description": "@Description Time of successful delivery",
+                    "type": "string"
+                },
+                "event": {
+                    "description": "@Description Event type",
+                    "type": "string"
+                },
+                "id": {
+                    "description": "@Description Delivery ID",
+                    "type": "integer"
+                },
+                "last_error": {
+                    "description": "@Description Error of the last attempt",
+                    "type": "string"
+                },
+                "next_attempt_at": {
+                    "description": "@Description Time of the next attempt",
+                    "type": "string"
+                },
+                "payload": {
+                    "description": "@Description JSON payload",
                     "type": "array",
                     "items": {
-                        "type": "string"
+                        "type": "integer"
                     }
                 },
-                "avg_temp": {
-                    "description": "@Description Average Temperature",
-                    "type": "number"
+                "response_status": {
+                    "description": "@Description HTTP status of the last attempt",
+                    "type": "integer"
                 },
-                "city": {
-                    "description": "@Description City",
+                "status": {
+                    "description": "@Description One of pending, delivered, failed",
                     "type": "string"
                 },
-                "country": {
-                    "description": "@Description Country",
-                    "type": "string"
+                "webhook_id": {
+                    "description": "@Description Webhook ID",
+                    "type": "integer"
                 }
             }
         }
@@ -0,0 +1,117 @@
+// Package cache stores serialized responses between requests.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache is a key-value store for serialized values. Implementations expire
+// values on their own, so callers only invalidate what they know changed. The
+// keys are plain strings grouped by prefix, which a Redis-compatible backend
+// can match with SCAN.
+type Cache interface {
+	// Get returns the value stored under key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte) error
+	// DeletePrefix removes every value whose key starts with prefix.
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// LRU is an in-process cache holding up to size values for ttl each. Once
+// full, it evicts the least recently used value.
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	items   map[string]*list.Element
+	recency *list.List // most recently used first
+	now     func() time.Time
+}
+
+type entry struct {
+	key     string
+	value   []byte
+	expires time.Time
+}
+
+// NewLRU creates a cache of size values, each kept for ttl. A non-positive
+// ttl keeps values until they are evicted.
+func NewLRU(size int, ttl time.Duration) *LRU {
+	if size < 1 {
+		size = 1
+	}
+	return &LRU{
+		size:    size,
+		ttl:     ttl,
+		items:   make(map[string]*list.Element),
+		recency: list.New(),
+		now:     time.Now,
+	}
+}
+
+func (c *LRU) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return nil, false, nil
+	}
+	e := elem.Value.(*entry)
+	if !e.expires.IsZero() && !c.now().Before(e.expires) {
+		c.remove(elem)
+		return nil, false, nil
+	}
+	c.recency.MoveToFront(elem)
+	return e.value, true, nil
+}
+
+func (c *LRU) Set(ctx context.Context, key string, value []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var expires time.Time
+	if c.ttl > 0 {
+		expires = c.now().Add(c.ttl)
+	}
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*entry)
+		e.value, e.expires = value, expires
+		c.recency.MoveToFront(elem)
+		return nil
+	}
+	c.items[key] = c.recency.PushFront(&entry{key: key, value: value, expires: expires})
+	if c.recency.Len() > c.size {
+		c.remove(c.recency.Back())
+	}
+	return nil
+}
+
+func (c *LRU) DeletePrefix(ctx context.Context, prefix string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, elem := range c.items {
+		if strings.HasPrefix(key, prefix) {
+			c.remove(elem)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of values stored, including expired ones not yet
+// evicted.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.recency.Len()
+}
+
+func (c *LRU) remove(elem *list.Element) {
+	c.recency.Remove(elem)
+	delete(c.items, elem.Value.(*entry).key)
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LRUTestSuite struct {
+	suite.Suite
+	cache *LRU
+	now   time.Time
+}
+
+func (suite *LRUTestSuite) SetupTest() {
+	suite.now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	suite.cache = NewLRU(2, time.Minute)
+	suite.cache.now = func() time.Time { return suite.now }
+}
+
+func (suite *LRUTestSuite) get(key string) (string, bool) {
+	value, ok, err := suite.cache.Get(context.Background(), key)
+	suite.Require().NoError(err)
+	return string(value), ok
+}
+
+func (suite *LRUTestSuite) set(key, value string) {
+	suite.Require().NoError(suite.cache.Set(context.Background(), key, []byte(value)))
+}
+
+func (suite *LRUTestSuite) TestGetSet() {
+	_, ok := suite.get("a")
+	assert.False(suite.T(), ok)
+
+	suite.set("a", "1")
+	suite.set("a", "2")
+	value, ok := suite.get("a")
+	assert.True(suite.T(), ok)
+	assert.Equal(suite.T(), "2", value)
+	assert.Equal(suite.T(), 1, suite.cache.Len())
+}
+
+func (suite *LRUTestSuite) TestEvictsLeastRecentlyUsed() {
+	suite.set("a", "1")
+	suite.set("b", "2")
+	suite.get("a")
+	suite.set("c", "3")
+
+	_, ok := suite.get("b")
+	assert.False(suite.T(), ok)
+	_, ok = suite.get("a")
+	assert.True(suite.T(), ok)
+	_, ok = suite.get("c")
+	assert.True(suite.T(), ok)
+}
+
+func (suite *LRUTestSuite) TestExpires() {
+	suite.set("a", "1")
+
+	suite.now = suite.now.Add(time.Minute)
+	_, ok := suite.get("a")
+	assert.False(suite.T(), ok)
+	assert.Equal(suite.T(), 0, suite.cache.Len())
+}
+
+func (suite *LRUTestSuite) TestDeletePrefix() {
+	suite.set("forecast:1:short", "1")
+	suite.set("forecast:12:short", "2")
+
+	assert.NoError(suite.T(), suite.cache.DeletePrefix(context.Background(), "forecast:1:"))
+	_, ok := suite.get("forecast:1:short")
+	assert.False(suite.T(), ok)
+	_, ok = suite.get("forecast:12:short")
+	assert.True(suite.T(), ok)
+}
+
+func TestLRUTestSuite(t *testing.T) {
+	suite.Run(t, new(LRUTestSuite))
+}
@@ -2,161 +2,451 @@ package datacollector
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/signal"
 	"sync"
-	"syscall"
 	"time"
 	"weather-app/config"
+	"weather-app/internal/lifecycle"
+	"weather-app/internal/metrics"
 	"weather-app/internal/models"
+	"weather-app/internal/provider"
+	"weather-app/internal/pubsub"
 	"weather-app/internal/service"
+	"weather-app/internal/tracing"
 
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// maxRunFailures bounds the failures recorded for a single run.
+	maxRunFailures = 100
+	// Loading cities is retried with a delay growing from retryDelay up to
+	// maxRetryDelay.
+	retryDelay    = 5 * time.Second
+	maxRetryDelay = 5 * time.Minute
 )
 
 type DataCollector struct {
 	services   *service.Service
 	citiesFile string
-	updateTime time.Duration
+	policy     schedulePolicy
 	parallel   bool
+	workers    int
 	apiKey     string
+	broker     *pubsub.Broker
+	provider   *provider.Client
+	runner     *lifecycle.Runner
+	retryDelay time.Duration
 }
 
-func NewDataCollector(cfg config.CollectorFlags, services *service.Service, apiKey string) *DataCollector {
+// NewDataCollector creates a collector. The provider client must be the one
+// the services use, so that quota checks see their requests.
+func NewDataCollector(cfg config.CollectorConfig, services *service.Service, apiKey string, broker *pubsub.Broker, provider *provider.Client) *DataCollector {
 	return &DataCollector{
 		services:   services,
-		citiesFile: cfg.Filename,
-		updateTime: cfg.UpdateTime,
+		citiesFile: cfg.CitiesFile,
+		policy:     newSchedulePolicy(cfg),
 		parallel:   cfg.Parallel,
+		workers:    cfg.Workers,
 		apiKey:     apiKey,
+		broker:     broker,
+		provider:   provider,
+		runner:     lifecycle.NewRunner(),
+		retryDelay: retryDelay,
 	}
 }
 
-func (dc *DataCollector) Start() {
-	if dc.citiesFile != "" {
-		citiesNames, err := readLines(dc.citiesFile)
+// Start updates all cities once and then keeps updating each city on its own
+// schedule until Stop is called or ctx is canceled.
+// Runs triggered through the collector service are handled until it stops.
+// While the database is unreachable or holds no cities, the collector waits
+// instead of failing.
+func (dc *DataCollector) Start(ctx context.Context) error {
+	return dc.runner.Run(ctx, func(ctx context.Context) error {
+		cities, ok := dc.waitForCities(ctx)
+		if !ok {
+			return nil
+		}
+		dc.collectForecasts(ctx, models.RunTriggerFull, cities)
+		logrus.Printf("Weather was updated at %v", time.Now())
+
+		trigger, detach := dc.services.CollectorService.AttachCollector()
+		defer detach()
+		dc.runScheduler(ctx, newScheduler(dc.policy), trigger)
+		return nil
+	})
+}
+
+// Stop stops scheduling fetches and waits for the ones in flight. Those still
+// running when ctx expires are canceled.
+func (dc *DataCollector) Stop(ctx context.Context) error {
+	return dc.runner.Stop(ctx)
+}
+
+// runScheduler checks which cities are due every few seconds and reloads the
+// schedules every minute, so admin changes and new cities are picked up. While
+// the provider quota is exhausted or its circuit breaker is open, due cities
+// wait. A trigger starts a run over all cities.
+func (dc *DataCollector) runScheduler(ctx context.Context, s *scheduler, trigger <-chan struct{}) {
+	var lastSync time.Time
+	paused := false
+	syncSchedules := func(now time.Time) {
+		schedules, err := dc.services.CityService.GetCitySchedules(ctx)
 		if err != nil {
-			logrus.Errorf("Failed to load cities from file: %v", err)
+			logrus.Errorf("Failed to load city schedules: %v", err)
+			return
+		}
+		s.sync(schedules, now)
+		lastSync = now
+	}
+	syncSchedules(time.Now())
+
+	ticker := time.NewTicker(schedulerTick)
+	defer ticker.Stop()
+	for {
+		var now time.Time
+		select {
+		case <-ctx.Done():
+			return
+		case <-dc.runner.Stopping():
+			return
+		case <-trigger:
+			dc.runTriggered(ctx)
+			continue
+		case now = <-ticker.C:
+		}
+		if now.Sub(lastSync) >= scheduleRefresh {
+			syncSchedules(now)
+		}
+		if err := dc.provider.Unavailable(ctx); err != nil {
+			if !paused {
+				logrus.Warnf("Collection is paused: %v", err)
+				paused = true
+			}
+			continue
+		}
+		paused = false
+		cities := s.due(now)
+		if len(cities) == 0 {
+			continue
+		}
+		dc.collectForecasts(ctx, models.RunTriggerScheduled, cities)
+		logrus.Printf("Weather was updated for %d cities at %v", len(cities), time.Now())
+	}
+}
+
+func (dc *DataCollector) runTriggered(ctx context.Context) {
+	cities, err := dc.services.CityService.GetCities(ctx)
+	if err != nil {
+		logrus.Errorf("Failed to load cities from database: %v", err)
+		return
+	}
+	dc.collectForecasts(ctx, models.RunTriggerManual, cities)
+	logrus.Printf("Weather was updated on demand for %d cities at %v", len(cities), time.Now())
+}
+
+// waitForCities imports the cities file if one is set and loads the cities,
+// retrying with a growing delay until the database is reachable and holds
+// cities. The file is imported again only while there are no cities. It
+// returns false if the collector stops first.
+func (dc *DataCollector) waitForCities(ctx context.Context) ([]models.City, bool) {
+	imported := false
+	delay := dc.retryDelay
+	for {
+		cities, err := dc.services.CityService.GetCities(ctx)
+		if err == nil && dc.citiesFile != "" && (!imported || len(cities) == 0) {
+			if err := dc.ImportCities(ctx); err != nil {
+				logrus.Error(err)
+			}
+			imported = true
+			cities, err = dc.services.CityService.GetCities(ctx)
 		}
-		dc.fetchAndCreateCities(citiesNames)
-		logrus.Printf("Cities data was updated at %v", time.Now())
+		switch {
+		case err != nil:
+			logrus.Warnf("Failed to load cities from database, retrying in %v: %v", delay, err)
+		case len(cities) == 0:
+			logrus.Warnf("No cities found in the database, retrying in %v", delay)
+		default:
+			return cities, true
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-dc.runner.Stopping():
+			return nil, false
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, maxRetryDelay)
 	}
+}
 
-	cities, err := dc.services.CityService.GetCities()
+// RunOnce imports the cities file if one is set and updates the forecasts of
+// all cities a single time.
+func (dc *DataCollector) RunOnce(ctx context.Context) error {
+	if dc.citiesFile != "" {
+		if err := dc.ImportCities(ctx); err != nil {
+			logrus.Error(err)
+		}
+	}
+
+	cities, err := dc.services.CityService.GetCities(ctx)
 	if err != nil {
-		logrus.Fatalf("Failed to load cities from database: %v", err)
+		return fmt.Errorf("Failed to load cities from database: %v", err)
 	}
 	if len(cities) == 0 {
-		logrus.Fatalf("No cities found in the database")
+		return errors.New("No cities found in the database")
 	}
 
-	dc.fetchAndCreateForecasts(cities)
+	dc.collectForecasts(ctx, models.RunTriggerFull, cities)
 	logrus.Printf("Weather was updated at %v", time.Now())
+	return nil
+}
 
-	ticker := time.NewTicker(dc.updateTime)
-	defer ticker.Stop()
+// ImportCities fetches the cities listed in the cities file and stores them.
+func (dc *DataCollector) ImportCities(ctx context.Context) error {
+	citiesNames, err := readLines(dc.citiesFile)
+	if err != nil {
+		return fmt.Errorf("Failed to load cities from file: %v", err)
+	}
+	dc.fetchAndCreateCities(ctx, citiesNames)
+	logrus.Printf("Cities data was updated at %v", time.Now())
+	return nil
+}
 
-	go func() {
-		for range ticker.C {
-			dc.fetchAndCreateForecasts(cities)
-			logrus.Printf("Weather was updated at %v", time.Now())
+func (dc *DataCollector) fetchAndCreateCities(ctx context.Context, citiesNames []string) {
+	dc.runJobs(ctx, len(citiesNames), func(i int) error {
+		city, err := dc.services.CityService.FetchCityData(ctx, citiesNames[i], dc.apiKey)
+		if err != nil {
+			return fmt.Errorf("Failed to fetch city data for %v: %v", citiesNames[i], err)
 		}
-	}()
+		if _, err := dc.services.CityService.CreateCity(ctx, city); err != nil {
+			return fmt.Errorf("Failed to create city record in db: %v", err)
+		}
+		return nil
+	})
+}
+
+// collectForecasts updates the forecasts of cities and records the run. A run
+// that cannot be recorded still goes ahead.
+func (dc *DataCollector) collectForecasts(ctx context.Context, triggeredBy string, cities []models.City) {
+	run, err := dc.services.CollectorService.StartRun(ctx, triggeredBy)
+	if err != nil {
+		logrus.Errorf("Failed to record collector run: %v", err)
+	}
+	recorded := err == nil
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
-	<-quit
+	start := time.Now()
+	dc.fetchAndCreateForecasts(ctx, cities, &run)
+	metrics.ObserveCollectorRun(triggeredBy, time.Since(start))
+
+	// The run is finished even when the collector is being stopped, so its
+	// outcome is not lost.
+	if recorded {
+		if err := dc.services.CollectorService.FinishRun(context.WithoutCancel(ctx), run); err != nil {
+			logrus.Errorf("Failed to record collector run: %v", err)
+		}
+	}
 }
 
-func (dc *DataCollector) fetchAndCreateCities(citiesNames []string) {
-	if dc.parallel {
-		var wg sync.WaitGroup
-		errorChan := make(chan error, len(citiesNames))
-
-		for _, cityName := range citiesNames {
-			wg.Add(1)
-			go func(cityName string) {
-				defer wg.Done()
-				city, err := dc.services.CityService.FetchCityData(cityName, dc.apiKey)
-				if err != nil {
-					errorChan <- fmt.Errorf("Failed to fetch city data for %v: %v", cityName, err)
-					return
-				}
-				_, err = dc.services.CityService.CreateCity(city)
-				if err != nil {
-					errorChan <- fmt.Errorf("Failed to create city record in db: %v", err)
-					return
-				}
-			}(cityName)
+// fetchAndCreateForecasts updates the forecasts of cities and counts the
+// outcome in run. A city succeeds once its forecasts are stored; failures to
+// record their freshness or notify subscribers are only logged. Each city is
+// updated independently, and one failing does not affect the others.
+func (dc *DataCollector) fetchAndCreateForecasts(ctx context.Context, cities []models.City, run *models.CollectorRun) {
+	intervals := dc.updateIntervals(ctx)
+	var mu sync.Mutex
+	fail := func(city models.City, cause error, err error) error {
+		mu.Lock()
+		run.CitiesFailed++
+		if len(run.Failures) < maxRunFailures {
+			run.Failures = append(run.Failures, models.RunFailure{CityId: city.Id, City: city.Name, Error: err.Error()})
 		}
+		mu.Unlock()
+		metrics.CityFailure(city)
+		dc.recordCityFailure(ctx, city, cause, err)
+		return err
+	}
 
-		wg.Wait()
-		close(errorChan)
+	attempted := dc.runJobs(ctx, len(cities), func(i int) (err error) {
+		city := cities[i]
+		// Each city is traced on its own, which keeps the traces of a run
+		// over many cities readable.
+		ctx, span := tracing.Start(ctx, "DataCollector.UpdateCity",
+			attribute.Int("city.id", city.Id), attribute.String("city.name", city.Name))
+		defer func() { tracing.End(span, err) }()
 
-		for err := range errorChan {
-			logrus.Error(err)
+		forecasts, err := dc.services.ForecastService.FetchForecastData(ctx, city, dc.apiKey)
+		if err != nil {
+			return fail(city, err, fmt.Errorf("Failed to fetch forecast data for %v: %v", city.Name, err))
 		}
-	} else {
-		for _, cityName := range citiesNames {
-			city, err := dc.services.CityService.FetchCityData(cityName, dc.apiKey)
-			if err != nil {
-				logrus.Errorf("Failed to fetch city data for %v: %v", cityName, err)
-				continue
-			}
-			_, err = dc.services.CityService.CreateCity(city)
-			if err != nil {
-				logrus.Fatalf("Failed to create city record in db: %v", err)
-			}
+		written, err := dc.services.ForecastService.ReplaceForecasts(ctx, city.Id, forecasts)
+		if err != nil {
+			return fail(city, err, fmt.Errorf("Failed to store forecasts of %v in db: %v", city.Name, err))
+		}
+		metrics.ForecastRowsWritten(written)
+		mu.Lock()
+		run.RowsWritten += written
+		run.CitiesSucceeded++
+		mu.Unlock()
+		dc.recordFreshness(ctx, city, intervals)
+		if err := dc.services.CollectorService.ClearCityFailures(ctx, city.Id); err != nil {
+			logrus.Errorf("Failed to clear failures of %v: %v", city.Name, err)
 		}
+		return dc.afterForecastsStored(ctx, city, forecasts)
+	})
+	run.CitiesAttempted = attempted
+	run.CitiesSkipped = len(cities) - attempted
+}
+
+// updateIntervals returns the update interval of each city by id. Cities
+// missing from it, as when the schedules fail to load, count as idle.
+func (dc *DataCollector) updateIntervals(ctx context.Context) map[int]time.Duration {
+	schedules, err := dc.services.CityService.GetCitySchedules(ctx)
+	if err != nil {
+		logrus.Errorf("Failed to load city schedules: %v", err)
+		return nil
 	}
+	intervals := make(map[int]time.Duration, len(schedules))
+	for _, schedule := range schedules {
+		intervals[schedule.Id] = dc.policy.interval(schedule)
+	}
+	return intervals
 }
 
-func (dc *DataCollector) fetchAndCreateForecasts(cities []models.City) {
-	if dc.parallel {
-		var wg sync.WaitGroup
-		errorChan := make(chan error, len(cities))
-
-		for _, city := range cities {
-			wg.Add(1)
-			go func(city models.City) {
-				defer wg.Done()
-				forecasts, err := dc.services.ForecastService.FetchForecastData(city, dc.apiKey)
-				if err != nil {
-					errorChan <- fmt.Errorf("Failed to fetch forecast data for %v: %v", city.Name, err)
-					return
-				}
-				for _, forecast := range forecasts {
-					_, err := dc.services.ForecastService.CreateForecast(forecast)
-					if err != nil {
-						errorChan <- fmt.Errorf("Failed to create forecast record in db: %v", err)
-						return
-					}
-				}
-			}(city)
-		}
+// recordFreshness records that the city's forecasts were just stored and when
+// they are due next, which clients use to revalidate and cache them.
+func (dc *DataCollector) recordFreshness(ctx context.Context, city models.City, intervals map[int]time.Duration) {
+	interval, ok := intervals[city.Id]
+	if !ok {
+		interval = dc.policy.interval(models.CitySchedule{City: city})
+	}
+	now := time.Now()
+	err := dc.services.ForecastService.SetForecastFreshness(ctx, models.ForecastFreshness{
+		CityId:     city.Id,
+		UpdatedAt:  now,
+		NextUpdate: now.Add(interval),
+	})
+	if err != nil {
+		logrus.Errorf("Failed to record freshness of %v: %v", city.Name, err)
+	}
+}
 
-		wg.Wait()
-		close(errorChan)
+// recordCityFailure counts a failed update towards the city's dead letter
+// record. Failures caused by the provider being unavailable as a whole or by
+// the collector stopping say nothing about the city and are not counted.
+func (dc *DataCollector) recordCityFailure(ctx context.Context, city models.City, cause error, err error) {
+	if ctx.Err() != nil || errors.Is(cause, provider.ErrCircuitOpen) || errors.Is(cause, provider.ErrQuotaExhausted) {
+		return
+	}
+	deadLettered, recordErr := dc.services.CollectorService.RecordCityFailure(ctx, city.Id, err)
+	if recordErr != nil {
+		logrus.Errorf("Failed to record failure of %v: %v", city.Name, recordErr)
+		return
+	}
+	if deadLettered {
+		logrus.Warnf("%v keeps failing to update and was added to the dead letter list: %v", city.Name, err)
+	}
+}
 
-		for err := range errorChan {
-			logrus.Error(err)
-		}
-	} else {
-		for _, city := range cities {
-			forecasts, err := dc.services.ForecastService.FetchForecastData(city, dc.apiKey)
-			if err != nil {
-				logrus.Errorf("Failed to fetch forecast data for %v: %v", city.Name, err)
-				continue
-			}
-			for _, forecast := range forecasts {
-				_, err := dc.services.ForecastService.CreateForecast(forecast)
-				if err != nil {
-					logrus.Fatalf("Failed to create forecast record in db: %v", err)
+// runJobs runs job for indexes 0 to n-1 on a pool of workers in parallel mode
+// or one by one otherwise, and logs the errors. Jobs left once the collector is
+// stopping or the provider becomes unavailable are skipped. It returns the
+// number of jobs run.
+func (dc *DataCollector) runJobs(ctx context.Context, n int, job func(i int) error) int {
+	workers := 1
+	if dc.parallel {
+		workers = min(dc.workers, n)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if err := job(i); err != nil {
+					logrus.Error(err)
 				}
 			}
+		}()
+	}
+
+	started := 0
+	for ; started < n; started++ {
+		if ctx.Err() != nil || dc.stopping() {
+			break
+		}
+		if err := dc.provider.Unavailable(ctx); err != nil {
+			logrus.Warnf("%d provider requests were skipped: %v", n-started, err)
+			break
 		}
+		jobs <- started
+	}
+	close(jobs)
+	wg.Wait()
+	return started
+}
+
+func (dc *DataCollector) stopping() bool {
+	select {
+	case <-dc.runner.Stopping():
+		return true
+	default:
+		return false
+	}
+}
+
+// afterForecastsStored notifies stream and webhook subscribers and evaluates
+// alert rules once a city's forecasts have been written.
+func (dc *DataCollector) afterForecastsStored(ctx context.Context, city models.City, forecasts []models.Forecast) error {
+	return errors.Join(
+		dc.publishForecastUpdate(ctx, city),
+		dc.notifyForecastUpdated(ctx, city),
+		dc.evaluateAlerts(ctx, city, forecasts),
+	)
+}
+
+func (dc *DataCollector) publishForecastUpdate(ctx context.Context, city models.City) error {
+	summary, err := dc.services.ForecastService.GetShortForecast(ctx, city.Id)
+	if err != nil {
+		return fmt.Errorf("Failed to load forecast summary for %v: %v", city.Name, err)
+	}
+	dc.broker.Publish(pubsub.ForecastUpdated(models.ForecastUpdate{
+		CityId:    city.Id,
+		UpdatedAt: time.Now(),
+		Forecast:  summary,
+	}))
+	return nil
+}
+
+func (dc *DataCollector) evaluateAlerts(ctx context.Context, city models.City, forecasts []models.Forecast) error {
+	triggered, err := dc.services.AlertService.EvaluateAlerts(ctx, city.Id, forecasts)
+	if err != nil {
+		return fmt.Errorf("Failed to evaluate alerts for %v: %v", city.Name, err)
+	}
+	if len(triggered) > 0 {
+		logrus.Printf("%d alerts were triggered for %v", len(triggered), city.Name)
+	}
+	for _, alert := range triggered {
+		dc.broker.Publish(pubsub.AlertTriggered(alert))
+	}
+	if err := dc.services.WebhookService.NotifyAlerts(ctx, triggered); err != nil {
+		return fmt.Errorf("Failed to queue alert webhooks for %v: %v", city.Name, err)
+	}
+	return nil
+}
+
+func (dc *DataCollector) notifyForecastUpdated(ctx context.Context, city models.City) error {
+	if err := dc.services.WebhookService.NotifyForecastUpdated(ctx, city); err != nil {
+		return fmt.Errorf("Failed to queue forecast webhooks for %v: %v", city.Name, err)
 	}
+	return nil
 }
 
 func readLines(filename string) ([]string, error) {
@@ -0,0 +1,167 @@
+package datacollector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+	"weather-app/config"
+	"weather-app/internal/models"
+	"weather-app/internal/provider"
+	"weather-app/internal/pubsub"
+	"weather-app/internal/repository/memory"
+	"weather-app/internal/service"
+	alertservice "weather-app/internal/service/alert_service"
+	cityservice "weather-app/internal/service/city_service"
+	collectorservice "weather-app/internal/service/collector_service"
+	forecastservice "weather-app/internal/service/forecast_service"
+	healthservice "weather-app/internal/service/health_service"
+	userservice "weather-app/internal/service/user_service"
+	webhookservice "weather-app/internal/service/webhook_service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// stubForecastService serves generated forecasts instead of calling the
+// provider, and fails for the cities in failing.
+type stubForecastService struct {
+	service.ForecastService
+	failing map[string]bool
+}
+
+func (s *stubForecastService) FetchForecastData(ctx context.Context, city models.City, openWeatherAPIKey string) ([]models.Forecast, error) {
+	if s.failing[city.Name] {
+		return nil, errors.New("city not found")
+	}
+	date := time.Now().AddDate(0, 0, 2).Truncate(time.Hour)
+	return []models.Forecast{
+		{CityId: city.Id, Temp: 10, Date: date, ForecastJson: json.RawMessage(`{}`)},
+		{CityId: city.Id, Temp: 12, Date: date.AddDate(0, 0, 1), ForecastJson: json.RawMessage(`{}`)},
+	}, nil
+}
+
+// DataCollectorTestSuite runs the collector against the in-memory storage backend.
+type DataCollectorTestSuite struct {
+	suite.Suite
+	services  *service.Service
+	collector *DataCollector
+}
+
+func (suite *DataCollectorTestSuite) SetupTest() {
+	storage := memory.NewStorage()
+	client := provider.NewClient(provider.Config{})
+	cityServ := cityservice.NewCityService(memory.NewCityRepository(storage), client)
+	forecastServ := &stubForecastService{
+		ForecastService: forecastservice.NewForecastService(cityServ, memory.NewForecastRepository(storage), client, nil),
+		failing:         map[string]bool{"Atlantis": true},
+	}
+	userServ := userservice.NewUserService(cityServ, memory.NewUserRepository(storage), userservice.LoginPolicy{})
+	alertServ := alertservice.NewAlertService(cityServ, memory.NewAlertRepository(storage))
+	webhookServ := webhookservice.NewWebhookService(memory.NewWebhookRepository(storage), webhookservice.DeliveryPolicy{})
+	collectorServ := collectorservice.NewCollectorService(memory.NewCollectorRunRepository(storage), client)
+	healthServ := healthservice.NewHealthService(memory.NewHealthRepository(storage), memory.NewCityRepository(storage), memory.NewForecastRepository(storage), healthservice.ReadinessPolicy{})
+	suite.services = service.NewService(userServ, cityServ, forecastServ, alertServ, webhookServ, collectorServ, healthServ)
+
+	suite.collector = NewDataCollector(config.CollectorConfig{
+		Workers:          1,
+		UpdateInterval:   config.Duration{Duration: 30 * time.Minute},
+		PopularInterval:  config.Duration{Duration: 10 * time.Minute},
+		PopularFavorites: 5,
+		IdleInterval:     config.Duration{Duration: time.Hour},
+	}, suite.services, "key", pubsub.NewBroker(), client)
+	suite.collector.retryDelay = 10 * time.Millisecond
+}
+
+func (suite *DataCollectorTestSuite) createCity(name string) models.City {
+	city := models.City{Name: name, Country: "XX"}
+	id, err := suite.services.CityService.CreateCity(context.Background(), city)
+	suite.Require().NoError(err)
+	city.Id = id
+	return city
+}
+
+func (suite *DataCollectorTestSuite) TestFailingCityDoesNotStopOthers() {
+	cities := []models.City{suite.createCity("Atlantis"), suite.createCity("London")}
+
+	for i := 0; i < 3; i++ {
+		suite.collector.collectForecasts(context.Background(), models.RunTriggerManual, cities)
+	}
+
+	runs, err := suite.services.CollectorService.GetRuns(context.Background(), 1)
+	suite.Require().NoError(err)
+	suite.Require().Len(runs, 1)
+	run := runs[0]
+	assert.NotNil(suite.T(), run.FinishedAt)
+	assert.Equal(suite.T(), 2, run.CitiesAttempted)
+	assert.Equal(suite.T(), 1, run.CitiesSucceeded)
+	assert.Equal(suite.T(), 1, run.CitiesFailed)
+	assert.Equal(suite.T(), 2, run.RowsWritten)
+	suite.Require().Len(run.Failures, 1)
+	assert.Equal(suite.T(), "Atlantis", run.Failures[0].City)
+
+	summary, err := suite.services.ForecastService.GetShortForecast(context.Background(), cities[1].Id)
+	assert.NoError(suite.T(), err)
+	assert.NotEmpty(suite.T(), summary.AvailableDates)
+
+	deadLetters, err := suite.services.CollectorService.GetDeadLetters(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Len(deadLetters, 1)
+	assert.Equal(suite.T(), cities[0].Id, deadLetters[0].CityId)
+	assert.Equal(suite.T(), 3, deadLetters[0].Failures)
+}
+
+func (suite *DataCollectorTestSuite) TestRecordsFreshness() {
+	cities := []models.City{suite.createCity("Atlantis"), suite.createCity("London")}
+	suite.Require().NoError(suite.services.CityService.UpdateCitySchedule(context.Background(), cities[1].Id, 600))
+
+	before := time.Now()
+	suite.collector.collectForecasts(context.Background(), models.RunTriggerManual, cities)
+
+	freshness, err := suite.services.ForecastService.GetForecastFreshness(context.Background(), cities[1].Id)
+	suite.Require().NoError(err)
+	assert.False(suite.T(), freshness.UpdatedAt.Before(before))
+	assert.Equal(suite.T(), 10*time.Minute, freshness.NextUpdate.Sub(freshness.UpdatedAt))
+
+	freshness, err = suite.services.ForecastService.GetForecastFreshness(context.Background(), cities[0].Id)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), freshness.UpdatedAt.IsZero(), "a failed update leaves the freshness alone")
+}
+
+func (suite *DataCollectorTestSuite) TestStartWaitsForCities() {
+	done := make(chan error, 1)
+	go func() {
+		done <- suite.collector.Start(context.Background())
+	}()
+
+	select {
+	case err := <-done:
+		suite.FailNow("collector exited without cities", "%v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	suite.createCity("London")
+	assert.Eventually(suite.T(), func() bool {
+		runs, err := suite.services.CollectorService.GetRuns(context.Background(), 1)
+		return err == nil && len(runs) == 1 && runs[0].FinishedAt != nil && runs[0].CitiesSucceeded == 1
+	}, time.Second, 10*time.Millisecond)
+
+	assert.NoError(suite.T(), suite.collector.Stop(context.Background()))
+	assert.NoError(suite.T(), <-done)
+}
+
+func (suite *DataCollectorTestSuite) TestStopWhileWaitingForCities() {
+	done := make(chan error, 1)
+	go func() {
+		done <- suite.collector.Start(context.Background())
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	assert.NoError(suite.T(), suite.collector.Stop(context.Background()))
+	assert.NoError(suite.T(), <-done)
+}
+
+func TestDataCollectorTestSuite(t *testing.T) {
+	suite.Run(t, new(DataCollectorTestSuite))
+}
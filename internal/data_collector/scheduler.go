@@ -0,0 +1,121 @@
+package datacollector
+
+import (
+	"sort"
+	"time"
+	"weather-app/config"
+	"weather-app/internal/models"
+)
+
+const (
+	schedulerTick   = 5 * time.Second
+	scheduleRefresh = time.Minute
+)
+
+// schedulePolicy picks a city's update interval: the one set by an admin or,
+// by default, one based on how many users have the city in favorites.
+type schedulePolicy struct {
+	defaultInterval  time.Duration
+	popularInterval  time.Duration
+	idleInterval     time.Duration
+	popularFavorites int
+}
+
+func newSchedulePolicy(cfg config.CollectorConfig) schedulePolicy {
+	return schedulePolicy{
+		defaultInterval:  cfg.UpdateInterval.Duration,
+		popularInterval:  cfg.PopularInterval.Duration,
+		idleInterval:     cfg.IdleInterval.Duration,
+		popularFavorites: cfg.PopularFavorites,
+	}
+}
+
+func (p schedulePolicy) interval(schedule models.CitySchedule) time.Duration {
+	switch {
+	case schedule.UpdateInterval > 0:
+		return time.Duration(schedule.UpdateInterval) * time.Second
+	case schedule.Favorites >= p.popularFavorites:
+		return p.popularInterval
+	case schedule.Favorites > 0:
+		return p.defaultInterval
+	default:
+		return p.idleInterval
+	}
+}
+
+type scheduledCity struct {
+	schedule models.CitySchedule
+	next     time.Time
+}
+
+// scheduler tracks when each city is due for an update. Cities known at the
+// first sync have just been updated, so their next updates are spread evenly
+// over their intervals instead of firing together; cities added later are due
+// immediately.
+type scheduler struct {
+	policy schedulePolicy
+	cities map[int]*scheduledCity
+	synced bool
+}
+
+func newScheduler(policy schedulePolicy) *scheduler {
+	return &scheduler{
+		policy: policy,
+		cities: make(map[int]*scheduledCity),
+	}
+}
+
+// sync applies the stored schedules, picking up new and removed cities as
+// well as changed intervals.
+func (s *scheduler) sync(schedules []models.CitySchedule, now time.Time) {
+	seen := make(map[int]bool, len(schedules))
+	var added []*scheduledCity
+	for _, schedule := range schedules {
+		seen[schedule.Id] = true
+		if city, ok := s.cities[schedule.Id]; ok {
+			city.next = city.next.Add(s.policy.interval(schedule) - s.policy.interval(city.schedule))
+			city.schedule = schedule
+			continue
+		}
+		city := &scheduledCity{schedule: schedule, next: now}
+		s.cities[schedule.Id] = city
+		added = append(added, city)
+	}
+	for id := range s.cities {
+		if !seen[id] {
+			delete(s.cities, id)
+		}
+	}
+
+	if !s.synced {
+		for i, city := range added {
+			offset := s.policy.interval(city.schedule) * time.Duration(i+1) / time.Duration(len(added))
+			city.next = now.Add(offset)
+		}
+		s.synced = true
+	}
+}
+
+// due returns the cities whose update time has come, most popular first, and
+// schedules their next updates.
+func (s *scheduler) due(now time.Time) []models.City {
+	var due []*scheduledCity
+	for _, city := range s.cities {
+		if !city.next.After(now) {
+			due = append(due, city)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].schedule.Favorites != due[j].schedule.Favorites {
+			return due[i].schedule.Favorites > due[j].schedule.Favorites
+		}
+		return due[i].schedule.Id < due[j].schedule.Id
+	})
+
+	cities := make([]models.City, 0, len(due))
+	for _, city := range due {
+		city.next = now.Add(s.policy.interval(city.schedule))
+		cities = append(cities, city.schedule.City)
+	}
+	return cities
+}
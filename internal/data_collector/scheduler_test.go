@@ -0,0 +1,76 @@
+package datacollector
+
+import (
+	"testing"
+	"time"
+	"weather-app/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SchedulerTestSuite struct {
+	suite.Suite
+	scheduler *scheduler
+	now       time.Time
+}
+
+func (suite *SchedulerTestSuite) SetupTest() {
+	suite.scheduler = newScheduler(schedulePolicy{
+		defaultInterval:  30 * time.Minute,
+		popularInterval:  10 * time.Minute,
+		idleInterval:     time.Hour,
+		popularFavorites: 5,
+	})
+	suite.now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func schedule(id, favorites, interval int) models.CitySchedule {
+	return models.CitySchedule{City: models.City{Id: id}, Favorites: favorites, UpdateInterval: interval}
+}
+
+func cityIds(cities []models.City) []int {
+	ids := make([]int, 0, len(cities))
+	for _, city := range cities {
+		ids = append(ids, city.Id)
+	}
+	return ids
+}
+
+func (suite *SchedulerTestSuite) TestInterval() {
+	policy := suite.scheduler.policy
+	assert.Equal(suite.T(), 10*time.Minute, policy.interval(schedule(1, 5, 0)))
+	assert.Equal(suite.T(), 30*time.Minute, policy.interval(schedule(1, 1, 0)))
+	assert.Equal(suite.T(), time.Hour, policy.interval(schedule(1, 0, 0)))
+	assert.Equal(suite.T(), 2*time.Minute, policy.interval(schedule(1, 10, 120)))
+}
+
+func (suite *SchedulerTestSuite) TestFirstSyncSpreadsUpdates() {
+	suite.scheduler.sync([]models.CitySchedule{schedule(1, 0, 600), schedule(2, 0, 600)}, suite.now)
+
+	assert.Empty(suite.T(), suite.scheduler.due(suite.now))
+	assert.Equal(suite.T(), []int{1}, cityIds(suite.scheduler.due(suite.now.Add(5*time.Minute))))
+	assert.Equal(suite.T(), []int{2}, cityIds(suite.scheduler.due(suite.now.Add(10*time.Minute))))
+	assert.Empty(suite.T(), suite.scheduler.due(suite.now.Add(11*time.Minute)))
+	assert.Equal(suite.T(), []int{1}, cityIds(suite.scheduler.due(suite.now.Add(15*time.Minute))))
+}
+
+func (suite *SchedulerTestSuite) TestDueOrdersByFavorites() {
+	suite.scheduler.sync(nil, suite.now)
+	suite.scheduler.sync([]models.CitySchedule{schedule(1, 0, 0), schedule(2, 7, 0), schedule(3, 2, 0)}, suite.now)
+
+	assert.Equal(suite.T(), []int{2, 3, 1}, cityIds(suite.scheduler.due(suite.now)))
+}
+
+func (suite *SchedulerTestSuite) TestSyncAppliesChanges() {
+	suite.scheduler.sync([]models.CitySchedule{schedule(1, 0, 600), schedule(2, 0, 600)}, suite.now)
+	suite.scheduler.sync([]models.CitySchedule{schedule(1, 0, 1200)}, suite.now)
+
+	assert.Empty(suite.T(), suite.scheduler.due(suite.now.Add(10*time.Minute)))
+	assert.Equal(suite.T(), []int{1}, cityIds(suite.scheduler.due(suite.now.Add(20*time.Minute))))
+	assert.NotContains(suite.T(), suite.scheduler.cities, 2)
+}
+
+func TestSchedulerTestSuite(t *testing.T) {
+	suite.Run(t, new(SchedulerTestSuite))
+}
@@ -0,0 +1,8 @@
+package dto
+
+type DTOAlertRule struct {
+	CityId      int     `json:"city_id"  db:"city_id"`
+	Metric      string  `json:"metric"  db:"metric"`
+	Threshold   float64 `json:"threshold"  db:"threshold"`
+	WithinHours int     `json:"within_hours"  db:"within_hours"`
+}
@@ -0,0 +1,5 @@
+package dto
+
+type DTOCitySchedule struct {
+	UpdateInterval int `json:"update_interval"  db:"update_interval"`
+}
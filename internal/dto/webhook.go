@@ -0,0 +1,5 @@
+package dto
+
+type DTOWebhook struct {
+	URL string `json:"url"  db:"url"`
+}
@@ -0,0 +1,268 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"weather-app/internal/dto"
+	"weather-app/internal/models"
+	"weather-app/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetCitySchedulesResponse struct {
+	Schedules []models.CitySchedule `json:"schedules"  db:"schedules"`
+}
+
+// getCitySchedules retrieves the update schedules of all cities
+// @Summary Get city schedules
+// @Description Lists cities with their admin-set update interval and number of favorites, most popular first. Cities with a zero interval are scheduled automatically by popularity
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} GetCitySchedulesResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/cities/schedules [get]
+func (h *Handler) getCitySchedules(c *gin.Context) {
+	schedules, err := h.services.CityService.GetCitySchedules(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, GetCitySchedulesResponse{schedules})
+}
+
+// updateCitySchedule sets the update interval of a city
+// @Summary Update city schedule
+// @Description Sets how often the collector updates the city, in seconds (60 to 86400). 0 returns the city to the automatic schedule
+// @Tags admin
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "City ID"
+// @Param input body dto.DTOCitySchedule true "City schedule"
+// @Success 200
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/cities/{id}/schedule [put]
+func (h *Handler) updateCitySchedule(c *gin.Context) {
+	cityId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	var input dto.DTOCitySchedule
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.services.CityService.UpdateCitySchedule(c.Request.Context(), int(cityId), input.UpdateInterval); err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+type GetCollectorRunsResponse struct {
+	Runs []models.CollectorRun `json:"runs"  db:"runs"`
+}
+
+// getCollectorRuns retrieves the latest data collector runs
+// @Summary Get collector runs
+// @Description Lists the latest data collector runs, newest first, with the number of cities attempted, succeeded, failed and skipped, rows written and failure reasons
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param limit query int false "Number of runs, 20 by default and at most 100"
+// @Success 200 {object} GetCollectorRunsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/collector/runs [get]
+func (h *Handler) getCollectorRuns(c *gin.Context) {
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.ParseInt(limitStr, 10, 64)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		limit = int(parsed)
+	}
+	runs, err := h.services.CollectorService.GetRuns(c.Request.Context(), limit)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, GetCollectorRunsResponse{runs})
+}
+
+// getCollectorStatus retrieves the state of the data collector
+// @Summary Get collector status
+// @Description Shows whether a collector runs in this process, the run in progress, the latest finished run and today's provider usage
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} models.CollectorStatus
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/collector/status [get]
+func (h *Handler) getCollectorStatus(c *gin.Context) {
+	status, err := h.services.CollectorService.GetStatus(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// triggerCollector starts a collector run over all cities
+// @Summary Trigger collector run
+// @Description Asks the data collector to update all cities. The run starts in the background; triggers made while one is pending are merged
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 202
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /api/admin/collector/trigger [post]
+func (h *Handler) triggerCollector(c *gin.Context) {
+	if err := h.services.CollectorService.Trigger(c.Request.Context()); err != nil {
+		if errors.Is(err, service.ErrCollectorNotRunning) {
+			newErrorResponse(c, http.StatusConflict, err.Error())
+			return
+		}
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+type GetDeadLettersResponse struct {
+	DeadLetters []models.DeadLetter `json:"dead_letters"  db:"dead_letters"`
+}
+
+// getDeadLetters retrieves the cities the collector keeps failing to update
+// @Summary Get collector dead letters
+// @Description Lists cities whose latest updates failed several times in a row, with the latest failure reason. A city leaves the list after its next successful update
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} GetDeadLettersResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/collector/dead-letters [get]
+func (h *Handler) getDeadLetters(c *gin.Context) {
+	deadLetters, err := h.services.CollectorService.GetDeadLetters(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, GetDeadLettersResponse{deadLetters})
+}
+
+type GetLockoutsResponse struct {
+	Lockouts []models.LoginFailure `json:"lockouts"  db:"lockouts"`
+}
+
+// getLockouts retrieves the logins and addresses locked out of sign-in
+// @Summary Get sign-in lockouts
+// @Description Lists the logins ("login:<login>") and addresses ("ip:<address>") locked out after too many failed sign-ins, with the time the lockouts end
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} GetLockoutsResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/users/lockouts [get]
+func (h *Handler) getLockouts(c *gin.Context) {
+	lockouts, err := h.services.UserService.GetLockouts(c.Request.Context())
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, GetLockoutsResponse{lockouts})
+}
+
+// unlockUser lifts the sign-in lockout of a user
+// @Summary Unlock user
+// @Description Lets the user sign in again right away by forgetting the failed sign-ins of their login. Lockouts of addresses are lifted with DELETE /api/admin/users/lockouts/{subject}
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param id path int true "User ID"
+// @Success 200
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/users/{id}/unlock [post]
+func (h *Handler) unlockUser(c *gin.Context) {
+	userId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.services.UserService.UnlockUser(c.Request.Context(), int(userId)); err != nil {
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// deleteLockout lifts a sign-in lockout
+// @Summary Delete sign-in lockout
+// @Description Lets a login or an address, given as the subject listed by GET /api/admin/users/lockouts, sign in again right away by forgetting its failed sign-ins
+// @Tags admin
+// @Security ApiKeyAuth
+// @Param subject path string true "Subject, such as login:alice or ip:203.0.113.7"
+// @Success 200
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/users/lockouts/{subject} [delete]
+func (h *Handler) deleteLockout(c *gin.Context) {
+	if err := h.services.UserService.ClearLockout(c.Request.Context(), c.Param("subject")); err != nil {
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+type GetSignInAttemptsResponse struct {
+	Attempts []models.SignInAttempt `json:"attempts"  db:"attempts"`
+}
+
+// getSignInAttempts retrieves the latest sign-in attempts
+// @Summary Get sign-in attempts
+// @Description Lists the latest sign-in attempts, newest first, with the address they came from and whether they succeeded, failed, or were refused as throttled or locked
+// @Tags admin
+// @Produce json
+// @Security ApiKeyAuth
+// @Param login query string false "Only attempts to sign in as this login"
+// @Param limit query int false "Number of attempts, 20 by default and at most 100"
+// @Success 200 {object} GetSignInAttemptsResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/admin/sign-in-attempts [get]
+func (h *Handler) getSignInAttempts(c *gin.Context) {
+	limit := 0
+	if limitStr := c.Query("limit"); limitStr != "" {
+		parsed, err := strconv.ParseInt(limitStr, 10, 64)
+		if err != nil {
+			newErrorResponse(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		limit = int(parsed)
+	}
+	attempts, err := h.services.UserService.GetSignInAttempts(c.Request.Context(), c.Query("login"), limit)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, GetSignInAttemptsResponse{attempts})
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"weather-app/internal/dto"
+	"weather-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CreateAlertRuleResponse struct {
+	Id int `json:"id"  db:"id"`
+}
+
+type GetAlertRulesResponse struct {
+	Rules []models.AlertRule `json:"rules"  db:"rules"`
+}
+
+type GetTriggeredAlertsResponse struct {
+	Alerts []models.TriggeredAlert `json:"alerts"  db:"alerts"`
+}
+
+// getAlertRules retrieves the alert rules of the authenticated user
+// @Summary Get alert rules
+// @Description Retrieves the list of alert rules for the authenticated user
+// @Tags alerts
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} GetAlertRulesResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/alerts [get]
+func (h *Handler) getAlertRules(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	rules, err := h.services.AlertService.GetAlertRules(c.Request.Context(), userId.(int))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, GetAlertRulesResponse{
+		Rules: rules,
+	})
+}
+
+// getAlertRule retrieves a single alert rule of the authenticated user
+// @Summary Get alert rule
+// @Description Retrieves an alert rule by ID
+// @Tags alerts
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Alert rule ID"
+// @Success 200 {object} models.AlertRule
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/alerts/{id} [get]
+func (h *Handler) getAlertRule(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	ruleId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	rule, err := h.services.AlertService.GetAlertRule(c.Request.Context(), userId.(int), int(ruleId))
+	if err != nil {
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
+
+// createAlertRule creates an alert rule for the authenticated user
+// @Summary Create alert rule
+// @Description Creates an alert rule on a city. Metric is one of temp_below, temp_above, wind_gust_above, pop_above
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param input body dto.DTOAlertRule true "Alert rule"
+// @Success 200 {object} CreateAlertRuleResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/alerts [post]
+func (h *Handler) createAlertRule(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	var input dto.DTOAlertRule
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	id, err := h.services.AlertService.CreateAlertRule(c.Request.Context(), models.AlertRule{
+		UserId:      userId.(int),
+		CityId:      input.CityId,
+		Metric:      input.Metric,
+		Threshold:   input.Threshold,
+		WithinHours: input.WithinHours,
+	})
+	if err != nil {
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, CreateAlertRuleResponse{id})
+}
+
+// updateAlertRule updates an alert rule of the authenticated user
+// @Summary Update alert rule
+// @Description Replaces the city, metric, threshold and window of an alert rule
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Alert rule ID"
+// @Param input body dto.DTOAlertRule true "Alert rule"
+// @Success 200
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/alerts/{id} [put]
+func (h *Handler) updateAlertRule(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	ruleId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	var input dto.DTOAlertRule
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	err = h.services.AlertService.UpdateAlertRule(c.Request.Context(), models.AlertRule{
+		Id:          int(ruleId),
+		UserId:      userId.(int),
+		CityId:      input.CityId,
+		Metric:      input.Metric,
+		Threshold:   input.Threshold,
+		WithinHours: input.WithinHours,
+	})
+	if err != nil {
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// deleteAlertRule deletes an alert rule of the authenticated user
+// @Summary Delete alert rule
+// @Description Deletes an alert rule together with its triggered alerts
+// @Tags alerts
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Alert rule ID"
+// @Success 200
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/alerts/{id} [delete]
+func (h *Handler) deleteAlertRule(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	ruleId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	err = h.services.AlertService.DeleteAlertRule(c.Request.Context(), userId.(int), int(ruleId))
+	if err != nil {
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// getTriggeredAlerts retrieves the triggered alerts of the authenticated user
+// @Summary Get triggered alerts
+// @Description Retrieves alerts triggered by the user's rules, newest first
+// @Tags alerts
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} GetTriggeredAlertsResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/alerts/triggered [get]
+func (h *Handler) getTriggeredAlerts(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	alerts, err := h.services.AlertService.GetTriggeredAlerts(c.Request.Context(), userId.(int))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, GetTriggeredAlertsResponse{
+		Alerts: alerts,
+	})
+}
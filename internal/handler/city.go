@@ -16,16 +16,18 @@ type GetCitiesResponse struct {
 // @Description Get the list of cities
 // @Tags cities
 // @Produce json
+// @Param If-None-Match header string false "ETag of the cached copy"
 // @Success 200 {object} GetCitiesResponse
+// @Success 304 "Not Modified"
 // @Failure 500 {object} ErrorResponse
 // @Router /api/cities [get]
 func (h *Handler) getCities(c *gin.Context) {
-	cities, err := h.services.CityService.GetCities()
+	cities, err := h.services.CityService.GetCities(c.Request.Context())
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	c.JSON(http.StatusOK, GetCitiesResponse{
+	jsonWithETag(c, GetCitiesResponse{
 		Cities: cities,
 	})
 }
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"weather-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// forecastNotModified sets ETag, Last-Modified and Cache-Control for a
+// response built from the forecasts of a city, and answers 304 Not Modified
+// if the client's copy is still current. Clients may keep the response until
+// the collector's next scheduled update. The caller stops when it returns true.
+func forecastNotModified(c *gin.Context, freshness models.ForecastFreshness) bool {
+	if freshness.UpdatedAt.IsZero() {
+		c.Header("Cache-Control", "no-cache")
+		return false
+	}
+	etag := fmt.Sprintf(`"%d-%d"`, freshness.CityId, freshness.UpdatedAt.UnixNano())
+	// HTTP dates have a precision of one second.
+	lastModified := freshness.UpdatedAt.UTC().Truncate(time.Second)
+	c.Header("ETag", etag)
+	c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge(freshness.NextUpdate)))
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		return abortNotModified(c, etagMatches(match, etag))
+	}
+	if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil {
+		return abortNotModified(c, !lastModified.After(since))
+	}
+	return false
+}
+
+// maxAge returns the seconds left until next, or 0 if it passed or is unknown.
+func maxAge(next time.Time) int {
+	if next.IsZero() {
+		return 0
+	}
+	return int(math.Max(0, math.Ceil(time.Until(next).Seconds())))
+}
+
+// jsonWithETag writes body as JSON tagged with a hash of its content, and
+// answers 304 Not Modified if it matches the client's copy. Clients revalidate
+// on every use, as the content changes at no particular time.
+func jsonWithETag(c *gin.Context, body any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	etag := fmt.Sprintf(`"%x"`, sha256.Sum256(data))
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "no-cache")
+	if abortNotModified(c, etagMatches(c.GetHeader("If-None-Match"), etag)) {
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+}
+
+func abortNotModified(c *gin.Context, notModified bool) bool {
+	if notModified {
+		c.AbortWithStatus(http.StatusNotModified)
+	}
+	return notModified
+}
+
+// etagMatches reports whether the If-None-Match header value lists etag. Weak
+// tags match their strong counterparts, as GET only needs weak comparison.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +1,38 @@
 package handler
 
 import (
+	"errors"
+	"net/http"
+	"weather-app/internal/repository"
+	"weather-app/internal/service"
+	"weather-app/internal/tracing"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 type ErrorResponse struct {
 	Message string `json:"message"`
+	// TraceId identifies the trace of the request, so that the failure can be
+	// found in the logs and the tracing backend.
+	TraceId string `json:"trace_id,omitempty"`
 }
 
 func newErrorResponse(c *gin.Context, statusCode int, message string) {
-	logrus.Errorf(message)
-	c.AbortWithStatusJSON(statusCode, ErrorResponse{message})
+	ctx := c.Request.Context()
+	logrus.WithContext(ctx).Errorf(message)
+	c.AbortWithStatusJSON(statusCode, ErrorResponse{Message: message, TraceId: tracing.TraceID(ctx)})
+}
+
+// errorStatus returns 400 for errors the client has to correct, 404 for
+// records that do not exist or belong to another user, and 500 for the rest.
+func errorStatus(err error) int {
+	var invalid *service.ValidationError
+	if errors.As(err, &invalid) {
+		return http.StatusBadRequest
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
 }
@@ -19,7 +19,10 @@ type GetShortForecastResponse struct {
 // @Tags forecast
 // @Produce json
 // @Param city_id path int true "City ID"
+// @Param If-None-Match header string false "ETag of the cached copy"
+// @Param If-Modified-Since header string false "Last-Modified of the cached copy"
 // @Success 200 {object} GetShortForecastResponse
+// @Success 304 "Not Modified"
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/forecast/short/{city_id} [get]
@@ -29,7 +32,15 @@ func (h *Handler) getShortForecast(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	forecast, err := h.services.ForecastService.GetShortForecast(int(cityId))
+	freshness, err := h.services.ForecastService.GetForecastFreshness(c.Request.Context(), int(cityId))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if forecastNotModified(c, freshness) {
+		return
+	}
+	forecast, err := h.services.ForecastService.GetShortForecast(c.Request.Context(), int(cityId))
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -51,7 +62,10 @@ type GetDetailedForecastResponse struct {
 // @Produce json
 // @Param city_id path int true "City ID"
 // @Param date query string true "Date" Format(date)
+// @Param If-None-Match header string false "ETag of the cached copy"
+// @Param If-Modified-Since header string false "Last-Modified of the cached copy"
 // @Success 200 {object} GetDetailedForecastResponse
+// @Success 304 "Not Modified"
 // @Failure 400 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /api/forecast/detailed/{city_id} [get]
@@ -75,12 +89,20 @@ func (h *Handler) getDetailedForecast(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, "Invalid date format. Use '2006-01-02' or '2006-01-02 15:04:05'")
 		return
 	}
-	forecasts, err := h.services.ForecastService.GetDetailedForecast(int(cityId), date)
+	freshness, err := h.services.ForecastService.GetForecastFreshness(c.Request.Context(), int(cityId))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if forecastNotModified(c, freshness) {
+		return
+	}
+	forecasts, err := h.services.ForecastService.GetDetailedForecast(c.Request.Context(), int(cityId), date)
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
 	}
-	city, err := h.services.CityService.GetCity(int(cityId))
+	city, err := h.services.CityService.GetCity(c.Request.Context(), int(cityId))
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -90,3 +112,33 @@ func (h *Handler) getDetailedForecast(c *gin.Context) {
 		Forecasts: forecasts,
 	})
 }
+
+type GetForecastHistoryResponse struct {
+	Days []models.DailyForecast `json:"days"`
+}
+
+// getForecastHistory retrieves the daily aggregates of past forecasts for a city
+// @Summary Get forecast history
+// @Description Get the daily aggregates of forecasts that passed their retention for a specific city
+// @Tags forecast
+// @Produce json
+// @Param city_id path int true "City ID"
+// @Success 200 {object} GetForecastHistoryResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/forecast/history/{city_id} [get]
+func (h *Handler) getForecastHistory(c *gin.Context) {
+	cityId, err := strconv.ParseInt(c.Param("city_id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	days, err := h.services.ForecastService.GetForecastHistory(c.Request.Context(), int(cityId))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, GetForecastHistoryResponse{
+		Days: days,
+	})
+}
@@ -1,28 +1,73 @@
 package handler
 
 import (
+	"strings"
+	"weather-app/internal/metrics"
+	"weather-app/internal/pubsub"
+	"weather-app/internal/ratelimit"
 	"weather-app/internal/service"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
 
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
 type Handler struct {
-	services *service.Service
+	services       *service.Service
+	broker         *pubsub.Broker
+	limiter        *rateLimiter
+	allowedOrigins map[string]bool
+	trustedProxies []string
+	upgrader       *websocket.Upgrader
 }
 
-func NewHandler(services *service.Service) *Handler {
-	return &Handler{services: services}
+// Options are the settings of the HTTP handlers.
+type Options struct {
+	// Limits are the request rates clients are limited to.
+	Limits ratelimit.Limits
+	// AllowedOrigins are the web origins, such as https://dash.example.com,
+	// whose pages may open WebSockets besides the server's own.
+	AllowedOrigins []string
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies
+	// in front of the server. Client addresses, which rate limits and sign-in
+	// lockouts are keyed by, are taken from X-Forwarded-For only on requests
+	// coming from them. None are trusted by default, since anyone could send
+	// the header to dodge those limits.
+	TrustedProxies []string
+}
+
+// NewHandler creates the HTTP handlers.
+func NewHandler(services *service.Service, broker *pubsub.Broker, options Options) *Handler {
+	h := &Handler{
+		services:       services,
+		broker:         broker,
+		limiter:        newRateLimiter(options.Limits),
+		allowedOrigins: make(map[string]bool, len(options.AllowedOrigins)),
+		trustedProxies: options.TrustedProxies,
+	}
+	for _, origin := range options.AllowedOrigins {
+		h.allowedOrigins[strings.ToLower(origin)] = true
+	}
+	h.upgrader = h.newUpgrader()
+	return h
 }
 
 func (h *Handler) InitRoutes() *gin.Engine {
 	router := gin.Default()
-	router.Use(cors.Default())
+	if err := router.SetTrustedProxies(h.trustedProxies); err != nil {
+		logrus.Errorf("Failed to set trusted proxies, none are trusted: %v", err)
+		router.SetTrustedProxies(nil)
+	}
+	router.Use(h.traceRequest(), cors.Default(), h.observeRequest, h.rateLimit)
 
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	router.GET("/healthz", h.healthz)
+	router.GET("/readyz", h.readyz)
 
 	auth := router.Group("/auth")
 	{
@@ -37,6 +82,25 @@ func (h *Handler) InitRoutes() *gin.Engine {
 			users.GET("/favorites", h.getFavorites)
 			users.POST("/favorites", h.addFavorite)
 			users.DELETE("/favorites", h.deleteFavorite)
+
+			alerts := users.Group("/alerts")
+			{
+				alerts.GET("", h.getAlertRules)
+				alerts.POST("", h.createAlertRule)
+				alerts.GET("/triggered", h.getTriggeredAlerts)
+				alerts.GET("/:id", h.getAlertRule)
+				alerts.PUT("/:id", h.updateAlertRule)
+				alerts.DELETE("/:id", h.deleteAlertRule)
+			}
+
+			webhooks := users.Group("/webhooks")
+			{
+				webhooks.GET("", h.getWebhooks)
+				webhooks.POST("", h.createWebhook)
+				webhooks.DELETE("/:id", h.deleteWebhook)
+				webhooks.GET("/:id/deliveries", h.getWebhookDeliveries)
+				webhooks.POST("/:id/test", h.testWebhook)
+			}
 		}
 
 		cities := api.Group("/cities")
@@ -48,6 +112,29 @@ func (h *Handler) InitRoutes() *gin.Engine {
 		{
 			forecasts.GET("/short/:city_id", h.getShortForecast)
 			forecasts.GET("/detailed/:city_id", h.getDetailedForecast)
+			forecasts.GET("/history/:city_id", h.getForecastHistory)
+		}
+
+		stream := api.Group("/stream")
+		{
+			stream.GET("/forecast", h.streamForecast)
+			stream.GET("/favorites", h.identifyUser, h.streamFavorites)
+		}
+
+		api.GET("/ws", h.tokenFromProtocol, h.identifyUser, h.serveWebSocket)
+
+		admin := api.Group("/admin", h.identifyUser, h.requireAdmin)
+		{
+			admin.GET("/cities/schedules", h.getCitySchedules)
+			admin.PUT("/cities/:id/schedule", h.updateCitySchedule)
+			admin.GET("/collector/runs", h.getCollectorRuns)
+			admin.GET("/collector/status", h.getCollectorStatus)
+			admin.POST("/collector/trigger", h.triggerCollector)
+			admin.GET("/collector/dead-letters", h.getDeadLetters)
+			admin.GET("/users/lockouts", h.getLockouts)
+			admin.DELETE("/users/lockouts/:subject", h.deleteLockout)
+			admin.POST("/users/:id/unlock", h.unlockUser)
+			admin.GET("/sign-in-attempts", h.getSignInAttempts)
 		}
 	}
 
@@ -0,0 +1,595 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/cache"
+	"weather-app/internal/models"
+	"weather-app/internal/provider"
+	"weather-app/internal/pubsub"
+	"weather-app/internal/ratelimit"
+	"weather-app/internal/repository/memory"
+	"weather-app/internal/service"
+	alertservice "weather-app/internal/service/alert_service"
+	cityservice "weather-app/internal/service/city_service"
+	collectorservice "weather-app/internal/service/collector_service"
+	forecastservice "weather-app/internal/service/forecast_service"
+	healthservice "weather-app/internal/service/health_service"
+	userservice "weather-app/internal/service/user_service"
+	webhookservice "weather-app/internal/service/webhook_service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// HandlerTestSuite runs requests end to end against the in-memory storage backend.
+type HandlerTestSuite struct {
+	suite.Suite
+	router   *gin.Engine
+	services *service.Service
+}
+
+func (suite *HandlerTestSuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	storage := memory.NewStorage()
+	client := provider.NewClient(provider.Config{})
+	cityServ := cityservice.NewCityService(memory.NewCityRepository(storage), client)
+	forecastServ := forecastservice.NewForecastService(cityServ, memory.NewForecastRepository(storage), client, cache.NewLRU(100, time.Minute))
+	userServ := userservice.NewUserService(cityServ, memory.NewUserRepository(storage), userservice.LoginPolicy{})
+	alertServ := alertservice.NewAlertService(cityServ, memory.NewAlertRepository(storage))
+	webhookServ := webhookservice.NewWebhookService(memory.NewWebhookRepository(storage), webhookservice.DeliveryPolicy{})
+	collectorServ := collectorservice.NewCollectorService(memory.NewCollectorRunRepository(storage), client)
+	healthServ := healthservice.NewHealthService(memory.NewHealthRepository(storage), memory.NewCityRepository(storage), memory.NewForecastRepository(storage), healthservice.ReadinessPolicy{})
+	suite.services = service.NewService(userServ, cityServ, forecastServ, alertServ, webhookServ, collectorServ, healthServ)
+	suite.router = NewHandler(suite.services, pubsub.NewBroker(), Options{}).InitRoutes()
+}
+
+func (suite *HandlerTestSuite) request(method, path, token string, body any) *httptest.ResponseRecorder {
+	var buf bytes.Buffer
+	if body != nil {
+		suite.Require().NoError(json.NewEncoder(&buf).Encode(body))
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if token != "" {
+		req.Header.Set(authorizationHeader, token)
+	}
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *HandlerTestSuite) conditionalRequest(path, header, value string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set(header, value)
+	w := httptest.NewRecorder()
+	suite.router.ServeHTTP(w, req)
+	return w
+}
+
+func (suite *HandlerTestSuite) signIn(login string) string {
+	w := suite.request(http.MethodPost, "/auth/sign-up", "", map[string]string{"login": login, "password": "secret", "email": login + "@example.com"})
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	w = suite.request(http.MethodPost, "/auth/sign-in", "", map[string]string{"login": login, "password": "secret"})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var resp SignInUserResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp.Token
+}
+
+func (suite *HandlerTestSuite) TestSignInWithWrongPassword() {
+	suite.signIn("alice")
+
+	w := suite.request(http.MethodPost, "/auth/sign-in", "", map[string]string{"login": "alice", "password": "wrong"})
+	assert.Equal(suite.T(), http.StatusInternalServerError, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestRateLimit() {
+	token := suite.signIn("alice")
+	suite.router = NewHandler(suite.services, pubsub.NewBroker(), Options{Limits: ratelimit.Limits{
+		Default: ratelimit.Rule{RequestsPerMinute: 60, Burst: 2},
+		Routes:  map[string]ratelimit.Rule{"POST /auth/sign-in": {RequestsPerMinute: 6, Burst: 1}},
+	}}).InitRoutes()
+
+	w := suite.request(http.MethodGet, "/api/cities", "", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Equal(suite.T(), "2", w.Header().Get("RateLimit-Limit"))
+	assert.Equal(suite.T(), "1", w.Header().Get("RateLimit-Remaining"))
+	assert.Equal(suite.T(), "1", w.Header().Get("RateLimit-Reset"))
+	suite.request(http.MethodGet, "/api/cities", "", nil)
+	w = suite.request(http.MethodGet, "/api/cities", "", nil)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+	assert.Equal(suite.T(), "1", w.Header().Get("Retry-After"))
+
+	w = suite.request(http.MethodGet, "/api/users/favorites", token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code, "signed-in users are limited on their own")
+
+	credentials := map[string]string{"login": "alice", "password": "secret"}
+	w = suite.request(http.MethodPost, "/auth/sign-in", "", credentials)
+	assert.Equal(suite.T(), http.StatusOK, w.Code, "sign-in has a budget of its own")
+	w = suite.request(http.MethodPost, "/auth/sign-in", "", credentials)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+	assert.Equal(suite.T(), "10", w.Header().Get("Retry-After"))
+}
+
+func (suite *HandlerTestSuite) TestRateLimitInvalidTokenIsAnonymous() {
+	suite.router = NewHandler(suite.services, pubsub.NewBroker(), Options{Limits: ratelimit.Limits{
+		Default: ratelimit.Rule{RequestsPerMinute: 60, Burst: 1},
+	}}).InitRoutes()
+
+	w := suite.request(http.MethodGet, "/api/cities", "made-up", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.request(http.MethodGet, "/api/cities", "another-made-up", nil)
+	assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code, "invalid tokens share the address's budget")
+
+	suite.router = NewHandler(suite.services, pubsub.NewBroker(), Options{}).InitRoutes()
+	w = suite.request(http.MethodGet, "/api/users/favorites", "made-up", nil)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code, "routes that need a token refuse it")
+}
+
+func (suite *HandlerTestSuite) TestRateLimitIgnoresSpoofedForwardedFor() {
+	limits := ratelimit.Limits{Default: ratelimit.Rule{RequestsPerMinute: 60, Burst: 1}}
+	requestFrom := func(forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/cities", nil)
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		w := httptest.NewRecorder()
+		suite.router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	suite.router = NewHandler(suite.services, pubsub.NewBroker(), Options{Limits: limits}).InitRoutes()
+	assert.Equal(suite.T(), http.StatusOK, requestFrom("203.0.113.1"))
+	assert.Equal(suite.T(), http.StatusTooManyRequests, requestFrom("203.0.113.2"), "the header of an untrusted peer is ignored")
+
+	// httptest requests come from 192.0.2.1.
+	suite.router = NewHandler(suite.services, pubsub.NewBroker(), Options{Limits: limits, TrustedProxies: []string{"192.0.2.0/24"}}).InitRoutes()
+	assert.Equal(suite.T(), http.StatusOK, requestFrom("203.0.113.1"))
+	assert.Equal(suite.T(), http.StatusOK, requestFrom("203.0.113.2"), "a trusted proxy forwards the client address")
+	assert.Equal(suite.T(), http.StatusTooManyRequests, requestFrom("203.0.113.2"))
+}
+
+func (suite *HandlerTestSuite) TestFavorites() {
+	token := suite.signIn("alice")
+	cityId, err := suite.services.CityService.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+
+	w := suite.request(http.MethodPost, fmt.Sprintf("/api/users/favorites?cityId=%d", cityId), token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request(http.MethodGet, "/api/users/favorites", token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var favorites GetFavoritesResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &favorites))
+	assert.Equal(suite.T(), []models.City{{Id: cityId, Name: "London", Country: "GB"}}, favorites.Cities)
+
+	w = suite.request(http.MethodDelete, fmt.Sprintf("/api/users/favorites?cityId=%d", cityId), token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestFavoritesRequireToken() {
+	w := suite.request(http.MethodGet, "/api/users/favorites", "", nil)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestStreamFavoritesRequiresValidToken() {
+	w := suite.request(http.MethodGet, "/api/stream/favorites", "made-up", nil)
+	assert.Equal(suite.T(), http.StatusUnauthorized, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestShortForecast() {
+	cityId, err := suite.services.CityService.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+	for i, temp := range []float32{10, 20} {
+		_, err := suite.services.ForecastService.CreateForecast(context.Background(), models.Forecast{
+			CityId:       cityId,
+			Temp:         temp,
+			Date:         time.Now().AddDate(0, 0, i+1),
+			ForecastJson: []byte(`{}`),
+		})
+		suite.Require().NoError(err)
+	}
+
+	w := suite.request(http.MethodGet, fmt.Sprintf("/api/forecast/short/%d", cityId), "", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var resp GetShortForecastResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(suite.T(), "London", resp.Forecast.City)
+	assert.Equal(suite.T(), float32(15), resp.Forecast.AvgTemp)
+	assert.Len(suite.T(), resp.Forecast.AvailableDates, 2)
+}
+
+func (suite *HandlerTestSuite) TestForecastConditionalRequests() {
+	cityId, err := suite.services.CityService.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+	path := fmt.Sprintf("/api/forecast/short/%d", cityId)
+
+	w := suite.request(http.MethodGet, path, "", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Empty(suite.T(), w.Header().Get("ETag"), "forecasts never stored have no validators")
+	assert.Equal(suite.T(), "no-cache", w.Header().Get("Cache-Control"))
+
+	updatedAt := time.Now().Add(-time.Minute)
+	suite.Require().NoError(suite.services.ForecastService.SetForecastFreshness(context.Background(), models.ForecastFreshness{
+		CityId:     cityId,
+		UpdatedAt:  updatedAt,
+		NextUpdate: updatedAt.Add(11 * time.Minute),
+	}))
+	w = suite.request(http.MethodGet, path, "", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	etag, lastModified := w.Header().Get("ETag"), w.Header().Get("Last-Modified")
+	assert.NotEmpty(suite.T(), etag)
+	assert.Equal(suite.T(), updatedAt.UTC().Format(http.TimeFormat), lastModified)
+	var maxAge int
+	_, err = fmt.Sscanf(w.Header().Get("Cache-Control"), "public, max-age=%d", &maxAge)
+	assert.NoError(suite.T(), err)
+	assert.InDelta(suite.T(), 600, maxAge, 5, "cached until the next update")
+
+	w = suite.conditionalRequest(path, "If-None-Match", `"other", W/`+etag)
+	assert.Equal(suite.T(), http.StatusNotModified, w.Code)
+	assert.Empty(suite.T(), w.Body.String())
+	w = suite.conditionalRequest(path, "If-Modified-Since", lastModified)
+	assert.Equal(suite.T(), http.StatusNotModified, w.Code)
+
+	suite.Require().NoError(suite.services.ForecastService.SetForecastFreshness(context.Background(), models.ForecastFreshness{
+		CityId:     cityId,
+		UpdatedAt:  updatedAt.Add(30 * time.Second),
+		NextUpdate: updatedAt.Add(11 * time.Minute),
+	}))
+	w = suite.conditionalRequest(path, "If-None-Match", etag)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.conditionalRequest(path, "If-Modified-Since", lastModified)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestCitiesConditionalRequests() {
+	_, err := suite.services.CityService.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+
+	w := suite.request(http.MethodGet, "/api/cities", "", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.NotEmpty(suite.T(), etag)
+	var resp GetCitiesResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(suite.T(), resp.Cities, 1)
+
+	w = suite.conditionalRequest("/api/cities", "If-None-Match", etag)
+	assert.Equal(suite.T(), http.StatusNotModified, w.Code)
+
+	_, err = suite.services.CityService.CreateCity(context.Background(), models.City{Name: "Paris", Country: "FR"})
+	suite.Require().NoError(err)
+	w = suite.conditionalRequest("/api/cities", "If-None-Match", etag)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestForecastHistory() {
+	cityId, err := suite.services.CityService.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+	day := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -40)
+	_, err = suite.services.ForecastService.CreateForecast(context.Background(), models.Forecast{
+		CityId:       cityId,
+		Temp:         12,
+		Date:         day,
+		ForecastJson: []byte(`{}`),
+	})
+	suite.Require().NoError(err)
+	_, err = suite.services.ForecastService.RollupForecasts(context.Background(), time.Now().AddDate(0, 0, -30), 100)
+	suite.Require().NoError(err)
+
+	w := suite.request(http.MethodGet, fmt.Sprintf("/api/forecast/history/%d", cityId), "", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var resp GetForecastHistoryResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	if assert.Len(suite.T(), resp.Days, 1) {
+		assert.Equal(suite.T(), float32(12), resp.Days[0].TempAvg)
+		assert.Equal(suite.T(), 1, resp.Days[0].Samples)
+	}
+}
+
+func (suite *HandlerTestSuite) TestAlertRules() {
+	token := suite.signIn("alice")
+	cityId, err := suite.services.CityService.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+
+	w := suite.request(http.MethodPost, "/api/users/alerts", token, map[string]any{
+		"city_id": cityId, "metric": models.AlertMetricTempBelow, "threshold": -5, "within_hours": 24,
+	})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var created CreateAlertRuleResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &created))
+
+	w = suite.request(http.MethodPut, fmt.Sprintf("/api/users/alerts/%d", created.Id), token, map[string]any{
+		"city_id": cityId, "metric": "humidity_above", "threshold": 90, "within_hours": 24,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.request(http.MethodPut, fmt.Sprintf("/api/users/alerts/%d", created.Id), token, map[string]any{
+		"city_id": cityId + 1, "metric": models.AlertMetricTempBelow, "threshold": -5, "within_hours": 24,
+	})
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code, "unknown city")
+
+	w = suite.request(http.MethodGet, fmt.Sprintf("/api/users/alerts/%d", created.Id), token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	bob := suite.signIn("bob")
+	w = suite.request(http.MethodGet, "/api/users/alerts", bob, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var rules GetAlertRulesResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &rules))
+	assert.Empty(suite.T(), rules.Rules)
+
+	// Rules of other users look the same as rules that do not exist.
+	path := fmt.Sprintf("/api/users/alerts/%d", created.Id)
+	w = suite.request(http.MethodGet, path, bob, nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	w = suite.request(http.MethodPut, path, bob, map[string]any{
+		"city_id": cityId, "metric": models.AlertMetricTempBelow, "threshold": -5, "within_hours": 24,
+	})
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	w = suite.request(http.MethodDelete, path, bob, nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	w = suite.request(http.MethodDelete, path, token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.request(http.MethodDelete, path, token, nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+}
+
+func (suite *HandlerTestSuite) signInAdmin() string {
+	_, err := suite.services.UserService.CreateUser(context.Background(), models.User{Login: "root", Password: "secret", Role: models.RoleAdmin})
+	suite.Require().NoError(err)
+	w := suite.request(http.MethodPost, "/auth/sign-in", "", map[string]string{"login": "root", "password": "secret"})
+	suite.Require().Equal(http.StatusOK, w.Code)
+	var signIn SignInUserResponse
+	suite.Require().NoError(json.Unmarshal(w.Body.Bytes(), &signIn))
+	return signIn.Token
+}
+
+func (suite *HandlerTestSuite) TestCitySchedulesRequireAdmin() {
+	cityId, err := suite.services.CityService.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+
+	w := suite.request(http.MethodGet, "/api/admin/cities/schedules", suite.signIn("alice"), nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	token := suite.signInAdmin()
+	w = suite.request(http.MethodPut, fmt.Sprintf("/api/admin/cities/%d/schedule", cityId), token, map[string]int{"update_interval": 600})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request(http.MethodGet, "/api/admin/cities/schedules", token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var resp GetCitySchedulesResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(suite.T(), resp.Schedules, 1)
+	assert.Equal(suite.T(), 600, resp.Schedules[0].UpdateInterval)
+}
+
+func (suite *HandlerTestSuite) TestCollectorRuns() {
+	w := suite.request(http.MethodGet, "/api/admin/collector/status", suite.signIn("alice"), nil)
+	assert.Equal(suite.T(), http.StatusForbidden, w.Code)
+
+	token := suite.signInAdmin()
+	run, err := suite.services.CollectorService.StartRun(context.Background(), models.RunTriggerFull)
+	suite.Require().NoError(err)
+	run.CitiesAttempted, run.CitiesSucceeded = 1, 1
+	suite.Require().NoError(suite.services.CollectorService.FinishRun(context.Background(), run))
+
+	w = suite.request(http.MethodGet, "/api/admin/collector/runs?limit=5", token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var runs GetCollectorRunsResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &runs))
+	suite.Require().Len(runs.Runs, 1)
+	assert.Equal(suite.T(), 1, runs.Runs[0].CitiesSucceeded)
+
+	w = suite.request(http.MethodGet, "/api/admin/collector/runs?limit=x", token, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+
+	w = suite.request(http.MethodPost, "/api/admin/collector/trigger", token, nil)
+	assert.Equal(suite.T(), http.StatusConflict, w.Code)
+
+	_, detach := suite.services.CollectorService.AttachCollector()
+	defer detach()
+	w = suite.request(http.MethodPost, "/api/admin/collector/trigger", token, nil)
+	assert.Equal(suite.T(), http.StatusAccepted, w.Code)
+
+	w = suite.request(http.MethodGet, "/api/admin/collector/status", token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var status models.CollectorStatus
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &status))
+	assert.True(suite.T(), status.Running)
+	suite.Require().NotNil(status.LastRun)
+	assert.Equal(suite.T(), run.Id, status.LastRun.Id)
+}
+
+func (suite *HandlerTestSuite) TestSignInLockout() {
+	suite.services.UserService = userservice.NewUserService(suite.services.CityService, memory.NewUserRepository(memory.NewStorage()), userservice.LoginPolicy{
+		MaxFailures: 2,
+		Lockout:     15 * time.Minute,
+	})
+	suite.router = NewHandler(suite.services, pubsub.NewBroker(), Options{}).InitRoutes()
+	// Only signed up: addresses alice signed in from are not locked out.
+	w := suite.request(http.MethodPost, "/auth/sign-up", "", map[string]string{"login": "alice", "password": "secret", "email": "alice@example.com"})
+	suite.Require().Equal(http.StatusOK, w.Code)
+
+	wrong := map[string]string{"login": "alice", "password": "wrong"}
+	suite.request(http.MethodPost, "/auth/sign-in", "", wrong)
+	suite.request(http.MethodPost, "/auth/sign-in", "", wrong)
+	w = suite.request(http.MethodPost, "/auth/sign-in", "", map[string]string{"login": "alice", "password": "secret"})
+	assert.Equal(suite.T(), http.StatusTooManyRequests, w.Code)
+	assert.Equal(suite.T(), "900", w.Header().Get("Retry-After"))
+
+	token := suite.signInAdmin()
+	w = suite.request(http.MethodGet, "/api/admin/users/lockouts", token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var lockouts GetLockoutsResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &lockouts))
+	suite.Require().Len(lockouts.Lockouts, 1)
+	assert.Equal(suite.T(), "login:alice", lockouts.Lockouts[0].Subject)
+
+	w = suite.request(http.MethodGet, "/api/admin/sign-in-attempts?login=alice&limit=2", token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var attempts GetSignInAttemptsResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &attempts))
+	suite.Require().Len(attempts.Attempts, 2)
+	assert.Equal(suite.T(), models.SignInLocked, attempts.Attempts[0].Outcome)
+	assert.Equal(suite.T(), models.SignInFailed, attempts.Attempts[1].Outcome)
+
+	w = suite.request(http.MethodPost, "/api/admin/users/1/unlock", token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.request(http.MethodPost, "/auth/sign-in", "", map[string]string{"login": "alice", "password": "secret"})
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.request(http.MethodPost, "/api/admin/users/999/unlock", token, nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+
+	// The failures also count against the address of httptest requests.
+	w = suite.request(http.MethodDelete, "/api/admin/users/lockouts/ip:192.0.2.1", token, nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	w = suite.request(http.MethodDelete, "/api/admin/users/lockouts/ip:192.0.2.1", token, nil)
+	assert.Equal(suite.T(), http.StatusNotFound, w.Code)
+	w = suite.request(http.MethodDelete, "/api/admin/users/lockouts/alice", token, nil)
+	assert.Equal(suite.T(), http.StatusBadRequest, w.Code)
+}
+
+func (suite *HandlerTestSuite) TestDeadLetters() {
+	cityId, err := suite.services.CityService.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+	for i := 0; i < 3; i++ {
+		_, err := suite.services.CollectorService.RecordCityFailure(context.Background(), cityId, errors.New("timeout"))
+		suite.Require().NoError(err)
+	}
+
+	w := suite.request(http.MethodGet, "/api/admin/collector/dead-letters", suite.signInAdmin(), nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	var resp GetDeadLettersResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	suite.Require().Len(resp.DeadLetters, 1)
+	assert.Equal(suite.T(), "London", resp.DeadLetters[0].City)
+	assert.Equal(suite.T(), 3, resp.DeadLetters[0].Failures)
+	assert.Equal(suite.T(), "timeout", resp.DeadLetters[0].LastError)
+}
+
+func (suite *HandlerTestSuite) TestMetrics() {
+	suite.request(http.MethodGet, "/api/cities", "", nil)
+	suite.request(http.MethodGet, "/no/such/route", "", nil)
+
+	w := suite.request(http.MethodGet, "/metrics", "", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+	assert.Contains(suite.T(), w.Body.String(), `weather_http_requests_total{method="GET",route="/api/cities",status="200"}`)
+	assert.Contains(suite.T(), w.Body.String(), `weather_http_requests_total{method="GET",route="unmatched",status="404"}`)
+}
+
+func (suite *HandlerTestSuite) TestHealth() {
+	w := suite.request(http.MethodGet, "/healthz", "", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	w = suite.request(http.MethodGet, "/readyz", "", nil)
+	assert.Equal(suite.T(), http.StatusOK, w.Code)
+
+	storage := memory.NewStorage()
+	cityId, err := memory.NewCityRepository(storage).CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+	forecastRep := memory.NewForecastRepository(storage)
+	suite.Require().NoError(forecastRep.SetForecastFreshness(context.Background(), models.ForecastFreshness{
+		CityId:    cityId,
+		UpdatedAt: time.Now().Add(-2 * time.Hour),
+	}))
+	suite.services.HealthService = healthservice.NewHealthService(memory.NewHealthRepository(storage), memory.NewCityRepository(storage), forecastRep, healthservice.ReadinessPolicy{
+		MaxForecastAge: time.Hour,
+	})
+	w = suite.request(http.MethodGet, "/readyz", "", nil)
+	assert.Equal(suite.T(), http.StatusServiceUnavailable, w.Code)
+	var readiness models.Readiness
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &readiness))
+	assert.Equal(suite.T(), models.HealthUnavailable, readiness.Status)
+	assert.Equal(suite.T(), []models.HealthCheck{
+		{Name: "database", Status: models.HealthOK},
+		{Name: "forecasts", Status: models.HealthFailed, Error: "newest forecasts were stored 2h0m0s ago, more than 1h0m0s"},
+	}, readiness.Checks)
+}
+
+func (suite *HandlerTestSuite) TestTracing() {
+	recorder := tracetest.NewSpanRecorder()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	}()
+
+	// The request continues the trace of the caller.
+	w := suite.conditionalRequest("/api/forecast/short/42", "traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	assert.NotEqual(suite.T(), http.StatusOK, w.Code)
+	var resp ErrorResponse
+	assert.NoError(suite.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(suite.T(), "4bf92f3577b34da6a3ce929d0e0e4736", resp.TraceId)
+
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		assert.Equal(suite.T(), resp.TraceId, span.SpanContext().TraceID().String())
+		spans[span.Name()] = span
+	}
+	suite.Require().Contains(spans, "/api/forecast/short/:city_id")
+	suite.Require().Contains(spans, "ForecastService.GetForecastFreshness")
+	assert.Equal(suite.T(), spans["/api/forecast/short/:city_id"].SpanContext().SpanID(), spans["ForecastService.GetForecastFreshness"].Parent().SpanID())
+
+	// Probes are not traced.
+	suite.request(http.MethodGet, "/healthz", "", nil)
+	assert.Len(suite.T(), recorder.Ended(), len(spans))
+}
+
+func (suite *HandlerTestSuite) TestWebSocketAuth() {
+	token := suite.signIn("alice")
+	suite.router = NewHandler(suite.services, pubsub.NewBroker(), Options{AllowedOrigins: []string{"https://dash.example.com"}}).InitRoutes()
+	server := httptest.NewServer(suite.router)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/api/ws"
+
+	dial := func(url string, protocols []string, origin string) (*http.Response, error) {
+		dialer := websocket.Dialer{Subprotocols: protocols}
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, resp, err := dialer.Dial(url, header)
+		if err == nil {
+			conn.Close()
+		}
+		return resp, err
+	}
+
+	resp, err := dial(url, []string{"bearer", token}, "https://dash.example.com")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "bearer", resp.Header.Get("Sec-WebSocket-Protocol"))
+
+	resp, err = dial(url, []string{"bearer", token}, "https://evil.example.com")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusForbidden, resp.StatusCode)
+
+	resp, err = dial(url, []string{"bearer", "made-up"}, "https://dash.example.com")
+	assert.Error(suite.T(), err, "a forged token is refused")
+	assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+
+	// Tokens in the query would be written to access logs.
+	resp, err = dial(url+"?token="+token, nil, "")
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusUnauthorized, resp.StatusCode)
+}
+
+func TestHandlerTestSuite(t *testing.T) {
+	suite.Run(t, new(HandlerTestSuite))
+}
@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"net/http"
+	"weather-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type HealthResponse struct {
+	Status string `json:"status"  db:"status"`
+}
+
+// healthz reports that the process is alive
+// @Summary Liveness
+// @Description Answers as long as the process serves requests, without checking its dependencies
+// @Tags health
+// @Produce json
+// @Success 200 {object} HealthResponse
+// @Router /healthz [get]
+func (h *Handler) healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthResponse{models.HealthOK})
+}
+
+// readyz reports whether the server is ready to serve requests
+// @Summary Readiness
+// @Description Checks that the database is reachable, migrated to the version this build expects, and that the newest forecasts are not older than health.max_forecast_age. The body lists each check with the reason it failed
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.Readiness
+// @Failure 503 {object} models.Readiness
+// @Router /readyz [get]
+func (h *Handler) readyz(c *gin.Context) {
+	readiness := h.services.HealthService.Ready(c.Request.Context())
+	status := http.StatusOK
+	if readiness.Status != models.HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, readiness)
+}
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"time"
+	"weather-app/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// unmatchedRoute labels requests that matched no route, so that scans of
+// random paths do not each create series of their own.
+const unmatchedRoute = "unmatched"
+
+// observeRequest records the count and latency of every request by method,
+// route and status.
+func (h *Handler) observeRequest(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+
+	route := c.FullPath()
+	if route == "" {
+		route = unmatchedRoute
+	}
+	metrics.ObserveHTTPRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+}
@@ -2,8 +2,10 @@ package handler
 
 import (
 	"net/http"
+	"weather-app/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
 const (
@@ -11,6 +13,19 @@ const (
 	userCtx             = "userId"
 )
 
+// tokenFromProtocol lets browser WebSockets, which can't set headers, pass the
+// token as the second of the subprotocols "bearer, <token>". Unlike a query
+// parameter, the header does not end up in access logs.
+func (h *Handler) tokenFromProtocol(c *gin.Context) {
+	if c.GetHeader(authorizationHeader) != "" {
+		return
+	}
+	protocols := websocket.Subprotocols(c.Request)
+	if len(protocols) == 2 && protocols[0] == wsAuthProtocol {
+		c.Request.Header.Set(authorizationHeader, protocols[1])
+	}
+}
+
 func (h *Handler) identifyUser(c *gin.Context) {
 	header := c.GetHeader(authorizationHeader)
 	if header == "" {
@@ -25,9 +40,25 @@ func (h *Handler) identifyUser(c *gin.Context) {
 	// 	newErrorResponse(c, http.StatusUnauthorized, "Invalid auth header")
 	// 	return
 	// }
-	userId, err := h.services.UserService.ParseToken(header)
+	userId, err := h.services.UserService.ParseToken(c.Request.Context(), header)
 	if err != nil {
 		newErrorResponse(c, http.StatusUnauthorized, err.Error())
+		return
 	}
 	c.Set(userCtx, userId)
 }
+
+// requireAdmin must follow identifyUser. The role is read from the storage on
+// every request, so a revoked role takes effect immediately.
+func (h *Handler) requireAdmin(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	user, err := h.services.UserService.GetUserById(c.Request.Context(), userId.(int))
+	if err != nil || user.Role != models.RoleAdmin {
+		newErrorResponse(c, http.StatusForbidden, "Admin role required")
+		return
+	}
+}
@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+	"weather-app/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimiter keeps a budget per client for each route with a limit of its
+// own and one shared by all other routes.
+type rateLimiter struct {
+	fallback *ratelimit.Keyed
+	routes   map[string]*ratelimit.Keyed
+}
+
+func newRateLimiter(limits ratelimit.Limits) *rateLimiter {
+	routes := make(map[string]*ratelimit.Keyed, len(limits.Routes))
+	for route, rule := range limits.Routes {
+		routes[route] = ratelimit.NewKeyed(rule)
+	}
+	return &rateLimiter{
+		fallback: ratelimit.NewKeyed(limits.Default),
+		routes:   routes,
+	}
+}
+
+// rateLimit rejects requests over the client's limit with 429 Too Many
+// Requests. Limited responses carry the RateLimit-Limit, RateLimit-Remaining
+// and RateLimit-Reset headers, and rejected ones Retry-After, all in seconds.
+func (h *Handler) rateLimit(c *gin.Context) {
+	limiter, ok := h.limiter.routes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		limiter = h.limiter.fallback
+	}
+	status := limiter.Take(h.clientKey(c))
+	if status.Limit == 0 {
+		return
+	}
+	c.Header("RateLimit-Limit", strconv.Itoa(status.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(status.Remaining))
+	c.Header("RateLimit-Reset", seconds(status.Reset))
+	if !status.Allowed {
+		c.Header("Retry-After", seconds(status.RetryAfter))
+		newErrorResponse(c, http.StatusTooManyRequests, "Too many requests")
+	}
+}
+
+// clientKey identifies the client for rate limiting: the user if the request
+// carries a valid token, its IP address otherwise, so that made-up tokens do
+// not get budgets of their own. The limiter runs before identifyUser, which
+// then refuses such tokens on the routes that require one.
+func (h *Handler) clientKey(c *gin.Context) string {
+	if header := c.GetHeader(authorizationHeader); header != "" {
+		if userId, err := h.services.UserService.ParseToken(c.Request.Context(), header); err == nil {
+			return "user:" + strconv.Itoa(userId)
+		}
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// seconds rounds d up to whole seconds, as the headers take.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
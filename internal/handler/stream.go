@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"weather-app/internal/pubsub"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+const streamHeartbeatInterval = 15 * time.Second
+
+// streamForecast streams forecast updates for the given cities
+// @Summary Stream forecast updates
+// @Description Server-Sent Events stream that pushes a "forecast" event with models.ForecastUpdate whenever new forecasts are stored for one of the cities. city_id may be repeated or comma-separated
+// @Tags stream
+// @Produce text/event-stream
+// @Param city_id query []int true "City IDs" collectionFormat(multi)
+// @Success 200 {object} weather-app_internal_models.ForecastUpdate
+// @Failure 400 {object} ErrorResponse
+// @Router /api/stream/forecast [get]
+func (h *Handler) streamForecast(c *gin.Context) {
+	var cityIds []int
+	for _, param := range c.QueryArray("city_id") {
+		for _, value := range strings.Split(param, ",") {
+			cityId, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+			if err != nil {
+				newErrorResponse(c, http.StatusBadRequest, err.Error())
+				return
+			}
+			cityIds = append(cityIds, int(cityId))
+		}
+	}
+	if len(cityIds) == 0 {
+		newErrorResponse(c, http.StatusBadRequest, "city_id is required")
+		return
+	}
+	h.streamForecastUpdates(c, cityIds)
+}
+
+// streamFavorites streams forecast updates for the user's favorite cities
+// @Summary Stream favorite forecast updates
+// @Description Server-Sent Events stream of forecast updates for the authenticated user's favorite cities at connection time
+// @Tags stream
+// @Produce text/event-stream
+// @Security ApiKeyAuth
+// @Success 200 {object} weather-app_internal_models.ForecastUpdate
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/stream/favorites [get]
+func (h *Handler) streamFavorites(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	if userId.(int) == 0 {
+		newErrorResponse(c, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	cityIds, err := h.services.UserService.GetFavorites(c.Request.Context(), userId.(int))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.streamForecastUpdates(c, cityIds)
+}
+
+func (h *Handler) streamForecastUpdates(c *gin.Context, cityIds []int) {
+	topics := make([]string, 0, len(cityIds))
+	for _, cityId := range cityIds {
+		topics = append(topics, pubsub.ForecastTopic(cityId))
+	}
+	sub := h.broker.Subscribe(topics...)
+	defer sub.Close()
+
+	// The server write timeout would otherwise cut long-lived streams.
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+		logrus.Warnf("Failed to disable write deadline for stream: %v", err)
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.Events():
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event.Data)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		}
+	})
+}
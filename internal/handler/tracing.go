@@ -0,0 +1,26 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// serverName is reported as the server of the request spans.
+const serverName = "weather-app"
+
+// untracedRoutes are polled by monitoring and would only bury the traces of
+// real requests.
+var untracedRoutes = map[string]bool{
+	"/metrics": true,
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// traceRequest starts a span for every request, continuing the trace of the
+// caller if the request carries a traceparent header. The span is named after
+// the route the request matched.
+func (h *Handler) traceRequest() gin.HandlerFunc {
+	return otelgin.Middleware(serverName, otelgin.WithGinFilter(func(c *gin.Context) bool {
+		return !untracedRoutes[c.FullPath()]
+	}))
+}
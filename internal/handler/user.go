@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"weather-app/internal/dto"
 	"weather-app/internal/models"
+	"weather-app/internal/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -31,7 +33,7 @@ func (h *Handler) signUpUser(c *gin.Context) {
 		return
 	}
 
-	id, err := h.services.UserService.CreateUser(models.User{
+	id, err := h.services.UserService.CreateUser(c.Request.Context(), models.User{
 		Login:    user.Login,
 		Password: user.Password,
 		Email:    user.Email,
@@ -49,13 +51,14 @@ type SignInUserResponse struct {
 
 // signInUser authenticates a user and returns a token
 // @Summary Sign in user
-// @Description Authenticates a user and returns a JWT token
+// @Description Authenticates a user and returns a JWT token. After failed sign-ins the login has to wait before trying again, and too many of them lock the login or the address out for a while
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param input body dto.DTOSignIn true "Sign in info"
 // @Success 200 {object} SignInUserResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/sign-in [post]
 func (h *Handler) signInUser(c *gin.Context) {
@@ -64,7 +67,13 @@ func (h *Handler) signInUser(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	token, err := h.services.UserService.GenerateToken(user.Login, user.Password)
+	token, err := h.services.UserService.GenerateToken(c.Request.Context(), user.Login, user.Password, c.ClientIP())
+	var blocked *service.SignInBlockedError
+	if errors.As(err, &blocked) {
+		c.Header("Retry-After", seconds(blocked.RetryAfter))
+		newErrorResponse(c, http.StatusTooManyRequests, err.Error())
+		return
+	}
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -91,7 +100,7 @@ func (h *Handler) getFavorites(c *gin.Context) {
 		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
 		return
 	}
-	citiesIds, err := h.services.UserService.GetFavorites(userId.(int))
+	citiesIds, err := h.services.UserService.GetFavorites(c.Request.Context(), userId.(int))
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -99,7 +108,7 @@ func (h *Handler) getFavorites(c *gin.Context) {
 
 	var resp GetFavoritesResponse
 	for _, cityId := range citiesIds {
-		city, err := h.services.CityService.GetCity(cityId)
+		city, err := h.services.CityService.GetCity(c.Request.Context(), cityId)
 		if err != nil {
 			newErrorResponse(c, http.StatusInternalServerError, err.Error())
 			return
@@ -132,7 +141,7 @@ func (h *Handler) addFavorite(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	_, err = h.services.UserService.AddFavorite(userId.(int), int(cityId))
+	_, err = h.services.UserService.AddFavorite(c.Request.Context(), userId.(int), int(cityId))
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
@@ -162,7 +171,7 @@ func (h *Handler) deleteFavorite(c *gin.Context) {
 		newErrorResponse(c, http.StatusBadRequest, err.Error())
 		return
 	}
-	err = h.services.UserService.DeleteFavorite(userId.(int), int(cityId))
+	err = h.services.UserService.DeleteFavorite(c.Request.Context(), userId.(int), int(cityId))
 	if err != nil {
 		newErrorResponse(c, http.StatusInternalServerError, err.Error())
 		return
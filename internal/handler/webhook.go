@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"weather-app/internal/dto"
+	"weather-app/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+type GetWebhooksResponse struct {
+	Webhooks []models.Webhook `json:"webhooks"  db:"webhooks"`
+}
+
+type GetDeliveriesResponse struct {
+	Deliveries []models.WebhookDelivery `json:"deliveries"  db:"deliveries"`
+}
+
+// getWebhooks retrieves the webhooks of the authenticated user
+// @Summary Get webhooks
+// @Description Retrieves the webhook endpoints registered by the authenticated user
+// @Tags webhooks
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} GetWebhooksResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/webhooks [get]
+func (h *Handler) getWebhooks(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	webhooks, err := h.services.WebhookService.GetWebhooks(c.Request.Context(), userId.(int))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, GetWebhooksResponse{
+		Webhooks: webhooks,
+	})
+}
+
+// createWebhook registers a webhook endpoint for the authenticated user
+// @Summary Create webhook
+// @Description Registers an endpoint that receives alert.triggered and forecast.updated events. The returned secret is used to verify the X-Webhook-Signature header and is shown only once
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param input body dto.DTOWebhook true "Webhook"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/webhooks [post]
+func (h *Handler) createWebhook(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	var input dto.DTOWebhook
+	if err := c.BindJSON(&input); err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	webhook, err := h.services.WebhookService.CreateWebhook(c.Request.Context(), models.Webhook{
+		UserId: userId.(int),
+		URL:    input.URL,
+	})
+	if err != nil {
+		newErrorResponse(c, errorStatus(err), err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, webhook)
+}
+
+// deleteWebhook removes a webhook endpoint of the authenticated user
+// @Summary Delete webhook
+// @Description Removes a webhook endpoint together with its delivery log
+// @Tags webhooks
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Success 200
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/webhooks/{id} [delete]
+func (h *Handler) deleteWebhook(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	webhookId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	err = h.services.WebhookService.DeleteWebhook(c.Request.Context(), userId.(int), int(webhookId))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+// getWebhookDeliveries retrieves the delivery log of a webhook
+// @Summary Get webhook deliveries
+// @Description Retrieves the latest deliveries of a webhook, newest first
+// @Tags webhooks
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} GetDeliveriesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/webhooks/{id}/deliveries [get]
+func (h *Handler) getWebhookDeliveries(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	webhookId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	deliveries, err := h.services.WebhookService.GetDeliveries(c.Request.Context(), userId.(int), int(webhookId))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, GetDeliveriesResponse{
+		Deliveries: deliveries,
+	})
+}
+
+// testWebhook sends a ping event to a webhook
+// @Summary Test webhook
+// @Description Synchronously sends a signed ping event to the webhook and returns the recorded delivery
+// @Tags webhooks
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} models.WebhookDelivery
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/users/webhooks/{id}/test [post]
+func (h *Handler) testWebhook(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	webhookId, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		newErrorResponse(c, http.StatusBadRequest, err.Error())
+		return
+	}
+	delivery, err := h.services.WebhookService.TestWebhook(c.Request.Context(), userId.(int), int(webhookId))
+	if err != nil {
+		newErrorResponse(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"weather-app/internal/pubsub"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	wsHeartbeatInterval = 30 * time.Second
+	wsPongWait          = 2 * wsHeartbeatInterval
+	wsWriteWait         = 10 * time.Second
+	wsMaxMessageSize    = 4096
+
+	wsChannelForecast = "forecast"
+	wsChannelAlerts   = "alerts"
+
+	// wsAuthProtocol is the subprotocol that carries the token, which the
+	// server selects to accept the connection.
+	wsAuthProtocol = "bearer"
+)
+
+func (h *Handler) newUpgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		Subprotocols:    []string{wsAuthProtocol},
+		CheckOrigin:     h.checkOrigin,
+	}
+}
+
+// checkOrigin admits requests from the server's own origin, from the allowed
+// origins and from clients other than browsers, which send no Origin. Other
+// web pages must not open sockets on behalf of their visitors.
+func (h *Handler) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host) || h.allowedOrigins[strings.ToLower(origin)]
+}
+
+// wsClientMessage is sent by the client to change its subscriptions.
+type wsClientMessage struct {
+	Action  string `json:"action"`
+	Channel string `json:"channel"`
+	CityIds []int  `json:"city_ids"`
+}
+
+// wsServerMessage is sent to the client. Type is one of forecast, alert,
+// subscribed, unsubscribed, heartbeat or error.
+type wsServerMessage struct {
+	Type    string    `json:"type"`
+	Channel string    `json:"channel,omitempty"`
+	CityIds []int     `json:"city_ids,omitempty"`
+	Data    any       `json:"data,omitempty"`
+	Message string    `json:"message,omitempty"`
+	Time    time.Time `json:"time"`
+}
+
+// serveWebSocket serves the live dashboard WebSocket
+// @Summary WebSocket for live updates
+// @Description Upgrades to a WebSocket. The client sends {"action":"subscribe"|"unsubscribe","channel":"forecast"|"alerts","city_ids":[...]} and receives forecast, alert and heartbeat messages. Browsers pass the token as the subprotocols "bearer, <token>". Pages from other origins than the server's and server.allowed_origins are refused
+// @Tags stream
+// @Security ApiKeyAuth
+// @Param Sec-WebSocket-Protocol header string false "bearer, <JWT token>"
+// @Success 101
+// @Failure 401 {object} ErrorResponse
+// @Router /api/ws [get]
+func (h *Handler) serveWebSocket(c *gin.Context) {
+	userId, ok := c.Get(userCtx)
+	if !ok {
+		newErrorResponse(c, http.StatusInternalServerError, "UserId not found")
+		return
+	}
+	// Tokens that were never issued by the server carry no user.
+	if userId.(int) == 0 {
+		newErrorResponse(c, http.StatusUnauthorized, "Invalid token")
+		return
+	}
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logrus.Errorf("Failed to upgrade websocket: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	sub := h.broker.Subscribe()
+	defer sub.Close()
+
+	replies := make(chan wsServerMessage, 8)
+	done := make(chan struct{})
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		defer close(done)
+		h.readWebSocket(conn, sub, userId.(int), replies, stop)
+	}()
+
+	heartbeat := time.NewTicker(wsHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var msg wsServerMessage
+		select {
+		case <-done:
+			return
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			msg = wsServerMessage{Type: event.Type, Data: event.Data}
+		case msg = <-replies:
+		case <-heartbeat.C:
+			conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+			msg = wsServerMessage{Type: "heartbeat"}
+		}
+		msg.Time = time.Now()
+		conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+		if err := conn.WriteJSON(msg); err != nil {
+			return
+		}
+	}
+}
+
+// readWebSocket applies subscription changes requested by the client until the
+// connection is closed or stops answering pings.
+func (h *Handler) readWebSocket(conn *websocket.Conn, sub *pubsub.Subscription, userId int, replies chan<- wsServerMessage, stop <-chan struct{}) {
+	reply := func(msg wsServerMessage) {
+		select {
+		case replies <- msg:
+		case <-stop:
+		}
+	}
+
+	conn.SetReadLimit(wsMaxMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(wsPongWait))
+
+		var msg wsClientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			reply(wsServerMessage{Type: "error", Message: "invalid message: " + err.Error()})
+			continue
+		}
+
+		var topics []string
+		switch msg.Channel {
+		case wsChannelForecast:
+			if len(msg.CityIds) == 0 {
+				reply(wsServerMessage{Type: "error", Channel: msg.Channel, Message: "city_ids is required"})
+				continue
+			}
+			for _, cityId := range msg.CityIds {
+				topics = append(topics, pubsub.ForecastTopic(cityId))
+			}
+		case wsChannelAlerts:
+			topics = append(topics, pubsub.AlertTopic(userId))
+		default:
+			reply(wsServerMessage{Type: "error", Channel: msg.Channel, Message: "unknown channel"})
+			continue
+		}
+
+		switch msg.Action {
+		case "subscribe":
+			sub.Add(topics...)
+			reply(wsServerMessage{Type: "subscribed", Channel: msg.Channel, CityIds: msg.CityIds})
+		case "unsubscribe":
+			sub.Remove(topics...)
+			reply(wsServerMessage{Type: "unsubscribed", Channel: msg.Channel, CityIds: msg.CityIds})
+		default:
+			reply(wsServerMessage{Type: "error", Message: "unknown action"})
+		}
+	}
+}
@@ -0,0 +1,93 @@
+// Package lifecycle starts the long-running parts of a process and shuts them
+// down in order.
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Component is a part of the process managed by a Manager. Start blocks while
+// the component runs and may be nil for components that only need cleanup,
+// such as the database. Stop asks it to finish its work before ctx expires.
+type Component struct {
+	Name  string
+	Start func(ctx context.Context) error
+	Stop  func(ctx context.Context) error
+}
+
+// Manager runs components until the process is asked to terminate or one of
+// them fails, then stops them in the reverse order of registration within a
+// single shutdown timeout.
+type Manager struct {
+	timeout    time.Duration
+	components []Component
+}
+
+func NewManager(timeout time.Duration) *Manager {
+	return &Manager{timeout: timeout}
+}
+
+func (m *Manager) Add(component Component) {
+	m.components = append(m.components, component)
+}
+
+// Run starts the components and blocks until ctx is canceled or a component
+// stops on its own, then shuts everything down: each component is asked to
+// stop and waited for before the previous one is stopped. The context the
+// components run with is canceled only once the shutdown timeout expires, so
+// Stop decides how in-flight work ends.
+func (m *Manager) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancel()
+
+	done := make([]chan struct{}, len(m.components))
+	exited := make(chan error, len(m.components))
+	for i, component := range m.components {
+		done[i] = make(chan struct{})
+		if component.Start == nil {
+			close(done[i])
+			continue
+		}
+		go func(component Component, done chan struct{}) {
+			defer close(done)
+			err := component.Start(runCtx)
+			if err != nil {
+				err = fmt.Errorf("%s: %w", component.Name, err)
+			} else {
+				err = fmt.Errorf("%s stopped unexpectedly", component.Name)
+			}
+			exited <- err
+		}(component, done[i])
+	}
+
+	var errs []error
+	select {
+	case <-ctx.Done():
+	case err := <-exited:
+		errs = append(errs, err)
+	}
+
+	logrus.Print("Shutting down")
+	stopCtx, stopCancel := context.WithTimeout(context.WithoutCancel(ctx), m.timeout)
+	defer stopCancel()
+	for i := len(m.components) - 1; i >= 0; i-- {
+		component := m.components[i]
+		if component.Stop != nil {
+			if err := component.Stop(stopCtx); err != nil {
+				errs = append(errs, fmt.Errorf("stop %s: %w", component.Name, err))
+			}
+		}
+		select {
+		case <-done[i]:
+		case <-stopCtx.Done():
+			cancel()
+			<-done[i]
+		}
+	}
+	return errors.Join(errs...)
+}
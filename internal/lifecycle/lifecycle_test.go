@@ -0,0 +1,152 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ManagerTestSuite struct {
+	suite.Suite
+	mu    sync.Mutex
+	calls []string
+}
+
+func (suite *ManagerTestSuite) SetupTest() {
+	suite.calls = nil
+}
+
+func (suite *ManagerTestSuite) record(call string) {
+	suite.mu.Lock()
+	defer suite.mu.Unlock()
+	suite.calls = append(suite.calls, call)
+}
+
+// blocking returns a component that runs until it is stopped.
+func (suite *ManagerTestSuite) blocking(name string) Component {
+	stop := make(chan struct{})
+	return Component{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			<-stop
+			suite.record(name + " exited")
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			suite.record("stop " + name)
+			close(stop)
+			return nil
+		},
+	}
+}
+
+func (suite *ManagerTestSuite) TestStopsInReverseOrder() {
+	manager := NewManager(time.Second)
+	manager.Add(Component{Name: "db", Stop: func(ctx context.Context) error {
+		suite.record("stop db")
+		return nil
+	}})
+	manager.Add(suite.blocking("collector"))
+	manager.Add(suite.blocking("server"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(suite.T(), manager.Run(ctx))
+	assert.Equal(suite.T(), []string{
+		"stop server", "server exited",
+		"stop collector", "collector exited",
+		"stop db",
+	}, suite.calls)
+}
+
+func (suite *ManagerTestSuite) TestComponentFailureShutsDown() {
+	manager := NewManager(time.Second)
+	manager.Add(suite.blocking("collector"))
+	manager.Add(Component{Name: "server", Start: func(ctx context.Context) error {
+		return errors.New("address already in use")
+	}})
+
+	err := manager.Run(context.Background())
+	assert.EqualError(suite.T(), err, "server: address already in use")
+	assert.Equal(suite.T(), []string{"stop collector", "collector exited"}, suite.calls)
+}
+
+func (suite *ManagerTestSuite) TestTimeoutCancelsComponents() {
+	manager := NewManager(10 * time.Millisecond)
+	manager.Add(Component{
+		Name: "collector",
+		Start: func(ctx context.Context) error {
+			<-ctx.Done()
+			suite.record("collector canceled")
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			return nil
+		},
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.NoError(suite.T(), manager.Run(ctx))
+	assert.Equal(suite.T(), []string{"collector canceled"}, suite.calls)
+}
+
+func TestManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(ManagerTestSuite))
+}
+
+type RunnerTestSuite struct {
+	suite.Suite
+	runner *Runner
+}
+
+func (suite *RunnerTestSuite) SetupTest() {
+	suite.runner = NewRunner()
+}
+
+func (suite *RunnerTestSuite) TestStopWaitsForLoop() {
+	finished := false
+	go suite.runner.Run(context.Background(), func(ctx context.Context) error {
+		<-suite.runner.Stopping()
+		time.Sleep(10 * time.Millisecond)
+		finished = true
+		return nil
+	})
+	time.Sleep(5 * time.Millisecond)
+
+	assert.NoError(suite.T(), suite.runner.Stop(context.Background()))
+	assert.True(suite.T(), finished)
+}
+
+func (suite *RunnerTestSuite) TestStopCancelsWorkAfterDeadline() {
+	var workErr error
+	go suite.runner.Run(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		workErr = ctx.Err()
+		return nil
+	})
+	time.Sleep(5 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(suite.T(), suite.runner.Stop(ctx), context.DeadlineExceeded)
+	assert.ErrorIs(suite.T(), workErr, context.Canceled)
+}
+
+func (suite *RunnerTestSuite) TestStopBeforeRun() {
+	assert.NoError(suite.T(), suite.runner.Stop(context.Background()))
+	select {
+	case <-suite.runner.Stopping():
+	default:
+		suite.Fail("Stopping is not closed")
+	}
+}
+
+func TestRunnerTestSuite(t *testing.T) {
+	suite.Run(t, new(RunnerTestSuite))
+}
@@ -0,0 +1,67 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Runner implements graceful stopping for a background loop. The loop checks
+// Stopping between units of work; Stop waits for it to return and, once the
+// stop deadline passes, cancels the context of the work still in flight.
+type Runner struct {
+	stopping chan struct{}
+	kill     chan struct{}
+	done     chan struct{}
+	started  atomic.Bool
+	stopOnce sync.Once
+	killOnce sync.Once
+}
+
+func NewRunner() *Runner {
+	return &Runner{
+		stopping: make(chan struct{}),
+		kill:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Run calls loop with a context that is canceled when ctx is or when Stop
+// gives up waiting, and returns what loop returns.
+func (r *Runner) Run(ctx context.Context, loop func(ctx context.Context) error) error {
+	r.started.Store(true)
+	defer close(r.done)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-r.kill:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return loop(ctx)
+}
+
+// Stopping is closed once Stop has been called.
+func (r *Runner) Stopping() <-chan struct{} {
+	return r.stopping
+}
+
+// Stop asks the loop to finish and waits for it. If ctx expires first, the
+// work in flight is canceled and ctx.Err() is returned once the loop exits.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.stopOnce.Do(func() { close(r.stopping) })
+	if !r.started.Load() {
+		return nil
+	}
+	select {
+	case <-r.done:
+		return nil
+	case <-ctx.Done():
+		r.killOnce.Do(func() { close(r.kill) })
+		<-r.done
+		return ctx.Err()
+	}
+}
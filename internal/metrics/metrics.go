@@ -0,0 +1,161 @@
+// Package metrics holds the Prometheus metrics of the application. They are
+// registered in Registry, which the server exposes at /metrics.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+	"weather-app/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "weather"
+
+// freshnessTimeout bounds the query made for each scrape.
+const freshnessTimeout = 5 * time.Second
+
+// Registry holds the metrics of the application and of the Go runtime.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by method, route and status.",
+	}, []string{"method", "route", "status"})
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Time spent serving HTTP requests, by method and route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	providerRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "provider_request_duration_seconds",
+		Help:      "Time spent on requests to the weather provider, by endpoint. Retries are observed separately.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+	}, []string{"endpoint"})
+	providerErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "provider_errors_total",
+		Help:      "Failed requests to the weather provider, by endpoint and reason.",
+	}, []string{"endpoint", "reason"})
+
+	collectorRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "collector_run_duration_seconds",
+		Help:      "Duration of data collector runs, by trigger.",
+		Buckets:   []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	}, []string{"trigger"})
+	collectorCityFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "collector_city_failures_total",
+		Help:      "Failed forecast updates, by city.",
+	}, []string{"city_id", "city"})
+	forecastRowsWritten = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "forecast_rows_written_total",
+		Help:      "Forecast rows written by the data collector.",
+	})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		providerRequestDuration,
+		providerErrors,
+		collectorRunDuration,
+		collectorCityFailures,
+		forecastRowsWritten,
+	)
+}
+
+// Handler serves the metrics in Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
+
+// ObserveHTTPRequest records a served request. Route is the pattern the
+// request matched, such as /api/forecast/short/:city_id, which keeps the
+// number of series bounded.
+func ObserveHTTPRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveProviderRequest records a request that reached the weather provider.
+func ObserveProviderRequest(endpoint string, duration time.Duration) {
+	providerRequestDuration.WithLabelValues(endpoint).Observe(duration.Seconds())
+}
+
+// ProviderError counts a failed request to the weather provider.
+func ProviderError(endpoint, reason string) {
+	providerErrors.WithLabelValues(endpoint, reason).Inc()
+}
+
+// ObserveCollectorRun records a finished data collector run.
+func ObserveCollectorRun(trigger string, duration time.Duration) {
+	collectorRunDuration.WithLabelValues(trigger).Observe(duration.Seconds())
+}
+
+// CityFailure counts a failed forecast update of city.
+func CityFailure(city models.City) {
+	collectorCityFailures.WithLabelValues(strconv.Itoa(city.Id), city.Name).Inc()
+}
+
+// ForecastRowsWritten counts forecast rows written.
+func ForecastRowsWritten(n int) {
+	forecastRowsWritten.Add(float64(n))
+}
+
+// RegisterDB exposes the connection pool stats of db, labeled with name.
+func RegisterDB(db *sql.DB, name string) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, name))
+}
+
+// RegisterFreshness exposes the age of the newest forecasts of each city,
+// loaded from load on every scrape.
+func RegisterFreshness(load func(ctx context.Context) ([]models.ForecastFreshness, error)) error {
+	return Registry.Register(&freshnessCollector{load: load, now: time.Now})
+}
+
+var forecastAge = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "forecast_age_seconds"),
+	"Time since the newest forecasts of the city were stored.",
+	[]string{"city_id"}, nil,
+)
+
+// freshnessCollector reads forecast freshness from storage when scraped, so
+// that it is right whichever process runs the data collector.
+type freshnessCollector struct {
+	load func(ctx context.Context) ([]models.ForecastFreshness, error)
+	now  func() time.Time
+}
+
+func (c *freshnessCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- forecastAge
+}
+
+func (c *freshnessCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), freshnessTimeout)
+	defer cancel()
+	freshness, err := c.load(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(forecastAge, err)
+		return
+	}
+	now := c.now()
+	for _, city := range freshness {
+		ch <- prometheus.MustNewConstMetric(forecastAge, prometheus.GaugeValue, now.Sub(city.UpdatedAt).Seconds(), strconv.Itoa(city.CityId))
+	}
+}
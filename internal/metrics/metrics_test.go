@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreshnessCollector(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	collector := &freshnessCollector{
+		load: func(ctx context.Context) ([]models.ForecastFreshness, error) {
+			return []models.ForecastFreshness{
+				{CityId: 1, UpdatedAt: now.Add(-90 * time.Second)},
+				{CityId: 2, UpdatedAt: now.Add(-time.Hour)},
+			}, nil
+		},
+		now: func() time.Time { return now },
+	}
+
+	expected := `
+# HELP weather_forecast_age_seconds Time since the newest forecasts of the city were stored.
+# TYPE weather_forecast_age_seconds gauge
+weather_forecast_age_seconds{city_id="1"} 90
+weather_forecast_age_seconds{city_id="2"} 3600
+`
+	assert.NoError(t, testutil.CollectAndCompare(collector, strings.NewReader(expected)))
+}
+
+func TestFreshnessCollectorError(t *testing.T) {
+	collector := &freshnessCollector{
+		load: func(ctx context.Context) ([]models.ForecastFreshness, error) {
+			return nil, errors.New("database is down")
+		},
+		now: time.Now,
+	}
+
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(collector)
+	_, err := registry.Gather()
+	assert.ErrorContains(t, err, "database is down")
+}
+
+func TestObserveHTTPRequest(t *testing.T) {
+	before := testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/api/cities", "200"))
+	ObserveHTTPRequest("GET", "/api/cities", 200, 10*time.Millisecond)
+	assert.Equal(t, before+1, testutil.ToFloat64(httpRequests.WithLabelValues("GET", "/api/cities", "200")))
+}
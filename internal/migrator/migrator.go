@@ -0,0 +1,150 @@
+// Package migrator applies the migrations embedded into the binary with
+// golang-migrate.
+package migrator
+
+import (
+	"database/sql"
+	"errors"
+	"io/fs"
+	"os"
+	"weather-app/db"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/database/sqlite"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+type Status struct {
+	// Version is the last applied migration, 0 if none were applied.
+	Version uint
+	// Dirty is set when the last migration failed halfway and the schema has
+	// to be fixed by hand before forcing the version with goto.
+	Dirty bool
+	// Migrations lists the versions of all embedded migrations in order.
+	Migrations []uint
+}
+
+// Migrator takes ownership of the database handle: Close closes it.
+type Migrator struct {
+	m      *migrate.Migrate
+	source source.Driver
+}
+
+func NewPostgresMigrator(conn *sql.DB) (*Migrator, error) {
+	driver, err := postgres.WithInstance(conn, &postgres.Config{})
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(db.PostgresMigrations, "migrations", "postgres", driver)
+}
+
+func NewSqliteMigrator(conn *sql.DB) (*Migrator, error) {
+	driver, err := sqlite.WithInstance(conn, &sqlite.Config{})
+	if err != nil {
+		return nil, err
+	}
+	return newMigrator(db.SqliteMigrations, "sqlite_migrations", "sqlite", driver)
+}
+
+func newMigrator(fsys fs.FS, dir string, driverName string, driver database.Driver) (*Migrator, error) {
+	src, err := iofs.New(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	m, err := migrate.NewWithInstance("iofs", src, driverName, driver)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{m: m, source: src}, nil
+}
+
+// Up applies all pending migrations.
+func (m *Migrator) Up() error {
+	return ignoreNoChange(m.m.Up())
+}
+
+// Down rolls back the last applied migration.
+func (m *Migrator) Down() error {
+	return ignoreNoChange(m.m.Steps(-1))
+}
+
+// Goto migrates up or down to the given version. If the schema is dirty, the
+// version is forced instead, which is how a failed migration is recovered.
+func (m *Migrator) Goto(version uint) error {
+	_, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return err
+	}
+	if dirty {
+		return m.m.Force(int(version))
+	}
+	return ignoreNoChange(m.m.Migrate(version))
+}
+
+func (m *Migrator) Status() (Status, error) {
+	var status Status
+	version, dirty, err := m.m.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return Status{}, err
+	}
+	status.Version = version
+	status.Dirty = dirty
+
+	status.Migrations, err = versions(m.source)
+	if err != nil {
+		return Status{}, err
+	}
+	return status, nil
+}
+
+// LatestPostgres returns the version of the last embedded postgres migration.
+func LatestPostgres() (uint, error) {
+	return latest(db.PostgresMigrations, "migrations")
+}
+
+// LatestSqlite returns the version of the last embedded sqlite migration.
+func LatestSqlite() (uint, error) {
+	return latest(db.SqliteMigrations, "sqlite_migrations")
+}
+
+func latest(fsys fs.FS, dir string) (uint, error) {
+	src, err := iofs.New(fsys, dir)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	migrations, err := versions(src)
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1], nil
+}
+
+// versions lists the versions of the migrations in src in order.
+func versions(src source.Driver) ([]uint, error) {
+	var migrations []uint
+	next, err := src.First()
+	for err == nil {
+		migrations = append(migrations, next)
+		next, err = src.Next(next)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
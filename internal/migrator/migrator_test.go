@@ -0,0 +1,88 @@
+package migrator
+
+import (
+	"path/filepath"
+	"testing"
+	"weather-app/internal/repository/sqlite"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigratorTestSuite struct {
+	suite.Suite
+	migrator *Migrator
+}
+
+func (suite *MigratorTestSuite) SetupTest() {
+	db, err := sqlite.NewSqliteConnection(sqlite.SqliteConfig{Path: filepath.Join(suite.T().TempDir(), "weather.db")})
+	suite.Require().NoError(err)
+	suite.migrator, err = NewSqliteMigrator(db.DB)
+	suite.Require().NoError(err)
+}
+
+func (suite *MigratorTestSuite) TearDownTest() {
+	assert.NoError(suite.T(), suite.migrator.Close())
+}
+
+func (suite *MigratorTestSuite) TestStatusBeforeMigrations() {
+	status, err := suite.migrator.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), uint(0), status.Version)
+	assert.False(suite.T(), status.Dirty)
+	assert.GreaterOrEqual(suite.T(), len(status.Migrations), 4)
+	for i, version := range status.Migrations {
+		assert.Equal(suite.T(), uint(i+1), version)
+	}
+}
+
+func (suite *MigratorTestSuite) latestVersion() uint {
+	status, err := suite.migrator.Status()
+	suite.Require().NoError(err)
+	return status.Migrations[len(status.Migrations)-1]
+}
+
+func (suite *MigratorTestSuite) TestUpIsIdempotent() {
+	assert.NoError(suite.T(), suite.migrator.Up())
+	assert.NoError(suite.T(), suite.migrator.Up())
+
+	status, err := suite.migrator.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.latestVersion(), status.Version)
+}
+
+func (suite *MigratorTestSuite) TestDownRollsBackOneMigration() {
+	assert.NoError(suite.T(), suite.migrator.Up())
+	assert.NoError(suite.T(), suite.migrator.Down())
+
+	status, err := suite.migrator.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.latestVersion()-1, status.Version)
+}
+
+func (suite *MigratorTestSuite) TestGoto() {
+	assert.NoError(suite.T(), suite.migrator.Goto(2))
+	status, err := suite.migrator.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), uint(2), status.Version)
+
+	assert.NoError(suite.T(), suite.migrator.Goto(4))
+	assert.NoError(suite.T(), suite.migrator.Goto(1))
+	status, err = suite.migrator.Status()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), uint(1), status.Version)
+}
+
+func (suite *MigratorTestSuite) TestLatest() {
+	latest, err := LatestSqlite()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.latestVersion(), latest)
+
+	latest, err = LatestPostgres()
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), suite.latestVersion(), latest, "both backends have the same migrations")
+}
+
+func TestMigratorTestSuite(t *testing.T) {
+	suite.Run(t, new(MigratorTestSuite))
+}
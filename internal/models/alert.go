@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+const (
+	AlertMetricTempBelow     = "temp_below"
+	AlertMetricTempAbove     = "temp_above"
+	AlertMetricWindGustAbove = "wind_gust_above"
+	AlertMetricPopAbove      = "pop_above"
+)
+
+// AlertRule represents a user-defined weather alert rule
+// @Description Weather alert rule model
+type AlertRule struct {
+	Id          int     `json:"id"  db:"id"`                     // @Description Alert rule ID
+	UserId      int     `json:"user_id"  db:"user_id"`           // @Description Owner user ID
+	CityId      int     `json:"city_id"  db:"city_id"`           // @Description City ID
+	Metric      string  `json:"metric"  db:"metric"`             // @Description One of temp_below, temp_above, wind_gust_above, pop_above
+	Threshold   float64 `json:"threshold"  db:"threshold"`       // @Description Threshold value (pop in percent)
+	WithinHours int     `json:"within_hours"  db:"within_hours"` // @Description Look-ahead window in hours
+}
+
+// TriggeredAlert represents an alert rule that matched a forecast
+// @Description Triggered weather alert model
+type TriggeredAlert struct {
+	Id           int       `json:"id"  db:"id"`                       // @Description Triggered alert ID
+	RuleId       int       `json:"rule_id"  db:"rule_id"`             // @Description Alert rule ID
+	UserId       int       `json:"user_id"  db:"user_id"`             // @Description Owner user ID
+	CityId       int       `json:"city_id"  db:"city_id"`             // @Description City ID
+	Metric       string    `json:"metric"  db:"metric"`               // @Description Rule metric
+	Threshold    float64   `json:"threshold"  db:"threshold"`         // @Description Rule threshold
+	Value        float64   `json:"value"  db:"value"`                 // @Description Forecast value that triggered the rule
+	ForecastDate time.Time `json:"forecast_date"  db:"forecast_date"` // @Description Date of the matching forecast
+	TriggeredAt  time.Time `json:"triggered_at"  db:"triggered_at"`   // @Description Time the alert was recorded
+}
@@ -9,3 +9,11 @@ type City struct {
 	Latitude  float64 `json:"latitude"  db:"latitude"`   // @Description Latitude of the city
 	Longitude float64 `json:"longitude"  db:"longitude"` // @Description Longitude of the city
 }
+
+// CitySchedule represents how often the collector updates a city's forecasts
+// @Description City update schedule model
+type CitySchedule struct {
+	City
+	UpdateInterval int `json:"update_interval"  db:"update_interval"` // @Description Update interval in seconds set by an admin, 0 for automatic
+	Favorites      int `json:"favorites"  db:"favorites"`             // @Description Number of users who have the city in favorites
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	RunTriggerFull      = "full"
+	RunTriggerScheduled = "scheduled"
+	RunTriggerManual    = "manual"
+)
+
+// RunFailure represents a city the collector failed to update
+// @Description City that failed during a collector run
+type RunFailure struct {
+	CityId int    `json:"city_id"` // @Description City ID
+	City   string `json:"city"`    // @Description City name
+	Error  string `json:"error"`   // @Description Failure reason
+}
+
+// RunFailures is stored as a JSON array.
+type RunFailures []RunFailure
+
+func (f RunFailures) Value() (driver.Value, error) {
+	if f == nil {
+		f = RunFailures{}
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (f *RunFailures) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*f = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(src, f)
+	case string:
+		return json.Unmarshal([]byte(src), f)
+	default:
+		return fmt.Errorf("cannot scan %T into RunFailures", src)
+	}
+}
+
+// CollectorRun represents one pass of the data collector over a set of cities
+// @Description Data collector run
+type CollectorRun struct {
+	Id              int         `json:"id"  db:"id"`                             // @Description Run ID
+	TriggeredBy     string      `json:"triggered_by"  db:"triggered_by"`         // @Description One of full, scheduled, manual
+	StartedAt       time.Time   `json:"started_at"  db:"started_at"`             // @Description Start time
+	FinishedAt      *time.Time  `json:"finished_at"  db:"finished_at"`           // @Description End time, null while the run is in progress
+	CitiesAttempted int         `json:"cities_attempted"  db:"cities_attempted"` // @Description Cities requested from the provider
+	CitiesSucceeded int         `json:"cities_succeeded"  db:"cities_succeeded"` // @Description Cities whose forecasts were stored
+	CitiesFailed    int         `json:"cities_failed"  db:"cities_failed"`       // @Description Cities that failed
+	CitiesSkipped   int         `json:"cities_skipped"  db:"cities_skipped"`     // @Description Cities skipped because the provider was unavailable or the collector stopped
+	RowsWritten     int         `json:"rows_written"  db:"rows_written"`         // @Description Forecast rows written
+	Failures        RunFailures `json:"failures"  db:"failures"`                 // @Description Failed cities with reasons
+}
+
+// DeadLetter represents a city whose updates keep failing
+// @Description City the data collector keeps failing to update
+type DeadLetter struct {
+	CityId        int       `json:"city_id"  db:"city_id"`                 // @Description City ID
+	City          string    `json:"city"  db:"city"`                       // @Description City name
+	Failures      int       `json:"failures"  db:"failures"`               // @Description Failed updates in a row
+	LastError     string    `json:"last_error"  db:"last_error"`           // @Description Latest failure reason
+	FirstFailedAt time.Time `json:"first_failed_at"  db:"first_failed_at"` // @Description First failure in the current streak
+	LastFailedAt  time.Time `json:"last_failed_at"  db:"last_failed_at"`   // @Description Latest failure
+}
+
+// CollectorStatus represents the state of the data collector
+// @Description Data collector status
+type CollectorStatus struct {
+	Running             bool          `json:"running"`                        // @Description Whether a collector runs in this process and accepts triggers
+	CurrentRun          *CollectorRun `json:"current_run"`                    // @Description Run in progress
+	LastRun             *CollectorRun `json:"last_run"`                       // @Description Latest finished run
+	ProviderRequests    int           `json:"provider_requests"`              // @Description Provider requests made today by this process
+	ProviderDailyQuota  int           `json:"provider_daily_quota"`           // @Description Daily provider quota, 0 if unlimited
+	ProviderUnavailable string        `json:"provider_unavailable,omitempty"` // @Description Why provider requests are refused, if they are
+}
@@ -23,3 +23,32 @@ type ForecastSummary struct {
 	AvgTemp        float32  `json:"avg_temp"  db:"avg_temp"`               // @Description Average Temperature
 	AvailableDates []string `json:"available_dates"  db:"available_dates"` // @Description Available dates for forecasts
 }
+
+// DailyForecast represents the forecasts of a city for one day, rolled up
+// once the raw forecasts passed their retention
+// @Description Daily forecast aggregate
+type DailyForecast struct {
+	CityId  int       `json:"city_id"  db:"city_id"`   // @Description City ID
+	Date    time.Time `json:"date"  db:"date"`         // @Description Day
+	TempMin float32   `json:"temp_min"  db:"temp_min"` // @Description Lowest temperature
+	TempMax float32   `json:"temp_max"  db:"temp_max"` // @Description Highest temperature
+	TempAvg float32   `json:"temp_avg"  db:"temp_avg"` // @Description Average temperature
+	Samples int       `json:"samples"  db:"samples"`   // @Description Number of forecasts aggregated
+}
+
+// ForecastFreshness tells when the forecasts of a city were last stored and
+// when the collector updates them next. Both are zero until the first update
+// @Description Forecast freshness model
+type ForecastFreshness struct {
+	CityId     int       `json:"city_id"  db:"id"`                        // @Description City ID
+	UpdatedAt  time.Time `json:"updated_at"  db:"forecasts_updated_at"`   // @Description Time the forecasts were stored
+	NextUpdate time.Time `json:"next_update"  db:"forecasts_next_update"` // @Description Time of the next scheduled update
+}
+
+// ForecastUpdate is pushed to stream subscribers when new forecasts are stored for a city
+// @Description Forecast update event
+type ForecastUpdate struct {
+	CityId    int             `json:"city_id"`    // @Description City ID
+	UpdatedAt time.Time       `json:"updated_at"` // @Description Time the forecasts were stored
+	Forecast  ForecastSummary `json:"forecast"`   // @Description Updated short forecast
+}
@@ -0,0 +1,22 @@
+package models
+
+const (
+	HealthOK          = "ok"
+	HealthFailed      = "failed"
+	HealthUnavailable = "unavailable"
+)
+
+// Readiness represents whether the server can serve requests
+// @Description Outcome of the readiness checks
+type Readiness struct {
+	Status string        `json:"status"` // @Description ok, or unavailable if any check failed
+	Checks []HealthCheck `json:"checks"` // @Description Checks that were run
+}
+
+// HealthCheck represents the outcome of one readiness check
+// @Description Outcome of a readiness check
+type HealthCheck struct {
+	Name   string `json:"name"`            // @Description One of database, migrations, forecasts
+	Status string `json:"status"`          // @Description ok or failed
+	Error  string `json:"error,omitempty"` // @Description Why the check failed
+}
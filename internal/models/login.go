@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Outcomes of sign-in attempts
+const (
+	SignInSucceeded = "succeeded"
+	SignInFailed    = "failed"
+	// SignInThrottled attempts came too soon after a failure.
+	SignInThrottled = "throttled"
+	SignInLocked    = "locked"
+)
+
+// LoginFailure represents the failed sign-ins of an account or an IP address
+// @Description Failed sign-ins of an account or an IP address
+type LoginFailure struct {
+	Subject      string     `json:"subject"  db:"subject"`                     // @Description "login:" followed by the login, or "ip:" followed by the address
+	Failures     int        `json:"failures"  db:"failures"`                   // @Description Failed sign-ins in a row
+	LastFailedAt time.Time  `json:"last_failed_at"  db:"last_failed_at"`       // @Description Latest failure
+	LockedUntil  *time.Time `json:"locked_until,omitempty"  db:"locked_until"` // @Description End of the lockout, if locked
+}
+
+// SignInAttempt represents an audit record of a sign-in
+// @Description Sign-in attempt
+type SignInAttempt struct {
+	Id          int       `json:"id"  db:"id"`                     // @Description Attempt ID
+	Login       string    `json:"login"  db:"login"`               // @Description Login given
+	IP          string    `json:"ip"  db:"ip"`                     // @Description Client IP address
+	UserId      *int      `json:"user_id,omitempty"  db:"user_id"` // @Description User ID, if the credentials were valid
+	Outcome     string    `json:"outcome"  db:"outcome"`           // @Description succeeded, failed, throttled or locked
+	AttemptedAt time.Time `json:"attempted_at"  db:"attempted_at"` // @Description Time of the attempt
+}
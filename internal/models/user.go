@@ -1,5 +1,10 @@
 package models
 
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 // User represents the user model
 // @Description User model
 type User struct {
@@ -7,4 +12,5 @@ type User struct {
 	Login    string `json:"login"  db:"login"`       // @Description User login
 	Password string `json:"password"  db:"password"` // @Description User password
 	Email    string `json:"email"  db:"email"`       // @Description User email
+	Role     string `json:"role"  db:"role"`         // @Description User role
 }
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	WebhookEventAlertTriggered  = "alert.triggered"
+	WebhookEventForecastUpdated = "forecast.updated"
+	WebhookEventPing            = "ping"
+)
+
+const (
+	DeliveryStatusPending   = "pending"
+	DeliveryStatusDelivered = "delivered"
+	DeliveryStatusFailed    = "failed"
+)
+
+// Webhook represents a user-registered webhook endpoint
+// @Description Webhook endpoint model
+type Webhook struct {
+	Id        int       `json:"id"  db:"id"`                   // @Description Webhook ID
+	UserId    int       `json:"user_id"  db:"user_id"`         // @Description Owner user ID
+	URL       string    `json:"url"  db:"url"`                 // @Description Endpoint URL
+	Secret    string    `json:"secret,omitempty"  db:"secret"` // @Description HMAC secret, returned only on creation
+	CreatedAt time.Time `json:"created_at"  db:"created_at"`   // @Description Creation time
+}
+
+// WebhookDelivery represents a single queued or attempted webhook delivery
+// @Description Webhook delivery log entry
+type WebhookDelivery struct {
+	Id             int             `json:"id"  db:"id"`                           // @Description Delivery ID
+	WebhookId      int             `json:"webhook_id"  db:"webhook_id"`           // @Description Webhook ID
+	Event          string          `json:"event"  db:"event"`                     // @Description Event type
+	Payload        json.RawMessage `json:"payload"  db:"payload"`                 // @Description JSON payload
+	Status         string          `json:"status"  db:"status"`                   // @Description One of pending, delivered, failed
+	Attempts       int             `json:"attempts"  db:"attempts"`               // @Description Number of delivery attempts
+	ResponseStatus int             `json:"response_status"  db:"response_status"` // @Description HTTP status of the last attempt
+	LastError      string          `json:"last_error"  db:"last_error"`           // @Description Error of the last attempt
+	NextAttemptAt  time.Time       `json:"next_attempt_at"  db:"next_attempt_at"` // @Description Time of the next attempt
+	CreatedAt      time.Time       `json:"created_at"  db:"created_at"`           // @Description Time the delivery was queued
+	DeliveredAt    *time.Time      `json:"delivered_at"  db:"delivered_at"`       // @Description Time of successful delivery
+}
@@ -0,0 +1,235 @@
+// Package provider wraps the HTTP calls to the weather provider so that all of
+// them share one client with timeouts, retries, a circuit breaker, a rate
+// limiter and a daily quota.
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"strconv"
+	"strings"
+	"time"
+	"weather-app/internal/metrics"
+	"weather-app/internal/ratelimit"
+	"weather-app/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	defaultBackoff = 500 * time.Millisecond
+	maxBackoff     = 30 * time.Second
+)
+
+// ErrQuotaExhausted is returned once the daily request quota has been used.
+var ErrQuotaExhausted = errors.New("daily request quota of the weather provider is exhausted")
+
+// Error is a non-2xx response of the provider.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("provider responded with status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("provider responded with status %d: %s", e.StatusCode, e.Message)
+}
+
+// Temporary reports whether the request may succeed if repeated.
+func (e *Error) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// Config holds the client settings. Zero values disable the corresponding
+// timeout, retries or limit.
+type Config struct {
+	// Timeout bounds each attempt, including reading the response body.
+	Timeout time.Duration
+	// Retries is how many times a request failing with a network error, 429
+	// or 5xx is repeated, with exponential backoff.
+	Retries int
+	// BreakerFailures failed requests in a row open the circuit breaker for
+	// BreakerCooldown, during which requests fail without reaching the
+	// provider.
+	BreakerFailures int
+	BreakerCooldown time.Duration
+
+	RequestsPerMinute int
+	Burst             int
+	// DailyQuota is the number of requests allowed per UTC day. Requests are
+	// refused once it is used, so the key itself is never exhausted. The
+	// requests are counted in this process unless ShareQuota is called.
+	DailyQuota int
+}
+
+type Client struct {
+	http    *http.Client
+	retries int
+	backoff time.Duration
+	breaker *breaker
+	limiter *ratelimit.Limiter
+	quota   int
+	usage   QuotaStore
+	now     func() time.Time
+}
+
+func NewClient(cfg Config) *Client {
+	return &Client{
+		http:    &http.Client{Timeout: cfg.Timeout},
+		retries: cfg.Retries,
+		backoff: defaultBackoff,
+		breaker: newBreaker(cfg.BreakerFailures, cfg.BreakerCooldown),
+		limiter: ratelimit.NewLimiter(ratelimit.PerMinute(cfg.RequestsPerMinute), cfg.Burst),
+		quota:   cfg.DailyQuota,
+		usage:   newLocalUsage(),
+		now:     time.Now,
+	}
+}
+
+// GetJSON requests url and decodes the JSON response into v. Temporary
+// failures are retried; each attempt counts against the rate limit and the
+// daily quota. The call is traced with a span per attempt.
+func (c *Client) GetJSON(ctx context.Context, url string, v any) (err error) {
+	endpoint := endpointOf(url)
+	ctx, span := tracing.Start(ctx, "provider "+endpoint, attribute.String("provider.endpoint", endpoint))
+	defer func() { tracing.End(span, err) }()
+
+	if err := c.breaker.allow(); err != nil {
+		metrics.ProviderError(endpoint, "circuit_open")
+		return err
+	}
+
+	for attempt := 0; ; attempt++ {
+		var retryAfter time.Duration
+		var temporary bool
+		attemptCtx, attemptSpan := tracing.Start(ctx, http.MethodGet+" "+endpoint,
+			semconv.HTTPRequestMethodGet, semconv.URLPath(endpoint), attribute.Int("provider.attempt", attempt))
+		retryAfter, temporary, err = c.get(attemptCtx, endpoint, url, v)
+		tracing.End(attemptSpan, err)
+		if ctx.Err() != nil {
+			c.breaker.abort()
+			return err
+		}
+		if !temporary || attempt == c.retries {
+			if errors.Is(err, ErrQuotaExhausted) {
+				c.breaker.abort()
+			} else {
+				c.breaker.record(temporary)
+			}
+			return err
+		}
+
+		delay := min(c.backoff<<attempt, maxBackoff)
+		if retryAfter > delay {
+			delay = min(retryAfter, maxBackoff)
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			c.breaker.abort()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// get performs a single attempt at endpoint, the path of url. It returns the
+// delay requested by the provider in Retry-After and whether the failure is
+// worth retrying.
+func (c *Client) get(ctx context.Context, endpoint, url string, v any) (time.Duration, bool, error) {
+	if err := c.takeQuota(ctx); err != nil {
+		if errors.Is(err, ErrQuotaExhausted) {
+			metrics.ProviderError(endpoint, "quota_exhausted")
+		}
+		return 0, false, err
+	}
+	if err := c.limiter.Wait(ctx); err != nil {
+		return 0, false, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	start := time.Now()
+	resp, err := c.http.Do(req)
+	if err != nil {
+		metrics.ObserveProviderRequest(endpoint, time.Since(start))
+		metrics.ProviderError(endpoint, "network")
+		return 0, true, redactURL(req, err)
+	}
+	defer resp.Body.Close()
+	trace.SpanFromContext(ctx).SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+
+	body, err := io.ReadAll(resp.Body)
+	metrics.ObserveProviderRequest(endpoint, time.Since(start))
+	if err != nil {
+		metrics.ProviderError(endpoint, "network")
+		return 0, true, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		metrics.ProviderError(endpoint, "status_"+strconv.Itoa(resp.StatusCode))
+		providerErr := &Error{StatusCode: resp.StatusCode, Message: errorMessage(body)}
+		return retryAfter(resp.Header.Get("Retry-After")), providerErr.Temporary(), providerErr
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		metrics.ProviderError(endpoint, "decode")
+		return 0, false, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return 0, false, nil
+}
+
+// endpointOf returns the path of url, which labels the provider metrics
+// without the query and its API key.
+func endpointOf(url string) string {
+	parsed, err := neturl.Parse(url)
+	if err != nil || parsed.Path == "" {
+		return "unknown"
+	}
+	return parsed.Path
+}
+
+// redactURL replaces the URL in an error of the HTTP client with its host and
+// path, since the query carries the API key and errors end up in logs, the
+// collector runs and the admin API.
+func redactURL(req *http.Request, err error) error {
+	var urlErr *neturl.Error
+	if !errors.As(err, &urlErr) {
+		return err
+	}
+	return fmt.Errorf("%s %s%s: %w", urlErr.Op, req.URL.Host, req.URL.Path, urlErr.Err)
+}
+
+// errorMessage extracts the message of an OpenWeather error body such as
+// {"cod":401,"message":"Invalid API key"}, falling back to the body itself.
+func errorMessage(body []byte) string {
+	var providerErr struct {
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal(body, &providerErr); err == nil && providerErr.Message != "" {
+		return providerErr.Message
+	}
+	message := strings.TrimSpace(string(body))
+	if len(message) > 200 {
+		message = message[:200]
+	}
+	return message
+}
+
+func retryAfter(header string) time.Duration {
+	seconds, err := strconv.Atoi(header)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
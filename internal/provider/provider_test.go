@@ -0,0 +1,194 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jarcoal/httpmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const testURL = "http://api.openweathermap.org/data/2.5/forecast"
+
+type ClientTestSuite struct {
+	suite.Suite
+	client *Client
+	now    time.Time
+}
+
+func (suite *ClientTestSuite) SetupTest() {
+	suite.now = time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	suite.client = suite.newClient(Config{DailyQuota: 2})
+
+	httpmock.Activate()
+	httpmock.RegisterResponder("GET", testURL, httpmock.NewStringResponder(200, `{"cnt":1}`))
+}
+
+func (suite *ClientTestSuite) TearDownTest() {
+	httpmock.DeactivateAndReset()
+}
+
+func (suite *ClientTestSuite) newClient(cfg Config) *Client {
+	client := NewClient(cfg)
+	client.backoff = time.Millisecond
+	client.now = func() time.Time { return suite.now }
+	client.breaker.now = client.now
+	return client
+}
+
+func (suite *ClientTestSuite) TestGetJSON() {
+	var resp struct {
+		Cnt int `json:"cnt"`
+	}
+	assert.NoError(suite.T(), suite.client.GetJSON(context.Background(), testURL, &resp))
+	assert.Equal(suite.T(), 1, resp.Cnt)
+}
+
+func (suite *ClientTestSuite) TestGetJSONNetworkErrorHidesAPIKey() {
+	url := testURL + "?q=London&appid=secret-key"
+	httpmock.RegisterResponder("GET", url, httpmock.NewErrorResponder(errors.New("connection refused")))
+
+	var resp struct{}
+	err := suite.client.GetJSON(context.Background(), url, &resp)
+	suite.Require().Error(err)
+	assert.NotContains(suite.T(), err.Error(), "secret-key")
+	assert.NotContains(suite.T(), err.Error(), "appid")
+	assert.Contains(suite.T(), err.Error(), "api.openweathermap.org/data/2.5/forecast")
+	assert.Contains(suite.T(), err.Error(), "connection refused")
+}
+
+func (suite *ClientTestSuite) TestGetJSONProviderError() {
+	httpmock.RegisterResponder("GET", testURL,
+		httpmock.NewStringResponder(401, `{"cod":401,"message":"Invalid API key"}`))
+
+	var resp struct{}
+	err := suite.client.GetJSON(context.Background(), testURL, &resp)
+	var providerErr *Error
+	assert.True(suite.T(), errors.As(err, &providerErr))
+	assert.Equal(suite.T(), http.StatusUnauthorized, providerErr.StatusCode)
+	assert.Equal(suite.T(), "Invalid API key", providerErr.Message)
+	assert.False(suite.T(), providerErr.Temporary())
+}
+
+func (suite *ClientTestSuite) TestGetJSONRetriesTemporaryErrors() {
+	client := suite.newClient(Config{Retries: 2})
+	httpmock.RegisterResponder("GET", testURL,
+		httpmock.NewStringResponder(503, "Service Unavailable").
+			Then(httpmock.NewStringResponder(429, `{"cod":429,"message":"rate limit"}`)).
+			Then(httpmock.NewStringResponder(200, `{"cnt":3}`)))
+
+	var resp struct {
+		Cnt int `json:"cnt"`
+	}
+	assert.NoError(suite.T(), client.GetJSON(context.Background(), testURL, &resp))
+	assert.Equal(suite.T(), 3, resp.Cnt)
+	assert.Equal(suite.T(), 3, httpmock.GetTotalCallCount())
+}
+
+func (suite *ClientTestSuite) TestGetJSONDoesNotRetryClientErrors() {
+	client := suite.newClient(Config{Retries: 2})
+	httpmock.RegisterResponder("GET", testURL, httpmock.NewStringResponder(404, `{"cod":"404","message":"city not found"}`))
+
+	var resp struct{}
+	assert.Error(suite.T(), client.GetJSON(context.Background(), testURL, &resp))
+	assert.Equal(suite.T(), 1, httpmock.GetTotalCallCount())
+}
+
+func (suite *ClientTestSuite) TestGetJSONStopsRetryingWhenCanceled() {
+	client := suite.newClient(Config{Retries: 5, BreakerFailures: 1, BreakerCooldown: time.Minute})
+	client.backoff = time.Hour
+	httpmock.RegisterResponder("GET", testURL, httpmock.NewStringResponder(503, "Service Unavailable"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	var resp struct{}
+	assert.ErrorIs(suite.T(), client.GetJSON(ctx, testURL, &resp), context.DeadlineExceeded)
+	assert.Equal(suite.T(), 1, httpmock.GetTotalCallCount())
+	assert.NoError(suite.T(), client.Unavailable(context.Background()))
+}
+
+func (suite *ClientTestSuite) TestCircuitBreaker() {
+	client := suite.newClient(Config{BreakerFailures: 2, BreakerCooldown: time.Minute})
+	httpmock.RegisterResponder("GET", testURL, httpmock.NewStringResponder(500, "Internal Server Error"))
+
+	var resp struct{}
+	assert.Error(suite.T(), client.GetJSON(context.Background(), testURL, &resp))
+	assert.NoError(suite.T(), client.Unavailable(context.Background()))
+	assert.Error(suite.T(), client.GetJSON(context.Background(), testURL, &resp))
+	assert.ErrorIs(suite.T(), client.Unavailable(context.Background()), ErrCircuitOpen)
+	assert.ErrorIs(suite.T(), client.GetJSON(context.Background(), testURL, &resp), ErrCircuitOpen)
+	assert.Equal(suite.T(), 2, httpmock.GetTotalCallCount())
+
+	suite.now = suite.now.Add(time.Minute)
+	httpmock.RegisterResponder("GET", testURL, httpmock.NewStringResponder(200, "{}"))
+	assert.NoError(suite.T(), client.GetJSON(context.Background(), testURL, &resp))
+	assert.NoError(suite.T(), client.Unavailable(context.Background()))
+}
+
+func (suite *ClientTestSuite) TestCircuitBreakerProbeFails() {
+	client := suite.newClient(Config{BreakerFailures: 1, BreakerCooldown: time.Minute})
+	httpmock.RegisterResponder("GET", testURL, httpmock.NewStringResponder(500, "Internal Server Error"))
+
+	var resp struct{}
+	assert.Error(suite.T(), client.GetJSON(context.Background(), testURL, &resp))
+	suite.now = suite.now.Add(time.Minute)
+	assert.Error(suite.T(), client.GetJSON(context.Background(), testURL, &resp))
+	assert.ErrorIs(suite.T(), client.GetJSON(context.Background(), testURL, &resp), ErrCircuitOpen)
+	assert.Equal(suite.T(), 2, httpmock.GetTotalCallCount())
+}
+
+func (suite *ClientTestSuite) TestQuota() {
+	var resp struct{}
+	for i := 0; i < 2; i++ {
+		assert.NoError(suite.T(), suite.client.GetJSON(context.Background(), testURL, &resp))
+	}
+	assert.True(suite.T(), suite.client.QuotaExhausted(context.Background()))
+	assert.ErrorIs(suite.T(), suite.client.Unavailable(context.Background()), ErrQuotaExhausted)
+
+	assert.ErrorIs(suite.T(), suite.client.GetJSON(context.Background(), testURL, &resp), ErrQuotaExhausted)
+	assert.Equal(suite.T(), 2, httpmock.GetTotalCallCount())
+}
+
+func (suite *ClientTestSuite) TestQuotaResetsDaily() {
+	var resp struct{}
+	suite.client.GetJSON(context.Background(), testURL, &resp)
+	suite.client.GetJSON(context.Background(), testURL, &resp)
+
+	suite.now = suite.now.Add(2 * time.Hour)
+	assert.False(suite.T(), suite.client.QuotaExhausted(context.Background()))
+	used, quota, err := suite.client.Usage(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 0, used)
+	assert.Equal(suite.T(), 2, quota)
+}
+
+func (suite *ClientTestSuite) TestSharedQuota() {
+	store := newLocalUsage()
+	first, second := suite.newClient(Config{DailyQuota: 2}), suite.newClient(Config{DailyQuota: 2})
+	first.ShareQuota(store)
+	second.ShareQuota(store)
+
+	var resp struct{}
+	assert.NoError(suite.T(), first.GetJSON(context.Background(), testURL, &resp))
+	assert.NoError(suite.T(), second.GetJSON(context.Background(), testURL, &resp))
+	assert.True(suite.T(), first.QuotaExhausted(context.Background()), "requests of the other client count")
+	assert.ErrorIs(suite.T(), first.GetJSON(context.Background(), testURL, &resp), ErrQuotaExhausted)
+	assert.Equal(suite.T(), 2, httpmock.GetTotalCallCount())
+}
+
+func (suite *ClientTestSuite) TestNoQuota() {
+	client := suite.newClient(Config{})
+	var resp struct{}
+	for i := 0; i < 3; i++ {
+		assert.NoError(suite.T(), client.GetJSON(context.Background(), testURL, &resp))
+	}
+	assert.False(suite.T(), client.QuotaExhausted(context.Background()))
+}
+
+func TestClientTestSuite(t *testing.T) {
+	suite.Run(t, new(ClientTestSuite))
+}
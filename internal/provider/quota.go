@@ -0,0 +1,109 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QuotaStore counts the requests made to the provider per UTC day. Processes
+// sharing a store share the daily quota, which is what keeps a split
+// deployment within the quota of the key.
+type QuotaStore interface {
+	// AddProviderRequest counts a request made on day unless quota requests
+	// were already made, and reports whether it was counted. A zero quota
+	// counts every request.
+	AddProviderRequest(ctx context.Context, day time.Time, quota int) (bool, error)
+	GetProviderRequests(ctx context.Context, day time.Time) (int, error)
+}
+
+// ShareQuota makes the client count its requests in store, usually the
+// database, instead of in memory. Call it before the first request.
+func (c *Client) ShareQuota(store QuotaStore) {
+	c.usage = store
+}
+
+// Unavailable returns the reason requests are refused right now without
+// reaching the provider, or nil if they are not.
+func (c *Client) Unavailable(ctx context.Context) error {
+	if c.QuotaExhausted(ctx) {
+		return ErrQuotaExhausted
+	}
+	if c.breaker.isOpen() {
+		return ErrCircuitOpen
+	}
+	return nil
+}
+
+// QuotaExhausted reports whether no requests are left for the current day. It
+// is false when the usage cannot be read: the request itself fails then.
+func (c *Client) QuotaExhausted(ctx context.Context) bool {
+	if c.quota == 0 {
+		return false
+	}
+	used, err := c.usage.GetProviderRequests(ctx, c.today())
+	return err == nil && used >= c.quota
+}
+
+// Usage returns the number of requests made during the current UTC day and
+// the daily quota.
+func (c *Client) Usage(ctx context.Context) (used int, quota int, err error) {
+	used, err = c.usage.GetProviderRequests(ctx, c.today())
+	return used, c.quota, err
+}
+
+func (c *Client) takeQuota(ctx context.Context) error {
+	counted, err := c.usage.AddProviderRequest(ctx, c.today(), c.quota)
+	if err != nil {
+		return fmt.Errorf("failed to count the request against the daily quota: %w", err)
+	}
+	if !counted {
+		return ErrQuotaExhausted
+	}
+	return nil
+}
+
+func (c *Client) today() time.Time {
+	return c.now().UTC().Truncate(24 * time.Hour)
+}
+
+// localUsage counts the requests of this process only.
+type localUsage struct {
+	mu   sync.Mutex
+	day  time.Time
+	used int
+}
+
+func newLocalUsage() *localUsage {
+	return &localUsage{}
+}
+
+func (u *localUsage) AddProviderRequest(ctx context.Context, day time.Time, quota int) (bool, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.resetDay(day)
+	if quota > 0 && u.used >= quota {
+		return false, nil
+	}
+	u.used++
+	return true, nil
+}
+
+func (u *localUsage) GetProviderRequests(ctx context.Context, day time.Time) (int, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	u.resetDay(day)
+	return u.used, nil
+}
+
+// resetDay starts counting anew when the UTC day changes. Must be called with
+// mu held.
+func (u *localUsage) resetDay(day time.Time) {
+	if !day.Equal(u.day) {
+		u.day = day
+		u.used = 0
+	}
+}
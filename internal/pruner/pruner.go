@@ -0,0 +1,111 @@
+package pruner
+
+import (
+	"context"
+	"time"
+	"weather-app/config"
+	"weather-app/internal/lifecycle"
+	"weather-app/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+// batchPause lets other writers through between two batches.
+const batchPause = 100 * time.Millisecond
+
+// Pruner deletes data past its retention. Forecasts are rolled up into daily
+// aggregates before they are deleted.
+type Pruner struct {
+	services *service.Service
+	cfg      config.RetentionConfig
+	runner   *lifecycle.Runner
+	now      func() time.Time
+}
+
+func NewPruner(cfg config.RetentionConfig, services *service.Service) *Pruner {
+	return &Pruner{
+		services: services,
+		cfg:      cfg,
+		runner:   lifecycle.NewRunner(),
+		now:      time.Now,
+	}
+}
+
+// Start prunes right away and then every interval until Stop is called or ctx
+// is canceled.
+func (p *Pruner) Start(ctx context.Context) error {
+	return p.runner.Run(ctx, func(ctx context.Context) error {
+		ticker := time.NewTicker(p.cfg.Interval.Duration)
+		defer ticker.Stop()
+		for {
+			if err := p.PruneOnce(ctx); err != nil {
+				logrus.Errorf("Failed to prune expired data: %v", err)
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-p.runner.Stopping():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// Stop waits for the batch being pruned. Batches are transactions, so one
+// canceled when ctx expires leaves no partial changes.
+func (p *Pruner) Stop(ctx context.Context) error {
+	return p.runner.Stop(ctx)
+}
+
+type task struct {
+	name      string
+	retention time.Duration
+	batch     func(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// PruneOnce deletes all data past its retention, in batches. Forecasts are
+// rolled up before daily aggregates are pruned, so aggregates rolled up for
+// days already past their own retention go in the same pass.
+func (p *Pruner) PruneOnce(ctx context.Context) error {
+	tasks := []task{
+		{"forecasts", p.cfg.Forecasts.Duration, p.services.ForecastService.RollupForecasts},
+		{"daily forecasts", p.cfg.DailyForecasts.Duration, p.services.ForecastService.PruneDailyForecasts},
+		{"collector runs", p.cfg.CollectorRuns.Duration, p.services.CollectorService.PruneRuns},
+		{"sign-in attempts", p.cfg.SignInAttempts.Duration, p.services.UserService.PruneSignInAttempts},
+		{"login failures", p.cfg.LoginFailures.Duration, p.services.UserService.PruneLoginFailures},
+	}
+	for _, task := range tasks {
+		if task.retention <= 0 {
+			continue
+		}
+		pruned, err := p.prune(ctx, task, p.now().Add(-task.retention))
+		if pruned > 0 {
+			logrus.Printf("%d %s past their retention were pruned", pruned, task.name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prune runs the task's batches until one comes back short or the pruner is
+// stopping, and returns the number of rows pruned.
+func (p *Pruner) prune(ctx context.Context, task task, before time.Time) (int, error) {
+	total := 0
+	for {
+		pruned, err := task.batch(ctx, before, p.cfg.BatchSize)
+		total += pruned
+		if err != nil || pruned < p.cfg.BatchSize {
+			return total, err
+		}
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		case <-p.runner.Stopping():
+			return total, nil
+		case <-time.After(batchPause):
+		}
+	}
+}
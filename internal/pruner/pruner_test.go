@@ -0,0 +1,127 @@
+package pruner
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weather-app/config"
+	"weather-app/internal/models"
+	"weather-app/internal/provider"
+	"weather-app/internal/repository"
+	"weather-app/internal/repository/memory"
+	"weather-app/internal/service"
+	alertservice "weather-app/internal/service/alert_service"
+	cityservice "weather-app/internal/service/city_service"
+	collectorservice "weather-app/internal/service/collector_service"
+	forecastservice "weather-app/internal/service/forecast_service"
+	healthservice "weather-app/internal/service/health_service"
+	userservice "weather-app/internal/service/user_service"
+	webhookservice "weather-app/internal/service/webhook_service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// PrunerTestSuite runs the pruner against the in-memory storage backend.
+type PrunerTestSuite struct {
+	suite.Suite
+	services  *service.Service
+	forecasts repository.ForecastRepository
+	users     repository.UserRepository
+	pruner    *Pruner
+	now       time.Time
+	cityId    int
+}
+
+func (suite *PrunerTestSuite) SetupTest() {
+	storage := memory.NewStorage()
+	client := provider.NewClient(provider.Config{})
+	cityServ := cityservice.NewCityService(memory.NewCityRepository(storage), client)
+	suite.forecasts = memory.NewForecastRepository(storage)
+	forecastServ := forecastservice.NewForecastService(cityServ, suite.forecasts, client, nil)
+	suite.users = memory.NewUserRepository(storage)
+	userServ := userservice.NewUserService(cityServ, suite.users, userservice.LoginPolicy{})
+	alertServ := alertservice.NewAlertService(cityServ, memory.NewAlertRepository(storage))
+	webhookServ := webhookservice.NewWebhookService(memory.NewWebhookRepository(storage), webhookservice.DeliveryPolicy{})
+	collectorServ := collectorservice.NewCollectorService(memory.NewCollectorRunRepository(storage), client)
+	healthServ := healthservice.NewHealthService(memory.NewHealthRepository(storage), memory.NewCityRepository(storage), memory.NewForecastRepository(storage), healthservice.ReadinessPolicy{})
+	suite.services = service.NewService(userServ, cityServ, forecastServ, alertServ, webhookServ, collectorServ, healthServ)
+
+	suite.now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	suite.pruner = NewPruner(config.RetentionConfig{
+		Forecasts:      config.Duration{Duration: 10 * 24 * time.Hour},
+		DailyForecasts: config.Duration{Duration: 30 * 24 * time.Hour},
+		CollectorRuns:  config.Duration{Duration: 7 * 24 * time.Hour},
+		SignInAttempts: config.Duration{Duration: 7 * 24 * time.Hour},
+		LoginFailures:  config.Duration{Duration: 24 * time.Hour},
+		Interval:       config.Duration{Duration: time.Hour},
+		BatchSize:      2,
+	}, suite.services)
+	suite.pruner.now = func() time.Time { return suite.now }
+
+	cityId, err := suite.services.CityService.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+	suite.cityId = cityId
+}
+
+func (suite *PrunerTestSuite) createForecast(daysAgo int) {
+	_, err := suite.services.ForecastService.CreateForecast(context.Background(), models.Forecast{
+		CityId:       suite.cityId,
+		Temp:         float32(daysAgo),
+		Date:         suite.now.AddDate(0, 0, -daysAgo),
+		ForecastJson: []byte(`{}`),
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *PrunerTestSuite) TestPruneOnce() {
+	for _, daysAgo := range []int{40, 20, 15, 12, 5, 0} {
+		suite.createForecast(daysAgo)
+	}
+	assert.NoError(suite.T(), suite.pruner.PruneOnce(context.Background()))
+
+	forecasts, err := suite.forecasts.GetForecasts(context.Background(), suite.cityId)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), forecasts, 2, "forecasts within the retention are kept")
+
+	daily, err := suite.services.ForecastService.GetForecastHistory(context.Background(), suite.cityId)
+	suite.Require().NoError(err)
+	var temps []float32
+	for _, day := range daily {
+		temps = append(temps, day.TempAvg)
+	}
+	assert.Equal(suite.T(), []float32{20, 15, 12}, temps, "the 40 days old aggregate is past its retention")
+}
+
+func (suite *PrunerTestSuite) TestPruneOnceLoginFailures() {
+	for _, subject := range []string{"ip:10.0.0.1", "ip:10.0.0.2", "ip:10.0.0.3"} {
+		_, err := suite.users.RecordLoginFailure(context.Background(), subject, suite.now.AddDate(0, 0, -2), suite.now.AddDate(0, 0, -3))
+		suite.Require().NoError(err)
+	}
+	_, err := suite.users.RecordLoginFailure(context.Background(), "login:alice", suite.now, suite.now.Add(-time.Hour))
+	suite.Require().NoError(err)
+
+	assert.NoError(suite.T(), suite.pruner.PruneOnce(context.Background()))
+
+	failure, err := suite.users.GetLoginFailure(context.Background(), "ip:10.0.0.3")
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), failure.Failures, "all batches are pruned")
+	failure, err = suite.users.GetLoginFailure(context.Background(), "login:alice")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, failure.Failures)
+}
+
+func (suite *PrunerTestSuite) TestPruneOnceKeepsForeverWithZeroRetention() {
+	suite.pruner.cfg.Forecasts = config.Duration{}
+	suite.createForecast(400)
+
+	assert.NoError(suite.T(), suite.pruner.PruneOnce(context.Background()))
+
+	forecasts, err := suite.forecasts.GetForecasts(context.Background(), suite.cityId)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), forecasts, 1)
+}
+
+func TestPrunerTestSuite(t *testing.T) {
+	suite.Run(t, new(PrunerTestSuite))
+}
@@ -0,0 +1,167 @@
+package pubsub
+
+import (
+	"fmt"
+	"sync"
+	"weather-app/internal/models"
+)
+
+const subscriptionBuffer = 16
+
+// Event is a message published to a topic.
+type Event struct {
+	Topic string
+	Type  string
+	Data  any
+}
+
+func ForecastTopic(cityId int) string {
+	return fmt.Sprintf("forecast:%d", cityId)
+}
+
+func AlertTopic(userId int) string {
+	return fmt.Sprintf("alerts:%d", userId)
+}
+
+// ForecastUpdated is the event of new forecasts stored for a city.
+func ForecastUpdated(update models.ForecastUpdate) Event {
+	return Event{Topic: ForecastTopic(update.CityId), Type: "forecast", Data: update}
+}
+
+// AlertTriggered is the event of an alert sent to its owner.
+func AlertTriggered(alert models.TriggeredAlert) Event {
+	return Event{Topic: AlertTopic(alert.UserId), Type: "alert", Data: alert}
+}
+
+// Broker is an in-process publish/subscribe hub. Publishing never blocks:
+// events for subscribers that don't keep up are dropped.
+type Broker struct {
+	mu     sync.RWMutex
+	topics map[string]map[*Subscription]struct{}
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		topics: make(map[string]map[*Subscription]struct{}),
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+type Subscription struct {
+	broker *Broker
+	topics map[string]struct{}
+	events chan Event
+	closed bool
+}
+
+// Subscribe creates a subscription to the given topics. The caller must Close
+// it. Subscriptions to a closed broker are closed from the start.
+func (b *Broker) Subscribe(topics ...string) *Subscription {
+	sub := &Subscription{
+		broker: b,
+		topics: make(map[string]struct{}),
+		events: make(chan Event, subscriptionBuffer),
+	}
+	b.mu.Lock()
+	if b.closed {
+		sub.closed = true
+		close(sub.events)
+	} else {
+		b.subs[sub] = struct{}{}
+	}
+	b.mu.Unlock()
+	sub.Add(topics...)
+	return sub
+}
+
+// Close closes all subscriptions, which ends the streams reading from them.
+func (b *Broker) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		sub.close()
+	}
+}
+
+func (b *Broker) Publish(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.topics[event.Topic] {
+		select {
+		case sub.events <- event:
+		default:
+		}
+	}
+}
+
+// Events returns the channel the subscription receives events on. It is closed by Close.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Add(topics ...string) {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if s.closed {
+		return
+	}
+	for _, topic := range topics {
+		if b.topics[topic] == nil {
+			b.topics[topic] = make(map[*Subscription]struct{})
+		}
+		b.topics[topic][s] = struct{}{}
+		s.topics[topic] = struct{}{}
+	}
+}
+
+func (s *Subscription) Remove(topics ...string) {
+	b := s.broker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, topic := range topics {
+		b.unsubscribe(s, topic)
+	}
+}
+
+// Topics returns the topics the subscription is currently subscribed to.
+func (s *Subscription) Topics() []string {
+	s.broker.mu.RLock()
+	defer s.broker.mu.RUnlock()
+	topics := make([]string, 0, len(s.topics))
+	for topic := range s.topics {
+		topics = append(topics, topic)
+	}
+	return topics
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.close()
+}
+
+// close must be called with the broker mu held.
+func (s *Subscription) close() {
+	b := s.broker
+	if s.closed {
+		return
+	}
+	for topic := range s.topics {
+		b.unsubscribe(s, topic)
+	}
+	delete(b.subs, s)
+	s.closed = true
+	close(s.events)
+}
+
+func (b *Broker) unsubscribe(s *Subscription, topic string) {
+	delete(s.topics, topic)
+	delete(b.topics[topic], s)
+	if len(b.topics[topic]) == 0 {
+		delete(b.topics, topic)
+	}
+}
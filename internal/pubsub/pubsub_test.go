@@ -0,0 +1,81 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishDeliversToTopicSubscribers(t *testing.T) {
+	broker := NewBroker()
+	london := broker.Subscribe(ForecastTopic(1))
+	defer london.Close()
+	paris := broker.Subscribe(ForecastTopic(2))
+	defer paris.Close()
+
+	broker.Publish(Event{Topic: ForecastTopic(1), Type: "forecast", Data: 1})
+
+	assert.Equal(t, Event{Topic: ForecastTopic(1), Type: "forecast", Data: 1}, <-london.Events())
+	assert.Empty(t, paris.Events())
+}
+
+func TestAddAndRemoveTopics(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe()
+	defer sub.Close()
+
+	sub.Add(ForecastTopic(1), ForecastTopic(2))
+	assert.ElementsMatch(t, []string{ForecastTopic(1), ForecastTopic(2)}, sub.Topics())
+
+	sub.Remove(ForecastTopic(1))
+	broker.Publish(Event{Topic: ForecastTopic(1)})
+	broker.Publish(Event{Topic: ForecastTopic(2)})
+
+	assert.Equal(t, ForecastTopic(2), (<-sub.Events()).Topic)
+	assert.Empty(t, sub.Events())
+}
+
+func TestPublishDropsEventsForSlowSubscribers(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe("topic")
+	defer sub.Close()
+
+	for i := 0; i < subscriptionBuffer*2; i++ {
+		broker.Publish(Event{Topic: "topic", Data: i})
+	}
+
+	assert.Len(t, sub.Events(), subscriptionBuffer)
+}
+
+func TestCloseStopsDelivery(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe("topic")
+	sub.Close()
+	sub.Close()
+
+	broker.Publish(Event{Topic: "topic"})
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	assert.Empty(t, broker.topics)
+}
+
+func TestBrokerCloseEndsSubscriptions(t *testing.T) {
+	broker := NewBroker()
+	sub := broker.Subscribe("topic")
+	idle := broker.Subscribe()
+
+	broker.Close()
+	sub.Close()
+
+	_, ok := <-sub.Events()
+	assert.False(t, ok)
+	_, ok = <-idle.Events()
+	assert.False(t, ok)
+	assert.Empty(t, broker.topics)
+
+	late := broker.Subscribe("topic")
+	_, ok = <-late.Events()
+	assert.False(t, ok)
+	assert.Empty(t, broker.topics)
+}
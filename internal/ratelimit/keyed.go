@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// Rule allows RequestsPerMinute events with bursts of up to Burst. Zero
+// requests per minute allows any rate.
+type Rule struct {
+	RequestsPerMinute int
+	Burst             int
+}
+
+// Limits are the request limits of an API. Default applies to each client
+// across all routes without a rule of their own; Routes apply to each client
+// on a single route, keyed by method and path, as in "POST /auth/sign-in".
+type Limits struct {
+	Default Rule
+	Routes  map[string]Rule
+}
+
+// Keyed holds a limiter per key, such as per client, created on first use.
+// Limiters idle long enough to refill are dropped, as a new one would be the
+// same, so the number kept follows the number of active keys.
+type Keyed struct {
+	mu        sync.Mutex
+	rule      Rule
+	limiters  map[string]*Limiter
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewKeyed(rule Rule) *Keyed {
+	return &Keyed{
+		rule:     rule,
+		limiters: make(map[string]*Limiter),
+		now:      time.Now,
+	}
+}
+
+// Take takes a token from the limiter of key.
+func (k *Keyed) Take(key string) Status {
+	if k.rule.RequestsPerMinute <= 0 {
+		return Status{Allowed: true}
+	}
+	k.mu.Lock()
+	k.sweep()
+	limiter, ok := k.limiters[key]
+	if !ok {
+		limiter = NewLimiter(PerMinute(k.rule.RequestsPerMinute), k.rule.Burst)
+		limiter.now = k.now
+		k.limiters[key] = limiter
+	}
+	k.mu.Unlock()
+	return limiter.Take()
+}
+
+// Len returns the number of limiters kept.
+func (k *Keyed) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return len(k.limiters)
+}
+
+// sweep drops the limiters that refilled, at most once per refill period.
+// Must be called with mu held.
+func (k *Keyed) sweep() {
+	now := k.now()
+	refill := time.Duration(float64(max(k.rule.Burst, 1)) / PerMinute(k.rule.RequestsPerMinute) * float64(time.Second))
+	if now.Sub(k.lastSweep) < refill {
+		return
+	}
+	k.lastSweep = now
+	for key, limiter := range k.limiters {
+		limiter.mu.Lock()
+		idle := now.Sub(limiter.last) >= refill
+		limiter.mu.Unlock()
+		if idle {
+			delete(k.limiters, key)
+		}
+	}
+}
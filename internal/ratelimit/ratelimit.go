@@ -0,0 +1,148 @@
+// Package ratelimit implements a token bucket limiter.
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Limiter hands out tokens at a fixed rate and lets up to burst of them
+// accumulate while idle. A nil Limiter or one with a non-positive rate never
+// blocks.
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+// NewLimiter creates a limiter allowing rate events per second with bursts of
+// up to burst events. The bucket starts full.
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		now:    time.Now,
+	}
+}
+
+// PerMinute converts a number of events per minute to a rate.
+func PerMinute(n int) float64 {
+	return float64(n) / 60
+}
+
+// Allow takes a token if one is available right now.
+func (l *Limiter) Allow() bool {
+	if l == nil || l.rate <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// Status describes a limiter right after Take.
+type Status struct {
+	Allowed bool
+	// Limit is the burst, the most events allowed at once.
+	Limit     int
+	Remaining int
+	// Reset is how long until the bucket is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the next token when the event was denied.
+	RetryAfter time.Duration
+}
+
+// Take is Allow reporting the state of the bucket, as rate limit headers
+// need. An unlimited limiter always allows and reports a zero Limit.
+func (l *Limiter) Take() Status {
+	if l == nil || l.rate <= 0 {
+		return Status{Allowed: true}
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	status := Status{Limit: int(l.burst)}
+	if l.tokens >= 1 {
+		l.tokens--
+		status.Allowed = true
+	} else {
+		status.RetryAfter = l.until(1)
+	}
+	status.Remaining = int(l.tokens)
+	status.Reset = l.until(l.burst)
+	return status
+}
+
+// until returns how long until the bucket holds n tokens. Must be called with
+// mu held.
+func (l *Limiter) until(n float64) time.Duration {
+	if l.tokens >= n {
+		return 0
+	}
+	return time.Duration((n - l.tokens) / l.rate * float64(time.Second))
+}
+
+// Wait blocks until a token is available or the context is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	if l == nil || l.rate <= 0 {
+		return ctx.Err()
+	}
+	delay := l.reserve()
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.mu.Lock()
+		l.tokens++
+		l.mu.Unlock()
+		return ctx.Err()
+	}
+}
+
+// reserve takes a token, possibly one that is yet to be added, and returns how
+// long to wait before it may be used.
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.refill()
+	l.tokens--
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+// refill adds the tokens accumulated since the last call. Must be called with
+// mu held.
+func (l *Limiter) refill() {
+	now := l.now()
+	if !l.last.IsZero() {
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if l.tokens > l.burst {
+			l.tokens = l.burst
+		}
+	}
+	l.last = now
+}
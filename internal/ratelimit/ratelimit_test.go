@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type LimiterTestSuite struct {
+	suite.Suite
+	limiter *Limiter
+	now     time.Time
+}
+
+func (suite *LimiterTestSuite) SetupTest() {
+	suite.now = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	suite.limiter = NewLimiter(1, 2)
+	suite.limiter.now = func() time.Time { return suite.now }
+}
+
+func (suite *LimiterTestSuite) TestAllowUsesBurst() {
+	assert.True(suite.T(), suite.limiter.Allow())
+	assert.True(suite.T(), suite.limiter.Allow())
+	assert.False(suite.T(), suite.limiter.Allow())
+}
+
+func (suite *LimiterTestSuite) TestAllowRefills() {
+	suite.limiter.Allow()
+	suite.limiter.Allow()
+
+	suite.now = suite.now.Add(time.Second)
+	assert.True(suite.T(), suite.limiter.Allow())
+	assert.False(suite.T(), suite.limiter.Allow())
+
+	suite.now = suite.now.Add(time.Hour)
+	assert.True(suite.T(), suite.limiter.Allow())
+	assert.True(suite.T(), suite.limiter.Allow())
+	assert.False(suite.T(), suite.limiter.Allow())
+}
+
+func (suite *LimiterTestSuite) TestReserveQueuesWaiters() {
+	assert.Equal(suite.T(), time.Duration(0), suite.limiter.reserve())
+	assert.Equal(suite.T(), time.Duration(0), suite.limiter.reserve())
+	assert.Equal(suite.T(), time.Second, suite.limiter.reserve())
+	assert.Equal(suite.T(), 2*time.Second, suite.limiter.reserve())
+}
+
+func (suite *LimiterTestSuite) TestWaitCanceled() {
+	suite.limiter.Allow()
+	suite.limiter.Allow()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(suite.T(), suite.limiter.Wait(ctx), context.Canceled)
+	assert.Equal(suite.T(), time.Second, suite.limiter.reserve())
+}
+
+func (suite *LimiterTestSuite) TestTakeReportsStatus() {
+	assert.Equal(suite.T(), Status{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}, suite.limiter.Take())
+	assert.Equal(suite.T(), Status{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}, suite.limiter.Take())
+	assert.Equal(suite.T(), Status{Allowed: false, Limit: 2, Remaining: 0, Reset: 2 * time.Second, RetryAfter: time.Second}, suite.limiter.Take())
+
+	suite.now = suite.now.Add(500 * time.Millisecond)
+	status := suite.limiter.Take()
+	assert.False(suite.T(), status.Allowed)
+	assert.Equal(suite.T(), 500*time.Millisecond, status.RetryAfter)
+}
+
+func (suite *LimiterTestSuite) TestKeyed() {
+	keyed := NewKeyed(Rule{RequestsPerMinute: 60, Burst: 1})
+	keyed.now = func() time.Time { return suite.now }
+
+	assert.True(suite.T(), keyed.Take("alice").Allowed)
+	assert.False(suite.T(), keyed.Take("alice").Allowed)
+	assert.True(suite.T(), keyed.Take("bob").Allowed, "each key has its own limiter")
+	assert.Equal(suite.T(), 2, keyed.Len())
+
+	suite.now = suite.now.Add(time.Second)
+	assert.True(suite.T(), keyed.Take("alice").Allowed)
+	assert.Equal(suite.T(), 1, keyed.Len(), "refilled limiters are dropped")
+
+	assert.True(suite.T(), NewKeyed(Rule{}).Take("alice").Allowed)
+}
+
+func (suite *LimiterTestSuite) TestUnlimited() {
+	var limiter *Limiter
+	assert.True(suite.T(), limiter.Allow())
+	assert.NoError(suite.T(), limiter.Wait(context.Background()))
+	assert.True(suite.T(), NewLimiter(0, 1).Allow())
+}
+
+func TestLimiterTestSuite(t *testing.T) {
+	suite.Run(t, new(LimiterTestSuite))
+}
@@ -0,0 +1,7 @@
+package repository
+
+import "errors"
+
+// ErrNotFound is returned by the storage backends when the record to read,
+// update or delete does not exist or belongs to another user.
+var ErrNotFound = errors.New("not found")
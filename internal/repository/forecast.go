@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"weather-app/internal/models"
+)
+
+// ForecastBatchSize keeps multi-row inserts well below the limit on bind
+// parameters per statement.
+const ForecastBatchSize = 1000
+
+// ForecastDay is the UTC day of t. Forecasts are stored one per city and day,
+// as in the postgres "date" column, by every backend.
+func ForecastDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// LatestPerDay keeps the last forecast of each day, in the order the days
+// first appear.
+func LatestPerDay(forecasts []models.Forecast) []models.Forecast {
+	index := make(map[time.Time]int, len(forecasts))
+	var latest []models.Forecast
+	for _, forecast := range forecasts {
+		day := ForecastDay(forecast.Date)
+		if i, ok := index[day]; ok {
+			latest[i] = forecast
+			continue
+		}
+		index[day] = len(latest)
+		latest = append(latest, forecast)
+	}
+	return latest
+}
+
+// FirstForecastDay returns the earliest day of forecasts, which must not be
+// empty. ReplaceForecasts replaces the stored forecasts from that day on.
+func FirstForecastDay(forecasts []models.Forecast) time.Time {
+	from := ForecastDay(forecasts[0].Date)
+	for _, forecast := range forecasts[1:] {
+		if day := ForecastDay(forecast.Date); day.Before(from) {
+			from = day
+		}
+	}
+	return from
+}
+
+// ForecastValues returns the values clause inserting batch into the city_id,
+// temp, date and forecast_json columns, with $n placeholders as both SQL
+// backends accept, and the matching arguments.
+func ForecastValues(cityId int, batch []models.Forecast) (string, []any) {
+	values := make([]string, 0, len(batch))
+	args := make([]any, 0, 4*len(batch))
+	for i, forecast := range batch {
+		values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d)", 4*i+1, 4*i+2, 4*i+3, 4*i+4))
+		args = append(args, cityId, forecast.Temp, ForecastDay(forecast.Date), forecast.ForecastJson)
+	}
+	return strings.Join(values, ", "), args
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"testing"
+	"time"
+	"weather-app/internal/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatestPerDay(t *testing.T) {
+	day := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	forecasts := LatestPerDay([]models.Forecast{
+		{Temp: 1, Date: day.AddDate(0, 0, 1)},
+		{Temp: 2, Date: day},
+		{Temp: 3, Date: day.AddDate(0, 0, 1).Add(3 * time.Hour)},
+	})
+
+	assert.Equal(t, []models.Forecast{
+		{Temp: 3, Date: day.AddDate(0, 0, 1).Add(3 * time.Hour)},
+		{Temp: 2, Date: day},
+	}, forecasts, "a day keeps its last forecast and its place")
+	assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC), FirstForecastDay(forecasts))
+}
+
+func TestForecastValues(t *testing.T) {
+	day := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	values, args := ForecastValues(7, []models.Forecast{{Temp: 1, Date: day}, {Temp: 2, Date: day.AddDate(0, 0, 1)}})
+
+	assert.Equal(t, "($1, $2, $3, $4), ($5, $6, $7, $8)", values)
+	assert.Len(t, args, 8)
+	assert.Equal(t, 7, args[4])
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.UTC), args[6])
+}
@@ -1,38 +1,114 @@
 package repository
 
 import (
+	"context"
+	"time"
 	"weather-app/internal/models"
 )
 
 type CityRepository interface {
-	CreateCity(models.City) (int, error)
-	GetCities() ([]models.City, error)
-	GetCity(cityId int) (models.City, error)
+	CreateCity(ctx context.Context, city models.City) (int, error)
+	GetCities(ctx context.Context) ([]models.City, error)
+	GetCity(ctx context.Context, cityId int) (models.City, error)
+	GetCitySchedules(ctx context.Context) ([]models.CitySchedule, error)
+	UpdateCityInterval(ctx context.Context, cityId int, interval int) error
 }
 
 type ForecastRepository interface {
-	CreateForecast(models.Forecast) (int, error)
-	GetForecasts(cityId int) ([]models.Forecast, error)
+	CreateForecast(ctx context.Context, forecast models.Forecast) (int, error)
+	ReplaceForecasts(ctx context.Context, cityId int, forecasts []models.Forecast) (int, error)
+	GetForecasts(ctx context.Context, cityId int) ([]models.Forecast, error)
+	RollupForecasts(ctx context.Context, before time.Time, limit int) (int, error)
+	GetDailyForecasts(ctx context.Context, cityId int) ([]models.DailyForecast, error)
+	PruneDailyForecasts(ctx context.Context, before time.Time, limit int) (int, error)
+	SetForecastFreshness(ctx context.Context, freshness models.ForecastFreshness) error
+	GetForecastFreshness(ctx context.Context, cityId int) (models.ForecastFreshness, error)
+	GetAllForecastFreshness(ctx context.Context) ([]models.ForecastFreshness, error)
 }
 
 type UserRepository interface {
-	CreateUser(user models.User) (int, error)
-	GetUser(login, password string) (models.User, error)
-	GetFavorites(userId int) ([]int, error)
-	AddFavorite(userId int, cityId int) (int, error)
-	DeleteFavorite(userId int, cityId int) error
+	CreateUser(ctx context.Context, user models.User) (int, error)
+	GetUser(ctx context.Context, login, password string) (models.User, error)
+	GetUserById(ctx context.Context, userId int) (models.User, error)
+	GetFavorites(ctx context.Context, userId int) ([]int, error)
+	AddFavorite(ctx context.Context, userId int, cityId int) (int, error)
+	DeleteFavorite(ctx context.Context, userId int, cityId int) error
+	RecordLoginFailure(ctx context.Context, subject string, failedAt time.Time, resetBefore time.Time) (models.LoginFailure, error)
+	GetLoginFailure(ctx context.Context, subject string) (models.LoginFailure, error)
+	LockLogin(ctx context.Context, subject string, until time.Time) error
+	ClearLoginFailures(ctx context.Context, subject string) error
+	GetLockedLogins(ctx context.Context, now time.Time) ([]models.LoginFailure, error)
+	PruneLoginFailures(ctx context.Context, before time.Time, limit int) (int, error)
+	CreateSignInAttempt(ctx context.Context, attempt models.SignInAttempt) (int, error)
+	GetSignInAttempts(ctx context.Context, login string, limit int) ([]models.SignInAttempt, error)
+	HasSignedInFrom(ctx context.Context, login, ip string) (bool, error)
+	PruneSignInAttempts(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+type AlertRepository interface {
+	CreateAlertRule(ctx context.Context, rule models.AlertRule) (int, error)
+	GetAlertRules(ctx context.Context, userId int) ([]models.AlertRule, error)
+	GetAlertRule(ctx context.Context, userId int, ruleId int) (models.AlertRule, error)
+	UpdateAlertRule(ctx context.Context, rule models.AlertRule) error
+	DeleteAlertRule(ctx context.Context, userId int, ruleId int) error
+	GetCityAlertRules(ctx context.Context, cityId int) ([]models.AlertRule, error)
+	CreateTriggeredAlert(ctx context.Context, alert models.TriggeredAlert) (int, error)
+	GetTriggeredAlerts(ctx context.Context, userId int) ([]models.TriggeredAlert, error)
+	GetTriggeredAlertsAfter(ctx context.Context, afterId int, limit int) ([]models.TriggeredAlert, error)
+	GetLastTriggeredAlertId(ctx context.Context) (int, error)
+}
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (int, error)
+	GetWebhooks(ctx context.Context, userId int) ([]models.Webhook, error)
+	GetWebhook(ctx context.Context, userId int, webhookId int) (models.Webhook, error)
+	GetWebhookById(ctx context.Context, webhookId int) (models.Webhook, error)
+	GetCityWebhooks(ctx context.Context, cityId int) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, userId int, webhookId int) error
+	CreateDelivery(ctx context.Context, delivery models.WebhookDelivery) (int, error)
+	ClaimPendingDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]models.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error
+	GetDeliveries(ctx context.Context, userId int, webhookId int, limit int) ([]models.WebhookDelivery, error)
+}
+
+type CollectorRunRepository interface {
+	CreateCollectorRun(ctx context.Context, run models.CollectorRun) (int, error)
+	FinishCollectorRun(ctx context.Context, run models.CollectorRun) error
+	GetCollectorRuns(ctx context.Context, limit int) ([]models.CollectorRun, error)
+	RecordCityFailure(ctx context.Context, cityId int, reason string, failedAt time.Time) (int, error)
+	ClearCityFailures(ctx context.Context, cityId int) error
+	GetDeadLetters(ctx context.Context, minFailures int) ([]models.DeadLetter, error)
+	PruneCollectorRuns(ctx context.Context, before time.Time, limit int) (int, error)
+	// The weather provider's daily quota is counted here, so that it is
+	// shared by every process using the storage.
+	AddProviderRequest(ctx context.Context, day time.Time, quota int) (bool, error)
+	GetProviderRequests(ctx context.Context, day time.Time) (int, error)
+}
+
+// HealthRepository tells whether the storage is reachable and migrated.
+type HealthRepository interface {
+	Ping(ctx context.Context) error
+	GetSchemaVersion(ctx context.Context) (version uint, dirty bool, err error)
 }
 
 type Repository struct {
 	CityRepository
 	ForecastRepository
 	UserRepository
+	AlertRepository
+	WebhookRepository
+	CollectorRunRepository
+	HealthRepository
 }
 
-func NewRepository(cityRep CityRepository, forecastRep ForecastRepository, userRep UserRepository) *Repository {
+func NewRepository(cityRep CityRepository, forecastRep ForecastRepository, userRep UserRepository, alertRep AlertRepository, webhookRep WebhookRepository, collectorRunRep CollectorRunRepository, healthRep HealthRepository) *Repository {
 	return &Repository{
-		CityRepository:     cityRep,
-		ForecastRepository: forecastRep,
-		UserRepository:     userRep,
+		CityRepository:         cityRep,
+		ForecastRepository:     forecastRep,
+		UserRepository:         userRep,
+		AlertRepository:        alertRep,
+		WebhookRepository:      webhookRep,
+		CollectorRunRepository: collectorRunRep,
+		HealthRepository:       healthRep,
 	}
 }
@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+)
+
+type AlertRepository struct {
+	s *Storage
+}
+
+func NewAlertRepository(s *Storage) *AlertRepository {
+	return &AlertRepository{s: s}
+}
+
+func (r *AlertRepository) CreateAlertRule(ctx context.Context, rule models.AlertRule) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[rule.UserId]; !ok {
+		return 0, foreignKeyViolation("alert_rules_user_id_fkey")
+	}
+	if _, ok := r.s.cities[rule.CityId]; !ok {
+		return 0, foreignKeyViolation("alert_rules_city_id_fkey")
+	}
+	rule.Id = r.s.nextId("alert_rules")
+	r.s.alertRules[rule.Id] = rule
+	return rule.Id, nil
+}
+
+func (r *AlertRepository) filterRules(match func(models.AlertRule) bool) []models.AlertRule {
+	var rules []models.AlertRule
+	for _, rule := range r.s.alertRules {
+		if match(rule) {
+			rules = append(rules, rule)
+		}
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Id < rules[j].Id
+	})
+	return rules
+}
+
+func (r *AlertRepository) GetAlertRules(ctx context.Context, userId int) ([]models.AlertRule, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.filterRules(func(rule models.AlertRule) bool { return rule.UserId == userId }), nil
+}
+
+func (r *AlertRepository) GetAlertRule(ctx context.Context, userId int, ruleId int) (models.AlertRule, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	rule, ok := r.s.alertRules[ruleId]
+	if !ok || rule.UserId != userId {
+		return models.AlertRule{}, repository.ErrNotFound
+	}
+	return rule, nil
+}
+
+func (r *AlertRepository) UpdateAlertRule(ctx context.Context, rule models.AlertRule) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	existing, ok := r.s.alertRules[rule.Id]
+	if !ok || existing.UserId != rule.UserId {
+		return repository.ErrNotFound
+	}
+	if _, ok := r.s.cities[rule.CityId]; !ok {
+		return foreignKeyViolation("alert_rules_city_id_fkey")
+	}
+	r.s.alertRules[rule.Id] = rule
+	return nil
+}
+
+func (r *AlertRepository) DeleteAlertRule(ctx context.Context, userId int, ruleId int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	rule, ok := r.s.alertRules[ruleId]
+	if !ok || rule.UserId != userId {
+		return repository.ErrNotFound
+	}
+	delete(r.s.alertRules, ruleId)
+	for id, alert := range r.s.triggeredAlerts {
+		if alert.RuleId == ruleId {
+			delete(r.s.triggeredAlerts, id)
+		}
+	}
+	return nil
+}
+
+func (r *AlertRepository) GetCityAlertRules(ctx context.Context, cityId int) ([]models.AlertRule, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.filterRules(func(rule models.AlertRule) bool { return rule.CityId == cityId }), nil
+}
+
+// CreateTriggeredAlert returns 0 if the alert was already recorded for the
+// rule and forecast date, like the postgres implementation.
+func (r *AlertRepository) CreateTriggeredAlert(ctx context.Context, alert models.TriggeredAlert) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.alertRules[alert.RuleId]; !ok {
+		return 0, foreignKeyViolation("triggered_alerts_rule_id_fkey")
+	}
+	for _, existing := range r.s.triggeredAlerts {
+		if existing.RuleId == alert.RuleId && existing.ForecastDate.Equal(alert.ForecastDate) {
+			return 0, nil
+		}
+	}
+	alert.Id = r.s.nextId("triggered_alerts")
+	alert.TriggeredAt = time.Now()
+	r.s.triggeredAlerts[alert.Id] = models.TriggeredAlert{
+		Id:           alert.Id,
+		RuleId:       alert.RuleId,
+		ForecastDate: alert.ForecastDate,
+		Value:        alert.Value,
+		TriggeredAt:  alert.TriggeredAt,
+	}
+	return alert.Id, nil
+}
+
+func (r *AlertRepository) GetTriggeredAlerts(ctx context.Context, userId int) ([]models.TriggeredAlert, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var alerts []models.TriggeredAlert
+	for _, alert := range r.s.triggeredAlerts {
+		rule := r.s.alertRules[alert.RuleId]
+		if rule.UserId != userId {
+			continue
+		}
+		alert.UserId = rule.UserId
+		alert.CityId = rule.CityId
+		alert.Metric = rule.Metric
+		alert.Threshold = rule.Threshold
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].TriggeredAt.After(alerts[j].TriggeredAt)
+	})
+	return alerts, nil
+}
+
+func (r *AlertRepository) GetTriggeredAlertsAfter(ctx context.Context, afterId int, limit int) ([]models.TriggeredAlert, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var alerts []models.TriggeredAlert
+	for _, alert := range r.s.triggeredAlerts {
+		if alert.Id <= afterId {
+			continue
+		}
+		rule := r.s.alertRules[alert.RuleId]
+		alert.UserId = rule.UserId
+		alert.CityId = rule.CityId
+		alert.Metric = rule.Metric
+		alert.Threshold = rule.Threshold
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Id < alerts[j].Id
+	})
+	if len(alerts) > limit {
+		alerts = alerts[:limit]
+	}
+	return alerts, nil
+}
+
+func (r *AlertRepository) GetLastTriggeredAlertId(ctx context.Context) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	last := 0
+	for id := range r.s.triggeredAlerts {
+		last = max(last, id)
+	}
+	return last, nil
+}
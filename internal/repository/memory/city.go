@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"weather-app/internal/models"
+)
+
+type CityRepository struct {
+	s *Storage
+}
+
+func NewCityRepository(s *Storage) *CityRepository {
+	return &CityRepository{s: s}
+}
+
+// CreateCity upserts the city by (name, country) like the postgres implementation.
+func (r *CityRepository) CreateCity(ctx context.Context, city models.City) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, existing := range r.s.cities {
+		if existing.Name == city.Name && existing.Country == city.Country {
+			existing.Latitude = city.Latitude
+			existing.Longitude = city.Longitude
+			r.s.cities[id] = existing
+			return id, nil
+		}
+	}
+	city.Id = r.s.nextId("cities")
+	r.s.cities[city.Id] = city
+	return city.Id, nil
+}
+
+func (r *CityRepository) GetCities(ctx context.Context) ([]models.City, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var cities []models.City
+	for _, city := range r.s.cities {
+		cities = append(cities, city)
+	}
+	sort.Slice(cities, func(i, j int) bool {
+		return cities[i].Name < cities[j].Name
+	})
+	return cities, nil
+}
+
+func (r *CityRepository) GetCity(ctx context.Context, cityId int) (models.City, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	city, ok := r.s.cities[cityId]
+	if !ok {
+		return models.City{}, sql.ErrNoRows
+	}
+	return city, nil
+}
+
+func (r *CityRepository) GetCitySchedules(ctx context.Context) ([]models.CitySchedule, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	favorites := make(map[int]int)
+	for _, fav := range r.s.favorites {
+		favorites[fav.CityId]++
+	}
+	var schedules []models.CitySchedule
+	for id, city := range r.s.cities {
+		schedules = append(schedules, models.CitySchedule{
+			City:           city,
+			UpdateInterval: r.s.cityIntervals[id],
+			Favorites:      favorites[id],
+		})
+	}
+	sort.Slice(schedules, func(i, j int) bool {
+		if schedules[i].Favorites != schedules[j].Favorites {
+			return schedules[i].Favorites > schedules[j].Favorites
+		}
+		return schedules[i].Name < schedules[j].Name
+	})
+	return schedules, nil
+}
+
+func (r *CityRepository) UpdateCityInterval(ctx context.Context, cityId int, interval int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.cities[cityId]; !ok {
+		return errors.New("no rows updated")
+	}
+	r.s.cityIntervals[cityId] = interval
+	return nil
+}
@@ -0,0 +1,151 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+	"weather-app/internal/models"
+)
+
+type CollectorRunRepository struct {
+	s *Storage
+}
+
+func NewCollectorRunRepository(s *Storage) *CollectorRunRepository {
+	return &CollectorRunRepository{s: s}
+}
+
+func (r *CollectorRunRepository) CreateCollectorRun(ctx context.Context, run models.CollectorRun) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	run.Id = r.s.nextId("collector_runs")
+	run.FinishedAt = nil
+	run.Failures = models.RunFailures{}
+	r.s.collectorRuns[run.Id] = run
+	return run.Id, nil
+}
+
+func (r *CollectorRunRepository) FinishCollectorRun(ctx context.Context, run models.CollectorRun) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	stored, ok := r.s.collectorRuns[run.Id]
+	if !ok {
+		return errors.New("no rows updated")
+	}
+	run.TriggeredBy = stored.TriggeredBy
+	run.StartedAt = stored.StartedAt
+	if run.Failures == nil {
+		run.Failures = models.RunFailures{}
+	}
+	r.s.collectorRuns[run.Id] = run
+	return nil
+}
+
+func (r *CollectorRunRepository) GetCollectorRuns(ctx context.Context, limit int) ([]models.CollectorRun, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var runs []models.CollectorRun
+	for _, run := range r.s.collectorRuns {
+		runs = append(runs, run)
+	}
+	sort.Slice(runs, func(i, j int) bool {
+		if !runs[i].StartedAt.Equal(runs[j].StartedAt) {
+			return runs[i].StartedAt.After(runs[j].StartedAt)
+		}
+		return runs[i].Id > runs[j].Id
+	})
+	if len(runs) > limit {
+		runs = runs[:limit]
+	}
+	return runs, nil
+}
+
+func (r *CollectorRunRepository) RecordCityFailure(ctx context.Context, cityId int, reason string, failedAt time.Time) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	city, ok := r.s.cities[cityId]
+	if !ok {
+		return 0, foreignKeyViolation("collector_dead_letters_city_id_fkey")
+	}
+	deadLetter, ok := r.s.deadLetters[cityId]
+	if !ok {
+		deadLetter = models.DeadLetter{CityId: cityId, FirstFailedAt: failedAt}
+	}
+	deadLetter.City = city.Name
+	deadLetter.Failures++
+	deadLetter.LastError = reason
+	deadLetter.LastFailedAt = failedAt
+	r.s.deadLetters[cityId] = deadLetter
+	return deadLetter.Failures, nil
+}
+
+func (r *CollectorRunRepository) ClearCityFailures(ctx context.Context, cityId int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.deadLetters, cityId)
+	return nil
+}
+
+func (r *CollectorRunRepository) GetDeadLetters(ctx context.Context, minFailures int) ([]models.DeadLetter, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var deadLetters []models.DeadLetter
+	for _, deadLetter := range r.s.deadLetters {
+		if deadLetter.Failures >= minFailures {
+			deadLetters = append(deadLetters, deadLetter)
+		}
+	}
+	sort.Slice(deadLetters, func(i, j int) bool {
+		if !deadLetters[i].LastFailedAt.Equal(deadLetters[j].LastFailedAt) {
+			return deadLetters[i].LastFailedAt.After(deadLetters[j].LastFailedAt)
+		}
+		return deadLetters[i].CityId < deadLetters[j].CityId
+	})
+	return deadLetters, nil
+}
+
+func (r *CollectorRunRepository) PruneCollectorRuns(ctx context.Context, before time.Time, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var old []int
+	for id, run := range r.s.collectorRuns {
+		if run.StartedAt.Before(before) {
+			old = append(old, id)
+		}
+	}
+	sort.Ints(old)
+	if len(old) > limit {
+		old = old[:limit]
+	}
+	for _, id := range old {
+		delete(r.s.collectorRuns, id)
+	}
+	return len(old), nil
+}
+
+func (r *CollectorRunRepository) AddProviderRequest(ctx context.Context, day time.Time, quota int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	day = day.UTC().Truncate(24 * time.Hour)
+	if quota > 0 && r.s.providerRequests[day] >= quota {
+		return false, nil
+	}
+	r.s.providerRequests[day]++
+	return true, nil
+}
+
+func (r *CollectorRunRepository) GetProviderRequests(ctx context.Context, day time.Time) (int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.s.providerRequests[day.UTC().Truncate(24*time.Hour)], nil
+}
@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+)
+
+type ForecastRepository struct {
+	s *Storage
+}
+
+func NewForecastRepository(s *Storage) *ForecastRepository {
+	return &ForecastRepository{s: s}
+}
+
+// CreateForecast upserts the forecast by (city_id, date) like the postgres implementation.
+func (r *ForecastRepository) CreateForecast(ctx context.Context, forecast models.Forecast) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.cities[forecast.CityId]; !ok {
+		return 0, foreignKeyViolation("forecasts_city_id_fkey")
+	}
+	forecast.Date = repository.ForecastDay(forecast.Date)
+	forecast.ForecastJson = append(json.RawMessage(nil), forecast.ForecastJson...)
+	for id, existing := range r.s.forecasts {
+		if existing.CityId == forecast.CityId && existing.Date.Equal(forecast.Date) {
+			existing.Temp = forecast.Temp
+			existing.ForecastJson = forecast.ForecastJson
+			r.s.forecasts[id] = existing
+			return id, nil
+		}
+	}
+	forecast.Id = r.s.nextId("forecasts")
+	r.s.forecasts[forecast.Id] = forecast
+	return forecast.Id, nil
+}
+
+// ReplaceForecasts replaces the city's forecasts from the first day of the
+// batch on, like the postgres implementation. Holding the lock for the whole
+// batch makes it atomic.
+func (r *ForecastRepository) ReplaceForecasts(ctx context.Context, cityId int, forecasts []models.Forecast) (int, error) {
+	if len(forecasts) == 0 {
+		return 0, nil
+	}
+
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.cities[cityId]; !ok {
+		return 0, foreignKeyViolation("forecasts_city_id_fkey")
+	}
+	forecasts = repository.LatestPerDay(forecasts)
+	from := repository.FirstForecastDay(forecasts)
+	for id, existing := range r.s.forecasts {
+		if existing.CityId == cityId && !existing.Date.Before(from) {
+			delete(r.s.forecasts, id)
+		}
+	}
+	for _, forecast := range forecasts {
+		forecast.Id = r.s.nextId("forecasts")
+		forecast.CityId = cityId
+		forecast.Date = repository.ForecastDay(forecast.Date)
+		forecast.ForecastJson = append(json.RawMessage(nil), forecast.ForecastJson...)
+		r.s.forecasts[forecast.Id] = forecast
+	}
+	return len(forecasts), nil
+}
+
+func (r *ForecastRepository) GetForecasts(ctx context.Context, cityId int) ([]models.Forecast, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var forecasts []models.Forecast
+	for _, forecast := range r.s.forecasts {
+		if forecast.CityId == cityId {
+			forecasts = append(forecasts, forecast)
+		}
+	}
+	sort.Slice(forecasts, func(i, j int) bool {
+		return forecasts[i].Id < forecasts[j].Id
+	})
+	return forecasts, nil
+}
+
+// RollupForecasts merges up to limit forecasts dated before the day of before,
+// oldest first, into the daily aggregates and deletes them.
+func (r *ForecastRepository) RollupForecasts(ctx context.Context, before time.Time, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	cutoff := repository.ForecastDay(before)
+	var old []models.Forecast
+	for _, forecast := range r.s.forecasts {
+		if forecast.Date.Before(cutoff) {
+			old = append(old, forecast)
+		}
+	}
+	sort.Slice(old, func(i, j int) bool {
+		if !old[i].Date.Equal(old[j].Date) {
+			return old[i].Date.Before(old[j].Date)
+		}
+		return old[i].Id < old[j].Id
+	})
+	if len(old) > limit {
+		old = old[:limit]
+	}
+
+	for _, forecast := range old {
+		key := dailyKey{CityId: forecast.CityId, Date: forecast.Date}
+		daily, ok := r.s.dailyForecasts[key]
+		if !ok {
+			daily = models.DailyForecast{CityId: forecast.CityId, Date: forecast.Date, TempMin: forecast.Temp, TempMax: forecast.Temp}
+		}
+		daily.TempMin = min(daily.TempMin, forecast.Temp)
+		daily.TempMax = max(daily.TempMax, forecast.Temp)
+		daily.TempAvg = (daily.TempAvg*float32(daily.Samples) + forecast.Temp) / float32(daily.Samples+1)
+		daily.Samples++
+		r.s.dailyForecasts[key] = daily
+		delete(r.s.forecasts, forecast.Id)
+	}
+	return len(old), nil
+}
+
+func (r *ForecastRepository) GetDailyForecasts(ctx context.Context, cityId int) ([]models.DailyForecast, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var daily []models.DailyForecast
+	for key, aggregate := range r.s.dailyForecasts {
+		if key.CityId == cityId {
+			daily = append(daily, aggregate)
+		}
+	}
+	sort.Slice(daily, func(i, j int) bool {
+		return daily[i].Date.Before(daily[j].Date)
+	})
+	return daily, nil
+}
+
+func (r *ForecastRepository) PruneDailyForecasts(ctx context.Context, before time.Time, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	cutoff := repository.ForecastDay(before)
+	var old []dailyKey
+	for key := range r.s.dailyForecasts {
+		if key.Date.Before(cutoff) {
+			old = append(old, key)
+		}
+	}
+	sort.Slice(old, func(i, j int) bool {
+		return old[i].Date.Before(old[j].Date)
+	})
+	if len(old) > limit {
+		old = old[:limit]
+	}
+	for _, key := range old {
+		delete(r.s.dailyForecasts, key)
+	}
+	return len(old), nil
+}
+
+func (r *ForecastRepository) SetForecastFreshness(ctx context.Context, freshness models.ForecastFreshness) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.cities[freshness.CityId]; !ok {
+		return errors.New("no rows updated")
+	}
+	freshness.UpdatedAt = freshness.UpdatedAt.UTC()
+	freshness.NextUpdate = freshness.NextUpdate.UTC()
+	r.s.freshness[freshness.CityId] = freshness
+	return nil
+}
+
+func (r *ForecastRepository) GetForecastFreshness(ctx context.Context, cityId int) (models.ForecastFreshness, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if _, ok := r.s.cities[cityId]; !ok {
+		return models.ForecastFreshness{}, sql.ErrNoRows
+	}
+	if freshness, ok := r.s.freshness[cityId]; ok {
+		return freshness, nil
+	}
+	return models.ForecastFreshness{CityId: cityId}, nil
+}
+
+func (r *ForecastRepository) GetAllForecastFreshness(ctx context.Context) ([]models.ForecastFreshness, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	freshness := make([]models.ForecastFreshness, 0, len(r.s.freshness))
+	for cityId, cityFreshness := range r.s.freshness {
+		if _, ok := r.s.cities[cityId]; ok {
+			freshness = append(freshness, cityFreshness)
+		}
+	}
+	sort.Slice(freshness, func(i, j int) bool { return freshness[i].CityId < freshness[j].CityId })
+	return freshness, nil
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"weather-app/internal/models"
+)
+
+func (r *UserRepository) RecordLoginFailure(ctx context.Context, subject string, failedAt time.Time, resetBefore time.Time) (models.LoginFailure, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	failure, ok := r.s.loginFailures[subject]
+	if !ok || failure.LastFailedAt.Before(resetBefore) {
+		failure = models.LoginFailure{Subject: subject}
+	}
+	failure.Failures++
+	failure.LastFailedAt = failedAt.UTC()
+	r.s.loginFailures[subject] = failure
+	return failure, nil
+}
+
+func (r *UserRepository) GetLoginFailure(ctx context.Context, subject string) (models.LoginFailure, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	if failure, ok := r.s.loginFailures[subject]; ok {
+		return failure, nil
+	}
+	return models.LoginFailure{Subject: subject}, nil
+}
+
+func (r *UserRepository) LockLogin(ctx context.Context, subject string, until time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if failure, ok := r.s.loginFailures[subject]; ok {
+		until = until.UTC()
+		failure.LockedUntil = &until
+		r.s.loginFailures[subject] = failure
+	}
+	return nil
+}
+
+func (r *UserRepository) ClearLoginFailures(ctx context.Context, subject string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	delete(r.s.loginFailures, subject)
+	return nil
+}
+
+func (r *UserRepository) GetLockedLogins(ctx context.Context, now time.Time) ([]models.LoginFailure, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var failures []models.LoginFailure
+	for _, failure := range r.s.loginFailures {
+		if failure.LockedUntil != nil && failure.LockedUntil.After(now) {
+			failures = append(failures, failure)
+		}
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if !failures[i].LockedUntil.Equal(*failures[j].LockedUntil) {
+			return failures[i].LockedUntil.After(*failures[j].LockedUntil)
+		}
+		return failures[i].Subject < failures[j].Subject
+	})
+	return failures, nil
+}
+
+func (r *UserRepository) PruneLoginFailures(ctx context.Context, before time.Time, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var subjects []string
+	for subject, failure := range r.s.loginFailures {
+		if failure.LastFailedAt.Before(before) && (failure.LockedUntil == nil || failure.LockedUntil.Before(before)) {
+			subjects = append(subjects, subject)
+		}
+	}
+	sort.Strings(subjects)
+	if len(subjects) > limit {
+		subjects = subjects[:limit]
+	}
+	for _, subject := range subjects {
+		delete(r.s.loginFailures, subject)
+	}
+	return len(subjects), nil
+}
+
+func (r *UserRepository) CreateSignInAttempt(ctx context.Context, attempt models.SignInAttempt) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	attempt.Id = r.s.nextId("sign_in_attempts")
+	attempt.AttemptedAt = attempt.AttemptedAt.UTC()
+	r.s.signInAttempts[attempt.Id] = attempt
+	return attempt.Id, nil
+}
+
+func (r *UserRepository) GetSignInAttempts(ctx context.Context, login string, limit int) ([]models.SignInAttempt, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var attempts []models.SignInAttempt
+	for _, attempt := range r.s.signInAttempts {
+		if login == "" || attempt.Login == login {
+			attempts = append(attempts, attempt)
+		}
+	}
+	sort.Slice(attempts, func(i, j int) bool {
+		return attempts[i].Id > attempts[j].Id
+	})
+	if len(attempts) > limit {
+		attempts = attempts[:limit]
+	}
+	return attempts, nil
+}
+
+func (r *UserRepository) HasSignedInFrom(ctx context.Context, login, ip string) (bool, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, attempt := range r.s.signInAttempts {
+		if attempt.Login == login && attempt.IP == ip && attempt.Outcome == models.SignInSucceeded {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *UserRepository) PruneSignInAttempts(ctx context.Context, before time.Time, limit int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var ids []int
+	for id, attempt := range r.s.signInAttempts {
+		if attempt.AttemptedAt.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	for _, id := range ids {
+		delete(r.s.signInAttempts, id)
+	}
+	return len(ids), nil
+}
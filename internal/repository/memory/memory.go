@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+	"weather-app/internal/models"
+)
+
+// Storage holds all tables of the in-memory backend. Repositories created from
+// the same Storage share data, like repositories sharing a database connection.
+type Storage struct {
+	mu sync.RWMutex
+
+	cities          map[int]models.City
+	cityIntervals   map[int]int
+	freshness       map[int]models.ForecastFreshness
+	forecasts       map[int]models.Forecast
+	dailyForecasts  map[dailyKey]models.DailyForecast
+	users           map[int]models.User
+	favorites       map[int]favorite
+	alertRules      map[int]models.AlertRule
+	triggeredAlerts map[int]models.TriggeredAlert
+	webhooks        map[int]models.Webhook
+	deliveries      map[int]models.WebhookDelivery
+	collectorRuns   map[int]models.CollectorRun
+	deadLetters     map[int]models.DeadLetter
+	loginFailures   map[string]models.LoginFailure
+	signInAttempts  map[int]models.SignInAttempt
+	// providerRequests counts the provider requests per UTC day.
+	providerRequests map[time.Time]int
+
+	lastId map[string]int
+}
+
+type dailyKey struct {
+	CityId int
+	Date   time.Time
+}
+
+type favorite struct {
+	UserId int
+	CityId int
+}
+
+func NewStorage() *Storage {
+	return &Storage{
+		cities:           make(map[int]models.City),
+		cityIntervals:    make(map[int]int),
+		freshness:        make(map[int]models.ForecastFreshness),
+		forecasts:        make(map[int]models.Forecast),
+		dailyForecasts:   make(map[dailyKey]models.DailyForecast),
+		users:            make(map[int]models.User),
+		favorites:        make(map[int]favorite),
+		alertRules:       make(map[int]models.AlertRule),
+		triggeredAlerts:  make(map[int]models.TriggeredAlert),
+		webhooks:         make(map[int]models.Webhook),
+		deliveries:       make(map[int]models.WebhookDelivery),
+		collectorRuns:    make(map[int]models.CollectorRun),
+		deadLetters:      make(map[int]models.DeadLetter),
+		loginFailures:    make(map[string]models.LoginFailure),
+		signInAttempts:   make(map[int]models.SignInAttempt),
+		providerRequests: make(map[time.Time]int),
+		lastId:           make(map[string]int),
+	}
+}
+
+// nextId emulates a serial column. Must be called with mu held.
+func (s *Storage) nextId(table string) int {
+	s.lastId[table]++
+	return s.lastId[table]
+}
+
+func uniqueViolation(constraint string) error {
+	return fmt.Errorf("duplicate key value violates unique constraint %q", constraint)
+}
+
+func foreignKeyViolation(constraint string) error {
+	return fmt.Errorf("insert or update violates foreign key constraint %q", constraint)
+}
@@ -0,0 +1,26 @@
+package memory
+
+import (
+	"testing"
+	"weather-app/internal/repository"
+	"weather-app/internal/repository/repotest"
+
+	"github.com/stretchr/testify/suite"
+)
+
+func newRepository() *repository.Repository {
+	s := NewStorage()
+	return repository.NewRepository(
+		NewCityRepository(s),
+		NewForecastRepository(s),
+		NewUserRepository(s),
+		NewAlertRepository(s),
+		NewWebhookRepository(s),
+		NewCollectorRunRepository(s),
+		NewHealthRepository(s),
+	)
+}
+
+func TestMemoryRepositorySuite(t *testing.T) {
+	suite.Run(t, &repotest.RepositorySuite{NewRepository: newRepository})
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"weather-app/internal/models"
+)
+
+type UserRepository struct {
+	s *Storage
+}
+
+func NewUserRepository(s *Storage) *UserRepository {
+	return &UserRepository{s: s}
+}
+
+func (r *UserRepository) CreateUser(ctx context.Context, user models.User) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, existing := range r.s.users {
+		if existing.Login == user.Login {
+			return 0, uniqueViolation("users_login_key")
+		}
+	}
+	user.Id = r.s.nextId("users")
+	r.s.users[user.Id] = user
+	return user.Id, nil
+}
+
+func (r *UserRepository) GetUser(ctx context.Context, login, password string) (models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	for _, user := range r.s.users {
+		if user.Login == login && user.Password == password {
+			return user, nil
+		}
+	}
+	return models.User{}, sql.ErrNoRows
+}
+
+func (r *UserRepository) GetUserById(ctx context.Context, userId int) (models.User, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	user, ok := r.s.users[userId]
+	if !ok {
+		return models.User{}, sql.ErrNoRows
+	}
+	return user, nil
+}
+
+func (r *UserRepository) GetFavorites(ctx context.Context, userId int) ([]int, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	var ids []int
+	for id, fav := range r.s.favorites {
+		if fav.UserId == userId {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	var favorites []int
+	for _, id := range ids {
+		favorites = append(favorites, r.s.favorites[id].CityId)
+	}
+	return favorites, nil
+}
+
+func (r *UserRepository) AddFavorite(ctx context.Context, userId int, cityId int) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[userId]; !ok {
+		return 0, foreignKeyViolation("users_cities_user_id_fkey")
+	}
+	if _, ok := r.s.cities[cityId]; !ok {
+		return 0, foreignKeyViolation("users_cities_city_id_fkey")
+	}
+	for _, fav := range r.s.favorites {
+		if fav.UserId == userId && fav.CityId == cityId {
+			return 0, uniqueViolation("users_cities_user_id_city_id_key")
+		}
+	}
+	id := r.s.nextId("users_cities")
+	r.s.favorites[id] = favorite{UserId: userId, CityId: cityId}
+	return id, nil
+}
+
+func (r *UserRepository) DeleteFavorite(ctx context.Context, userId int, cityId int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for id, fav := range r.s.favorites {
+		if fav.UserId == userId && fav.CityId == cityId {
+			delete(r.s.favorites, id)
+			return nil
+		}
+	}
+	return errors.New("no rows deleted")
+}
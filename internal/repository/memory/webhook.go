@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+	"weather-app/internal/models"
+)
+
+type WebhookRepository struct {
+	s *Storage
+}
+
+func NewWebhookRepository(s *Storage) *WebhookRepository {
+	return &WebhookRepository{s: s}
+}
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, webhook models.Webhook) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.users[webhook.UserId]; !ok {
+		return 0, foreignKeyViolation("webhooks_user_id_fkey")
+	}
+	webhook.Id = r.s.nextId("webhooks")
+	webhook.CreatedAt = time.Now()
+	r.s.webhooks[webhook.Id] = webhook
+	return webhook.Id, nil
+}
+
+func (r *WebhookRepository) filterWebhooks(match func(models.Webhook) bool) []models.Webhook {
+	var webhooks []models.Webhook
+	for _, webhook := range r.s.webhooks {
+		if match(webhook) {
+			webhooks = append(webhooks, webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].Id < webhooks[j].Id
+	})
+	return webhooks
+}
+
+func (r *WebhookRepository) GetWebhooks(ctx context.Context, userId int) ([]models.Webhook, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	return r.filterWebhooks(func(w models.Webhook) bool { return w.UserId == userId }), nil
+}
+
+func (r *WebhookRepository) GetWebhook(ctx context.Context, userId int, webhookId int) (models.Webhook, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	webhook, ok := r.s.webhooks[webhookId]
+	if !ok || webhook.UserId != userId {
+		return models.Webhook{}, sql.ErrNoRows
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) GetWebhookById(ctx context.Context, webhookId int) (models.Webhook, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	webhook, ok := r.s.webhooks[webhookId]
+	if !ok {
+		return models.Webhook{}, sql.ErrNoRows
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) GetCityWebhooks(ctx context.Context, cityId int) ([]models.Webhook, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	users := make(map[int]struct{})
+	for _, fav := range r.s.favorites {
+		if fav.CityId == cityId {
+			users[fav.UserId] = struct{}{}
+		}
+	}
+	return r.filterWebhooks(func(w models.Webhook) bool {
+		_, ok := users[w.UserId]
+		return ok
+	}), nil
+}
+
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, userId int, webhookId int) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	webhook, ok := r.s.webhooks[webhookId]
+	if !ok || webhook.UserId != userId {
+		return errors.New("no rows deleted")
+	}
+	delete(r.s.webhooks, webhookId)
+	for id, delivery := range r.s.deliveries {
+		if delivery.WebhookId == webhookId {
+			delete(r.s.deliveries, id)
+		}
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if _, ok := r.s.webhooks[delivery.WebhookId]; !ok {
+		return 0, foreignKeyViolation("webhook_deliveries_webhook_id_fkey")
+	}
+	delivery.Id = r.s.nextId("webhook_deliveries")
+	delivery.CreatedAt = time.Now()
+	delivery.Payload = append(json.RawMessage(nil), delivery.Payload...)
+	r.s.deliveries[delivery.Id] = delivery
+	return delivery.Id, nil
+}
+
+func (r *WebhookRepository) ClaimPendingDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]models.WebhookDelivery, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	var due []models.WebhookDelivery
+	for _, delivery := range r.s.deliveries {
+		if delivery.Status == models.DeliveryStatusPending && !delivery.NextAttemptAt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].NextAttemptAt.Before(due[j].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+	for i := range due {
+		due[i].NextAttemptAt = leaseUntil
+		r.s.deliveries[due[i].Id] = due[i]
+	}
+	return due, nil
+}
+
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	existing, ok := r.s.deliveries[delivery.Id]
+	if !ok {
+		return nil
+	}
+	existing.Status = delivery.Status
+	existing.Attempts = delivery.Attempts
+	existing.ResponseStatus = delivery.ResponseStatus
+	existing.LastError = delivery.LastError
+	existing.NextAttemptAt = delivery.NextAttemptAt
+	existing.DeliveredAt = delivery.DeliveredAt
+	r.s.deliveries[delivery.Id] = existing
+	return nil
+}
+
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, userId int, webhookId int, limit int) ([]models.WebhookDelivery, error) {
+	r.s.mu.RLock()
+	defer r.s.mu.RUnlock()
+
+	webhook, ok := r.s.webhooks[webhookId]
+	if !ok || webhook.UserId != userId {
+		return nil, nil
+	}
+	var deliveries []models.WebhookDelivery
+	for _, delivery := range r.s.deliveries {
+		if delivery.WebhookId == webhookId {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].Id > deliveries[j].Id
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
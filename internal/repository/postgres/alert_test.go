@@ -0,0 +1,237 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type AlertRepositoryTestSuite struct {
+	suite.Suite
+	db   *sqlx.DB
+	mock sqlmock.Sqlmock
+	repo *AlertRepository
+}
+
+func (suite *AlertRepositoryTestSuite) SetupTest() {
+	var err error
+	db, mock, err := sqlmock.New()
+	assert.NoError(suite.T(), err)
+	suite.db = sqlx.NewDb(db, "sqlmock")
+	suite.mock = mock
+	suite.repo = NewAlertRepository(suite.db)
+}
+
+func (suite *AlertRepositoryTestSuite) TearDownTest() {
+	suite.db.Close()
+}
+
+func (suite *AlertRepositoryTestSuite) TestCreateAlertRule() {
+	rule := models.AlertRule{
+		UserId:      1,
+		CityId:      2,
+		Metric:      models.AlertMetricTempBelow,
+		Threshold:   -5,
+		WithinHours: 24,
+	}
+
+	suite.mock.ExpectQuery("insert into alert_rules").
+		WithArgs(rule.UserId, rule.CityId, rule.Metric, rule.Threshold, rule.WithinHours).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	id, err := suite.repo.CreateAlertRule(context.Background(), rule)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, id)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestCreateAlertRuleError() {
+	rule := models.AlertRule{
+		UserId:      1,
+		CityId:      2,
+		Metric:      models.AlertMetricTempBelow,
+		Threshold:   -5,
+		WithinHours: 24,
+	}
+
+	suite.mock.ExpectQuery("insert into alert_rules").
+		WithArgs(rule.UserId, rule.CityId, rule.Metric, rule.Threshold, rule.WithinHours).
+		WillReturnError(fmt.Errorf("insertion error"))
+
+	id, err := suite.repo.CreateAlertRule(context.Background(), rule)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), 0, id)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestGetAlertRules() {
+	rules := []models.AlertRule{
+		{Id: 1, UserId: 1, CityId: 2, Metric: models.AlertMetricTempBelow, Threshold: -5, WithinHours: 24},
+		{Id: 2, UserId: 1, CityId: 3, Metric: models.AlertMetricPopAbove, Threshold: 80, WithinHours: 12},
+	}
+	rows := sqlmock.NewRows([]string{"id", "user_id", "city_id", "metric", "threshold", "within_hours"})
+	for _, r := range rules {
+		rows.AddRow(r.Id, r.UserId, r.CityId, r.Metric, r.Threshold, r.WithinHours)
+	}
+
+	suite.mock.ExpectQuery("select id, user_id, city_id, metric, threshold, within_hours from alert_rules where user_id=\\$1 order by id").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	result, err := suite.repo.GetAlertRules(context.Background(), 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), rules, result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestGetAlertRuleNotFound() {
+	suite.mock.ExpectQuery("select id, user_id, city_id, metric, threshold, within_hours from alert_rules where user_id=\\$1 and id=\\$2").
+		WithArgs(1, 999).
+		WillReturnError(sql.ErrNoRows)
+
+	result, err := suite.repo.GetAlertRule(context.Background(), 1, 999)
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+	assert.Equal(suite.T(), models.AlertRule{}, result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestUpdateAlertRule() {
+	rule := models.AlertRule{Id: 1, UserId: 1, CityId: 2, Metric: models.AlertMetricWindGustAbove, Threshold: 20, WithinHours: 6}
+
+	suite.mock.ExpectExec("update alert_rules set city_id=\\$1, metric=\\$2, threshold=\\$3, within_hours=\\$4").
+		WithArgs(rule.CityId, rule.Metric, rule.Threshold, rule.WithinHours, rule.UserId, rule.Id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := suite.repo.UpdateAlertRule(context.Background(), rule)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestUpdateAlertRuleNoRows() {
+	rule := models.AlertRule{Id: 1, UserId: 2, CityId: 2, Metric: models.AlertMetricWindGustAbove, Threshold: 20, WithinHours: 6}
+
+	suite.mock.ExpectExec("update alert_rules").
+		WithArgs(rule.CityId, rule.Metric, rule.Threshold, rule.WithinHours, rule.UserId, rule.Id).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := suite.repo.UpdateAlertRule(context.Background(), rule)
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestDeleteAlertRule() {
+	suite.mock.ExpectExec("delete from alert_rules where user_id=\\$1 and id=\\$2").
+		WithArgs(1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := suite.repo.DeleteAlertRule(context.Background(), 1, 1)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestDeleteAlertRuleNoRows() {
+	suite.mock.ExpectExec("delete from alert_rules where user_id=\\$1 and id=\\$2").
+		WithArgs(1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := suite.repo.DeleteAlertRule(context.Background(), 1, 1)
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestGetCityAlertRules() {
+	rows := sqlmock.NewRows([]string{"id", "user_id", "city_id", "metric", "threshold", "within_hours"}).
+		AddRow(1, 1, 2, models.AlertMetricTempBelow, -5, 24)
+
+	suite.mock.ExpectQuery("select id, user_id, city_id, metric, threshold, within_hours from alert_rules where city_id=\\$1").
+		WithArgs(2).
+		WillReturnRows(rows)
+
+	result, err := suite.repo.GetCityAlertRules(context.Background(), 2)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), result, 1)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestCreateTriggeredAlert() {
+	alert := models.TriggeredAlert{RuleId: 1, ForecastDate: time.Now(), Value: -7}
+
+	suite.mock.ExpectQuery("insert into triggered_alerts").
+		WithArgs(alert.RuleId, alert.ForecastDate, alert.Value).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	id, err := suite.repo.CreateTriggeredAlert(context.Background(), alert)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 5, id)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestCreateTriggeredAlertDuplicate() {
+	alert := models.TriggeredAlert{RuleId: 1, ForecastDate: time.Now(), Value: -7}
+
+	suite.mock.ExpectQuery("insert into triggered_alerts").
+		WithArgs(alert.RuleId, alert.ForecastDate, alert.Value).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	id, err := suite.repo.CreateTriggeredAlert(context.Background(), alert)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, id)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestGetTriggeredAlerts() {
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "rule_id", "user_id", "city_id", "metric", "threshold", "value", "forecast_date", "triggered_at"}).
+		AddRow(1, 1, 1, 2, models.AlertMetricTempBelow, -5, -7, now, now)
+
+	suite.mock.ExpectQuery("select t.id, t.rule_id, r.user_id, r.city_id, r.metric, r.threshold, t.value, t.forecast_date, t.triggered_at").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	result, err := suite.repo.GetTriggeredAlerts(context.Background(), 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.TriggeredAlert{
+		{Id: 1, RuleId: 1, UserId: 1, CityId: 2, Metric: models.AlertMetricTempBelow, Threshold: -5, Value: -7, ForecastDate: now, TriggeredAt: now},
+	}, result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestGetTriggeredAlertsAfter() {
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "rule_id", "user_id", "city_id", "metric", "threshold", "value", "forecast_date", "triggered_at"}).
+		AddRow(8, 1, 1, 2, models.AlertMetricTempBelow, -5, -7, now, now)
+
+	suite.mock.ExpectQuery("select .* from triggered_alerts t join alert_rules r on r.id = t.rule_id where t.id > \\$1 order by t.id limit \\$2").
+		WithArgs(7, 100).
+		WillReturnRows(rows)
+
+	result, err := suite.repo.GetTriggeredAlertsAfter(context.Background(), 7, 100)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.TriggeredAlert{
+		{Id: 8, RuleId: 1, UserId: 1, CityId: 2, Metric: models.AlertMetricTempBelow, Threshold: -5, Value: -7, ForecastDate: now, TriggeredAt: now},
+	}, result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *AlertRepositoryTestSuite) TestGetLastTriggeredAlertId() {
+	suite.mock.ExpectQuery("select coalesce\\(max\\(id\\), 0\\) from triggered_alerts").
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(8))
+
+	id, err := suite.repo.GetLastTriggeredAlertId(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 8, id)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func TestAlertRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(AlertRepositoryTestSuite))
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"weather-app/internal/models"
@@ -44,7 +45,7 @@ func (suite *CityRepositoryTestSuite) TestCreateCity() {
 		WithArgs(city.Name, city.Country, city.Latitude, city.Longitude).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	id, err := suite.repo.CreateCity(city)
+	id, err := suite.repo.CreateCity(context.Background(), city)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 1, id)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -62,7 +63,7 @@ func (suite *CityRepositoryTestSuite) TestCreateCityConflict() {
 		WithArgs(city.Name, city.Country, city.Latitude, city.Longitude).
 		WillReturnError(fmt.Errorf("conflict error"))
 
-	id, err := suite.repo.CreateCity(city)
+	id, err := suite.repo.CreateCity(context.Background(), city)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), 0, id)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -92,7 +93,7 @@ func (suite *CityRepositoryTestSuite) TestGetCities() {
 
 	suite.mock.ExpectQuery("select id, name, country, latitude, longitude from cities order by name").WillReturnRows(rows)
 
-	result, err := suite.repo.GetCities()
+	result, err := suite.repo.GetCities(context.Background())
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), cities, result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -103,7 +104,7 @@ func (suite *CityRepositoryTestSuite) TestGetCitiesEmpty() {
 
 	suite.mock.ExpectQuery("select id, name, country, latitude, longitude from cities order by name").WillReturnRows(rows)
 
-	result, err := suite.repo.GetCities()
+	result, err := suite.repo.GetCities(context.Background())
 	assert.NoError(suite.T(), err)
 	assert.Empty(suite.T(), result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -125,7 +126,7 @@ func (suite *CityRepositoryTestSuite) TestGetCity() {
 		WithArgs(city.Id).
 		WillReturnRows(rows)
 
-	result, err := suite.repo.GetCity(city.Id)
+	result, err := suite.repo.GetCity(context.Background(), city.Id)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), city, result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -136,12 +137,51 @@ func (suite *CityRepositoryTestSuite) TestGetCityNotFound() {
 		WithArgs(999).
 		WillReturnError(fmt.Errorf("sql: no rows in result set"))
 
-	result, err := suite.repo.GetCity(999)
+	result, err := suite.repo.GetCity(context.Background(), 999)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), models.City{}, result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
 }
 
+func (suite *CityRepositoryTestSuite) TestGetCitySchedules() {
+	schedule := models.CitySchedule{
+		City:           models.City{Id: 1, Name: "London", Country: "GB", Latitude: 51.5074, Longitude: -0.1278},
+		UpdateInterval: 600,
+		Favorites:      3,
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "name", "country", "latitude", "longitude", "update_interval", "favorites"}).
+		AddRow(schedule.Id, schedule.Name, schedule.Country, schedule.Latitude, schedule.Longitude, schedule.UpdateInterval, schedule.Favorites)
+
+	suite.mock.ExpectQuery("select (.+) count\\(uc.id\\) as favorites from cities c left join users_cities uc").
+		WillReturnRows(rows)
+
+	result, err := suite.repo.GetCitySchedules(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.CitySchedule{schedule}, result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CityRepositoryTestSuite) TestUpdateCityInterval() {
+	suite.mock.ExpectExec("update cities set update_interval=\\$1 where id=\\$2").
+		WithArgs(600, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := suite.repo.UpdateCityInterval(context.Background(), 1, 600)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CityRepositoryTestSuite) TestUpdateCityIntervalNotFound() {
+	suite.mock.ExpectExec("update cities set update_interval=\\$1 where id=\\$2").
+		WithArgs(600, 999).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := suite.repo.UpdateCityInterval(context.Background(), 999, 600)
+	assert.Error(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
 func TestCityRepositoryTestSuite(t *testing.T) {
 	suite.Run(t, new(CityRepositoryTestSuite))
 }
@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"weather-app/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const collectorRunColumns = "id, triggered_by, started_at, finished_at, cities_attempted, cities_succeeded, cities_failed, cities_skipped, rows_written, failures"
+
+type CollectorRunRepository struct {
+	db *sqlx.DB
+}
+
+func NewCollectorRunRepository(db *sqlx.DB) *CollectorRunRepository {
+	return &CollectorRunRepository{db: db}
+}
+
+func (r *CollectorRunRepository) CreateCollectorRun(ctx context.Context, run models.CollectorRun) (int, error) {
+	var id int
+	query := fmt.Sprintf("insert into %s (triggered_by, started_at) values ($1, $2) returning id", CollectorRunsTable)
+	row := r.db.QueryRowContext(ctx, query, run.TriggeredBy, run.StartedAt)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *CollectorRunRepository) FinishCollectorRun(ctx context.Context, run models.CollectorRun) error {
+	query := fmt.Sprintf(`
+		update %s set finished_at=$1, cities_attempted=$2, cities_succeeded=$3, cities_failed=$4,
+			cities_skipped=$5, rows_written=$6, failures=$7
+		where id=$8
+	`, CollectorRunsTable)
+	result, err := r.db.ExecContext(ctx, query, run.FinishedAt, run.CitiesAttempted, run.CitiesSucceeded, run.CitiesFailed,
+		run.CitiesSkipped, run.RowsWritten, run.Failures, run.Id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("no rows updated")
+	}
+	return nil
+}
+
+// GetCollectorRuns returns the latest runs, newest first.
+func (r *CollectorRunRepository) GetCollectorRuns(ctx context.Context, limit int) ([]models.CollectorRun, error) {
+	var runs []models.CollectorRun
+	query := fmt.Sprintf("select %s from %s order by started_at desc, id desc limit $1", collectorRunColumns, CollectorRunsTable)
+	err := r.db.SelectContext(ctx, &runs, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// RecordCityFailure counts a failed update of the city and returns the number
+// of failures in a row.
+func (r *CollectorRunRepository) RecordCityFailure(ctx context.Context, cityId int, reason string, failedAt time.Time) (int, error) {
+	var failures int
+	query := fmt.Sprintf(`
+		insert into %s (city_id, failures, last_error, first_failed_at, last_failed_at)
+		values ($1, 1, $2, $3, $3)
+		on conflict (city_id) do update set
+			failures = %s.failures + 1,
+			last_error = excluded.last_error,
+			last_failed_at = excluded.last_failed_at
+		returning failures
+	`, DeadLettersTable, DeadLettersTable)
+	row := r.db.QueryRowContext(ctx, query, cityId, reason, failedAt)
+	if err := row.Scan(&failures); err != nil {
+		return 0, err
+	}
+	return failures, nil
+}
+
+func (r *CollectorRunRepository) ClearCityFailures(ctx context.Context, cityId int) error {
+	query := fmt.Sprintf("delete from %s where city_id=$1", DeadLettersTable)
+	_, err := r.db.ExecContext(ctx, query, cityId)
+	return err
+}
+
+// GetDeadLetters returns the cities that failed at least minFailures times in
+// a row, most recent failures first.
+func (r *CollectorRunRepository) GetDeadLetters(ctx context.Context, minFailures int) ([]models.DeadLetter, error) {
+	var deadLetters []models.DeadLetter
+	query := fmt.Sprintf(`
+		select dl.city_id, c.name as city, dl.failures, dl.last_error, dl.first_failed_at, dl.last_failed_at
+		from %s dl
+		inner join %s c on c.id = dl.city_id
+		where dl.failures >= $1
+		order by dl.last_failed_at desc, dl.city_id
+	`, DeadLettersTable, CitiesTable)
+	err := r.db.SelectContext(ctx, &deadLetters, query, minFailures)
+	if err != nil {
+		return nil, err
+	}
+	return deadLetters, nil
+}
+
+// PruneCollectorRuns deletes up to limit runs started before before and
+// returns the number deleted.
+func (r *CollectorRunRepository) PruneCollectorRuns(ctx context.Context, before time.Time, limit int) (int, error) {
+	query := fmt.Sprintf(`
+		delete from %s where id in (
+			select id from %s where started_at < $1 order by id limit $2
+		)
+	`, CollectorRunsTable, CollectorRunsTable)
+	result, err := r.db.ExecContext(ctx, query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
+
+// AddProviderRequest counts a provider request made on day unless quota
+// requests were already counted. The check and the increment are one
+// statement, so processes sharing the database never exceed the quota
+// together.
+func (r *CollectorRunRepository) AddProviderRequest(ctx context.Context, day time.Time, quota int) (bool, error) {
+	query := fmt.Sprintf(`
+		insert into %s (day, requests) values ($1, 1)
+		on conflict (day) do update set requests = %s.requests + 1
+		where $2 = 0 or %s.requests < $2
+		returning requests
+	`, ProviderRequestsTable, ProviderRequestsTable, ProviderRequestsTable)
+	var requests int
+	err := r.db.QueryRowContext(ctx, query, day.Format(time.DateOnly), quota).Scan(&requests)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *CollectorRunRepository) GetProviderRequests(ctx context.Context, day time.Time) (int, error) {
+	var requests int
+	query := fmt.Sprintf("select coalesce(sum(requests), 0) from %s where day=$1", ProviderRequestsTable)
+	if err := r.db.GetContext(ctx, &requests, query, day.Format(time.DateOnly)); err != nil {
+		return 0, err
+	}
+	return requests, nil
+}
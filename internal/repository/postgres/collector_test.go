@@ -0,0 +1,170 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CollectorRunRepositoryTestSuite struct {
+	suite.Suite
+	db   *sqlx.DB
+	mock sqlmock.Sqlmock
+	repo *CollectorRunRepository
+}
+
+func (suite *CollectorRunRepositoryTestSuite) SetupTest() {
+	var err error
+	db, mock, err := sqlmock.New()
+	assert.NoError(suite.T(), err)
+	suite.db = sqlx.NewDb(db, "sqlmock")
+	suite.mock = mock
+	suite.repo = NewCollectorRunRepository(suite.db)
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TearDownTest() {
+	suite.db.Close()
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TestCreateCollectorRun() {
+	run := models.CollectorRun{TriggeredBy: models.RunTriggerScheduled, StartedAt: time.Now()}
+
+	suite.mock.ExpectQuery("insert into collector_runs").
+		WithArgs(run.TriggeredBy, run.StartedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	id, err := suite.repo.CreateCollectorRun(context.Background(), run)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, id)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TestFinishCollectorRun() {
+	finishedAt := time.Now()
+	run := models.CollectorRun{Id: 1, FinishedAt: &finishedAt, CitiesAttempted: 2, CitiesSucceeded: 1, CitiesFailed: 1, RowsWritten: 40,
+		Failures: models.RunFailures{{CityId: 7, City: "Paris", Error: "timeout"}}}
+
+	suite.mock.ExpectExec("update collector_runs set finished_at=\\$1").
+		WithArgs(run.FinishedAt, 2, 1, 1, 0, 40, `[{"city_id":7,"city":"Paris","error":"timeout"}]`, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := suite.repo.FinishCollectorRun(context.Background(), run)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TestFinishCollectorRunNoRows() {
+	suite.mock.ExpectExec("update collector_runs").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := suite.repo.FinishCollectorRun(context.Background(), models.CollectorRun{Id: 999})
+	assert.Error(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TestGetCollectorRuns() {
+	startedAt := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "triggered_by", "started_at", "finished_at", "cities_attempted", "cities_succeeded",
+		"cities_failed", "cities_skipped", "rows_written", "failures"}).
+		AddRow(2, "manual", startedAt, nil, 0, 0, 0, 0, 0, []byte("[]"))
+
+	suite.mock.ExpectQuery("select .* from collector_runs order by started_at desc, id desc limit \\$1").
+		WithArgs(5).
+		WillReturnRows(rows)
+
+	result, err := suite.repo.GetCollectorRuns(context.Background(), 5)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.CollectorRun{{Id: 2, TriggeredBy: "manual", StartedAt: startedAt, Failures: models.RunFailures{}}}, result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TestRecordCityFailure() {
+	failedAt := time.Now()
+
+	suite.mock.ExpectQuery("insert into collector_dead_letters .* on conflict \\(city_id\\) do update set").
+		WithArgs(7, "timeout", failedAt).
+		WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(3))
+
+	failures, err := suite.repo.RecordCityFailure(context.Background(), 7, "timeout", failedAt)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 3, failures)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TestClearCityFailures() {
+	suite.mock.ExpectExec("delete from collector_dead_letters where city_id=\\$1").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := suite.repo.ClearCityFailures(context.Background(), 7)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TestGetDeadLetters() {
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"city_id", "city", "failures", "last_error", "first_failed_at", "last_failed_at"}).
+		AddRow(7, "Paris", 3, "timeout", now, now)
+
+	suite.mock.ExpectQuery("select .* from collector_dead_letters dl inner join cities c .* where dl.failures >= \\$1").
+		WithArgs(3).
+		WillReturnRows(rows)
+
+	result, err := suite.repo.GetDeadLetters(context.Background(), 3)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.DeadLetter{{CityId: 7, City: "Paris", Failures: 3, LastError: "timeout", FirstFailedAt: now, LastFailedAt: now}}, result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TestPruneCollectorRuns() {
+	before := time.Now()
+
+	suite.mock.ExpectExec("delete from collector_runs where id in \\( select id from collector_runs where started_at < \\$1 order by id limit \\$2 \\)").
+		WithArgs(before, 100).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+
+	pruned, err := suite.repo.PruneCollectorRuns(context.Background(), before, 100)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 4, pruned)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TestAddProviderRequest() {
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	suite.mock.ExpectQuery("insert into provider_requests .* on conflict \\(day\\) do update set .* where \\$2 = 0 or provider_requests.requests < \\$2").
+		WithArgs("2024-03-10", 100).
+		WillReturnRows(sqlmock.NewRows([]string{"requests"}).AddRow(5))
+	suite.mock.ExpectQuery("insert into provider_requests").
+		WithArgs("2024-03-10", 100).
+		WillReturnRows(sqlmock.NewRows([]string{"requests"}))
+
+	counted, err := suite.repo.AddProviderRequest(context.Background(), day, 100)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), counted)
+	counted, err = suite.repo.AddProviderRequest(context.Background(), day, 100)
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), counted, "no row is returned once the quota is used")
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *CollectorRunRepositoryTestSuite) TestGetProviderRequests() {
+	suite.mock.ExpectQuery("select coalesce\\(sum\\(requests\\), 0\\) from provider_requests where day=\\$1").
+		WithArgs("2024-03-10").
+		WillReturnRows(sqlmock.NewRows([]string{"requests"}).AddRow(5))
+
+	requests, err := suite.repo.GetProviderRequests(context.Background(), time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 5, requests)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func TestCollectorRunRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(CollectorRunRepositoryTestSuite))
+}
@@ -1,8 +1,13 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"time"
 	"weather-app/internal/models"
+	"weather-app/internal/repository"
 
 	"github.com/jmoiron/sqlx"
 )
@@ -15,7 +20,7 @@ func NewForecastRepository(db *sqlx.DB) *ForecastRepository {
 	return &ForecastRepository{db: db}
 }
 
-func (r *ForecastRepository) CreateForecast(forecast models.Forecast) (int, error) {
+func (r *ForecastRepository) CreateForecast(ctx context.Context, forecast models.Forecast) (int, error) {
 	var id int
 	query := fmt.Sprintf(`
 		insert into %s (city_id, temp, date, forecast_json)
@@ -25,19 +30,188 @@ func (r *ForecastRepository) CreateForecast(forecast models.Forecast) (int, erro
 			forecast_json = excluded.forecast_json
 		returning id
 	`, ForecastsTable)
-	row := r.db.QueryRow(query, forecast.CityId, forecast.Temp, forecast.Date, forecast.ForecastJson)
+	row := r.db.QueryRowContext(ctx, query, forecast.CityId, forecast.Temp, forecast.Date, forecast.ForecastJson)
 	if err := row.Scan(&id); err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
-func (r *ForecastRepository) GetForecasts(cityId int) ([]models.Forecast, error) {
+// ReplaceForecasts stores a city's forecasts in one transaction. Stored
+// forecasts from the first day of the batch on are replaced by the batch;
+// earlier days are kept. As with CreateForecast, a day keeps the last forecast
+// given for it. It returns the number of rows written.
+func (r *ForecastRepository) ReplaceForecasts(ctx context.Context, cityId int, forecasts []models.Forecast) (int, error) {
+	forecasts = repository.LatestPerDay(forecasts)
+	if len(forecasts) == 0 {
+		return 0, nil
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	query := fmt.Sprintf("delete from %s where city_id=$1 and date >= $2", ForecastsTable)
+	if _, err := tx.ExecContext(ctx, query, cityId, repository.FirstForecastDay(forecasts)); err != nil {
+		return 0, err
+	}
+	for start := 0; start < len(forecasts); start += repository.ForecastBatchSize {
+		values, args := repository.ForecastValues(cityId, forecasts[start:min(start+repository.ForecastBatchSize, len(forecasts))])
+		query := fmt.Sprintf("insert into %s (city_id, temp, date, forecast_json) values %s", ForecastsTable, values)
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(forecasts), nil
+}
+
+func (r *ForecastRepository) GetForecasts(ctx context.Context, cityId int) ([]models.Forecast, error) {
 	var forecasts []models.Forecast
 	query := fmt.Sprintf("select id, city_id, temp, date, forecast_json from %s where city_id=$1", ForecastsTable)
-	err := r.db.Select(&forecasts, query, cityId)
+	err := r.db.SelectContext(ctx, &forecasts, query, cityId)
 	if err != nil {
 		return nil, err
 	}
 	return forecasts, nil
 }
+
+// RollupForecasts moves up to limit forecasts dated before the day of before
+// into the daily aggregates and deletes them, in one transaction. Aggregates of
+// a day rolled up over several batches are merged. It returns the number of
+// forecasts deleted.
+func (r *ForecastRepository) RollupForecasts(ctx context.Context, before time.Time, limit int) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var ids []int
+	query := fmt.Sprintf("select id from %s where date < $1 order by date, id limit $2", ForecastsTable)
+	if err := tx.SelectContext(ctx, &ids, query, repository.ForecastDay(before), limit); err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	placeholders, args := idList(ids, 1)
+
+	query = fmt.Sprintf(`
+		insert into %s (city_id, date, temp_min, temp_max, temp_avg, samples)
+		select city_id, date, min(temp), max(temp), avg(temp), count(*) from %s
+		where id in (%s)
+		group by city_id, date
+		on conflict (city_id, date) do update set
+			temp_min = least(%s.temp_min, excluded.temp_min),
+			temp_max = greatest(%s.temp_max, excluded.temp_max),
+			temp_avg = (%s.temp_avg * %s.samples + excluded.temp_avg * excluded.samples) / (%s.samples + excluded.samples),
+			samples = %s.samples + excluded.samples
+	`, DailyForecastsTable, ForecastsTable, placeholders, DailyForecastsTable, DailyForecastsTable,
+		DailyForecastsTable, DailyForecastsTable, DailyForecastsTable, DailyForecastsTable)
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return 0, err
+	}
+	query = fmt.Sprintf("delete from %s where id in (%s)", ForecastsTable, placeholders)
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(ids), nil
+}
+
+func (r *ForecastRepository) GetDailyForecasts(ctx context.Context, cityId int) ([]models.DailyForecast, error) {
+	var daily []models.DailyForecast
+	query := fmt.Sprintf("select city_id, date, temp_min, temp_max, temp_avg, samples from %s where city_id=$1 order by date", DailyForecastsTable)
+	err := r.db.SelectContext(ctx, &daily, query, cityId)
+	if err != nil {
+		return nil, err
+	}
+	return daily, nil
+}
+
+// PruneDailyForecasts deletes up to limit daily aggregates dated before the day
+// of before and returns the number deleted.
+func (r *ForecastRepository) PruneDailyForecasts(ctx context.Context, before time.Time, limit int) (int, error) {
+	query := fmt.Sprintf(`
+		delete from %s where (city_id, date) in (
+			select city_id, date from %s where date < $1 order by date limit $2
+		)
+	`, DailyForecastsTable, DailyForecastsTable)
+	result, err := r.db.ExecContext(ctx, query, repository.ForecastDay(before), limit)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
+
+// SetForecastFreshness records when the city's forecasts were stored and when
+// they are updated next.
+func (r *ForecastRepository) SetForecastFreshness(ctx context.Context, freshness models.ForecastFreshness) error {
+	query := fmt.Sprintf("update %s set forecasts_updated_at=$1, forecasts_next_update=$2 where id=$3", CitiesTable)
+	result, err := r.db.ExecContext(ctx, query, freshness.UpdatedAt.UTC(), freshness.NextUpdate.UTC(), freshness.CityId)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("no rows updated")
+	}
+	return nil
+}
+
+// freshnessRow is the freshness of a city as stored: never updated cities
+// hold nulls.
+type freshnessRow struct {
+	CityId     int          `db:"id"`
+	UpdatedAt  sql.NullTime `db:"forecasts_updated_at"`
+	NextUpdate sql.NullTime `db:"forecasts_next_update"`
+}
+
+func (row freshnessRow) freshness() models.ForecastFreshness {
+	freshness := models.ForecastFreshness{CityId: row.CityId}
+	if row.UpdatedAt.Valid {
+		freshness.UpdatedAt = row.UpdatedAt.Time.UTC()
+	}
+	if row.NextUpdate.Valid {
+		freshness.NextUpdate = row.NextUpdate.Time.UTC()
+	}
+	return freshness
+}
+
+func (r *ForecastRepository) GetForecastFreshness(ctx context.Context, cityId int) (models.ForecastFreshness, error) {
+	var row freshnessRow
+	query := fmt.Sprintf("select id, forecasts_updated_at, forecasts_next_update from %s where id=$1", CitiesTable)
+	if err := r.db.GetContext(ctx, &row, query, cityId); err != nil {
+		return models.ForecastFreshness{}, err
+	}
+	return row.freshness(), nil
+}
+
+// GetAllForecastFreshness returns the freshness of every city whose forecasts
+// were stored, by city id.
+func (r *ForecastRepository) GetAllForecastFreshness(ctx context.Context) ([]models.ForecastFreshness, error) {
+	var rows []freshnessRow
+	query := fmt.Sprintf("select id, forecasts_updated_at, forecasts_next_update from %s where forecasts_updated_at is not null order by id", CitiesTable)
+	if err := r.db.SelectContext(ctx, &rows, query); err != nil {
+		return nil, err
+	}
+	freshness := make([]models.ForecastFreshness, 0, len(rows))
+	for _, row := range rows {
+		freshness = append(freshness, row.freshness())
+	}
+	return freshness, nil
+}
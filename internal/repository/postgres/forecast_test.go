@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"fmt"
 	"testing"
 	"time"
@@ -45,7 +46,7 @@ func (suite *ForecastRepositoryTestSuite) TestCreateForecast() {
 		WithArgs(forecast.CityId, forecast.Temp, forecast.Date, forecast.ForecastJson).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	id, err := suite.repo.CreateForecast(forecast)
+	id, err := suite.repo.CreateForecast(context.Background(), forecast)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 1, id)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -63,7 +64,7 @@ func (suite *ForecastRepositoryTestSuite) TestCreateForecastConflict() {
 		WithArgs(forecast.CityId, forecast.Temp, forecast.Date, forecast.ForecastJson).
 		WillReturnError(fmt.Errorf("conflict error"))
 
-	id, err := suite.repo.CreateForecast(forecast)
+	id, err := suite.repo.CreateForecast(context.Background(), forecast)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), 0, id)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -81,7 +82,7 @@ func (suite *ForecastRepositoryTestSuite) TestCreateForecastError() {
 		WithArgs(forecast.CityId, forecast.Temp, forecast.Date, forecast.ForecastJson).
 		WillReturnError(fmt.Errorf("insertion error"))
 
-	id, err := suite.repo.CreateForecast(forecast)
+	id, err := suite.repo.CreateForecast(context.Background(), forecast)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), 0, id)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -113,7 +114,7 @@ func (suite *ForecastRepositoryTestSuite) TestGetForecasts() {
 		WithArgs(1).
 		WillReturnRows(rows)
 
-	result, err := suite.repo.GetForecasts(1)
+	result, err := suite.repo.GetForecasts(context.Background(), 1)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), forecasts, result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -126,7 +127,7 @@ func (suite *ForecastRepositoryTestSuite) TestGetForecastsEmpty() {
 		WithArgs(1).
 		WillReturnRows(rows)
 
-	result, err := suite.repo.GetForecasts(1)
+	result, err := suite.repo.GetForecasts(context.Background(), 1)
 	assert.NoError(suite.T(), err)
 	assert.Empty(suite.T(), result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -137,12 +138,114 @@ func (suite *ForecastRepositoryTestSuite) TestGetForecastsQueryError() {
 		WithArgs(1).
 		WillReturnError(fmt.Errorf("query error"))
 
-	result, err := suite.repo.GetForecasts(1)
+	result, err := suite.repo.GetForecasts(context.Background(), 1)
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
 }
 
+func (suite *ForecastRepositoryTestSuite) TestReplaceForecasts() {
+	day := time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC)
+	forecasts := []models.Forecast{
+		{CityId: 1, Temp: 1, Date: day.Add(9 * time.Hour), ForecastJson: []byte(`{"slot":1}`)},
+		{CityId: 1, Temp: 2, Date: day.Add(12 * time.Hour), ForecastJson: []byte(`{"slot":2}`)},
+		{CityId: 1, Temp: 3, Date: day.AddDate(0, 0, 1), ForecastJson: []byte(`{"slot":3}`)},
+	}
+
+	suite.mock.ExpectBegin()
+	suite.mock.ExpectExec("delete from forecasts where city_id=\\$1 and date >= \\$2").
+		WithArgs(1, day).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	suite.mock.ExpectExec("insert into forecasts \\(city_id, temp, date, forecast_json\\) values \\(\\$1, \\$2, \\$3, \\$4\\), \\(\\$5, \\$6, \\$7, \\$8\\)").
+		WithArgs(1, float32(2), day, forecasts[1].ForecastJson, 1, float32(3), day.AddDate(0, 0, 1), forecasts[2].ForecastJson).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	suite.mock.ExpectCommit()
+
+	written, err := suite.repo.ReplaceForecasts(context.Background(), 1, forecasts)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, written)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *ForecastRepositoryTestSuite) TestReplaceForecastsRollsBack() {
+	forecasts := []models.Forecast{{CityId: 1, Temp: 1, Date: time.Now(), ForecastJson: []byte(`{}`)}}
+
+	suite.mock.ExpectBegin()
+	suite.mock.ExpectExec("delete from forecasts").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	suite.mock.ExpectExec("insert into forecasts").
+		WillReturnError(fmt.Errorf("database error"))
+	suite.mock.ExpectRollback()
+
+	written, err := suite.repo.ReplaceForecasts(context.Background(), 1, forecasts)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), 0, written)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *ForecastRepositoryTestSuite) TestRollupForecasts() {
+	before := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	suite.mock.ExpectBegin()
+	suite.mock.ExpectQuery("select id from forecasts where date < \\$1 order by date, id limit \\$2").
+		WithArgs(time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), 100).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3).AddRow(5))
+	suite.mock.ExpectExec("insert into forecast_daily .* from forecasts where id in \\(\\$1, \\$2\\) group by city_id, date on conflict").
+		WithArgs(3, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	suite.mock.ExpectExec("delete from forecasts where id in \\(\\$1, \\$2\\)").
+		WithArgs(3, 5).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	suite.mock.ExpectCommit()
+
+	rolledUp, err := suite.repo.RollupForecasts(context.Background(), before, 100)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, rolledUp)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *ForecastRepositoryTestSuite) TestRollupForecastsRollsBack() {
+	suite.mock.ExpectBegin()
+	suite.mock.ExpectQuery("select id from forecasts").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+	suite.mock.ExpectExec("insert into forecast_daily").
+		WillReturnError(fmt.Errorf("database error"))
+	suite.mock.ExpectRollback()
+
+	rolledUp, err := suite.repo.RollupForecasts(context.Background(), time.Now(), 100)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), 0, rolledUp)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *ForecastRepositoryTestSuite) TestPruneDailyForecasts() {
+	before := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+
+	suite.mock.ExpectExec("delete from forecast_daily where \\(city_id, date\\) in \\( select city_id, date from forecast_daily where date < \\$1 order by date limit \\$2 \\)").
+		WithArgs(time.Date(2030, 1, 2, 0, 0, 0, 0, time.UTC), 100).
+		WillReturnResult(sqlmock.NewResult(0, 7))
+
+	pruned, err := suite.repo.PruneDailyForecasts(context.Background(), before, 100)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 7, pruned)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *ForecastRepositoryTestSuite) TestGetForecastFreshness() {
+	updatedAt := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "forecasts_updated_at", "forecasts_next_update"}).
+		AddRow(1, updatedAt, nil)
+
+	suite.mock.ExpectQuery("select id, forecasts_updated_at, forecasts_next_update from cities where id=\\$1").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	freshness, err := suite.repo.GetForecastFreshness(context.Background(), 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.ForecastFreshness{CityId: 1, UpdatedAt: updatedAt}, freshness)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
 func TestForecastRepositoryTestSuite(t *testing.T) {
 	suite.Run(t, new(ForecastRepositoryTestSuite))
 }
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type HealthRepositoryTestSuite struct {
+	suite.Suite
+	db   *sqlx.DB
+	mock sqlmock.Sqlmock
+	repo *HealthRepository
+}
+
+func (suite *HealthRepositoryTestSuite) SetupTest() {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	assert.NoError(suite.T(), err)
+	suite.db = sqlx.NewDb(db, "sqlmock")
+	suite.mock = mock
+	suite.repo = NewHealthRepository(suite.db)
+}
+
+func (suite *HealthRepositoryTestSuite) TearDownTest() {
+	suite.db.Close()
+}
+
+func (suite *HealthRepositoryTestSuite) TestPing() {
+	suite.mock.ExpectPing()
+
+	assert.NoError(suite.T(), suite.repo.Ping(context.Background()))
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *HealthRepositoryTestSuite) TestGetSchemaVersion() {
+	suite.mock.ExpectQuery("select version, dirty from schema_migrations limit 1").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(11, false))
+
+	version, dirty, err := suite.repo.GetSchemaVersion(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), uint(11), version)
+	assert.False(suite.T(), dirty)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *HealthRepositoryTestSuite) TestGetSchemaVersionNone() {
+	suite.mock.ExpectQuery("select version, dirty from schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}))
+
+	version, dirty, err := suite.repo.GetSchemaVersion(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), uint(0), version)
+	assert.False(suite.T(), dirty)
+}
+
+func TestHealthRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(HealthRepositoryTestSuite))
+}
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"weather-app/internal/models"
+)
+
+// RecordLoginFailure counts a failed sign-in of subject and returns its
+// record. Failures last counted before resetBefore are forgotten first, along
+// with an expired lockout.
+func (r *UserRepository) RecordLoginFailure(ctx context.Context, subject string, failedAt time.Time, resetBefore time.Time) (models.LoginFailure, error) {
+	var failure models.LoginFailure
+	query := fmt.Sprintf(`
+		insert into %s (subject, failures, last_failed_at)
+		values ($1, 1, $2)
+		on conflict (subject) do update set
+			failures = case when %s.last_failed_at < $3 then 1 else %s.failures + 1 end,
+			locked_until = case when %s.last_failed_at < $3 then null else %s.locked_until end,
+			last_failed_at = excluded.last_failed_at
+		returning subject, failures, last_failed_at, locked_until
+	`, LoginFailuresTable, LoginFailuresTable, LoginFailuresTable, LoginFailuresTable, LoginFailuresTable)
+	err := r.db.GetContext(ctx, &failure, query, subject, failedAt.UTC(), resetBefore.UTC())
+	if err != nil {
+		return models.LoginFailure{}, err
+	}
+	return failure, nil
+}
+
+// GetLoginFailure returns the failed sign-ins of subject, with no failures if
+// there are none.
+func (r *UserRepository) GetLoginFailure(ctx context.Context, subject string) (models.LoginFailure, error) {
+	var failure models.LoginFailure
+	query := fmt.Sprintf("select subject, failures, last_failed_at, locked_until from %s where subject=$1", LoginFailuresTable)
+	err := r.db.GetContext(ctx, &failure, query, subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.LoginFailure{Subject: subject}, nil
+	}
+	if err != nil {
+		return models.LoginFailure{}, err
+	}
+	return failure, nil
+}
+
+func (r *UserRepository) LockLogin(ctx context.Context, subject string, until time.Time) error {
+	query := fmt.Sprintf("update %s set locked_until=$1 where subject=$2", LoginFailuresTable)
+	_, err := r.db.ExecContext(ctx, query, until.UTC(), subject)
+	return err
+}
+
+func (r *UserRepository) ClearLoginFailures(ctx context.Context, subject string) error {
+	query := fmt.Sprintf("delete from %s where subject=$1", LoginFailuresTable)
+	_, err := r.db.ExecContext(ctx, query, subject)
+	return err
+}
+
+// GetLockedLogins returns the subjects locked out at now, the longest locked
+// first.
+func (r *UserRepository) GetLockedLogins(ctx context.Context, now time.Time) ([]models.LoginFailure, error) {
+	var failures []models.LoginFailure
+	query := fmt.Sprintf(`
+		select subject, failures, last_failed_at, locked_until from %s
+		where locked_until > $1
+		order by locked_until desc, subject
+	`, LoginFailuresTable)
+	err := r.db.SelectContext(ctx, &failures, query, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	return failures, nil
+}
+
+// PruneLoginFailures deletes up to limit records of subjects that last failed
+// to sign in before before and are not locked out after it, and returns the
+// number deleted.
+func (r *UserRepository) PruneLoginFailures(ctx context.Context, before time.Time, limit int) (int, error) {
+	query := fmt.Sprintf(`
+		delete from %s where subject in (
+			select subject from %s
+			where last_failed_at < $1 and (locked_until is null or locked_until < $1)
+			order by subject limit $2
+		)
+	`, LoginFailuresTable, LoginFailuresTable)
+	result, err := r.db.ExecContext(ctx, query, before.UTC(), limit)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
+
+func (r *UserRepository) CreateSignInAttempt(ctx context.Context, attempt models.SignInAttempt) (int, error) {
+	var id int
+	query := fmt.Sprintf(`
+		insert into %s (login, ip, user_id, outcome, attempted_at)
+		values ($1, $2, $3, $4, $5)
+		returning id
+	`, SignInAttemptsTable)
+	row := r.db.QueryRowContext(ctx, query, attempt.Login, attempt.IP, attempt.UserId, attempt.Outcome, attempt.AttemptedAt.UTC())
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// GetSignInAttempts returns up to limit latest sign-in attempts, only those
+// for login unless it is empty.
+func (r *UserRepository) GetSignInAttempts(ctx context.Context, login string, limit int) ([]models.SignInAttempt, error) {
+	var attempts []models.SignInAttempt
+	query := fmt.Sprintf(`
+		select id, login, ip, user_id, outcome, attempted_at from %s
+		where $1 = '' or login = $1
+		order by id desc
+		limit $2
+	`, SignInAttemptsTable)
+	err := r.db.SelectContext(ctx, &attempts, query, login, limit)
+	if err != nil {
+		return nil, err
+	}
+	return attempts, nil
+}
+
+// HasSignedInFrom reports whether login signed in successfully from ip since
+// the sign-in attempts were last pruned.
+func (r *UserRepository) HasSignedInFrom(ctx context.Context, login, ip string) (bool, error) {
+	var signedIn bool
+	query := fmt.Sprintf("select exists (select 1 from %s where login = $1 and ip = $2 and outcome = $3)", SignInAttemptsTable)
+	if err := r.db.GetContext(ctx, &signedIn, query, login, ip, models.SignInSucceeded); err != nil {
+		return false, err
+	}
+	return signedIn, nil
+}
+
+// PruneSignInAttempts deletes up to limit sign-in attempts made before before
+// and returns the number deleted.
+func (r *UserRepository) PruneSignInAttempts(ctx context.Context, before time.Time, limit int) (int, error) {
+	query := fmt.Sprintf(`
+		delete from %s where id in (
+			select id from %s where attempted_at < $1 order by id limit $2
+		)
+	`, SignInAttemptsTable, SignInAttemptsTable)
+	result, err := r.db.ExecContext(ctx, query, before.UTC(), limit)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(rowsAffected), nil
+}
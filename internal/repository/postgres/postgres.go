@@ -2,15 +2,29 @@ package postgres
 
 import (
 	"fmt"
+	"strings"
+	"weather-app/internal/tracing"
 
 	"github.com/jmoiron/sqlx"
 )
 
 const (
-	UsersTable       = "users"
-	UsersCitiesTable = "users_cities"
-	CitiesTable      = "cities"
-	ForecastsTable   = "forecasts"
+	UsersTable            = "users"
+	UsersCitiesTable      = "users_cities"
+	CitiesTable           = "cities"
+	ForecastsTable        = "forecasts"
+	DailyForecastsTable   = "forecast_daily"
+	AlertRulesTable       = "alert_rules"
+	TriggeredAlertsTable  = "triggered_alerts"
+	WebhooksTable         = "webhooks"
+	DeliveriesTable       = "webhook_deliveries"
+	CollectorRunsTable    = "collector_runs"
+	DeadLettersTable      = "collector_dead_letters"
+	LoginFailuresTable    = "login_failures"
+	SignInAttemptsTable   = "sign_in_attempts"
+	ProviderRequestsTable = "provider_requests"
+	// MigrationsTable is kept by golang-migrate.
+	MigrationsTable = "schema_migrations"
 )
 
 type Repository struct {
@@ -22,12 +36,12 @@ func NewRepository(db *sqlx.DB) *Repository {
 }
 
 type PGConfig struct {
-	Host     string
-	Port     string
-	Username string
-	Password string
-	DBName   string
-	SSLMode  string
+	Host     string `yaml:"host" toml:"host"`
+	Port     string `yaml:"port" toml:"port"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+	DBName   string `yaml:"dbname" toml:"dbname"`
+	SSLMode  string `yaml:"sslmode" toml:"sslmode"`
 }
 
 func NewPgConnection(conn PGConfig) (*sqlx.DB, error) {
@@ -41,10 +55,11 @@ func NewPgConnection(conn PGConfig) (*sqlx.DB, error) {
 		conn.SSLMode,
 	)
 
-	db, err := sqlx.Open("postgres", connString)
+	sqlDB, err := tracing.OpenDB("postgres", connString, "postgresql")
 	if err != nil {
 		return nil, err
 	}
+	db := sqlx.NewDb(sqlDB, "postgres")
 
 	if err := db.Ping(); err != nil {
 		return nil, err
@@ -52,3 +67,15 @@ func NewPgConnection(conn PGConfig) (*sqlx.DB, error) {
 
 	return db, nil
 }
+
+// idList returns placeholders for ids numbered from $first, for use in an
+// "in" clause, along with the matching arguments.
+func idList(ids []int, first int) (string, []any) {
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", first+i)
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
@@ -0,0 +1,81 @@
+package postgres
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"weather-app/internal/migrator"
+	"weather-app/internal/repository"
+	"weather-app/internal/repository/repotest"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+	"github.com/stretchr/testify/suite"
+)
+
+// newTestRepository connects to the database at TEST_POSTGRES_DSN, applies
+// the embedded migrations and returns a function creating a repository over
+// it; all tables are truncated before each repository is returned. The test is
+// skipped if the variable is not set, except in CI, where the Postgres SQL
+// must not go untested.
+func newTestRepository(tb testing.TB) func() *repository.Repository {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		if os.Getenv("CI") != "" {
+			tb.Fatal("TEST_POSTGRES_DSN must be set in CI")
+		}
+		tb.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	db, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		tb.Fatalf("open database: %v", err)
+	}
+	tb.Cleanup(func() { db.Close() })
+
+	// The migrator closes its own connection.
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		tb.Fatalf("open database: %v", err)
+	}
+	m, err := migrator.NewPostgresMigrator(conn)
+	if err != nil {
+		tb.Fatalf("create migrator: %v", err)
+	}
+	defer m.Close()
+	if err := m.Up(); err != nil {
+		tb.Fatalf("apply migrations: %v", err)
+	}
+
+	tables := []string{
+		DeadLettersTable, CollectorRunsTable, DeliveriesTable, WebhooksTable, TriggeredAlertsTable, AlertRulesTable,
+		SignInAttemptsTable, LoginFailuresTable, UsersCitiesTable, UsersTable, DailyForecastsTable, ForecastsTable, CitiesTable,
+	}
+	truncate := fmt.Sprintf("truncate %s restart identity cascade", strings.Join(tables, ", "))
+
+	return func() *repository.Repository {
+		if _, err := db.Exec(truncate); err != nil {
+			tb.Fatalf("truncate tables: %v", err)
+		}
+		return repository.NewRepository(
+			NewCityRepository(db),
+			NewForecastRepository(db),
+			NewUserRepository(db),
+			NewAlertRepository(db),
+			NewWebhookRepository(db),
+			NewCollectorRunRepository(db),
+			NewHealthRepository(db),
+		)
+	}
+}
+
+// TestPostgresRepositorySuite runs the backend conformance suite against a
+// migrated database.
+func TestPostgresRepositorySuite(t *testing.T) {
+	suite.Run(t, &repotest.RepositorySuite{NewRepository: newTestRepository(t)})
+}
+
+func BenchmarkForecastWrites(b *testing.B) {
+	repotest.BenchmarkForecastWrites(b, newTestRepository(b))
+}
@@ -1,6 +1,7 @@
 package postgres
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"weather-app/internal/models"
@@ -16,43 +17,50 @@ func NewUserRepository(db *sqlx.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) CreateUser(user models.User) (int, error) {
+func (r *UserRepository) CreateUser(ctx context.Context, user models.User) (int, error) {
 	var id int
-	query := fmt.Sprintf("insert into %s (login, password, email) values ($1, $2, $3) returning id", UsersTable)
-	row := r.db.QueryRow(query, user.Login, user.Password, user.Email)
+	query := fmt.Sprintf("insert into %s (login, password, email, role) values ($1, $2, $3, $4) returning id", UsersTable)
+	row := r.db.QueryRowContext(ctx, query, user.Login, user.Password, user.Email, user.Role)
 	if err := row.Scan(&id); err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
-func (r *UserRepository) GetUser(login, password string) (models.User, error) {
+func (r *UserRepository) GetUser(ctx context.Context, login, password string) (models.User, error) {
 	var user models.User
-	query := fmt.Sprintf("select id, login, password, email from %s where login=$1 and password=$2", UsersTable)
-	err := r.db.Get(&user, query, login, password)
+	query := fmt.Sprintf("select id, login, password, email, role from %s where login=$1 and password=$2", UsersTable)
+	err := r.db.GetContext(ctx, &user, query, login, password)
 	return user, err
 }
 
-func (r *UserRepository) GetFavorites(userId int) ([]int, error) {
+func (r *UserRepository) GetUserById(ctx context.Context, userId int) (models.User, error) {
+	var user models.User
+	query := fmt.Sprintf("select id, login, password, email, role from %s where id=$1", UsersTable)
+	err := r.db.GetContext(ctx, &user, query, userId)
+	return user, err
+}
+
+func (r *UserRepository) GetFavorites(ctx context.Context, userId int) ([]int, error) {
 	var favorites []int
 	query := fmt.Sprintf("select city_id from %s where user_id=$1", UsersCitiesTable)
-	err := r.db.Select(&favorites, query, userId)
+	err := r.db.SelectContext(ctx, &favorites, query, userId)
 	return favorites, err
 }
 
-func (r *UserRepository) AddFavorite(userId int, cityId int) (int, error) {
+func (r *UserRepository) AddFavorite(ctx context.Context, userId int, cityId int) (int, error) {
 	var id int
 	query := fmt.Sprintf("insert into %s (user_id, city_id) values ($1, $2) returning id", UsersCitiesTable)
-	row := r.db.QueryRow(query, userId, cityId)
+	row := r.db.QueryRowContext(ctx, query, userId, cityId)
 	if err := row.Scan(&id); err != nil {
 		return 0, err
 	}
 	return id, nil
 }
 
-func (r *UserRepository) DeleteFavorite(userId int, cityId int) error {
+func (r *UserRepository) DeleteFavorite(ctx context.Context, userId int, cityId int) error {
 	query := fmt.Sprintf("delete from %s where user_id=$1 and city_id=$2", UsersCitiesTable)
-	result, err := r.db.Exec(query, userId, cityId)
+	result, err := r.db.ExecContext(ctx, query, userId, cityId)
 	if err != nil {
 		return err
 	}
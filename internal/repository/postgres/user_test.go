@@ -1,9 +1,12 @@
 package postgres
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"testing"
+	"time"
 	"weather-app/internal/models"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -38,13 +41,14 @@ func (suite *UserRepositoryTestSuite) TestCreateUser() {
 		Login:    "testuser",
 		Password: "password",
 		Email:    "testuser@example.com",
+		Role:     models.RoleUser,
 	}
 
 	suite.mock.ExpectQuery("insert into users").
-		WithArgs(user.Login, user.Password, user.Email).
+		WithArgs(user.Login, user.Password, user.Email, user.Role).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	id, err := suite.repo.CreateUser(user)
+	id, err := suite.repo.CreateUser(context.Background(), user)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 1, id)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -55,13 +59,14 @@ func (suite *UserRepositoryTestSuite) TestCreateUserError() {
 		Login:    "testuser",
 		Password: "password",
 		Email:    "testuser@example.com",
+		Role:     models.RoleUser,
 	}
 
 	suite.mock.ExpectQuery("insert into users").
-		WithArgs(user.Login, user.Password, user.Email).
+		WithArgs(user.Login, user.Password, user.Email, user.Role).
 		WillReturnError(fmt.Errorf("insertion error"))
 
-	id, err := suite.repo.CreateUser(user)
+	id, err := suite.repo.CreateUser(context.Background(), user)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), 0, id)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -73,36 +78,57 @@ func (suite *UserRepositoryTestSuite) TestGetUser() {
 		Login:    "testuser",
 		Password: "password",
 		Email:    "testuser@example.com",
+		Role:     models.RoleAdmin,
 	}
 
-	suite.mock.ExpectQuery("select id, login, password, email from users where login=\\$1 and password=\\$2").
+	suite.mock.ExpectQuery("select id, login, password, email, role from users where login=\\$1 and password=\\$2").
 		WithArgs(user.Login, user.Password).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "email"}).
-			AddRow(user.Id, user.Login, user.Password, user.Email))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "email", "role"}).
+			AddRow(user.Id, user.Login, user.Password, user.Email, user.Role))
 
-	result, err := suite.repo.GetUser(user.Login, user.Password)
+	result, err := suite.repo.GetUser(context.Background(), user.Login, user.Password)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), user, result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *UserRepositoryTestSuite) TestGetUserById() {
+	user := models.User{
+		Id:       1,
+		Login:    "testuser",
+		Password: "password",
+		Email:    "testuser@example.com",
+		Role:     models.RoleUser,
+	}
+
+	suite.mock.ExpectQuery("select id, login, password, email, role from users where id=\\$1").
+		WithArgs(user.Id).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "login", "password", "email", "role"}).
+			AddRow(user.Id, user.Login, user.Password, user.Email, user.Role))
+
+	result, err := suite.repo.GetUserById(context.Background(), user.Id)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), user, result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
 }
 
 func (suite *UserRepositoryTestSuite) TestGetUserNotFound() {
-	suite.mock.ExpectQuery("select id, login, password, email from users where login=\\$1 and password=\\$2").
+	suite.mock.ExpectQuery("select id, login, password, email, role from users where login=\\$1 and password=\\$2").
 		WithArgs("unknownuser", "wrongpassword").
 		WillReturnError(fmt.Errorf("sql: no rows in result set"))
 
-	result, err := suite.repo.GetUser("unknownuser", "wrongpassword")
+	result, err := suite.repo.GetUser(context.Background(), "unknownuser", "wrongpassword")
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), models.User{}, result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
 }
 
 func (suite *UserRepositoryTestSuite) TestGetUserQueryError() {
-	suite.mock.ExpectQuery("select id, login, password, email from users where login=\\$1 and password=\\$2").
+	suite.mock.ExpectQuery("select id, login, password, email, role from users where login=\\$1 and password=\\$2").
 		WithArgs("testuser", "password").
 		WillReturnError(fmt.Errorf("query error"))
 
-	result, err := suite.repo.GetUser("testuser", "password")
+	result, err := suite.repo.GetUser(context.Background(), "testuser", "password")
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), models.User{}, result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -118,7 +144,7 @@ func (suite *UserRepositoryTestSuite) TestGetFavorites() {
 			AddRow(favorites[1]).
 			AddRow(favorites[2]))
 
-	result, err := suite.repo.GetFavorites(1)
+	result, err := suite.repo.GetFavorites(context.Background(), 1)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), favorites, result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -129,7 +155,7 @@ func (suite *UserRepositoryTestSuite) TestGetFavoritesQueryError() {
 		WithArgs(1).
 		WillReturnError(fmt.Errorf("query error"))
 
-	result, err := suite.repo.GetFavorites(1)
+	result, err := suite.repo.GetFavorites(context.Background(), 1)
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), result)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -140,7 +166,7 @@ func (suite *UserRepositoryTestSuite) TestAddFavorite() {
 		WithArgs(1, 1).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
-	id, err := suite.repo.AddFavorite(1, 1)
+	id, err := suite.repo.AddFavorite(context.Background(), 1, 1)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 1, id)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -151,7 +177,7 @@ func (suite *UserRepositoryTestSuite) TestAddFavoriteError() {
 		WithArgs(1, 1).
 		WillReturnError(fmt.Errorf("insertion error"))
 
-	id, err := suite.repo.AddFavorite(1, 1)
+	id, err := suite.repo.AddFavorite(context.Background(), 1, 1)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), 0, id)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
@@ -162,7 +188,7 @@ func (suite *UserRepositoryTestSuite) TestDeleteFavorite() {
 		WithArgs(1, 1).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err := suite.repo.DeleteFavorite(1, 1)
+	err := suite.repo.DeleteFavorite(context.Background(), 1, 1)
 	assert.NoError(suite.T(), err)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
 }
@@ -172,12 +198,38 @@ func (suite *UserRepositoryTestSuite) TestDeleteFavoriteNoRows() {
 		WithArgs(1, 1).
 		WillReturnResult(sqlmock.NewResult(0, 0))
 
-	err := suite.repo.DeleteFavorite(1, 1)
+	err := suite.repo.DeleteFavorite(context.Background(), 1, 1)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), errors.New("no rows deleted"), err)
 	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
 }
 
+func (suite *UserRepositoryTestSuite) TestRecordLoginFailure() {
+	failedAt := time.Date(2030, 1, 2, 15, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"subject", "failures", "last_failed_at", "locked_until"}).
+		AddRow("login:alice", 3, failedAt, nil)
+
+	suite.mock.ExpectQuery("insert into login_failures \\(subject, failures, last_failed_at\\) values \\(\\$1, 1, \\$2\\) on conflict \\(subject\\) do update set").
+		WithArgs("login:alice", failedAt, failedAt.Add(-time.Hour)).
+		WillReturnRows(rows)
+
+	failure, err := suite.repo.RecordLoginFailure(context.Background(), "login:alice", failedAt, failedAt.Add(-time.Hour))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.LoginFailure{Subject: "login:alice", Failures: 3, LastFailedAt: failedAt}, failure)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *UserRepositoryTestSuite) TestGetLoginFailureNone() {
+	suite.mock.ExpectQuery("select subject, failures, last_failed_at, locked_until from login_failures where subject=\\$1").
+		WithArgs("ip:10.0.0.1").
+		WillReturnError(sql.ErrNoRows)
+
+	failure, err := suite.repo.GetLoginFailure(context.Background(), "ip:10.0.0.1")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.LoginFailure{Subject: "ip:10.0.0.1"}, failure)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
 func TestUserRepositoryTestSuite(t *testing.T) {
 	suite.Run(t, new(UserRepositoryTestSuite))
 }
@@ -0,0 +1,173 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WebhookRepositoryTestSuite struct {
+	suite.Suite
+	db   *sqlx.DB
+	mock sqlmock.Sqlmock
+	repo *WebhookRepository
+}
+
+func (suite *WebhookRepositoryTestSuite) SetupTest() {
+	var err error
+	db, mock, err := sqlmock.New()
+	assert.NoError(suite.T(), err)
+	suite.db = sqlx.NewDb(db, "sqlmock")
+	suite.mock = mock
+	suite.repo = NewWebhookRepository(suite.db)
+}
+
+func (suite *WebhookRepositoryTestSuite) TearDownTest() {
+	suite.db.Close()
+}
+
+func (suite *WebhookRepositoryTestSuite) TestCreateWebhook() {
+	webhook := models.Webhook{UserId: 1, URL: "https://example.com/hook", Secret: "secret"}
+
+	suite.mock.ExpectQuery("insert into webhooks").
+		WithArgs(webhook.UserId, webhook.URL, webhook.Secret).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+
+	id, err := suite.repo.CreateWebhook(context.Background(), webhook)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, id)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *WebhookRepositoryTestSuite) TestGetWebhooks() {
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "user_id", "url", "secret", "created_at"}).
+		AddRow(1, 1, "https://example.com/hook", "secret", now)
+
+	suite.mock.ExpectQuery("select id, user_id, url, secret, created_at from webhooks where user_id=\\$1 order by id").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	result, err := suite.repo.GetWebhooks(context.Background(), 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.Webhook{{Id: 1, UserId: 1, URL: "https://example.com/hook", Secret: "secret", CreatedAt: now}}, result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *WebhookRepositoryTestSuite) TestGetWebhookNotFound() {
+	suite.mock.ExpectQuery("select id, user_id, url, secret, created_at from webhooks where user_id=\\$1 and id=\\$2").
+		WithArgs(1, 999).
+		WillReturnError(fmt.Errorf("sql: no rows in result set"))
+
+	result, err := suite.repo.GetWebhook(context.Background(), 1, 999)
+	assert.Error(suite.T(), err)
+	assert.Equal(suite.T(), models.Webhook{}, result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *WebhookRepositoryTestSuite) TestGetCityWebhooks() {
+	rows := sqlmock.NewRows([]string{"id", "user_id", "url", "secret", "created_at"}).
+		AddRow(1, 1, "https://example.com/a", "s1", time.Now()).
+		AddRow(2, 3, "https://example.com/b", "s2", time.Now())
+
+	suite.mock.ExpectQuery("select w.id, w.user_id, w.url, w.secret, w.created_at").
+		WithArgs(5).
+		WillReturnRows(rows)
+
+	result, err := suite.repo.GetCityWebhooks(context.Background(), 5)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), result, 2)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *WebhookRepositoryTestSuite) TestDeleteWebhookNoRows() {
+	suite.mock.ExpectExec("delete from webhooks where user_id=\\$1 and id=\\$2").
+		WithArgs(1, 1).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := suite.repo.DeleteWebhook(context.Background(), 1, 1)
+	assert.Equal(suite.T(), errors.New("no rows deleted"), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *WebhookRepositoryTestSuite) TestCreateDelivery() {
+	delivery := models.WebhookDelivery{
+		WebhookId:     1,
+		Event:         models.WebhookEventPing,
+		Payload:       []byte(`{"event":"ping"}`),
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: time.Now(),
+	}
+
+	suite.mock.ExpectQuery("insert into webhook_deliveries").
+		WithArgs(delivery.WebhookId, delivery.Event, delivery.Payload, delivery.Status, delivery.Attempts,
+			delivery.ResponseStatus, delivery.LastError, delivery.NextAttemptAt, delivery.DeliveredAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	id, err := suite.repo.CreateDelivery(context.Background(), delivery)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 7, id)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *WebhookRepositoryTestSuite) TestClaimPendingDeliveries() {
+	now := time.Now()
+	leaseUntil := now.Add(time.Minute)
+	rows := sqlmock.NewRows([]string{"id", "webhook_id", "event", "payload", "status", "attempts", "response_status", "last_error", "next_attempt_at", "created_at", "delivered_at"}).
+		AddRow(7, 1, models.WebhookEventPing, []byte(`{}`), models.DeliveryStatusPending, 1, 500, "boom", leaseUntil, now, nil)
+
+	suite.mock.ExpectQuery("update webhook_deliveries set next_attempt_at=\\$2").
+		WithArgs(10, leaseUntil).
+		WillReturnRows(rows)
+
+	result, err := suite.repo.ClaimPendingDeliveries(context.Background(), 10, leaseUntil)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), result, 1)
+	assert.Equal(suite.T(), 7, result[0].Id)
+	assert.Nil(suite.T(), result[0].DeliveredAt)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *WebhookRepositoryTestSuite) TestUpdateDelivery() {
+	now := time.Now()
+	delivery := models.WebhookDelivery{
+		Id:             7,
+		Status:         models.DeliveryStatusDelivered,
+		Attempts:       2,
+		ResponseStatus: 200,
+		NextAttemptAt:  now,
+		DeliveredAt:    &now,
+	}
+
+	suite.mock.ExpectExec("update webhook_deliveries set status=\\$1").
+		WithArgs(delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.LastError,
+			delivery.NextAttemptAt, delivery.DeliveredAt, delivery.Id).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := suite.repo.UpdateDelivery(context.Background(), delivery)
+	assert.NoError(suite.T(), err)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func (suite *WebhookRepositoryTestSuite) TestGetDeliveriesQueryError() {
+	suite.mock.ExpectQuery("select d.id, d.webhook_id").
+		WithArgs(1, 2, 50).
+		WillReturnError(fmt.Errorf("query error"))
+
+	result, err := suite.repo.GetDeliveries(context.Background(), 1, 2, 50)
+	assert.Error(suite.T(), err)
+	assert.Nil(suite.T(), result)
+	assert.NoError(suite.T(), suite.mock.ExpectationsWereMet())
+}
+
+func TestWebhookRepositoryTestSuite(t *testing.T) {
+	suite.Run(t, new(WebhookRepositoryTestSuite))
+}
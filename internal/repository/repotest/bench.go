@@ -0,0 +1,59 @@
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+)
+
+// forecastSlots is the number of three-hour slots in a five-day forecast
+// returned by the provider for one city.
+const forecastSlots = 40
+
+// BenchmarkForecastWrites compares storing a city's forecast row by row with
+// CreateForecast against one ReplaceForecasts batch. newRepository must
+// return a repository backed by empty storage.
+func BenchmarkForecastWrites(b *testing.B, newRepository func() *repository.Repository) {
+	b.Run("CreateForecast", func(b *testing.B) {
+		repo, _, forecasts := setupForecastBenchmark(b, newRepository)
+		for i := 0; i < b.N; i++ {
+			for _, forecast := range forecasts {
+				if _, err := repo.CreateForecast(context.Background(), forecast); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("ReplaceForecasts", func(b *testing.B) {
+		repo, cityId, forecasts := setupForecastBenchmark(b, newRepository)
+		for i := 0; i < b.N; i++ {
+			if _, err := repo.ReplaceForecasts(context.Background(), cityId, forecasts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func setupForecastBenchmark(b *testing.B, newRepository func() *repository.Repository) (*repository.Repository, int, []models.Forecast) {
+	repo := newRepository()
+	cityId, err := repo.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	start := time.Now().UTC().Truncate(3 * time.Hour)
+	forecasts := make([]models.Forecast, forecastSlots)
+	for i := range forecasts {
+		forecasts[i] = models.Forecast{
+			CityId:       cityId,
+			Temp:         float32(i),
+			Date:         start.Add(time.Duration(i) * 3 * time.Hour),
+			ForecastJson: []byte(fmt.Sprintf(`{"slot":%d,"weather":[{"main":"Clouds"}],"wind":{"speed":3.5,"gust":7.1},"pop":0.2}`, i)),
+		}
+	}
+	b.ResetTimer()
+	return repo, cityId, forecasts
+}
@@ -0,0 +1,714 @@
+// Package repotest contains a behavioural test suite that every repository
+// backend must pass, so all backends share the same uniqueness semantics.
+package repotest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RepositorySuite struct {
+	suite.Suite
+	// NewRepository must return a repository backed by empty storage.
+	NewRepository func() *repository.Repository
+	repo          *repository.Repository
+}
+
+func (suite *RepositorySuite) SetupTest() {
+	suite.repo = suite.NewRepository()
+}
+
+func (suite *RepositorySuite) createCity(name, country string) int {
+	id, err := suite.repo.CreateCity(context.Background(), models.City{Name: name, Country: country, Latitude: 1, Longitude: 2})
+	suite.Require().NoError(err)
+	return id
+}
+
+func (suite *RepositorySuite) createUser(login string) int {
+	id, err := suite.repo.CreateUser(context.Background(), models.User{Login: login, Password: "hash", Email: login + "@example.com"})
+	suite.Require().NoError(err)
+	return id
+}
+
+func (suite *RepositorySuite) TestCreateCityUpsertsByNameAndCountry() {
+	id := suite.createCity("London", "GB")
+	suite.createCity("London", "CA")
+
+	sameId, err := suite.repo.CreateCity(context.Background(), models.City{Name: "London", Country: "GB", Latitude: 51.5, Longitude: -0.12})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), id, sameId)
+
+	city, err := suite.repo.GetCity(context.Background(), id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.City{Id: id, Name: "London", Country: "GB", Latitude: 51.5, Longitude: -0.12}, city)
+}
+
+func (suite *RepositorySuite) TestGetCitiesOrderedByName() {
+	suite.createCity("Paris", "FR")
+	suite.createCity("Berlin", "DE")
+
+	cities, err := suite.repo.GetCities(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), cities, 2)
+	assert.Equal(suite.T(), "Berlin", cities[0].Name)
+	assert.Equal(suite.T(), "Paris", cities[1].Name)
+}
+
+func (suite *RepositorySuite) TestGetCityNotFound() {
+	_, err := suite.repo.GetCity(context.Background(), 999)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RepositorySuite) TestCitySchedules() {
+	london := suite.createCity("London", "GB")
+	paris := suite.createCity("Paris", "FR")
+	alice := suite.createUser("alice")
+	_, err := suite.repo.AddFavorite(context.Background(), alice, paris)
+	suite.Require().NoError(err)
+
+	assert.NoError(suite.T(), suite.repo.UpdateCityInterval(context.Background(), london, 600))
+	assert.Error(suite.T(), suite.repo.UpdateCityInterval(context.Background(), 999, 600))
+
+	schedules, err := suite.repo.GetCitySchedules(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), schedules, 2)
+	assert.Equal(suite.T(), paris, schedules[0].Id, "popular cities come first")
+	assert.Equal(suite.T(), 1, schedules[0].Favorites)
+	assert.Equal(suite.T(), 0, schedules[0].UpdateInterval)
+	assert.Equal(suite.T(), london, schedules[1].Id)
+	assert.Equal(suite.T(), "London", schedules[1].Name)
+	assert.Equal(suite.T(), 600, schedules[1].UpdateInterval)
+}
+
+func (suite *RepositorySuite) TestCreateForecastUpsertsByCityAndDay() {
+	cityId := suite.createCity("London", "GB")
+	day := time.Date(2030, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	id, err := suite.repo.CreateForecast(context.Background(), models.Forecast{CityId: cityId, Temp: 1, Date: day, ForecastJson: []byte(`{"a":1}`)})
+	assert.NoError(suite.T(), err)
+	sameId, err := suite.repo.CreateForecast(context.Background(), models.Forecast{CityId: cityId, Temp: 2, Date: day.Add(3 * time.Hour), ForecastJson: []byte(`{"a":2}`)})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), id, sameId)
+
+	forecasts, err := suite.repo.GetForecasts(context.Background(), cityId)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), forecasts, 1)
+	assert.Equal(suite.T(), float32(2), forecasts[0].Temp)
+	assert.JSONEq(suite.T(), `{"a":2}`, string(forecasts[0].ForecastJson))
+}
+
+func (suite *RepositorySuite) TestCreateForecastRequiresCity() {
+	_, err := suite.repo.CreateForecast(context.Background(), models.Forecast{CityId: 999, Date: time.Now(), ForecastJson: []byte(`{}`)})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RepositorySuite) TestReplaceForecasts() {
+	cityId := suite.createCity("London", "GB")
+	otherId := suite.createCity("Paris", "FR")
+	day := time.Date(2030, 1, 2, 9, 0, 0, 0, time.UTC)
+	for _, forecast := range []models.Forecast{
+		{CityId: cityId, Temp: 1, Date: day.AddDate(0, 0, -2), ForecastJson: []byte(`{"kept":true}`)},
+		{CityId: cityId, Temp: 1, Date: day.AddDate(0, 0, 3), ForecastJson: []byte(`{"stale":true}`)},
+		{CityId: otherId, Temp: 1, Date: day.AddDate(0, 0, 1), ForecastJson: []byte(`{}`)},
+	} {
+		_, err := suite.repo.CreateForecast(context.Background(), forecast)
+		suite.Require().NoError(err)
+	}
+
+	written, err := suite.repo.ReplaceForecasts(context.Background(), cityId, []models.Forecast{
+		{CityId: cityId, Temp: 5, Date: day, ForecastJson: []byte(`{"slot":1}`)},
+		{CityId: cityId, Temp: 6, Date: day.Add(3 * time.Hour), ForecastJson: []byte(`{"slot":2}`)},
+		{CityId: cityId, Temp: 7, Date: day.AddDate(0, 0, 1), ForecastJson: []byte(`{"slot":3}`)},
+	})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, written)
+
+	forecasts, err := suite.repo.GetForecasts(context.Background(), cityId)
+	suite.Require().NoError(err)
+	sort.Slice(forecasts, func(i, j int) bool { return forecasts[i].Date.Before(forecasts[j].Date) })
+	suite.Require().Len(forecasts, 3)
+	assert.JSONEq(suite.T(), `{"kept":true}`, string(forecasts[0].ForecastJson))
+	assert.Equal(suite.T(), float32(6), forecasts[1].Temp)
+	assert.JSONEq(suite.T(), `{"slot":2}`, string(forecasts[1].ForecastJson))
+	assert.Equal(suite.T(), float32(7), forecasts[2].Temp)
+
+	other, err := suite.repo.GetForecasts(context.Background(), otherId)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), other, 1)
+
+	written, err = suite.repo.ReplaceForecasts(context.Background(), cityId, nil)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, written)
+}
+
+func (suite *RepositorySuite) TestReplaceForecastsRequiresCity() {
+	_, err := suite.repo.ReplaceForecasts(context.Background(), 999, []models.Forecast{{CityId: 999, Date: time.Now(), ForecastJson: []byte(`{}`)}})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RepositorySuite) TestForecastFreshness() {
+	cityId := suite.createCity("London", "GB")
+	freshness, err := suite.repo.GetForecastFreshness(context.Background(), cityId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.ForecastFreshness{CityId: cityId}, freshness, "a city never updated has no freshness")
+
+	updatedAt := time.Date(2024, 3, 10, 12, 30, 15, 0, time.UTC)
+	assert.NoError(suite.T(), suite.repo.SetForecastFreshness(context.Background(), models.ForecastFreshness{
+		CityId:     cityId,
+		UpdatedAt:  updatedAt,
+		NextUpdate: updatedAt.Add(30 * time.Minute),
+	}))
+	freshness, err = suite.repo.GetForecastFreshness(context.Background(), cityId)
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), updatedAt.Equal(freshness.UpdatedAt))
+	assert.True(suite.T(), updatedAt.Add(30*time.Minute).Equal(freshness.NextUpdate))
+
+	suite.createCity("Paris", "FR")
+	all, err := suite.repo.GetAllForecastFreshness(context.Background())
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(all, 1, "cities never updated are left out")
+	assert.Equal(suite.T(), cityId, all[0].CityId)
+	assert.True(suite.T(), updatedAt.Equal(all[0].UpdatedAt))
+
+	assert.Error(suite.T(), suite.repo.SetForecastFreshness(context.Background(), models.ForecastFreshness{CityId: 999}))
+	_, err = suite.repo.GetForecastFreshness(context.Background(), 999)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RepositorySuite) TestHealth() {
+	assert.NoError(suite.T(), suite.repo.Ping(context.Background()))
+	_, dirty, err := suite.repo.GetSchemaVersion(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), dirty)
+}
+
+func (suite *RepositorySuite) TestCreateUserLoginIsUnique() {
+	suite.createUser("alice")
+
+	_, err := suite.repo.CreateUser(context.Background(), models.User{Login: "alice", Password: "other"})
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RepositorySuite) TestGetUserByCredentials() {
+	id := suite.createUser("alice")
+
+	user, err := suite.repo.GetUser(context.Background(), "alice", "hash")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), id, user.Id)
+
+	_, err = suite.repo.GetUser(context.Background(), "alice", "wrong")
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RepositorySuite) TestUserRoleIsStored() {
+	id, err := suite.repo.CreateUser(context.Background(), models.User{Login: "admin", Password: "hash", Role: models.RoleAdmin})
+	suite.Require().NoError(err)
+
+	user, err := suite.repo.GetUser(context.Background(), "admin", "hash")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.RoleAdmin, user.Role)
+
+	user, err = suite.repo.GetUserById(context.Background(), id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), "admin", user.Login)
+	assert.Equal(suite.T(), models.RoleAdmin, user.Role)
+
+	_, err = suite.repo.GetUserById(context.Background(), 999)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *RepositorySuite) TestFavorites() {
+	userId := suite.createUser("alice")
+	london := suite.createCity("London", "GB")
+	paris := suite.createCity("Paris", "FR")
+
+	_, err := suite.repo.AddFavorite(context.Background(), userId, london)
+	assert.NoError(suite.T(), err)
+	_, err = suite.repo.AddFavorite(context.Background(), userId, paris)
+	assert.NoError(suite.T(), err)
+	_, err = suite.repo.AddFavorite(context.Background(), userId, london)
+	assert.Error(suite.T(), err, "favorites are unique per user and city")
+	_, err = suite.repo.AddFavorite(context.Background(), userId, 999)
+	assert.Error(suite.T(), err, "favorite city must exist")
+
+	favorites, err := suite.repo.GetFavorites(context.Background(), userId)
+	assert.NoError(suite.T(), err)
+	assert.ElementsMatch(suite.T(), []int{london, paris}, favorites)
+
+	assert.NoError(suite.T(), suite.repo.DeleteFavorite(context.Background(), userId, london))
+	assert.Error(suite.T(), suite.repo.DeleteFavorite(context.Background(), userId, london))
+}
+
+func (suite *RepositorySuite) TestLoginFailures() {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	failure, err := suite.repo.GetLoginFailure(context.Background(), "login:alice")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.LoginFailure{Subject: "login:alice"}, failure)
+
+	for i := 1; i <= 3; i++ {
+		failure, err = suite.repo.RecordLoginFailure(context.Background(), "login:alice", now.Add(time.Duration(i)*time.Second), now.Add(-time.Hour))
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), i, failure.Failures)
+	}
+	_, err = suite.repo.RecordLoginFailure(context.Background(), "ip:10.0.0.1", now, now.Add(-time.Hour))
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.repo.LockLogin(context.Background(), "login:alice", now.Add(15*time.Minute)))
+	failure, err = suite.repo.GetLoginFailure(context.Background(), "login:alice")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 3, failure.Failures)
+	assert.True(suite.T(), now.Add(3*time.Second).Equal(failure.LastFailedAt))
+	if assert.NotNil(suite.T(), failure.LockedUntil) {
+		assert.True(suite.T(), now.Add(15*time.Minute).Equal(*failure.LockedUntil))
+	}
+
+	locked, err := suite.repo.GetLockedLogins(context.Background(), now)
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(locked, 1)
+	assert.Equal(suite.T(), "login:alice", locked[0].Subject)
+	locked, err = suite.repo.GetLockedLogins(context.Background(), now.Add(time.Hour))
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), locked, "expired lockouts are not listed")
+
+	failure, err = suite.repo.RecordLoginFailure(context.Background(), "login:alice", now.Add(2*time.Hour), now.Add(time.Hour))
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, failure.Failures, "stale failures are forgotten")
+	assert.Nil(suite.T(), failure.LockedUntil)
+
+	assert.NoError(suite.T(), suite.repo.ClearLoginFailures(context.Background(), "login:alice"))
+	failure, err = suite.repo.GetLoginFailure(context.Background(), "login:alice")
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, failure.Failures)
+}
+
+func (suite *RepositorySuite) TestPruneLoginFailures() {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, failure := range []struct {
+		subject  string
+		failedAt time.Time
+	}{
+		{"ip:10.0.0.1", now.Add(-3 * time.Hour)},
+		{"ip:10.0.0.2", now.Add(-2 * time.Hour)},
+		{"login:alice", now.Add(-2 * time.Hour)},
+		{"login:bob", now},
+	} {
+		_, err := suite.repo.RecordLoginFailure(context.Background(), failure.subject, failure.failedAt, failure.failedAt.Add(-time.Hour))
+		suite.Require().NoError(err)
+	}
+	suite.Require().NoError(suite.repo.LockLogin(context.Background(), "login:alice", now.Add(time.Hour)))
+
+	pruned, err := suite.repo.PruneLoginFailures(context.Background(), now.Add(-time.Hour), 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, pruned)
+	pruned, err = suite.repo.PruneLoginFailures(context.Background(), now.Add(-time.Hour), 10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, pruned)
+
+	for subject, failures := range map[string]int{"ip:10.0.0.1": 0, "ip:10.0.0.2": 0, "login:alice": 1, "login:bob": 1} {
+		failure, err := suite.repo.GetLoginFailure(context.Background(), subject)
+		assert.NoError(suite.T(), err)
+		assert.Equal(suite.T(), failures, failure.Failures, subject)
+	}
+}
+
+func (suite *RepositorySuite) TestSignInAttempts() {
+	userId := suite.createUser("alice")
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	attempts := []models.SignInAttempt{
+		{Login: "alice", IP: "10.0.0.1", Outcome: models.SignInFailed, AttemptedAt: now.AddDate(0, 0, -10)},
+		{Login: "bob", IP: "10.0.0.2", Outcome: models.SignInFailed, AttemptedAt: now.AddDate(0, 0, -5)},
+		{Login: "alice", IP: "10.0.0.1", UserId: &userId, Outcome: models.SignInSucceeded, AttemptedAt: now},
+	}
+	for _, attempt := range attempts {
+		_, err := suite.repo.CreateSignInAttempt(context.Background(), attempt)
+		suite.Require().NoError(err)
+	}
+
+	stored, err := suite.repo.GetSignInAttempts(context.Background(), "alice", 10)
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(stored, 2)
+	assert.Equal(suite.T(), models.SignInSucceeded, stored[0].Outcome, "latest first")
+	if assert.NotNil(suite.T(), stored[0].UserId) {
+		assert.Equal(suite.T(), userId, *stored[0].UserId)
+	}
+	assert.Nil(suite.T(), stored[1].UserId)
+	assert.Equal(suite.T(), "10.0.0.1", stored[1].IP)
+	assert.True(suite.T(), now.AddDate(0, 0, -10).Equal(stored[1].AttemptedAt))
+
+	stored, err = suite.repo.GetSignInAttempts(context.Background(), "", 2)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), stored, 2)
+
+	signedIn, err := suite.repo.HasSignedInFrom(context.Background(), "alice", "10.0.0.1")
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), signedIn)
+	signedIn, err = suite.repo.HasSignedInFrom(context.Background(), "bob", "10.0.0.2")
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), signedIn, "only successful sign-ins count")
+
+	pruned, err := suite.repo.PruneSignInAttempts(context.Background(), now.AddDate(0, 0, -1), 10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, pruned)
+	stored, err = suite.repo.GetSignInAttempts(context.Background(), "", 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), stored, 1)
+}
+
+func (suite *RepositorySuite) TestAlertRules() {
+	alice := suite.createUser("alice")
+	bob := suite.createUser("bob")
+	cityId := suite.createCity("London", "GB")
+
+	rule := models.AlertRule{UserId: alice, CityId: cityId, Metric: models.AlertMetricTempBelow, Threshold: -5, WithinHours: 24}
+	id, err := suite.repo.CreateAlertRule(context.Background(), rule)
+	assert.NoError(suite.T(), err)
+	rule.Id = id
+
+	stored, err := suite.repo.GetAlertRule(context.Background(), alice, id)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), rule, stored)
+
+	_, err = suite.repo.GetAlertRule(context.Background(), bob, id)
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound, "rules are scoped to their owner")
+	_, err = suite.repo.GetAlertRule(context.Background(), alice, id+1)
+	assert.ErrorIs(suite.T(), err, repository.ErrNotFound)
+
+	rule.Threshold = -10
+	assert.NoError(suite.T(), suite.repo.UpdateAlertRule(context.Background(), rule))
+	assert.ErrorIs(suite.T(), suite.repo.UpdateAlertRule(context.Background(), models.AlertRule{Id: id, UserId: bob, CityId: cityId}), repository.ErrNotFound)
+	assert.ErrorIs(suite.T(), suite.repo.UpdateAlertRule(context.Background(), models.AlertRule{Id: id + 1, UserId: alice, CityId: cityId}), repository.ErrNotFound)
+
+	rules, err := suite.repo.GetCityAlertRules(context.Background(), cityId)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.AlertRule{rule}, rules)
+
+	assert.ErrorIs(suite.T(), suite.repo.DeleteAlertRule(context.Background(), bob, id), repository.ErrNotFound)
+	assert.NoError(suite.T(), suite.repo.DeleteAlertRule(context.Background(), alice, id))
+	assert.ErrorIs(suite.T(), suite.repo.DeleteAlertRule(context.Background(), alice, id), repository.ErrNotFound)
+	rules, err = suite.repo.GetAlertRules(context.Background(), alice)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), rules)
+}
+
+func (suite *RepositorySuite) TestTriggeredAlertsAreDeduplicated() {
+	userId := suite.createUser("alice")
+	cityId := suite.createCity("London", "GB")
+	ruleId, err := suite.repo.CreateAlertRule(context.Background(), models.AlertRule{UserId: userId, CityId: cityId, Metric: models.AlertMetricTempBelow, Threshold: 0, WithinHours: 24})
+	suite.Require().NoError(err)
+	forecastDate := time.Date(2030, 1, 2, 9, 0, 0, 0, time.UTC)
+
+	id, err := suite.repo.CreateTriggeredAlert(context.Background(), models.TriggeredAlert{RuleId: ruleId, ForecastDate: forecastDate, Value: -3})
+	assert.NoError(suite.T(), err)
+	assert.NotZero(suite.T(), id)
+
+	duplicate, err := suite.repo.CreateTriggeredAlert(context.Background(), models.TriggeredAlert{RuleId: ruleId, ForecastDate: forecastDate, Value: -4})
+	assert.NoError(suite.T(), err)
+	assert.Zero(suite.T(), duplicate)
+
+	alerts, err := suite.repo.GetTriggeredAlerts(context.Background(), userId)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), alerts, 1)
+	assert.Equal(suite.T(), cityId, alerts[0].CityId)
+	assert.Equal(suite.T(), models.AlertMetricTempBelow, alerts[0].Metric)
+	assert.Equal(suite.T(), float64(-3), alerts[0].Value)
+}
+
+func (suite *RepositorySuite) TestTriggeredAlertsAfter() {
+	last, err := suite.repo.GetLastTriggeredAlertId(context.Background())
+	suite.Require().NoError(err)
+	suite.Zero(last)
+
+	userId := suite.createUser("alice")
+	cityId := suite.createCity("London", "GB")
+	ruleId, err := suite.repo.CreateAlertRule(context.Background(), models.AlertRule{UserId: userId, CityId: cityId, Metric: models.AlertMetricTempBelow, Threshold: 0, WithinHours: 24})
+	suite.Require().NoError(err)
+	var ids []int
+	for day := 1; day <= 3; day++ {
+		id, err := suite.repo.CreateTriggeredAlert(context.Background(), models.TriggeredAlert{RuleId: ruleId, ForecastDate: time.Date(2030, 1, day, 9, 0, 0, 0, time.UTC), Value: -1})
+		suite.Require().NoError(err)
+		ids = append(ids, id)
+	}
+
+	last, err = suite.repo.GetLastTriggeredAlertId(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal(ids[2], last)
+
+	alerts, err := suite.repo.GetTriggeredAlertsAfter(context.Background(), ids[0], 1)
+	suite.Require().NoError(err)
+	suite.Require().Len(alerts, 1)
+	suite.Equal(ids[1], alerts[0].Id)
+	suite.Equal(userId, alerts[0].UserId)
+	suite.Equal(cityId, alerts[0].CityId)
+
+	alerts, err = suite.repo.GetTriggeredAlertsAfter(context.Background(), ids[2], 10)
+	suite.Require().NoError(err)
+	suite.Empty(alerts)
+}
+
+func (suite *RepositorySuite) TestCityWebhooksFollowFavorites() {
+	alice := suite.createUser("alice")
+	bob := suite.createUser("bob")
+	cityId := suite.createCity("London", "GB")
+	_, err := suite.repo.AddFavorite(context.Background(), alice, cityId)
+	suite.Require().NoError(err)
+
+	aliceHook, err := suite.repo.CreateWebhook(context.Background(), models.Webhook{UserId: alice, URL: "http://a", Secret: "s"})
+	assert.NoError(suite.T(), err)
+	_, err = suite.repo.CreateWebhook(context.Background(), models.Webhook{UserId: bob, URL: "http://b", Secret: "s"})
+	assert.NoError(suite.T(), err)
+
+	webhooks, err := suite.repo.GetCityWebhooks(context.Background(), cityId)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), webhooks, 1)
+	assert.Equal(suite.T(), aliceHook, webhooks[0].Id)
+}
+
+func (suite *RepositorySuite) TestDeliveryQueue() {
+	userId := suite.createUser("alice")
+	webhookId, err := suite.repo.CreateWebhook(context.Background(), models.Webhook{UserId: userId, URL: "http://a", Secret: "s"})
+	suite.Require().NoError(err)
+
+	deliveryId, err := suite.repo.CreateDelivery(context.Background(), models.WebhookDelivery{
+		WebhookId:     webhookId,
+		Event:         models.WebhookEventPing,
+		Payload:       []byte(`{"event":"ping"}`),
+		Status:        models.DeliveryStatusPending,
+		NextAttemptAt: time.Now().Add(-time.Minute),
+	})
+	assert.NoError(suite.T(), err)
+
+	claimed, err := suite.repo.ClaimPendingDeliveries(context.Background(), 10, time.Now().Add(time.Hour))
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), claimed, 1)
+	assert.Equal(suite.T(), deliveryId, claimed[0].Id)
+
+	claimed, err = suite.repo.ClaimPendingDeliveries(context.Background(), 10, time.Now().Add(time.Hour))
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), claimed, "claimed deliveries are leased")
+
+	deliveredAt := time.Now()
+	assert.NoError(suite.T(), suite.repo.UpdateDelivery(context.Background(), models.WebhookDelivery{
+		Id:             deliveryId,
+		Status:         models.DeliveryStatusDelivered,
+		Attempts:       1,
+		ResponseStatus: 200,
+		NextAttemptAt:  deliveredAt,
+		DeliveredAt:    &deliveredAt,
+	}))
+
+	deliveries, err := suite.repo.GetDeliveries(context.Background(), userId, webhookId, 10)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), deliveries, 1)
+	assert.Equal(suite.T(), models.DeliveryStatusDelivered, deliveries[0].Status)
+	assert.NotNil(suite.T(), deliveries[0].DeliveredAt)
+
+	assert.NoError(suite.T(), suite.repo.DeleteWebhook(context.Background(), userId, webhookId))
+	deliveries, err = suite.repo.GetDeliveries(context.Background(), userId, webhookId, 10)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), deliveries)
+}
+
+func (suite *RepositorySuite) TestCollectorRuns() {
+	startedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+	firstId, err := suite.repo.CreateCollectorRun(context.Background(), models.CollectorRun{TriggeredBy: models.RunTriggerFull, StartedAt: startedAt})
+	suite.Require().NoError(err)
+	secondId, err := suite.repo.CreateCollectorRun(context.Background(), models.CollectorRun{TriggeredBy: models.RunTriggerManual, StartedAt: startedAt.Add(time.Second)})
+	suite.Require().NoError(err)
+
+	finishedAt := startedAt.Add(30 * time.Second)
+	assert.NoError(suite.T(), suite.repo.FinishCollectorRun(context.Background(), models.CollectorRun{
+		Id:              firstId,
+		FinishedAt:      &finishedAt,
+		CitiesAttempted: 2,
+		CitiesSucceeded: 1,
+		CitiesFailed:    1,
+		CitiesSkipped:   3,
+		RowsWritten:     40,
+		Failures:        models.RunFailures{{CityId: 7, City: "Paris", Error: "timeout"}},
+	}))
+	assert.Error(suite.T(), suite.repo.FinishCollectorRun(context.Background(), models.CollectorRun{Id: 999}))
+
+	runs, err := suite.repo.GetCollectorRuns(context.Background(), 10)
+	assert.NoError(suite.T(), err)
+	suite.Require().Len(runs, 2)
+	assert.Equal(suite.T(), secondId, runs[0].Id)
+	assert.Nil(suite.T(), runs[0].FinishedAt)
+	assert.Empty(suite.T(), runs[0].Failures)
+
+	first := runs[1]
+	assert.Equal(suite.T(), models.RunTriggerFull, first.TriggeredBy)
+	assert.True(suite.T(), startedAt.Equal(first.StartedAt))
+	suite.Require().NotNil(suite.T(), first.FinishedAt)
+	assert.True(suite.T(), finishedAt.Equal(*first.FinishedAt))
+	assert.Equal(suite.T(), 40, first.RowsWritten)
+	assert.Equal(suite.T(), 3, first.CitiesSkipped)
+	assert.Equal(suite.T(), models.RunFailures{{CityId: 7, City: "Paris", Error: "timeout"}}, first.Failures)
+
+	runs, err = suite.repo.GetCollectorRuns(context.Background(), 1)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), runs, 1)
+}
+
+func (suite *RepositorySuite) TestDeadLetters() {
+	cityId, err := suite.repo.CreateCity(context.Background(), models.City{Name: "Paris", Country: "FR"})
+	suite.Require().NoError(err)
+	otherId, err := suite.repo.CreateCity(context.Background(), models.City{Name: "Rome", Country: "IT"})
+	suite.Require().NoError(err)
+
+	firstFailedAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for i := 0; i < 3; i++ {
+		failures, err := suite.repo.RecordCityFailure(context.Background(), cityId, fmt.Sprintf("error %d", i), firstFailedAt.Add(time.Duration(i)*time.Minute))
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), i+1, failures)
+	}
+	_, err = suite.repo.RecordCityFailure(context.Background(), otherId, "timeout", firstFailedAt)
+	suite.Require().NoError(err)
+	_, err = suite.repo.RecordCityFailure(context.Background(), 999, "timeout", firstFailedAt)
+	assert.Error(suite.T(), err)
+
+	deadLetters, err := suite.repo.GetDeadLetters(context.Background(), 2)
+	suite.Require().NoError(err)
+	suite.Require().Len(deadLetters, 1)
+	assert.Equal(suite.T(), cityId, deadLetters[0].CityId)
+	assert.Equal(suite.T(), "Paris", deadLetters[0].City)
+	assert.Equal(suite.T(), 3, deadLetters[0].Failures)
+	assert.Equal(suite.T(), "error 2", deadLetters[0].LastError)
+	assert.True(suite.T(), firstFailedAt.Equal(deadLetters[0].FirstFailedAt))
+	assert.True(suite.T(), firstFailedAt.Add(2*time.Minute).Equal(deadLetters[0].LastFailedAt))
+
+	deadLetters, err = suite.repo.GetDeadLetters(context.Background(), 1)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), deadLetters, 2)
+
+	suite.Require().NoError(suite.repo.ClearCityFailures(context.Background(), cityId))
+	assert.NoError(suite.T(), suite.repo.ClearCityFailures(context.Background(), cityId))
+	failures, err := suite.repo.RecordCityFailure(context.Background(), cityId, "error", time.Now())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 1, failures)
+}
+
+func (suite *RepositorySuite) TestProviderRequests() {
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		counted, err := suite.repo.AddProviderRequest(context.Background(), day, 2)
+		suite.Require().NoError(err)
+		assert.True(suite.T(), counted)
+	}
+	counted, err := suite.repo.AddProviderRequest(context.Background(), day, 2)
+	suite.Require().NoError(err)
+	assert.False(suite.T(), counted, "the quota is used")
+
+	counted, err = suite.repo.AddProviderRequest(context.Background(), day, 0)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), counted, "a zero quota counts every request")
+	requests, err := suite.repo.GetProviderRequests(context.Background(), day)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), 3, requests)
+
+	requests, err = suite.repo.GetProviderRequests(context.Background(), day.AddDate(0, 0, 1))
+	suite.Require().NoError(err)
+	assert.Zero(suite.T(), requests, "each day is counted anew")
+}
+
+func (suite *RepositorySuite) TestRollupForecasts() {
+	cityId := suite.createCity("London", "GB")
+	otherId := suite.createCity("Paris", "FR")
+	day := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, forecast := range []models.Forecast{
+		{CityId: cityId, Temp: 4, Date: day, ForecastJson: []byte(`{}`)},
+		{CityId: cityId, Temp: 6, Date: day.AddDate(0, 0, 1), ForecastJson: []byte(`{}`)},
+		{CityId: otherId, Temp: 8, Date: day, ForecastJson: []byte(`{}`)},
+		{CityId: cityId, Temp: 9, Date: day.AddDate(0, 0, 5), ForecastJson: []byte(`{}`)},
+	} {
+		_, err := suite.repo.CreateForecast(context.Background(), forecast)
+		suite.Require().NoError(err)
+	}
+
+	before := day.AddDate(0, 0, 5)
+	rolledUp, err := suite.repo.RollupForecasts(context.Background(), before, 2)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 2, rolledUp)
+	rolledUp, err = suite.repo.RollupForecasts(context.Background(), before, 2)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, rolledUp)
+	rolledUp, err = suite.repo.RollupForecasts(context.Background(), before, 2)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, rolledUp)
+
+	forecasts, err := suite.repo.GetForecasts(context.Background(), cityId)
+	suite.Require().NoError(err)
+	suite.Require().Len(forecasts, 1)
+	assert.Equal(suite.T(), float32(9), forecasts[0].Temp)
+
+	// A late forecast of a day already rolled up is merged into its aggregate.
+	_, err = suite.repo.CreateForecast(context.Background(), models.Forecast{CityId: cityId, Temp: 2, Date: day, ForecastJson: []byte(`{}`)})
+	suite.Require().NoError(err)
+	rolledUp, err = suite.repo.RollupForecasts(context.Background(), before, 10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, rolledUp)
+
+	daily, err := suite.repo.GetDailyForecasts(context.Background(), cityId)
+	suite.Require().NoError(err)
+	suite.Require().Len(daily, 2)
+	assert.True(suite.T(), time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC).Equal(daily[0].Date))
+	assert.Equal(suite.T(), float32(2), daily[0].TempMin)
+	assert.Equal(suite.T(), float32(4), daily[0].TempMax)
+	assert.InDelta(suite.T(), 3, daily[0].TempAvg, 0.001)
+	assert.Equal(suite.T(), 2, daily[0].Samples)
+	assert.Equal(suite.T(), float32(6), daily[1].TempAvg)
+	assert.Equal(suite.T(), 1, daily[1].Samples)
+
+	other, err := suite.repo.GetDailyForecasts(context.Background(), otherId)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), other, 1)
+}
+
+func (suite *RepositorySuite) TestPruneDailyForecasts() {
+	cityId := suite.createCity("London", "GB")
+	day := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		_, err := suite.repo.CreateForecast(context.Background(), models.Forecast{CityId: cityId, Temp: 1, Date: day.AddDate(0, 0, i), ForecastJson: []byte(`{}`)})
+		suite.Require().NoError(err)
+	}
+	_, err := suite.repo.RollupForecasts(context.Background(), day.AddDate(0, 0, 3), 10)
+	suite.Require().NoError(err)
+
+	pruned, err := suite.repo.PruneDailyForecasts(context.Background(), day.AddDate(0, 0, 2), 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, pruned)
+	pruned, err = suite.repo.PruneDailyForecasts(context.Background(), day.AddDate(0, 0, 2), 10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, pruned)
+
+	daily, err := suite.repo.GetDailyForecasts(context.Background(), cityId)
+	suite.Require().NoError(err)
+	suite.Require().Len(daily, 1)
+	assert.True(suite.T(), time.Date(2024, 3, 12, 0, 0, 0, 0, time.UTC).Equal(daily[0].Date))
+}
+
+func (suite *RepositorySuite) TestPruneCollectorRuns() {
+	now := time.Now().Truncate(time.Second)
+	for _, startedAt := range []time.Time{now.AddDate(0, 0, -10), now.AddDate(0, 0, -9), now} {
+		_, err := suite.repo.CreateCollectorRun(context.Background(), models.CollectorRun{TriggeredBy: models.RunTriggerScheduled, StartedAt: startedAt})
+		suite.Require().NoError(err)
+	}
+
+	pruned, err := suite.repo.PruneCollectorRuns(context.Background(), now.AddDate(0, 0, -1), 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, pruned)
+	pruned, err = suite.repo.PruneCollectorRuns(context.Background(), now.AddDate(0, 0, -1), 10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, pruned)
+
+	runs, err := suite.repo.GetCollectorRuns(context.Background(), 10)
+	suite.Require().NoError(err)
+	suite.Require().Len(runs, 1)
+	assert.True(suite.T(), now.Equal(runs[0].StartedAt))
+}
@@ -0,0 +1,165 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type AlertRepository struct {
+	db *sqlx.DB
+}
+
+func NewAlertRepository(db *sqlx.DB) *AlertRepository {
+	return &AlertRepository{db: db}
+}
+
+func (r *AlertRepository) CreateAlertRule(ctx context.Context, rule models.AlertRule) (int, error) {
+	var id int
+	query := fmt.Sprintf(`
+		insert into %s (user_id, city_id, metric, threshold, within_hours)
+		values ($1, $2, $3, $4, $5)
+		returning id
+	`, AlertRulesTable)
+	row := r.db.QueryRowContext(ctx, query, rule.UserId, rule.CityId, rule.Metric, rule.Threshold, rule.WithinHours)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *AlertRepository) GetAlertRules(ctx context.Context, userId int) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	query := fmt.Sprintf("select id, user_id, city_id, metric, threshold, within_hours from %s where user_id=$1 order by id", AlertRulesTable)
+	err := r.db.SelectContext(ctx, &rules, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+func (r *AlertRepository) GetAlertRule(ctx context.Context, userId int, ruleId int) (models.AlertRule, error) {
+	var rule models.AlertRule
+	query := fmt.Sprintf("select id, user_id, city_id, metric, threshold, within_hours from %s where user_id=$1 and id=$2", AlertRulesTable)
+	err := r.db.GetContext(ctx, &rule, query, userId, ruleId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return models.AlertRule{}, repository.ErrNotFound
+	}
+	if err != nil {
+		return models.AlertRule{}, err
+	}
+	return rule, nil
+}
+
+func (r *AlertRepository) UpdateAlertRule(ctx context.Context, rule models.AlertRule) error {
+	query := fmt.Sprintf(`
+		update %s set city_id=$1, metric=$2, threshold=$3, within_hours=$4
+		where user_id=$5 and id=$6
+	`, AlertRulesTable)
+	result, err := r.db.ExecContext(ctx, query, rule.CityId, rule.Metric, rule.Threshold, rule.WithinHours, rule.UserId, rule.Id)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *AlertRepository) DeleteAlertRule(ctx context.Context, userId int, ruleId int) error {
+	query := fmt.Sprintf("delete from %s where user_id=$1 and id=$2", AlertRulesTable)
+	result, err := r.db.ExecContext(ctx, query, userId, ruleId)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *AlertRepository) GetCityAlertRules(ctx context.Context, cityId int) ([]models.AlertRule, error) {
+	var rules []models.AlertRule
+	query := fmt.Sprintf("select id, user_id, city_id, metric, threshold, within_hours from %s where city_id=$1", AlertRulesTable)
+	err := r.db.SelectContext(ctx, &rules, query, cityId)
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// CreateTriggeredAlert records a triggered alert. The same rule fires at most
+// once per forecast date, so 0 is returned if the alert was already recorded.
+func (r *AlertRepository) CreateTriggeredAlert(ctx context.Context, alert models.TriggeredAlert) (int, error) {
+	var id int
+	query := fmt.Sprintf(`
+		insert into %s (rule_id, forecast_date, value)
+		values ($1, $2, $3)
+		on conflict (rule_id, forecast_date) do nothing
+		returning id
+	`, TriggeredAlertsTable)
+	row := r.db.QueryRowContext(ctx, query, alert.RuleId, alert.ForecastDate.UTC(), alert.Value)
+	if err := row.Scan(&id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *AlertRepository) GetTriggeredAlerts(ctx context.Context, userId int) ([]models.TriggeredAlert, error) {
+	var alerts []models.TriggeredAlert
+	query := fmt.Sprintf(`
+		select t.id, t.rule_id, r.user_id, r.city_id, r.metric, r.threshold, t.value, t.forecast_date, t.triggered_at
+		from %s t join %s r on r.id = t.rule_id
+		where r.user_id=$1
+		order by t.triggered_at desc
+	`, TriggeredAlertsTable, AlertRulesTable)
+	err := r.db.SelectContext(ctx, &alerts, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// GetTriggeredAlertsAfter returns up to limit alerts recorded after the alert
+// afterId, oldest first.
+func (r *AlertRepository) GetTriggeredAlertsAfter(ctx context.Context, afterId int, limit int) ([]models.TriggeredAlert, error) {
+	var alerts []models.TriggeredAlert
+	query := fmt.Sprintf(`
+		select t.id, t.rule_id, r.user_id, r.city_id, r.metric, r.threshold, t.value, t.forecast_date, t.triggered_at
+		from %s t join %s r on r.id = t.rule_id
+		where t.id > $1
+		order by t.id
+		limit $2
+	`, TriggeredAlertsTable, AlertRulesTable)
+	if err := r.db.SelectContext(ctx, &alerts, query, afterId, limit); err != nil {
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// GetLastTriggeredAlertId returns the id of the latest alert, 0 if there are
+// none.
+func (r *AlertRepository) GetLastTriggeredAlertId(ctx context.Context) (int, error) {
+	var id int
+	query := fmt.Sprintf(`select coalesce(max(id), 0) from %s`, TriggeredAlertsTable)
+	if err := r.db.GetContext(ctx, &id, query); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
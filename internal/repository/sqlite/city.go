@@ -0,0 +1,88 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"weather-app/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type CityRepository struct {
+	db *sqlx.DB
+}
+
+func NewCityRepository(db *sqlx.DB) *CityRepository {
+	return &CityRepository{db: db}
+}
+
+func (r *CityRepository) CreateCity(ctx context.Context, city models.City) (int, error) {
+	var id int
+	query := fmt.Sprintf(`
+		insert into %s (name, country, latitude, longitude)
+		values ($1, $2, $3, $4)
+		on conflict (name, country) do update set
+			latitude = excluded.latitude,
+			longitude = excluded.longitude
+		returning id
+	`, CitiesTable)
+	row := r.db.QueryRowContext(ctx, query, city.Name, city.Country, city.Latitude, city.Longitude)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *CityRepository) GetCities(ctx context.Context) ([]models.City, error) {
+	var cities []models.City
+	query := fmt.Sprintf("select id, name, country, latitude, longitude from %s order by name", CitiesTable)
+	err := r.db.SelectContext(ctx, &cities, query)
+	if err != nil {
+		return nil, err
+	}
+	return cities, nil
+}
+
+func (r *CityRepository) GetCity(ctx context.Context, cityId int) (models.City, error) {
+	var city models.City
+	query := fmt.Sprintf("select id, name, country, latitude, longitude from %s where id=$1", CitiesTable)
+	err := r.db.GetContext(ctx, &city, query, cityId)
+	if err != nil {
+		return models.City{}, err
+	}
+	return city, nil
+}
+
+// GetCitySchedules returns all cities with their update interval and the
+// number of users who have them in favorites, most popular first.
+func (r *CityRepository) GetCitySchedules(ctx context.Context) ([]models.CitySchedule, error) {
+	var schedules []models.CitySchedule
+	query := fmt.Sprintf(`
+		select c.id, c.name, c.country, c.latitude, c.longitude, c.update_interval, count(uc.id) as favorites
+		from %s c left join %s uc on uc.city_id = c.id
+		group by c.id
+		order by favorites desc, c.name
+	`, CitiesTable, UsersCitiesTable)
+	err := r.db.SelectContext(ctx, &schedules, query)
+	if err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *CityRepository) UpdateCityInterval(ctx context.Context, cityId int, interval int) error {
+	query := fmt.Sprintf("update %s set update_interval=$1 where id=$2", CitiesTable)
+	result, err := r.db.ExecContext(ctx, query, interval, cityId)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("no rows updated")
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type HealthRepository struct {
+	db *sqlx.DB
+}
+
+func NewHealthRepository(db *sqlx.DB) *HealthRepository {
+	return &HealthRepository{db: db}
+}
+
+func (r *HealthRepository) Ping(ctx context.Context) error {
+	return r.db.PingContext(ctx)
+}
+
+// GetSchemaVersion returns the last applied migration, 0 if none were.
+func (r *HealthRepository) GetSchemaVersion(ctx context.Context) (uint, bool, error) {
+	var row struct {
+		Version int64 `db:"version"`
+		Dirty   bool  `db:"dirty"`
+	}
+	query := fmt.Sprintf("select version, dirty from %s limit 1", MigrationsTable)
+	if err := r.db.GetContext(ctx, &row, query); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return uint(row.Version), row.Dirty, nil
+}
@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"weather-app/internal/tracing"
+
+	"github.com/jmoiron/sqlx"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	UsersTable            = "users"
+	UsersCitiesTable      = "users_cities"
+	CitiesTable           = "cities"
+	ForecastsTable        = "forecasts"
+	DailyForecastsTable   = "forecast_daily"
+	AlertRulesTable       = "alert_rules"
+	TriggeredAlertsTable  = "triggered_alerts"
+	WebhooksTable         = "webhooks"
+	DeliveriesTable       = "webhook_deliveries"
+	CollectorRunsTable    = "collector_runs"
+	DeadLettersTable      = "collector_dead_letters"
+	LoginFailuresTable    = "login_failures"
+	SignInAttemptsTable   = "sign_in_attempts"
+	ProviderRequestsTable = "provider_requests"
+	// MigrationsTable is kept by golang-migrate.
+	MigrationsTable = "schema_migrations"
+)
+
+type SqliteConfig struct {
+	Path string `yaml:"path" toml:"path"`
+}
+
+// NewSqliteConnection opens the database file with foreign keys enabled (they
+// are off by default in SQLite) and WAL journaling, so readers don't block the
+// collector while it writes.
+func NewSqliteConnection(conn SqliteConfig) (*sqlx.DB, error) {
+	connString := fmt.Sprintf(
+		"file:%s?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite",
+		conn.Path,
+	)
+
+	sqlDB, err := tracing.OpenDB("sqlite", connString, "sqlite")
+	if err != nil {
+		return nil, err
+	}
+	db := sqlx.NewDb(sqlDB, "sqlite")
+
+	if err := db.Ping(); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Timestamps are stored as text, so they are normalized to UTC to keep
+// comparisons in queries consistent.
+func utcPtr(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// idList returns placeholders for ids numbered from $first, for use in an
+// "in" clause, along with the matching arguments.
+func idList(ids []int, first int) (string, []any) {
+	placeholders := make([]string, len(ids))
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		placeholders[i] = fmt.Sprintf("$%d", first+i)
+		args[i] = id
+	}
+	return strings.Join(placeholders, ", "), args
+}
@@ -0,0 +1,71 @@
+package sqlite
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"weather-app/internal/migrator"
+	"weather-app/internal/repository"
+	"weather-app/internal/repository/repotest"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/suite"
+)
+
+// newTestDB creates a database file in a temporary directory and applies the
+// embedded sqlite migrations to it.
+func newTestDB(t testing.TB) *sqlx.DB {
+	db, err := NewSqliteConnection(SqliteConfig{Path: filepath.Join(t.TempDir(), "weather.db")})
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	m, err := migrator.NewSqliteMigrator(db.DB)
+	if err != nil {
+		t.Fatalf("create migrator: %v", err)
+	}
+	if err := m.Up(); err != nil {
+		t.Fatalf("apply migrations: %v", err)
+	}
+	return db
+}
+
+func newTestRepository(t testing.TB) *repository.Repository {
+	db := newTestDB(t)
+	return repository.NewRepository(
+		NewCityRepository(db),
+		NewForecastRepository(db),
+		NewUserRepository(db),
+		NewAlertRepository(db),
+		NewWebhookRepository(db),
+		NewCollectorRunRepository(db),
+		NewHealthRepository(db),
+	)
+}
+
+func TestSqliteRepositorySuite(t *testing.T) {
+	suite.Run(t, &repotest.RepositorySuite{NewRepository: func() *repository.Repository {
+		return newTestRepository(t)
+	}})
+}
+
+func TestSchemaVersion(t *testing.T) {
+	version, dirty, err := NewHealthRepository(newTestDB(t)).GetSchemaVersion(context.Background())
+	if err != nil {
+		t.Fatalf("get schema version: %v", err)
+	}
+	latest, err := migrator.LatestSqlite()
+	if err != nil {
+		t.Fatalf("get latest migration: %v", err)
+	}
+	if version != latest || dirty {
+		t.Errorf("schema version is %d (dirty: %t), want %d", version, dirty, latest)
+	}
+}
+
+func BenchmarkForecastWrites(b *testing.B) {
+	repotest.BenchmarkForecastWrites(b, func() *repository.Repository {
+		return newTestRepository(b)
+	})
+}
@@ -0,0 +1,156 @@
+package sqlite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"weather-app/internal/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const deliveryColumns = "id, webhook_id, event, payload, status, attempts, response_status, last_error, next_attempt_at, created_at, delivered_at"
+
+type WebhookRepository struct {
+	db *sqlx.DB
+}
+
+func NewWebhookRepository(db *sqlx.DB) *WebhookRepository {
+	return &WebhookRepository{db: db}
+}
+
+func (r *WebhookRepository) CreateWebhook(ctx context.Context, webhook models.Webhook) (int, error) {
+	var id int
+	query := fmt.Sprintf("insert into %s (user_id, url, secret) values ($1, $2, $3) returning id", WebhooksTable)
+	row := r.db.QueryRowContext(ctx, query, webhook.UserId, webhook.URL, webhook.Secret)
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (r *WebhookRepository) GetWebhooks(ctx context.Context, userId int) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	query := fmt.Sprintf("select id, user_id, url, secret, created_at from %s where user_id=$1 order by id", WebhooksTable)
+	err := r.db.SelectContext(ctx, &webhooks, query, userId)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) GetWebhook(ctx context.Context, userId int, webhookId int) (models.Webhook, error) {
+	var webhook models.Webhook
+	query := fmt.Sprintf("select id, user_id, url, secret, created_at from %s where user_id=$1 and id=$2", WebhooksTable)
+	err := r.db.GetContext(ctx, &webhook, query, userId, webhookId)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepository) GetWebhookById(ctx context.Context, webhookId int) (models.Webhook, error) {
+	var webhook models.Webhook
+	query := fmt.Sprintf("select id, user_id, url, secret, created_at from %s where id=$1", WebhooksTable)
+	err := r.db.GetContext(ctx, &webhook, query, webhookId)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+// GetCityWebhooks returns the webhooks of all users who have the city in favorites.
+func (r *WebhookRepository) GetCityWebhooks(ctx context.Context, cityId int) ([]models.Webhook, error) {
+	var webhooks []models.Webhook
+	query := fmt.Sprintf(`
+		select w.id, w.user_id, w.url, w.secret, w.created_at
+		from %s w join %s uc on uc.user_id = w.user_id
+		where uc.city_id=$1
+	`, WebhooksTable, UsersCitiesTable)
+	err := r.db.SelectContext(ctx, &webhooks, query, cityId)
+	if err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (r *WebhookRepository) DeleteWebhook(ctx context.Context, userId int, webhookId int) error {
+	query := fmt.Sprintf("delete from %s where user_id=$1 and id=$2", WebhooksTable)
+	result, err := r.db.ExecContext(ctx, query, userId, webhookId)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New("no rows deleted")
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	var id int
+	query := fmt.Sprintf(`
+		insert into %s (webhook_id, event, payload, status, attempts, response_status, last_error, next_attempt_at, delivered_at)
+		values ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		returning id
+	`, DeliveriesTable)
+	row := r.db.QueryRowContext(ctx, query, delivery.WebhookId, delivery.Event, delivery.Payload, delivery.Status,
+		delivery.Attempts, delivery.ResponseStatus, delivery.LastError, delivery.NextAttemptAt.UTC(), utcPtr(delivery.DeliveredAt))
+	if err := row.Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// ClaimPendingDeliveries selects due pending deliveries and pushes their next
+// attempt time to leaseUntil. SQLite has no row locks, but it serializes
+// writers, so the single update statement is enough to lease the rows.
+func (r *WebhookRepository) ClaimPendingDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := fmt.Sprintf(`
+		update %s set next_attempt_at=$2
+		where id in (
+			select id from %s
+			where status='pending' and next_attempt_at <= $3
+			order by next_attempt_at
+			limit $1
+		)
+		returning %s
+	`, DeliveriesTable, DeliveriesTable, deliveryColumns)
+	err := r.db.SelectContext(ctx, &deliveries, query, limit, leaseUntil.UTC(), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	query := fmt.Sprintf(`
+		update %s set status=$1, attempts=$2, response_status=$3, last_error=$4, next_attempt_at=$5, delivered_at=$6
+		where id=$7
+	`, DeliveriesTable)
+	_, err := r.db.ExecContext(ctx, query, delivery.Status, delivery.Attempts, delivery.ResponseStatus, delivery.LastError,
+		delivery.NextAttemptAt.UTC(), utcPtr(delivery.DeliveredAt), delivery.Id)
+	return err
+}
+
+func (r *WebhookRepository) GetDeliveries(ctx context.Context, userId int, webhookId int, limit int) ([]models.WebhookDelivery, error) {
+	var deliveries []models.WebhookDelivery
+	query := fmt.Sprintf(`
+		select d.id, d.webhook_id, d.event, d.payload, d.status, d.attempts, d.response_status,
+			d.last_error, d.next_attempt_at, d.created_at, d.delivered_at
+		from %s d join %s w on w.id = d.webhook_id
+		where w.user_id=$1 and d.webhook_id=$2
+		order by d.created_at desc
+		limit $3
+	`, DeliveriesTable, WebhooksTable)
+	err := r.db.SelectContext(ctx, &deliveries, query, userId, webhookId, limit)
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
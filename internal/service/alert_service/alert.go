@@ -0,0 +1,177 @@
+package alertservice
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+	"weather-app/internal/service"
+)
+
+const maxWithinHours = 120
+
+type AlertService struct {
+	cityService service.CityService
+	alertRep    repository.AlertRepository
+}
+
+func NewAlertService(cityService service.CityService, alertRep repository.AlertRepository) *AlertService {
+	return &AlertService{
+		cityService: cityService,
+		alertRep:    alertRep,
+	}
+}
+
+func validateAlertRule(rule models.AlertRule) error {
+	switch rule.Metric {
+	case models.AlertMetricTempBelow, models.AlertMetricTempAbove, models.AlertMetricWindGustAbove:
+	case models.AlertMetricPopAbove:
+		if rule.Threshold < 0 || rule.Threshold > 100 {
+			return &service.ValidationError{Message: "threshold of pop_above must be between 0 and 100"}
+		}
+	default:
+		return &service.ValidationError{Message: fmt.Sprintf("unknown alert metric: %q", rule.Metric)}
+	}
+	if rule.WithinHours <= 0 || rule.WithinHours > maxWithinHours {
+		return &service.ValidationError{Message: fmt.Sprintf("within_hours must be between 1 and %d", maxWithinHours)}
+	}
+	return nil
+}
+
+// checkCity requires the city of a rule to exist. An unknown city is the
+// client's mistake, while failing to look it up is not.
+func (s *AlertService) checkCity(ctx context.Context, cityId int) error {
+	_, err := s.cityService.GetCity(ctx, cityId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &service.ValidationError{Message: fmt.Sprintf("city %d not found", cityId)}
+	}
+	return err
+}
+
+func (s *AlertService) CreateAlertRule(ctx context.Context, rule models.AlertRule) (int, error) {
+	if err := validateAlertRule(rule); err != nil {
+		return 0, err
+	}
+	if err := s.checkCity(ctx, rule.CityId); err != nil {
+		return 0, err
+	}
+	return s.alertRep.CreateAlertRule(ctx, rule)
+}
+
+func (s *AlertService) GetAlertRules(ctx context.Context, userId int) ([]models.AlertRule, error) {
+	return s.alertRep.GetAlertRules(ctx, userId)
+}
+
+func (s *AlertService) GetAlertRule(ctx context.Context, userId int, ruleId int) (models.AlertRule, error) {
+	return s.alertRep.GetAlertRule(ctx, userId, ruleId)
+}
+
+func (s *AlertService) UpdateAlertRule(ctx context.Context, rule models.AlertRule) error {
+	if err := validateAlertRule(rule); err != nil {
+		return err
+	}
+	if err := s.checkCity(ctx, rule.CityId); err != nil {
+		return err
+	}
+	return s.alertRep.UpdateAlertRule(ctx, rule)
+}
+
+func (s *AlertService) DeleteAlertRule(ctx context.Context, userId int, ruleId int) error {
+	return s.alertRep.DeleteAlertRule(ctx, userId, ruleId)
+}
+
+func (s *AlertService) GetTriggeredAlerts(ctx context.Context, userId int) ([]models.TriggeredAlert, error) {
+	return s.alertRep.GetTriggeredAlerts(ctx, userId)
+}
+
+// GetTriggeredAlertsAfter returns up to limit alerts of all users recorded
+// after the alert afterId, oldest first.
+func (s *AlertService) GetTriggeredAlertsAfter(ctx context.Context, afterId int, limit int) ([]models.TriggeredAlert, error) {
+	return s.alertRep.GetTriggeredAlertsAfter(ctx, afterId, limit)
+}
+
+func (s *AlertService) GetLastTriggeredAlertId(ctx context.Context) (int, error) {
+	return s.alertRep.GetLastTriggeredAlertId(ctx)
+}
+
+type forecastDetails struct {
+	Pop  float64 `json:"pop"`
+	Wind struct {
+		Gust float64 `json:"gust"`
+	} `json:"wind"`
+}
+
+// metricValue extracts the value a rule is compared against from a forecast.
+// Precipitation probability is reported in percent.
+func metricValue(metric string, forecast models.Forecast) (float64, error) {
+	switch metric {
+	case models.AlertMetricTempBelow, models.AlertMetricTempAbove:
+		return float64(forecast.Temp), nil
+	}
+	var details forecastDetails
+	if err := json.Unmarshal(forecast.ForecastJson, &details); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal forecast details: %w", err)
+	}
+	if metric == models.AlertMetricWindGustAbove {
+		return details.Wind.Gust, nil
+	}
+	return details.Pop * 100, nil
+}
+
+func ruleMatches(rule models.AlertRule, value float64) bool {
+	if rule.Metric == models.AlertMetricTempBelow {
+		return value < rule.Threshold
+	}
+	return value > rule.Threshold
+}
+
+// EvaluateAlerts checks the city's alert rules against freshly fetched forecasts
+// and returns only the alerts that were recorded for the first time.
+func (s *AlertService) EvaluateAlerts(ctx context.Context, cityId int, forecasts []models.Forecast) ([]models.TriggeredAlert, error) {
+	rules, err := s.alertRep.GetCityAlertRules(ctx, cityId)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var triggered []models.TriggeredAlert
+	for _, rule := range rules {
+		deadline := now.Add(time.Duration(rule.WithinHours) * time.Hour)
+		for _, forecast := range forecasts {
+			if !forecast.Date.After(now) || forecast.Date.After(deadline) {
+				continue
+			}
+			value, err := metricValue(rule.Metric, forecast)
+			if err != nil {
+				return triggered, err
+			}
+			if !ruleMatches(rule, value) {
+				continue
+			}
+			alert := models.TriggeredAlert{
+				RuleId:       rule.Id,
+				UserId:       rule.UserId,
+				CityId:       rule.CityId,
+				Metric:       rule.Metric,
+				Threshold:    rule.Threshold,
+				Value:        value,
+				ForecastDate: forecast.Date,
+				TriggeredAt:  now,
+			}
+			id, err := s.alertRep.CreateTriggeredAlert(ctx, alert)
+			if err != nil {
+				return triggered, err
+			}
+			if id == 0 {
+				continue
+			}
+			alert.Id = id
+			triggered = append(triggered, alert)
+		}
+	}
+	return triggered, nil
+}
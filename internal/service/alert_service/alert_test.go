@@ -0,0 +1,246 @@
+package alertservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockCityService struct {
+	mock.Mock
+}
+
+func (m *MockCityService) CreateCity(ctx context.Context, city models.City) (int, error) {
+	args := m.Called(city)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockCityService) GetCities(ctx context.Context) ([]models.City, error) {
+	args := m.Called()
+	return args.Get(0).([]models.City), args.Error(1)
+}
+
+func (m *MockCityService) GetCity(ctx context.Context, cityId int) (models.City, error) {
+	args := m.Called(cityId)
+	return args.Get(0).(models.City), args.Error(1)
+}
+
+func (m *MockCityService) FetchCityData(ctx context.Context, cityName string, openWeatherAPIKey string) (models.City, error) {
+	args := m.Called(cityName, openWeatherAPIKey)
+	return args.Get(0).(models.City), args.Error(1)
+}
+
+func (m *MockCityService) GetCitySchedules(ctx context.Context) ([]models.CitySchedule, error) {
+	args := m.Called()
+	return args.Get(0).([]models.CitySchedule), args.Error(1)
+}
+
+func (m *MockCityService) UpdateCitySchedule(ctx context.Context, cityId int, interval int) error {
+	args := m.Called(cityId, interval)
+	return args.Error(0)
+}
+
+type MockAlertRepository struct {
+	mock.Mock
+}
+
+func (m *MockAlertRepository) CreateAlertRule(ctx context.Context, rule models.AlertRule) (int, error) {
+	args := m.Called(rule)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAlertRepository) GetAlertRules(ctx context.Context, userId int) ([]models.AlertRule, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]models.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertRepository) GetAlertRule(ctx context.Context, userId int, ruleId int) (models.AlertRule, error) {
+	args := m.Called(userId, ruleId)
+	return args.Get(0).(models.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertRepository) UpdateAlertRule(ctx context.Context, rule models.AlertRule) error {
+	args := m.Called(rule)
+	return args.Error(0)
+}
+
+func (m *MockAlertRepository) DeleteAlertRule(ctx context.Context, userId int, ruleId int) error {
+	args := m.Called(userId, ruleId)
+	return args.Error(0)
+}
+
+func (m *MockAlertRepository) GetCityAlertRules(ctx context.Context, cityId int) ([]models.AlertRule, error) {
+	args := m.Called(cityId)
+	return args.Get(0).([]models.AlertRule), args.Error(1)
+}
+
+func (m *MockAlertRepository) CreateTriggeredAlert(ctx context.Context, alert models.TriggeredAlert) (int, error) {
+	args := m.Called(alert)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAlertRepository) GetTriggeredAlertsAfter(ctx context.Context, afterId int, limit int) ([]models.TriggeredAlert, error) {
+	args := m.Called(afterId, limit)
+	return args.Get(0).([]models.TriggeredAlert), args.Error(1)
+}
+
+func (m *MockAlertRepository) GetLastTriggeredAlertId(ctx context.Context) (int, error) {
+	args := m.Called()
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAlertRepository) GetTriggeredAlerts(ctx context.Context, userId int) ([]models.TriggeredAlert, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]models.TriggeredAlert), args.Error(1)
+}
+
+type AlertServiceTestSuite struct {
+	suite.Suite
+	service      *AlertService
+	mockCitySvc  *MockCityService
+	mockAlertRep *MockAlertRepository
+}
+
+func (suite *AlertServiceTestSuite) SetupTest() {
+	suite.mockCitySvc = new(MockCityService)
+	suite.mockAlertRep = new(MockAlertRepository)
+	suite.service = NewAlertService(suite.mockCitySvc, suite.mockAlertRep)
+}
+
+func (suite *AlertServiceTestSuite) TestCreateAlertRule() {
+	rule := models.AlertRule{UserId: 1, CityId: 2, Metric: models.AlertMetricTempBelow, Threshold: -5, WithinHours: 24}
+
+	suite.mockCitySvc.On("GetCity", 2).Return(models.City{Id: 2}, nil)
+	suite.mockAlertRep.On("CreateAlertRule", rule).Return(1, nil)
+
+	id, err := suite.service.CreateAlertRule(context.Background(), rule)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, id)
+	suite.mockCitySvc.AssertExpectations(suite.T())
+	suite.mockAlertRep.AssertExpectations(suite.T())
+}
+
+func (suite *AlertServiceTestSuite) TestCreateAlertRuleInvalidMetric() {
+	rule := models.AlertRule{UserId: 1, CityId: 2, Metric: "humidity_above", Threshold: 90, WithinHours: 24}
+
+	id, err := suite.service.CreateAlertRule(context.Background(), rule)
+	var invalid *service.ValidationError
+	assert.ErrorAs(suite.T(), err, &invalid)
+	assert.Equal(suite.T(), 0, id)
+	suite.mockAlertRep.AssertNotCalled(suite.T(), "CreateAlertRule", mock.Anything)
+}
+
+func (suite *AlertServiceTestSuite) TestCreateAlertRuleInvalidWindow() {
+	rule := models.AlertRule{UserId: 1, CityId: 2, Metric: models.AlertMetricPopAbove, Threshold: 50, WithinHours: 0}
+
+	_, err := suite.service.CreateAlertRule(context.Background(), rule)
+	var invalid *service.ValidationError
+	assert.ErrorAs(suite.T(), err, &invalid)
+	suite.mockAlertRep.AssertNotCalled(suite.T(), "CreateAlertRule", mock.Anything)
+}
+
+func (suite *AlertServiceTestSuite) TestCreateAlertRuleInvalidThreshold() {
+	rule := models.AlertRule{UserId: 1, CityId: 2, Metric: models.AlertMetricPopAbove, Threshold: 150, WithinHours: 24}
+
+	_, err := suite.service.CreateAlertRule(context.Background(), rule)
+	var invalid *service.ValidationError
+	assert.ErrorAs(suite.T(), err, &invalid)
+	suite.mockAlertRep.AssertNotCalled(suite.T(), "CreateAlertRule", mock.Anything)
+}
+
+func (suite *AlertServiceTestSuite) TestCreateAlertRuleUnknownCity() {
+	rule := models.AlertRule{UserId: 1, CityId: 999, Metric: models.AlertMetricTempBelow, Threshold: -5, WithinHours: 24}
+
+	suite.mockCitySvc.On("GetCity", 999).Return(models.City{}, sql.ErrNoRows)
+
+	_, err := suite.service.CreateAlertRule(context.Background(), rule)
+	var invalid *service.ValidationError
+	assert.ErrorAs(suite.T(), err, &invalid)
+	assert.Equal(suite.T(), "city 999 not found", err.Error())
+	suite.mockAlertRep.AssertNotCalled(suite.T(), "CreateAlertRule", mock.Anything)
+}
+
+func (suite *AlertServiceTestSuite) TestUpdateAlertRuleCityLookupFails() {
+	rule := models.AlertRule{Id: 1, UserId: 1, CityId: 2, Metric: models.AlertMetricTempBelow, Threshold: -5, WithinHours: 24}
+	lookupErr := errors.New("connection refused")
+
+	suite.mockCitySvc.On("GetCity", 2).Return(models.City{}, lookupErr)
+
+	err := suite.service.UpdateAlertRule(context.Background(), rule)
+	assert.ErrorIs(suite.T(), err, lookupErr)
+	var invalid *service.ValidationError
+	assert.False(suite.T(), errors.As(err, &invalid), "a failing storage is not the client's mistake")
+	suite.mockAlertRep.AssertNotCalled(suite.T(), "UpdateAlertRule", mock.Anything)
+}
+
+func (suite *AlertServiceTestSuite) TestUpdateAlertRule() {
+	rule := models.AlertRule{Id: 1, UserId: 1, CityId: 2, Metric: models.AlertMetricWindGustAbove, Threshold: 15, WithinHours: 6}
+
+	suite.mockCitySvc.On("GetCity", 2).Return(models.City{Id: 2}, nil)
+	suite.mockAlertRep.On("UpdateAlertRule", rule).Return(nil)
+
+	err := suite.service.UpdateAlertRule(context.Background(), rule)
+	assert.NoError(suite.T(), err)
+	suite.mockAlertRep.AssertExpectations(suite.T())
+}
+
+func (suite *AlertServiceTestSuite) TestDeleteAlertRule() {
+	suite.mockAlertRep.On("DeleteAlertRule", 1, 1).Return(errors.New("no rows deleted"))
+
+	err := suite.service.DeleteAlertRule(context.Background(), 1, 1)
+	assert.Error(suite.T(), err)
+	suite.mockAlertRep.AssertExpectations(suite.T())
+}
+
+func (suite *AlertServiceTestSuite) TestEvaluateAlerts() {
+	rules := []models.AlertRule{
+		{Id: 1, UserId: 1, CityId: 2, Metric: models.AlertMetricTempBelow, Threshold: 0, WithinHours: 12},
+		{Id: 2, UserId: 1, CityId: 2, Metric: models.AlertMetricWindGustAbove, Threshold: 15, WithinHours: 12},
+		{Id: 3, UserId: 3, CityId: 2, Metric: models.AlertMetricPopAbove, Threshold: 70, WithinHours: 12},
+	}
+	forecasts := []models.Forecast{
+		{CityId: 2, Temp: -3, Date: time.Now().Add(3 * time.Hour), ForecastJson: []byte(`{"pop":0.8,"wind":{"gust":10}}`)},
+		{CityId: 2, Temp: 4, Date: time.Now().Add(6 * time.Hour), ForecastJson: []byte(`{"pop":0.2,"wind":{"gust":18}}`)},
+		{CityId: 2, Temp: -8, Date: time.Now().Add(48 * time.Hour), ForecastJson: []byte(`{"pop":1,"wind":{"gust":30}}`)},
+	}
+
+	suite.mockAlertRep.On("GetCityAlertRules", 2).Return(rules, nil)
+	suite.mockAlertRep.On("CreateTriggeredAlert", mock.MatchedBy(func(a models.TriggeredAlert) bool {
+		return a.RuleId == 1
+	})).Return(10, nil)
+	suite.mockAlertRep.On("CreateTriggeredAlert", mock.MatchedBy(func(a models.TriggeredAlert) bool {
+		return a.RuleId == 2 && a.Value == 18
+	})).Return(11, nil)
+	suite.mockAlertRep.On("CreateTriggeredAlert", mock.MatchedBy(func(a models.TriggeredAlert) bool {
+		return a.RuleId == 3
+	})).Return(0, nil)
+
+	triggered, err := suite.service.EvaluateAlerts(context.Background(), 2, forecasts)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), triggered, 2)
+	assert.Equal(suite.T(), 10, triggered[0].Id)
+	assert.Equal(suite.T(), float64(-3), triggered[0].Value)
+	assert.Equal(suite.T(), 11, triggered[1].Id)
+	assert.Equal(suite.T(), 1, triggered[1].UserId)
+	suite.mockAlertRep.AssertNumberOfCalls(suite.T(), "CreateTriggeredAlert", 3)
+}
+
+func (suite *AlertServiceTestSuite) TestEvaluateAlertsRepositoryError() {
+	suite.mockAlertRep.On("GetCityAlertRules", 2).Return([]models.AlertRule(nil), errors.New("select error"))
+
+	triggered, err := suite.service.EvaluateAlerts(context.Background(), 2, nil)
+	assert.Error(suite.T(), err)
+	assert.Nil(suite.T(), triggered)
+}
+
+func TestAlertServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(AlertServiceTestSuite))
+}
@@ -1,33 +1,52 @@
 package cityservice
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"io"
-	"net/http"
 	"weather-app/internal/models"
+	"weather-app/internal/provider"
 	"weather-app/internal/repository"
 )
 
+const (
+	minUpdateInterval = 60
+	maxUpdateInterval = 24 * 60 * 60
+)
+
 type CityService struct {
-	cityRep repository.CityRepository
+	cityRep  repository.CityRepository
+	provider *provider.Client
 }
 
-func NewCityService(cityRep repository.CityRepository) *CityService {
+func NewCityService(cityRep repository.CityRepository, provider *provider.Client) *CityService {
 	return &CityService{
-		cityRep: cityRep,
+		cityRep:  cityRep,
+		provider: provider,
 	}
 }
 
-func (s *CityService) CreateCity(city models.City) (int, error) {
-	return s.cityRep.CreateCity(city)
+func (s *CityService) CreateCity(ctx context.Context, city models.City) (int, error) {
+	return s.cityRep.CreateCity(ctx, city)
 }
 
-func (s *CityService) GetCities() ([]models.City, error) {
-	return s.cityRep.GetCities()
+func (s *CityService) GetCities(ctx context.Context) ([]models.City, error) {
+	return s.cityRep.GetCities(ctx)
 }
-func (s *CityService) GetCity(cityId int) (models.City, error) {
-	return s.cityRep.GetCity(cityId)
+func (s *CityService) GetCity(ctx context.Context, cityId int) (models.City, error) {
+	return s.cityRep.GetCity(ctx, cityId)
+}
+
+func (s *CityService) GetCitySchedules(ctx context.Context) ([]models.CitySchedule, error) {
+	return s.cityRep.GetCitySchedules(ctx)
+}
+
+// UpdateCitySchedule sets how often the city is updated, in seconds. Zero
+// returns the city to the automatic schedule based on its popularity.
+func (s *CityService) UpdateCitySchedule(ctx context.Context, cityId int, interval int) error {
+	if interval != 0 && (interval < minUpdateInterval || interval > maxUpdateInterval) {
+		return fmt.Errorf("update_interval must be 0 or between %d and %d seconds", minUpdateInterval, maxUpdateInterval)
+	}
+	return s.cityRep.UpdateCityInterval(ctx, cityId, interval)
 }
 
 type geocodingResponse struct {
@@ -37,24 +56,13 @@ type geocodingResponse struct {
 	Lon     float64 `json:"lon"`
 }
 
-func (s *CityService) FetchCityData(cityName string, openWeatherAPIKey string) (models.City, error) {
+func (s *CityService) FetchCityData(ctx context.Context, cityName string, openWeatherAPIKey string) (models.City, error) {
 	var city models.City
 	url := fmt.Sprintf("http://api.openweathermap.org/geo/1.0/direct?q=%s&limit=1&appid=%s", cityName, openWeatherAPIKey)
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return city, fmt.Errorf("failed to make request to OpenWeather Geocoding API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return city, fmt.Errorf("failed to read response body: %w", err)
-	}
-
 	var geocodingResponses []geocodingResponse
-	if err := json.Unmarshal(body, &geocodingResponses); err != nil {
-		return city, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := s.provider.GetJSON(ctx, url, &geocodingResponses); err != nil {
+		return city, fmt.Errorf("failed to make request to OpenWeather Geocoding API: %w", err)
 	}
 
 	if len(geocodingResponses) == 0 {
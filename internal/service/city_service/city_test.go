@@ -1,10 +1,12 @@
 package cityservice
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"testing"
 	"weather-app/internal/models"
+	"weather-app/internal/provider"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
@@ -16,12 +18,12 @@ type MockCityRepository struct {
 	mock.Mock
 }
 
-func (m *MockCityRepository) CreateCity(city models.City) (int, error) {
+func (m *MockCityRepository) CreateCity(ctx context.Context, city models.City) (int, error) {
 	args := m.Called(city)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockCityRepository) GetCities() ([]models.City, error) {
+func (m *MockCityRepository) GetCities(ctx context.Context) ([]models.City, error) {
 	args := m.Called()
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
@@ -29,11 +31,21 @@ func (m *MockCityRepository) GetCities() ([]models.City, error) {
 	return args.Get(0).([]models.City), args.Error(1)
 }
 
-func (m *MockCityRepository) GetCity(cityId int) (models.City, error) {
+func (m *MockCityRepository) GetCity(ctx context.Context, cityId int) (models.City, error) {
 	args := m.Called(cityId)
 	return args.Get(0).(models.City), args.Error(1)
 }
 
+func (m *MockCityRepository) GetCitySchedules(ctx context.Context) ([]models.CitySchedule, error) {
+	args := m.Called()
+	return args.Get(0).([]models.CitySchedule), args.Error(1)
+}
+
+func (m *MockCityRepository) UpdateCityInterval(ctx context.Context, cityId int, interval int) error {
+	args := m.Called(cityId, interval)
+	return args.Error(0)
+}
+
 type CityServiceTestSuite struct {
 	suite.Suite
 	service  *CityService
@@ -43,7 +55,7 @@ type CityServiceTestSuite struct {
 
 func (suite *CityServiceTestSuite) SetupTest() {
 	suite.mockRepo = new(MockCityRepository)
-	suite.service = NewCityService(suite.mockRepo)
+	suite.service = NewCityService(suite.mockRepo, provider.NewClient(provider.Config{}))
 	suite.apiKey = "test-api-key"
 	httpmock.Activate()
 }
@@ -62,7 +74,7 @@ func (suite *CityServiceTestSuite) TestCreateCity() {
 
 	suite.mockRepo.On("CreateCity", city).Return(1, nil)
 
-	id, err := suite.service.CreateCity(city)
+	id, err := suite.service.CreateCity(context.Background(), city)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 1, id)
 	suite.mockRepo.AssertExpectations(suite.T())
@@ -78,7 +90,7 @@ func (suite *CityServiceTestSuite) TestCreateCityError() {
 
 	suite.mockRepo.On("CreateCity", city).Return(0, fmt.Errorf("insert error"))
 
-	id, err := suite.service.CreateCity(city)
+	id, err := suite.service.CreateCity(context.Background(), city)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), 0, id)
 	suite.mockRepo.AssertExpectations(suite.T())
@@ -104,7 +116,7 @@ func (suite *CityServiceTestSuite) TestGetCities() {
 
 	suite.mockRepo.On("GetCities").Return(cities, nil)
 
-	result, err := suite.service.GetCities()
+	result, err := suite.service.GetCities(context.Background())
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), cities, result)
 	suite.mockRepo.AssertExpectations(suite.T())
@@ -113,7 +125,7 @@ func (suite *CityServiceTestSuite) TestGetCities() {
 func (suite *CityServiceTestSuite) TestGetCitiesError() {
 	suite.mockRepo.On("GetCities").Return(nil, fmt.Errorf("select error"))
 
-	result, err := suite.service.GetCities()
+	result, err := suite.service.GetCities(context.Background())
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), result)
 	suite.mockRepo.AssertExpectations(suite.T())
@@ -130,7 +142,7 @@ func (suite *CityServiceTestSuite) TestGetCity() {
 
 	suite.mockRepo.On("GetCity", 1).Return(city, nil)
 
-	result, err := suite.service.GetCity(1)
+	result, err := suite.service.GetCity(context.Background(), 1)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), city, result)
 	suite.mockRepo.AssertExpectations(suite.T())
@@ -139,7 +151,7 @@ func (suite *CityServiceTestSuite) TestGetCity() {
 func (suite *CityServiceTestSuite) TestGetCityError() {
 	suite.mockRepo.On("GetCity", 1).Return(models.City{}, fmt.Errorf("select error"))
 
-	result, err := suite.service.GetCity(1)
+	result, err := suite.service.GetCity(context.Background(), 1)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), models.City{}, result)
 	suite.mockRepo.AssertExpectations(suite.T())
@@ -166,7 +178,7 @@ func (suite *CityServiceTestSuite) TestFetchCityData() {
 		Longitude: -0.1278,
 	}
 
-	result, err := suite.service.FetchCityData(cityName, suite.apiKey)
+	result, err := suite.service.FetchCityData(context.Background(), cityName, suite.apiKey)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), expectedCity, result)
 }
@@ -176,7 +188,7 @@ func (suite *CityServiceTestSuite) TestFetchCityDataNoResults() {
 	httpmock.RegisterResponder("GET", fmt.Sprintf("http://api.openweathermap.org/geo/1.0/direct?q=%s&limit=1&appid=%s", cityName, suite.apiKey),
 		httpmock.NewStringResponder(200, "[]"))
 
-	result, err := suite.service.FetchCityData(cityName, suite.apiKey)
+	result, err := suite.service.FetchCityData(context.Background(), cityName, suite.apiKey)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), models.City{}, result)
 	assert.Equal(suite.T(), fmt.Errorf("no results found for city: %s", cityName), err)
@@ -187,7 +199,7 @@ func (suite *CityServiceTestSuite) TestFetchCityDataError() {
 	httpmock.RegisterResponder("GET", fmt.Sprintf("http://api.openweathermap.org/geo/1.0/direct?q=%s&limit=1&appid=%s", cityName, suite.apiKey),
 		httpmock.NewErrorResponder(fmt.Errorf("network error")))
 
-	result, err := suite.service.FetchCityData(cityName, suite.apiKey)
+	result, err := suite.service.FetchCityData(context.Background(), cityName, suite.apiKey)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), models.City{}, result)
 }
@@ -197,7 +209,7 @@ func (suite *CityServiceTestSuite) TestFetchCityDataInvalidJSON() {
 	httpmock.RegisterResponder("GET", fmt.Sprintf("http://api.openweathermap.org/geo/1.0/direct?q=%s&limit=1&appid=%s", cityName, suite.apiKey),
 		httpmock.NewStringResponder(200, "{invalid json}"))
 
-	result, err := suite.service.FetchCityData(cityName, suite.apiKey)
+	result, err := suite.service.FetchCityData(context.Background(), cityName, suite.apiKey)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), models.City{}, result)
 }
@@ -207,11 +219,38 @@ func (suite *CityServiceTestSuite) TestFetchCityDataHTTPStatusError() {
 	httpmock.RegisterResponder("GET", fmt.Sprintf("http://api.openweathermap.org/geo/1.0/direct?q=%s&limit=1&appid=%s", cityName, suite.apiKey),
 		httpmock.NewStringResponder(500, "Internal Server Error"))
 
-	result, err := suite.service.FetchCityData(cityName, suite.apiKey)
+	result, err := suite.service.FetchCityData(context.Background(), cityName, suite.apiKey)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), models.City{}, result)
 }
 
+func (suite *CityServiceTestSuite) TestFetchCityDataProviderMessage() {
+	cityName := "London"
+	httpmock.RegisterResponder("GET", fmt.Sprintf("http://api.openweathermap.org/geo/1.0/direct?q=%s&limit=1&appid=%s", cityName, suite.apiKey),
+		httpmock.NewStringResponder(401, `{"cod":401,"message":"Invalid API key"}`))
+
+	_, err := suite.service.FetchCityData(context.Background(), cityName, suite.apiKey)
+	var providerErr *provider.Error
+	assert.ErrorAs(suite.T(), err, &providerErr)
+	assert.Equal(suite.T(), "Invalid API key", providerErr.Message)
+}
+
+func (suite *CityServiceTestSuite) TestUpdateCitySchedule() {
+	suite.mockRepo.On("UpdateCityInterval", 1, 600).Return(nil)
+	suite.mockRepo.On("UpdateCityInterval", 1, 0).Return(nil)
+
+	assert.NoError(suite.T(), suite.service.UpdateCitySchedule(context.Background(), 1, 600))
+	assert.NoError(suite.T(), suite.service.UpdateCitySchedule(context.Background(), 1, 0))
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+func (suite *CityServiceTestSuite) TestUpdateCityScheduleInvalidInterval() {
+	assert.Error(suite.T(), suite.service.UpdateCitySchedule(context.Background(), 1, 30))
+	assert.Error(suite.T(), suite.service.UpdateCitySchedule(context.Background(), 1, -60))
+	assert.Error(suite.T(), suite.service.UpdateCitySchedule(context.Background(), 1, 2*24*60*60))
+	suite.mockRepo.AssertNotCalled(suite.T(), "UpdateCityInterval", mock.Anything, mock.Anything)
+}
+
 func TestCityServiceTestSuite(t *testing.T) {
 	suite.Run(t, new(CityServiceTestSuite))
 }
@@ -0,0 +1,171 @@
+package collectorservice
+
+import (
+	"context"
+	"sync"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/provider"
+	"weather-app/internal/repository"
+	"weather-app/internal/service"
+)
+
+const (
+	defaultRunsLimit = 20
+	maxRunsLimit     = 100
+	// deadLetterFailures failed updates in a row put a city in the dead
+	// letter list.
+	deadLetterFailures = 3
+)
+
+type CollectorService struct {
+	runRep repository.CollectorRunRepository
+	client *provider.Client
+
+	mu      sync.Mutex
+	trigger chan struct{}
+}
+
+func NewCollectorService(runRep repository.CollectorRunRepository, client *provider.Client) *CollectorService {
+	return &CollectorService{
+		runRep: runRep,
+		client: client,
+	}
+}
+
+func (s *CollectorService) StartRun(ctx context.Context, triggeredBy string) (models.CollectorRun, error) {
+	run := models.CollectorRun{
+		TriggeredBy: triggeredBy,
+		StartedAt:   time.Now(),
+	}
+	id, err := s.runRep.CreateCollectorRun(ctx, run)
+	if err != nil {
+		return models.CollectorRun{}, err
+	}
+	run.Id = id
+	return run, nil
+}
+
+func (s *CollectorService) FinishRun(ctx context.Context, run models.CollectorRun) error {
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	return s.runRep.FinishCollectorRun(ctx, run)
+}
+
+func (s *CollectorService) GetRuns(ctx context.Context, limit int) ([]models.CollectorRun, error) {
+	if limit <= 0 {
+		limit = defaultRunsLimit
+	}
+	if limit > maxRunsLimit {
+		limit = maxRunsLimit
+	}
+	runs, err := s.runRep.GetCollectorRuns(ctx, limit)
+	if err != nil {
+		return nil, err
+	}
+	if runs == nil {
+		runs = []models.CollectorRun{}
+	}
+	return runs, nil
+}
+
+func (s *CollectorService) GetStatus(ctx context.Context) (models.CollectorStatus, error) {
+	s.mu.Lock()
+	status := models.CollectorStatus{Running: s.trigger != nil}
+	s.mu.Unlock()
+
+	// Runs are listed newest first, so an unfinished run can only be at the
+	// head; older unfinished runs were interrupted and never completed.
+	runs, err := s.runRep.GetCollectorRuns(ctx, defaultRunsLimit)
+	if err != nil {
+		return models.CollectorStatus{}, err
+	}
+	for i := range runs {
+		if runs[i].FinishedAt == nil {
+			if i == 0 && status.Running {
+				status.CurrentRun = &runs[i]
+			}
+			continue
+		}
+		status.LastRun = &runs[i]
+		break
+	}
+
+	if s.client != nil {
+		status.ProviderRequests, status.ProviderDailyQuota, err = s.client.Usage(ctx)
+		if err != nil {
+			return models.CollectorStatus{}, err
+		}
+		if err := s.client.Unavailable(ctx); err != nil {
+			status.ProviderUnavailable = err.Error()
+		}
+	}
+	return status, nil
+}
+
+// Trigger asks the attached collector to run over all cities. Triggers that
+// arrive while one is already pending are merged into it.
+func (s *CollectorService) Trigger(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.trigger == nil {
+		return service.ErrCollectorNotRunning
+	}
+	select {
+	case s.trigger <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// AttachCollector registers the collector running in this process and returns
+// the channel it receives triggers on, along with a function to detach it.
+func (s *CollectorService) AttachCollector() (<-chan struct{}, func()) {
+	trigger := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	s.trigger = trigger
+	s.mu.Unlock()
+
+	return trigger, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.trigger == trigger {
+			s.trigger = nil
+		}
+	}
+}
+
+// RecordCityFailure counts a failed update of the city. It reports whether
+// the city has just entered the dead letter list.
+func (s *CollectorService) RecordCityFailure(ctx context.Context, cityId int, reason error) (bool, error) {
+	failures, err := s.runRep.RecordCityFailure(ctx, cityId, reason.Error(), time.Now())
+	if err != nil {
+		return false, err
+	}
+	return failures == deadLetterFailures, nil
+}
+
+// ClearCityFailures is called after a successful update and takes the city
+// off the dead letter list.
+func (s *CollectorService) ClearCityFailures(ctx context.Context, cityId int) error {
+	return s.runRep.ClearCityFailures(ctx, cityId)
+}
+
+func (s *CollectorService) GetDeadLetters(ctx context.Context) ([]models.DeadLetter, error) {
+	deadLetters, err := s.runRep.GetDeadLetters(ctx, deadLetterFailures)
+	if err != nil {
+		return nil, err
+	}
+	if deadLetters == nil {
+		deadLetters = []models.DeadLetter{}
+	}
+	return deadLetters, nil
+}
+
+// PruneRuns deletes a batch of runs started before before and returns its
+// size.
+func (s *CollectorService) PruneRuns(ctx context.Context, before time.Time, limit int) (int, error) {
+	return s.runRep.PruneCollectorRuns(ctx, before, limit)
+}
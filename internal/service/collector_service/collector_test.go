@@ -0,0 +1,190 @@
+package collectorservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/provider"
+	"weather-app/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockCollectorRunRepository struct {
+	mock.Mock
+}
+
+func (m *MockCollectorRunRepository) CreateCollectorRun(ctx context.Context, run models.CollectorRun) (int, error) {
+	args := m.Called(run)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockCollectorRunRepository) FinishCollectorRun(ctx context.Context, run models.CollectorRun) error {
+	args := m.Called(run)
+	return args.Error(0)
+}
+
+func (m *MockCollectorRunRepository) GetCollectorRuns(ctx context.Context, limit int) ([]models.CollectorRun, error) {
+	args := m.Called(limit)
+	return args.Get(0).([]models.CollectorRun), args.Error(1)
+}
+
+func (m *MockCollectorRunRepository) RecordCityFailure(ctx context.Context, cityId int, reason string, failedAt time.Time) (int, error) {
+	args := m.Called(cityId, reason)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockCollectorRunRepository) ClearCityFailures(ctx context.Context, cityId int) error {
+	args := m.Called(cityId)
+	return args.Error(0)
+}
+
+func (m *MockCollectorRunRepository) GetDeadLetters(ctx context.Context, minFailures int) ([]models.DeadLetter, error) {
+	args := m.Called(minFailures)
+	return args.Get(0).([]models.DeadLetter), args.Error(1)
+}
+
+func (m *MockCollectorRunRepository) PruneCollectorRuns(ctx context.Context, before time.Time, limit int) (int, error) {
+	args := m.Called(before, limit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockCollectorRunRepository) AddProviderRequest(ctx context.Context, day time.Time, quota int) (bool, error) {
+	args := m.Called(day, quota)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockCollectorRunRepository) GetProviderRequests(ctx context.Context, day time.Time) (int, error) {
+	args := m.Called(day)
+	return args.Int(0), args.Error(1)
+}
+
+type CollectorServiceTestSuite struct {
+	suite.Suite
+	mockRunRep *MockCollectorRunRepository
+	service    *CollectorService
+}
+
+func (suite *CollectorServiceTestSuite) SetupTest() {
+	suite.mockRunRep = new(MockCollectorRunRepository)
+	suite.service = NewCollectorService(suite.mockRunRep, provider.NewClient(provider.Config{DailyQuota: 100}))
+}
+
+func (suite *CollectorServiceTestSuite) TestStartRun() {
+	suite.mockRunRep.On("CreateCollectorRun", mock.MatchedBy(func(run models.CollectorRun) bool {
+		return run.TriggeredBy == models.RunTriggerManual && !run.StartedAt.IsZero()
+	})).Return(5, nil)
+
+	run, err := suite.service.StartRun(context.Background(), models.RunTriggerManual)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 5, run.Id)
+	suite.mockRunRep.AssertExpectations(suite.T())
+}
+
+func (suite *CollectorServiceTestSuite) TestFinishRun() {
+	suite.mockRunRep.On("FinishCollectorRun", mock.MatchedBy(func(run models.CollectorRun) bool {
+		return run.Id == 5 && run.FinishedAt != nil && run.RowsWritten == 40
+	})).Return(nil)
+
+	err := suite.service.FinishRun(context.Background(), models.CollectorRun{Id: 5, RowsWritten: 40})
+	assert.NoError(suite.T(), err)
+	suite.mockRunRep.AssertExpectations(suite.T())
+}
+
+func (suite *CollectorServiceTestSuite) TestGetRunsClampsLimit() {
+	suite.mockRunRep.On("GetCollectorRuns", defaultRunsLimit).Return([]models.CollectorRun(nil), nil).Once()
+	suite.mockRunRep.On("GetCollectorRuns", maxRunsLimit).Return([]models.CollectorRun{{Id: 1}}, nil).Once()
+
+	runs, err := suite.service.GetRuns(context.Background(), 0)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.CollectorRun{}, runs)
+
+	runs, err = suite.service.GetRuns(context.Background(), 1000)
+	assert.NoError(suite.T(), err)
+	assert.Len(suite.T(), runs, 1)
+	suite.mockRunRep.AssertExpectations(suite.T())
+}
+
+func (suite *CollectorServiceTestSuite) TestGetStatus() {
+	finishedAt := time.Now()
+	suite.mockRunRep.On("GetCollectorRuns", defaultRunsLimit).Return([]models.CollectorRun{
+		{Id: 3},
+		{Id: 2},
+		{Id: 1, FinishedAt: &finishedAt},
+	}, nil)
+	_, detach := suite.service.AttachCollector()
+	defer detach()
+
+	status, err := suite.service.GetStatus(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.True(suite.T(), status.Running)
+	suite.Require().NotNil(status.CurrentRun)
+	assert.Equal(suite.T(), 3, status.CurrentRun.Id)
+	suite.Require().NotNil(status.LastRun)
+	assert.Equal(suite.T(), 1, status.LastRun.Id)
+	assert.Equal(suite.T(), 100, status.ProviderDailyQuota)
+	assert.Empty(suite.T(), status.ProviderUnavailable)
+}
+
+func (suite *CollectorServiceTestSuite) TestGetStatusNotRunning() {
+	suite.mockRunRep.On("GetCollectorRuns", defaultRunsLimit).Return([]models.CollectorRun{{Id: 3}}, nil)
+
+	status, err := suite.service.GetStatus(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.False(suite.T(), status.Running)
+	assert.Nil(suite.T(), status.CurrentRun)
+	assert.Nil(suite.T(), status.LastRun)
+}
+
+func (suite *CollectorServiceTestSuite) TestGetStatusError() {
+	suite.mockRunRep.On("GetCollectorRuns", defaultRunsLimit).Return([]models.CollectorRun(nil), errors.New("db error"))
+
+	_, err := suite.service.GetStatus(context.Background())
+	assert.Error(suite.T(), err)
+}
+
+func (suite *CollectorServiceTestSuite) TestTrigger() {
+	err := suite.service.Trigger(context.Background())
+	assert.ErrorIs(suite.T(), err, service.ErrCollectorNotRunning)
+
+	trigger, detach := suite.service.AttachCollector()
+	assert.NoError(suite.T(), suite.service.Trigger(context.Background()))
+	assert.NoError(suite.T(), suite.service.Trigger(context.Background()))
+	assert.Len(suite.T(), trigger, 1)
+
+	detach()
+	err = suite.service.Trigger(context.Background())
+	assert.ErrorIs(suite.T(), err, service.ErrCollectorNotRunning)
+}
+
+func (suite *CollectorServiceTestSuite) TestRecordCityFailure() {
+	suite.mockRunRep.On("RecordCityFailure", 7, "timeout").Return(deadLetterFailures-1, nil).Once()
+	suite.mockRunRep.On("RecordCityFailure", 7, "timeout").Return(deadLetterFailures, nil).Once()
+	suite.mockRunRep.On("RecordCityFailure", 7, "timeout").Return(deadLetterFailures+1, nil).Once()
+
+	var entered []bool
+	for i := 0; i < 3; i++ {
+		deadLettered, err := suite.service.RecordCityFailure(context.Background(), 7, errors.New("timeout"))
+		suite.Require().NoError(err)
+		entered = append(entered, deadLettered)
+	}
+	assert.Equal(suite.T(), []bool{false, true, false}, entered)
+	suite.mockRunRep.AssertExpectations(suite.T())
+}
+
+func (suite *CollectorServiceTestSuite) TestGetDeadLetters() {
+	suite.mockRunRep.On("GetDeadLetters", deadLetterFailures).Return([]models.DeadLetter(nil), nil)
+
+	deadLetters, err := suite.service.GetDeadLetters(context.Background())
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.DeadLetter{}, deadLetters)
+	suite.mockRunRep.AssertExpectations(suite.T())
+}
+
+func TestCollectorServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(CollectorServiceTestSuite))
+}
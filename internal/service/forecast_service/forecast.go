@@ -1,32 +1,187 @@
 package forecastservice
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"sort"
 	"time"
+	"weather-app/internal/cache"
 	"weather-app/internal/models"
+	"weather-app/internal/provider"
 	"weather-app/internal/repository"
 	"weather-app/internal/service"
+	"weather-app/internal/tracing"
+
+	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type ForecastService struct {
 	cityService service.CityService
 	forecastRep repository.ForecastRepository
+	provider    *provider.Client
+	cache       cache.Cache
 }
 
-func NewForecastService(cityservice service.CityService, forecastRep repository.ForecastRepository) *ForecastService {
+// NewForecastService creates the forecast service. Short and detailed
+// forecasts are kept in responseCache until the city's forecasts change; a nil
+// cache disables caching.
+func NewForecastService(cityservice service.CityService, forecastRep repository.ForecastRepository, provider *provider.Client, responseCache cache.Cache) *ForecastService {
 	return &ForecastService{
 		cityService: cityservice,
 		forecastRep: forecastRep,
+		provider:    provider,
+		cache:       responseCache,
+	}
+}
+
+func (s *ForecastService) CreateForecast(ctx context.Context, forecast models.Forecast) (int, error) {
+	id, err := s.forecastRep.CreateForecast(ctx, forecast)
+	if err != nil {
+		return 0, err
+	}
+	s.invalidate(ctx, cityCachePrefix(forecast.CityId))
+	return id, nil
+}
+
+// ReplaceForecasts stores a freshly fetched forecast of the city in one
+// transaction, so readers never see it half-updated.
+func (s *ForecastService) ReplaceForecasts(ctx context.Context, cityId int, forecasts []models.Forecast) (_ int, err error) {
+	ctx, span := tracing.Start(ctx, "ForecastService.ReplaceForecasts", cityIdAttribute(cityId))
+	defer func() { tracing.End(span, err) }()
+
+	written, err := s.forecastRep.ReplaceForecasts(ctx, cityId, forecasts)
+	if err != nil {
+		return 0, err
+	}
+	s.invalidate(ctx, cityCachePrefix(cityId))
+	return written, nil
+}
+
+// cachePrefix groups the keys of all cached forecasts.
+const cachePrefix = "forecast:"
+
+func cityCachePrefix(cityId int) string {
+	return fmt.Sprintf("%s%d:", cachePrefix, cityId)
+}
+
+// cacheKey returns the key caching the response name about the city, or
+// false if the response must not be cached. Keys carry the time the city's
+// forecasts were last stored. A response computed from forecasts replaced in
+// the meantime, by this process or a collector in another one, is stored
+// under a key that is no longer read.
+func (s *ForecastService) cacheKey(ctx context.Context, cityId int, name string) (string, bool) {
+	if s.cache == nil {
+		return "", false
+	}
+	// Unknown cities and storage errors are reported by the uncached read.
+	freshness, err := s.forecastRep.GetForecastFreshness(ctx, cityId)
+	if err != nil {
+		return "", false
+	}
+	return fmt.Sprintf("%s%d:%s", cityCachePrefix(cityId), freshness.UpdatedAt.UnixNano(), name), true
+}
+
+func cityIdAttribute(cityId int) attribute.KeyValue {
+	return attribute.Int("city.id", cityId)
+}
+
+// cached decodes the value stored under key into dst and reports whether it
+// was found, also noting it on the span in ctx. Cache failures are logged and
+// treated as misses.
+func (s *ForecastService) cached(ctx context.Context, key string, dst any) bool {
+	if s.cache == nil {
+		return false
+	}
+	data, ok, err := s.cache.Get(ctx, key)
+	if err != nil {
+		logrus.WithContext(ctx).Warnf("Failed to read %s from the cache: %v", key, err)
+		return false
+	}
+	hit := ok && json.Unmarshal(data, dst) == nil
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Bool("cache.hit", hit))
+	return hit
+}
+
+func (s *ForecastService) store(ctx context.Context, key string, value any) {
+	if s.cache == nil {
+		return
+	}
+	data, err := json.Marshal(value)
+	if err == nil {
+		err = s.cache.Set(ctx, key, data)
+	}
+	if err != nil {
+		logrus.WithContext(ctx).Warnf("Failed to cache %s: %v", key, err)
+	}
+}
+
+func (s *ForecastService) invalidate(ctx context.Context, prefix string) {
+	if s.cache == nil {
+		return
+	}
+	if err := s.cache.DeletePrefix(ctx, prefix); err != nil {
+		logrus.WithContext(ctx).Warnf("Failed to invalidate cached forecasts %s*: %v", prefix, err)
 	}
 }
 
-func (s *ForecastService) CreateForecast(forecast models.Forecast) (int, error) {
-	return s.forecastRep.CreateForecast(forecast)
+// GetForecastHistory returns the daily aggregates of the city's forecasts
+// that passed their retention, oldest first.
+func (s *ForecastService) GetForecastHistory(ctx context.Context, cityId int) (_ []models.DailyForecast, err error) {
+	ctx, span := tracing.Start(ctx, "ForecastService.GetForecastHistory", cityIdAttribute(cityId))
+	defer func() { tracing.End(span, err) }()
+
+	if _, err := s.cityService.GetCity(ctx, cityId); err != nil {
+		return nil, err
+	}
+	daily, err := s.forecastRep.GetDailyForecasts(ctx, cityId)
+	if err != nil {
+		return nil, err
+	}
+	if daily == nil {
+		daily = []models.DailyForecast{}
+	}
+	return daily, nil
+}
+
+// RollupForecasts moves a batch of forecasts dated before the day of before
+// into daily aggregates and returns its size.
+func (s *ForecastService) RollupForecasts(ctx context.Context, before time.Time, limit int) (int, error) {
+	rolledUp, err := s.forecastRep.RollupForecasts(ctx, before, limit)
+	if rolledUp > 0 {
+		s.invalidate(ctx, cachePrefix)
+	}
+	return rolledUp, err
+}
+
+// PruneDailyForecasts deletes a batch of daily aggregates dated before the
+// day of before and returns its size.
+func (s *ForecastService) PruneDailyForecasts(ctx context.Context, before time.Time, limit int) (int, error) {
+	return s.forecastRep.PruneDailyForecasts(ctx, before, limit)
+}
+
+// SetForecastFreshness records when the city's forecasts were stored and when
+// the collector updates them next.
+func (s *ForecastService) SetForecastFreshness(ctx context.Context, freshness models.ForecastFreshness) error {
+	return s.forecastRep.SetForecastFreshness(ctx, freshness)
+}
+
+// GetForecastFreshness tells when the city's forecasts were stored and when
+// they change next, which lets clients revalidate their copies.
+func (s *ForecastService) GetForecastFreshness(ctx context.Context, cityId int) (_ models.ForecastFreshness, err error) {
+	ctx, span := tracing.Start(ctx, "ForecastService.GetForecastFreshness", cityIdAttribute(cityId))
+	defer func() { tracing.End(span, err) }()
+
+	return s.forecastRep.GetForecastFreshness(ctx, cityId)
+}
+
+// GetAllForecastFreshness tells when the forecasts of each city that has them
+// were stored.
+func (s *ForecastService) GetAllForecastFreshness(ctx context.Context) ([]models.ForecastFreshness, error) {
+	return s.forecastRep.GetAllForecastFreshness(ctx)
 }
 
 func filterFutureForecasts(forecasts []models.Forecast) []models.Forecast {
@@ -40,14 +195,20 @@ func filterFutureForecasts(forecasts []models.Forecast) []models.Forecast {
 	return futureForecasts
 }
 
-func (s *ForecastService) GetShortForecast(cityId int) (models.ForecastSummary, error) {
-	var summary models.ForecastSummary
-	city, err := s.cityService.GetCity(cityId)
+func (s *ForecastService) GetShortForecast(ctx context.Context, cityId int) (summary models.ForecastSummary, err error) {
+	ctx, span := tracing.Start(ctx, "ForecastService.GetShortForecast", cityIdAttribute(cityId))
+	defer func() { tracing.End(span, err) }()
+
+	key, cacheable := s.cacheKey(ctx, cityId, "short")
+	if cacheable && s.cached(ctx, key, &summary) {
+		return summary, nil
+	}
+	city, err := s.cityService.GetCity(ctx, cityId)
 	if err != nil {
 		return summary, err
 	}
 	summary.City, summary.Country = city.Name, city.Country
-	forecasts, err := s.forecastRep.GetForecasts(cityId)
+	forecasts, err := s.forecastRep.GetForecasts(ctx, cityId)
 	if err != nil {
 		return summary, err
 	}
@@ -74,6 +235,9 @@ func (s *ForecastService) GetShortForecast(cityId int) (models.ForecastSummary,
 	} else {
 		summary.AvgTemp = 0
 	}
+	if cacheable {
+		s.store(ctx, key, summary)
+	}
 	return summary, nil
 }
 
@@ -98,18 +262,29 @@ func filterForecastsByDateTime(forecasts []models.Forecast, target time.Time) []
 	return filtered
 }
 
-func (s *ForecastService) GetDetailedForecast(cityId int, date time.Time) ([]models.Forecast, error) {
-	forecasts, err := s.forecastRep.GetForecasts(cityId)
+func (s *ForecastService) GetDetailedForecast(ctx context.Context, cityId int, date time.Time) (_ []models.Forecast, err error) {
+	ctx, span := tracing.Start(ctx, "ForecastService.GetDetailedForecast", cityIdAttribute(cityId))
+	defer func() { tracing.End(span, err) }()
+
+	var filtered []models.Forecast
+	key, cacheable := s.cacheKey(ctx, cityId, "detailed:"+date.Format(time.RFC3339))
+	if cacheable && s.cached(ctx, key, &filtered) {
+		return filtered, nil
+	}
+	forecasts, err := s.forecastRep.GetForecasts(ctx, cityId)
 	if err != nil {
 		return nil, err
 	}
-	filtered := filterForecastsByDateTime(forecasts, date)
+	filtered = filterForecastsByDateTime(forecasts, date)
 	if len(filtered) == 0 {
 		return nil, errors.New("no forecasts were found")
 	}
 	sort.Slice(forecasts, func(i, j int) bool {
 		return forecasts[i].Date.Before(forecasts[j].Date)
 	})
+	if cacheable {
+		s.store(ctx, key, filtered)
+	}
 	return filtered, nil
 }
 
@@ -156,25 +331,17 @@ type forecastResponse struct {
 	List []forecastItem `json:"list"`
 }
 
-func (s *ForecastService) FetchForecastData(city models.City, openWeatherAPIKey string) ([]models.Forecast, error) {
+func (s *ForecastService) FetchForecastData(ctx context.Context, city models.City, openWeatherAPIKey string) (_ []models.Forecast, err error) {
+	ctx, span := tracing.Start(ctx, "ForecastService.FetchForecastData", cityIdAttribute(city.Id))
+	defer func() { tracing.End(span, err) }()
+
 	var forecasts []models.Forecast
 
 	url := fmt.Sprintf("http://api.openweathermap.org/data/2.5/forecast?lat=%f&lon=%f&units=metric&appid=%s", city.Latitude, city.Longitude, openWeatherAPIKey)
 
-	resp, err := http.Get(url)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request to OpenWeather API: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
-	}
-
 	var forecastResponse forecastResponse
-	if err := json.Unmarshal(body, &forecastResponse); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := s.provider.GetJSON(ctx, url, &forecastResponse); err != nil {
+		return nil, fmt.Errorf("failed to make request to OpenWeather API: %w", err)
 	}
 
 	for _, item := range forecastResponse.List {
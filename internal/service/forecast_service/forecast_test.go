@@ -1,11 +1,14 @@
 package forecastservice
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"testing"
 	"time"
+	"weather-app/internal/cache"
 	"weather-app/internal/models"
+	"weather-app/internal/provider"
 
 	"github.com/jarcoal/httpmock"
 	"github.com/stretchr/testify/assert"
@@ -17,36 +20,81 @@ type MockCityService struct {
 	mock.Mock
 }
 
-func (m *MockCityService) CreateCity(city models.City) (int, error) {
+func (m *MockCityService) CreateCity(ctx context.Context, city models.City) (int, error) {
 	args := m.Called(city)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockCityService) GetCities() ([]models.City, error) {
+func (m *MockCityService) GetCities(ctx context.Context) ([]models.City, error) {
 	args := m.Called()
 	return args.Get(0).([]models.City), args.Error(1)
 }
 
-func (m *MockCityService) GetCity(cityId int) (models.City, error) {
+func (m *MockCityService) GetCity(ctx context.Context, cityId int) (models.City, error) {
 	args := m.Called(cityId)
 	return args.Get(0).(models.City), args.Error(1)
 }
 
-func (m *MockCityService) FetchCityData(cityName string, openWeatherAPIKey string) (models.City, error) {
+func (m *MockCityService) FetchCityData(ctx context.Context, cityName string, openWeatherAPIKey string) (models.City, error) {
 	args := m.Called(cityName, openWeatherAPIKey)
 	return args.Get(0).(models.City), args.Error(1)
 }
 
+func (m *MockCityService) GetCitySchedules(ctx context.Context) ([]models.CitySchedule, error) {
+	args := m.Called()
+	return args.Get(0).([]models.CitySchedule), args.Error(1)
+}
+
+func (m *MockCityService) UpdateCitySchedule(ctx context.Context, cityId int, interval int) error {
+	args := m.Called(cityId, interval)
+	return args.Error(0)
+}
+
 type MockForecastRepository struct {
 	mock.Mock
 }
 
-func (m *MockForecastRepository) CreateForecast(forecast models.Forecast) (int, error) {
+func (m *MockForecastRepository) CreateForecast(ctx context.Context, forecast models.Forecast) (int, error) {
 	args := m.Called(forecast)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockForecastRepository) GetForecasts(cityId int) ([]models.Forecast, error) {
+func (m *MockForecastRepository) ReplaceForecasts(ctx context.Context, cityId int, forecasts []models.Forecast) (int, error) {
+	args := m.Called(cityId, forecasts)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockForecastRepository) RollupForecasts(ctx context.Context, before time.Time, limit int) (int, error) {
+	args := m.Called(before, limit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockForecastRepository) GetDailyForecasts(ctx context.Context, cityId int) ([]models.DailyForecast, error) {
+	args := m.Called(cityId)
+	return args.Get(0).([]models.DailyForecast), args.Error(1)
+}
+
+func (m *MockForecastRepository) PruneDailyForecasts(ctx context.Context, before time.Time, limit int) (int, error) {
+	args := m.Called(before, limit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockForecastRepository) SetForecastFreshness(ctx context.Context, freshness models.ForecastFreshness) error {
+	args := m.Called(freshness)
+	return args.Error(0)
+}
+
+func (m *MockForecastRepository) GetForecastFreshness(ctx context.Context, cityId int) (models.ForecastFreshness, error) {
+	args := m.Called(cityId)
+	return args.Get(0).(models.ForecastFreshness), args.Error(1)
+}
+
+func (m *MockForecastRepository) GetAllForecastFreshness(ctx context.Context) ([]models.ForecastFreshness, error) {
+	args := m.Called()
+	return args.Get(0).([]models.ForecastFreshness), args.Error(1)
+}
+
+func (m *MockForecastRepository) GetForecasts(ctx context.Context, cityId int) ([]models.Forecast, error) {
 	args := m.Called(cityId)
 	return args.Get(0).([]models.Forecast), args.Error(1)
 }
@@ -62,7 +110,7 @@ type ForecastServiceTestSuite struct {
 func (suite *ForecastServiceTestSuite) SetupTest() {
 	suite.mockCitySvc = new(MockCityService)
 	suite.mockForecastRep = new(MockForecastRepository)
-	suite.service = NewForecastService(suite.mockCitySvc, suite.mockForecastRep)
+	suite.service = NewForecastService(suite.mockCitySvc, suite.mockForecastRep, provider.NewClient(provider.Config{}), nil)
 	suite.apiKey = "test-api-key"
 	httpmock.Activate()
 }
@@ -81,12 +129,41 @@ func (suite *ForecastServiceTestSuite) TestCreateForecast() {
 
 	suite.mockForecastRep.On("CreateForecast", forecast).Return(1, nil)
 
-	id, err := suite.service.CreateForecast(forecast)
+	id, err := suite.service.CreateForecast(context.Background(), forecast)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 1, id)
 	suite.mockForecastRep.AssertExpectations(suite.T())
 }
 
+func (suite *ForecastServiceTestSuite) TestReplaceForecasts() {
+	forecasts := []models.Forecast{{CityId: 1, Temp: 20.5, Date: time.Now(), ForecastJson: []byte(`{"weather":"sunny"}`)}}
+
+	suite.mockForecastRep.On("ReplaceForecasts", 1, forecasts).Return(1, nil)
+
+	written, err := suite.service.ReplaceForecasts(context.Background(), 1, forecasts)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, written)
+	suite.mockForecastRep.AssertExpectations(suite.T())
+}
+
+func (suite *ForecastServiceTestSuite) TestGetForecastHistory() {
+	suite.mockCitySvc.On("GetCity", 1).Return(models.City{Id: 1}, nil)
+	suite.mockForecastRep.On("GetDailyForecasts", 1).Return([]models.DailyForecast(nil), nil)
+
+	daily, err := suite.service.GetForecastHistory(context.Background(), 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), []models.DailyForecast{}, daily)
+	suite.mockForecastRep.AssertExpectations(suite.T())
+}
+
+func (suite *ForecastServiceTestSuite) TestGetForecastHistoryUnknownCity() {
+	suite.mockCitySvc.On("GetCity", 999).Return(models.City{}, fmt.Errorf("sql: no rows in result set"))
+
+	_, err := suite.service.GetForecastHistory(context.Background(), 999)
+	assert.Error(suite.T(), err)
+	suite.mockForecastRep.AssertNotCalled(suite.T(), "GetDailyForecasts", mock.Anything)
+}
+
 func (suite *ForecastServiceTestSuite) TestCreateForecastError() {
 	forecast := models.Forecast{
 		CityId:       1,
@@ -97,7 +174,7 @@ func (suite *ForecastServiceTestSuite) TestCreateForecastError() {
 
 	suite.mockForecastRep.On("CreateForecast", forecast).Return(0, fmt.Errorf("insert error"))
 
-	id, err := suite.service.CreateForecast(forecast)
+	id, err := suite.service.CreateForecast(context.Background(), forecast)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), 0, id)
 	suite.mockForecastRep.AssertExpectations(suite.T())
@@ -130,7 +207,7 @@ func (suite *ForecastServiceTestSuite) TestGetShortForecast() {
 	suite.mockCitySvc.On("GetCity", 1).Return(city, nil)
 	suite.mockForecastRep.On("GetForecasts", 1).Return(forecasts, nil)
 
-	result, err := suite.service.GetShortForecast(1)
+	result, err := suite.service.GetShortForecast(context.Background(), 1)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), city.Name, result.City)
 	assert.Equal(suite.T(), city.Country, result.Country)
@@ -140,10 +217,51 @@ func (suite *ForecastServiceTestSuite) TestGetShortForecast() {
 	suite.mockForecastRep.AssertExpectations(suite.T())
 }
 
+func (suite *ForecastServiceTestSuite) TestGetShortForecastCached() {
+	suite.service.cache = cache.NewLRU(10, time.Minute)
+	forecasts := []models.Forecast{{CityId: 1, Temp: 20, Date: time.Now().Add(24 * time.Hour), ForecastJson: []byte(`{}`)}}
+	suite.mockCitySvc.On("GetCity", 1).Return(models.City{Id: 1, Name: "London"}, nil)
+	suite.mockForecastRep.On("GetForecastFreshness", 1).Return(models.ForecastFreshness{CityId: 1, UpdatedAt: time.Now()}, nil)
+	suite.mockForecastRep.On("GetForecasts", 1).Return(forecasts, nil).Once()
+
+	first, err := suite.service.GetShortForecast(context.Background(), 1)
+	suite.Require().NoError(err)
+	second, err := suite.service.GetShortForecast(context.Background(), 1)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), first, second)
+	suite.mockForecastRep.AssertNumberOfCalls(suite.T(), "GetForecasts", 1)
+
+	suite.mockForecastRep.On("ReplaceForecasts", 1, forecasts).Return(1, nil)
+	suite.mockForecastRep.On("GetForecasts", 1).Return(forecasts, nil).Once()
+	_, err = suite.service.ReplaceForecasts(context.Background(), 1, forecasts)
+	suite.Require().NoError(err)
+	_, err = suite.service.GetShortForecast(context.Background(), 1)
+	suite.Require().NoError(err)
+	suite.mockForecastRep.AssertNumberOfCalls(suite.T(), "GetForecasts", 2)
+}
+
+func (suite *ForecastServiceTestSuite) TestGetShortForecastNotCachedAcrossUpdates() {
+	suite.service.cache = cache.NewLRU(10, time.Minute)
+	forecasts := []models.Forecast{{CityId: 1, Temp: 20, Date: time.Now().Add(24 * time.Hour), ForecastJson: []byte(`{}`)}}
+	storedAt := time.Now()
+	suite.mockCitySvc.On("GetCity", 1).Return(models.City{Id: 1, Name: "London"}, nil)
+	suite.mockForecastRep.On("GetForecasts", 1).Return(forecasts, nil)
+	// The forecasts are replaced, possibly by another process, after the first
+	// read looked up its cache key: its result is stale before it is stored.
+	suite.mockForecastRep.On("GetForecastFreshness", 1).Return(models.ForecastFreshness{CityId: 1, UpdatedAt: storedAt}, nil).Once()
+	suite.mockForecastRep.On("GetForecastFreshness", 1).Return(models.ForecastFreshness{CityId: 1, UpdatedAt: storedAt.Add(time.Minute)}, nil).Once()
+
+	_, err := suite.service.GetShortForecast(context.Background(), 1)
+	suite.Require().NoError(err)
+	_, err = suite.service.GetShortForecast(context.Background(), 1)
+	suite.Require().NoError(err)
+	suite.mockForecastRep.AssertNumberOfCalls(suite.T(), "GetForecasts", 2)
+}
+
 func (suite *ForecastServiceTestSuite) TestGetShortForecastError() {
 	suite.mockCitySvc.On("GetCity", 1).Return(models.City{}, fmt.Errorf("city not found"))
 
-	result, err := suite.service.GetShortForecast(1)
+	result, err := suite.service.GetShortForecast(context.Background(), 1)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), models.ForecastSummary{}, result)
 	suite.mockCitySvc.AssertExpectations(suite.T())
@@ -164,7 +282,7 @@ func (suite *ForecastServiceTestSuite) TestGetDetailedForecast() {
 
 	suite.mockForecastRep.On("GetForecasts", cityId).Return(forecasts, nil)
 
-	result, err := suite.service.GetDetailedForecast(cityId, date)
+	result, err := suite.service.GetDetailedForecast(context.Background(), cityId, date)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), forecasts, result)
 	suite.mockForecastRep.AssertExpectations(suite.T())
@@ -176,7 +294,7 @@ func (suite *ForecastServiceTestSuite) TestGetDetailedForecastNoResults() {
 
 	suite.mockForecastRep.On("GetForecasts", cityId).Return([]models.Forecast{}, nil)
 
-	result, err := suite.service.GetDetailedForecast(cityId, date)
+	result, err := suite.service.GetDetailedForecast(context.Background(), cityId, date)
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), result)
 	assert.Equal(suite.T(), "no forecasts were found", err.Error())
@@ -210,7 +328,7 @@ func (suite *ForecastServiceTestSuite) TestFetchForecastData() {
 		Date:   time.Now(),
 	}
 
-	result, err := suite.service.FetchForecastData(city, suite.apiKey)
+	result, err := suite.service.FetchForecastData(context.Background(), city, suite.apiKey)
 	assert.NoError(suite.T(), err)
 
 	assert.Equal(suite.T(), expectedForecast.CityId, result[0].CityId)
@@ -232,7 +350,7 @@ func (suite *ForecastServiceTestSuite) TestFetchForecastDataError() {
 	httpmock.RegisterResponder("GET", fmt.Sprintf("http://api.openweathermap.org/data/2.5/forecast?lat=%f&lon=%f&units=metric&appid=%s", city.Latitude, city.Longitude, suite.apiKey),
 		httpmock.NewErrorResponder(fmt.Errorf("network error")))
 
-	result, err := suite.service.FetchForecastData(city, suite.apiKey)
+	result, err := suite.service.FetchForecastData(context.Background(), city, suite.apiKey)
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), result)
 }
@@ -249,7 +367,7 @@ func (suite *ForecastServiceTestSuite) TestFetchForecastDataInvalidJSON() {
 	httpmock.RegisterResponder("GET", fmt.Sprintf("http://api.openweathermap.org/data/2.5/forecast?lat=%f&lon=%f&units=metric&appid=%s", city.Latitude, city.Longitude, suite.apiKey),
 		httpmock.NewStringResponder(200, "{invalid json}"))
 
-	result, err := suite.service.FetchForecastData(city, suite.apiKey)
+	result, err := suite.service.FetchForecastData(context.Background(), city, suite.apiKey)
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), result)
 }
@@ -266,7 +384,7 @@ func (suite *ForecastServiceTestSuite) TestFetchForecastDataHTTPStatusError() {
 	httpmock.RegisterResponder("GET", fmt.Sprintf("http://api.openweathermap.org/data/2.5/forecast?lat=%f&lon=%f&units=metric&appid=%s", city.Latitude, city.Longitude, suite.apiKey),
 		httpmock.NewStringResponder(500, "Internal Server Error"))
 
-	result, err := suite.service.FetchForecastData(city, suite.apiKey)
+	result, err := suite.service.FetchForecastData(context.Background(), city, suite.apiKey)
 	assert.Error(suite.T(), err)
 	assert.Nil(suite.T(), result)
 }
@@ -0,0 +1,153 @@
+package healthservice
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+
+	"github.com/sirupsen/logrus"
+)
+
+// ReadinessPolicy sets what the storage has to look like for the server to be
+// ready.
+type ReadinessPolicy struct {
+	// SchemaVersion is the migration the storage has to be at. Zero skips the
+	// check, as for the in-memory storage.
+	SchemaVersion uint
+	// MaxForecastAge is how old the newest stored forecasts may get, which
+	// tells that the data collector keeps running. Zero skips the check. The
+	// check also passes while no cities are tracked, and for MaxForecastAge
+	// after the start while none of them has forecasts yet, so that a fresh
+	// deployment becomes ready.
+	MaxForecastAge time.Duration
+	// Timeout bounds all checks together.
+	Timeout time.Duration
+}
+
+type HealthService struct {
+	healthRep   repository.HealthRepository
+	cityRep     repository.CityRepository
+	forecastRep repository.ForecastRepository
+	policy      ReadinessPolicy
+	now         func() time.Time
+	startedAt   time.Time
+}
+
+func NewHealthService(healthRep repository.HealthRepository, cityRep repository.CityRepository, forecastRep repository.ForecastRepository, policy ReadinessPolicy) *HealthService {
+	return &HealthService{
+		healthRep:   healthRep,
+		cityRep:     cityRep,
+		forecastRep: forecastRep,
+		policy:      policy,
+		now:         time.Now,
+		startedAt:   time.Now(),
+	}
+}
+
+// errStorage is what a check reports when the storage fails it. The response
+// is public, so the storage error itself is only logged.
+var errStorage = errors.New("storage is not available")
+
+// storageError logs err, which the storage returned to check, and hides it
+// behind errStorage.
+func storageError(check string, err error) error {
+	logrus.Errorf("Readiness check %s failed: %v", check, err)
+	return errStorage
+}
+
+type check struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// Ready runs the readiness checks and reports each outcome. The server is
+// ready only if all of them pass.
+func (s *HealthService) Ready(ctx context.Context) models.Readiness {
+	if s.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.policy.Timeout)
+		defer cancel()
+	}
+
+	checks := []check{{"database", s.checkDatabase}}
+	if s.policy.SchemaVersion > 0 {
+		checks = append(checks, check{"migrations", s.checkMigrations})
+	}
+	if s.policy.MaxForecastAge > 0 {
+		checks = append(checks, check{"forecasts", s.checkForecasts})
+	}
+
+	readiness := models.Readiness{Status: models.HealthOK, Checks: make([]models.HealthCheck, 0, len(checks))}
+	for _, check := range checks {
+		result := models.HealthCheck{Name: check.name, Status: models.HealthOK}
+		if err := check.run(ctx); err != nil {
+			result.Status, result.Error = models.HealthFailed, err.Error()
+			readiness.Status = models.HealthUnavailable
+		}
+		readiness.Checks = append(readiness.Checks, result)
+	}
+	return readiness
+}
+
+func (s *HealthService) checkDatabase(ctx context.Context) error {
+	if err := s.healthRep.Ping(ctx); err != nil {
+		return storageError("database", err)
+	}
+	return nil
+}
+
+// checkMigrations requires the schema to be at the version this build
+// expects, so that a server is not sent traffic before migrations finish.
+func (s *HealthService) checkMigrations(ctx context.Context) error {
+	version, dirty, err := s.healthRep.GetSchemaVersion(ctx)
+	if err != nil {
+		return storageError("migrations", err)
+	}
+	if dirty {
+		return fmt.Errorf("migration %d failed halfway and has to be fixed by hand", version)
+	}
+	if version != s.policy.SchemaVersion {
+		return fmt.Errorf("schema is at migration %d, want %d", version, s.policy.SchemaVersion)
+	}
+	return nil
+}
+
+// checkForecasts requires the newest forecasts of any city to be younger than
+// MaxForecastAge. A single city failing to update does not make the server
+// unready; the collector stopping does.
+func (s *HealthService) checkForecasts(ctx context.Context) error {
+	freshness, err := s.forecastRep.GetAllForecastFreshness(ctx)
+	if err != nil {
+		return storageError("forecasts", err)
+	}
+	var newest time.Time
+	for _, city := range freshness {
+		if city.UpdatedAt.After(newest) {
+			newest = city.UpdatedAt
+		}
+	}
+	if newest.IsZero() {
+		return s.checkNoForecasts(ctx)
+	}
+	if age := s.now().Sub(newest); age > s.policy.MaxForecastAge {
+		return fmt.Errorf("newest forecasts were stored %v ago, more than %v", age.Round(time.Second), s.policy.MaxForecastAge)
+	}
+	return nil
+}
+
+// checkNoForecasts decides about a storage without forecasts. There is nothing
+// to collect until a city is tracked, and the collector needs some time for
+// its first run.
+func (s *HealthService) checkNoForecasts(ctx context.Context) error {
+	cities, err := s.cityRep.GetCities(ctx)
+	if err != nil {
+		return storageError("forecasts", err)
+	}
+	if len(cities) == 0 || s.now().Sub(s.startedAt) <= s.policy.MaxForecastAge {
+		return nil
+	}
+	return fmt.Errorf("no forecasts stored in %v since the start", s.policy.MaxForecastAge)
+}
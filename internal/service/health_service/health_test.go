@@ -0,0 +1,138 @@
+package healthservice
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type stubHealthRepository struct {
+	pingErr error
+	version uint
+	dirty   bool
+}
+
+func (r *stubHealthRepository) Ping(ctx context.Context) error {
+	return r.pingErr
+}
+
+func (r *stubHealthRepository) GetSchemaVersion(ctx context.Context) (uint, bool, error) {
+	return r.version, r.dirty, r.pingErr
+}
+
+type HealthServiceTestSuite struct {
+	suite.Suite
+	service   *HealthService
+	healthRep *stubHealthRepository
+	cities    *memory.CityRepository
+	forecasts *memory.ForecastRepository
+	cityId    int
+	now       time.Time
+}
+
+func (suite *HealthServiceTestSuite) SetupTest() {
+	storage := memory.NewStorage()
+	suite.cities = memory.NewCityRepository(storage)
+	cityId, err := suite.cities.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+	suite.cityId = cityId
+
+	suite.healthRep = &stubHealthRepository{version: 11}
+	suite.forecasts = memory.NewForecastRepository(storage)
+	suite.service = NewHealthService(suite.healthRep, suite.cities, suite.forecasts, ReadinessPolicy{
+		SchemaVersion:  11,
+		MaxForecastAge: 2 * time.Hour,
+		Timeout:        time.Second,
+	})
+	suite.now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	suite.service.now = func() time.Time { return suite.now }
+	suite.service.startedAt = suite.now.Add(-3 * time.Hour)
+}
+
+func (suite *HealthServiceTestSuite) storeForecasts(age time.Duration) {
+	suite.Require().NoError(suite.forecasts.SetForecastFreshness(context.Background(), models.ForecastFreshness{
+		CityId:    suite.cityId,
+		UpdatedAt: suite.now.Add(-age),
+	}))
+}
+
+// failed returns the checks of readiness that failed, by name.
+func failed(readiness models.Readiness) map[string]string {
+	failed := make(map[string]string)
+	for _, check := range readiness.Checks {
+		if check.Status != models.HealthOK {
+			failed[check.Name] = check.Error
+		}
+	}
+	return failed
+}
+
+func (suite *HealthServiceTestSuite) TestReady() {
+	suite.storeForecasts(time.Hour)
+
+	readiness := suite.service.Ready(context.Background())
+	assert.Equal(suite.T(), models.HealthOK, readiness.Status)
+	assert.Len(suite.T(), readiness.Checks, 3)
+	assert.Empty(suite.T(), failed(readiness))
+}
+
+func (suite *HealthServiceTestSuite) TestDatabaseDown() {
+	suite.storeForecasts(time.Hour)
+	suite.healthRep.pingErr = errors.New("connection refused")
+
+	readiness := suite.service.Ready(context.Background())
+	assert.Equal(suite.T(), models.HealthUnavailable, readiness.Status)
+	assert.Equal(suite.T(), map[string]string{
+		"database":   "storage is not available",
+		"migrations": "storage is not available",
+	}, failed(readiness), "storage errors are only logged")
+}
+
+func (suite *HealthServiceTestSuite) TestMigrations() {
+	suite.storeForecasts(time.Hour)
+	suite.healthRep.version = 10
+
+	readiness := suite.service.Ready(context.Background())
+	assert.Equal(suite.T(), models.HealthUnavailable, readiness.Status)
+	assert.Equal(suite.T(), map[string]string{"migrations": "schema is at migration 10, want 11"}, failed(readiness))
+
+	suite.healthRep.version, suite.healthRep.dirty = 11, true
+	assert.Contains(suite.T(), failed(suite.service.Ready(context.Background()))["migrations"], "failed halfway")
+}
+
+func (suite *HealthServiceTestSuite) TestStaleForecasts() {
+	readiness := suite.service.Ready(context.Background())
+	assert.Equal(suite.T(), map[string]string{"forecasts": "no forecasts stored in 2h0m0s since the start"}, failed(readiness))
+
+	suite.storeForecasts(3 * time.Hour)
+	readiness = suite.service.Ready(context.Background())
+	assert.Equal(suite.T(), map[string]string{"forecasts": "newest forecasts were stored 3h0m0s ago, more than 2h0m0s"}, failed(readiness))
+}
+
+func (suite *HealthServiceTestSuite) TestFreshDeployment() {
+	suite.service.startedAt = suite.now.Add(-time.Hour)
+	assert.Empty(suite.T(), failed(suite.service.Ready(context.Background())), "the collector has time for its first run")
+
+	suite.service.startedAt = suite.now.Add(-3 * time.Hour)
+	suite.service.cityRep = memory.NewCityRepository(memory.NewStorage())
+	assert.Empty(suite.T(), failed(suite.service.Ready(context.Background())), "no cities are tracked")
+}
+
+func (suite *HealthServiceTestSuite) TestDisabledChecks() {
+	suite.service.policy = ReadinessPolicy{}
+
+	readiness := suite.service.Ready(context.Background())
+	assert.Equal(suite.T(), models.HealthOK, readiness.Status)
+	suite.Require().Len(readiness.Checks, 1)
+	assert.Equal(suite.T(), "database", readiness.Checks[0].Name)
+}
+
+func TestHealthServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(HealthServiceTestSuite))
+}
@@ -1,43 +1,141 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"time"
 	"weather-app/internal/models"
 )
 
 type UserService interface {
-	CreateUser(user models.User) (int, error)
-	GenerateToken(login string, password string) (string, error)
-	ParseToken(accessToken string) (int, error)
-	GetFavorites(userId int) ([]int, error)
-	AddFavorite(userId int, cityId int) (int, error)
-	DeleteFavorite(userId int, cityId int) error
+	CreateUser(ctx context.Context, user models.User) (int, error)
+	GenerateToken(ctx context.Context, login string, password string, ip string) (string, error)
+	ParseToken(ctx context.Context, accessToken string) (int, error)
+	GetUserById(ctx context.Context, userId int) (models.User, error)
+	GetFavorites(ctx context.Context, userId int) ([]int, error)
+	AddFavorite(ctx context.Context, userId int, cityId int) (int, error)
+	DeleteFavorite(ctx context.Context, userId int, cityId int) error
+	UnlockUser(ctx context.Context, userId int) error
+	ClearLockout(ctx context.Context, subject string) error
+	GetLockouts(ctx context.Context) ([]models.LoginFailure, error)
+	GetSignInAttempts(ctx context.Context, login string, limit int) ([]models.SignInAttempt, error)
+	PruneSignInAttempts(ctx context.Context, before time.Time, limit int) (int, error)
+	PruneLoginFailures(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+// SignInBlockedError is returned when a sign-in is refused without checking
+// the password because of earlier failures.
+type SignInBlockedError struct {
+	// Locked is set when the login or the address is locked out, rather than
+	// asked to wait after a recent failure.
+	Locked     bool
+	RetryAfter time.Duration
+}
+
+func (e *SignInBlockedError) Error() string {
+	wait := (e.RetryAfter + time.Second - 1).Truncate(time.Second)
+	if e.Locked {
+		return fmt.Sprintf("too many failed sign-ins, try again in %v", wait)
+	}
+	return fmt.Sprintf("sign-in failed recently, try again in %v", wait)
+}
+
+// ValidationError is returned when a request is rejected because of the
+// values it carries, which the client has to correct.
+type ValidationError struct {
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
 }
 
 type CityService interface {
-	CreateCity(models.City) (int, error)
-	GetCities() ([]models.City, error)
-	GetCity(cityId int) (models.City, error)
-	FetchCityData(cityName string, openWeatherAPIKey string) (models.City, error)
+	CreateCity(ctx context.Context, city models.City) (int, error)
+	GetCities(ctx context.Context) ([]models.City, error)
+	GetCity(ctx context.Context, cityId int) (models.City, error)
+	FetchCityData(ctx context.Context, cityName string, openWeatherAPIKey string) (models.City, error)
+	GetCitySchedules(ctx context.Context) ([]models.CitySchedule, error)
+	UpdateCitySchedule(ctx context.Context, cityId int, interval int) error
 }
 
 type ForecastService interface {
-	CreateForecast(models.Forecast) (int, error)
-	GetShortForecast(cityId int) (models.ForecastSummary, error)
-	GetDetailedForecast(cityId int, date time.Time) ([]models.Forecast, error)
-	FetchForecastData(city models.City, openWeatherAPIKey string) ([]models.Forecast, error)
+	CreateForecast(ctx context.Context, forecast models.Forecast) (int, error)
+	ReplaceForecasts(ctx context.Context, cityId int, forecasts []models.Forecast) (int, error)
+	GetShortForecast(ctx context.Context, cityId int) (models.ForecastSummary, error)
+	GetDetailedForecast(ctx context.Context, cityId int, date time.Time) ([]models.Forecast, error)
+	FetchForecastData(ctx context.Context, city models.City, openWeatherAPIKey string) ([]models.Forecast, error)
+	GetForecastHistory(ctx context.Context, cityId int) ([]models.DailyForecast, error)
+	RollupForecasts(ctx context.Context, before time.Time, limit int) (int, error)
+	PruneDailyForecasts(ctx context.Context, before time.Time, limit int) (int, error)
+	SetForecastFreshness(ctx context.Context, freshness models.ForecastFreshness) error
+	GetForecastFreshness(ctx context.Context, cityId int) (models.ForecastFreshness, error)
+	GetAllForecastFreshness(ctx context.Context) ([]models.ForecastFreshness, error)
+}
+
+type AlertService interface {
+	CreateAlertRule(ctx context.Context, rule models.AlertRule) (int, error)
+	GetAlertRules(ctx context.Context, userId int) ([]models.AlertRule, error)
+	GetAlertRule(ctx context.Context, userId int, ruleId int) (models.AlertRule, error)
+	UpdateAlertRule(ctx context.Context, rule models.AlertRule) error
+	DeleteAlertRule(ctx context.Context, userId int, ruleId int) error
+	GetTriggeredAlerts(ctx context.Context, userId int) ([]models.TriggeredAlert, error)
+	GetTriggeredAlertsAfter(ctx context.Context, afterId int, limit int) ([]models.TriggeredAlert, error)
+	GetLastTriggeredAlertId(ctx context.Context) (int, error)
+	EvaluateAlerts(ctx context.Context, cityId int, forecasts []models.Forecast) ([]models.TriggeredAlert, error)
+}
+
+type WebhookService interface {
+	CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error)
+	GetWebhooks(ctx context.Context, userId int) ([]models.Webhook, error)
+	DeleteWebhook(ctx context.Context, userId int, webhookId int) error
+	GetDeliveries(ctx context.Context, userId int, webhookId int) ([]models.WebhookDelivery, error)
+	TestWebhook(ctx context.Context, userId int, webhookId int) (models.WebhookDelivery, error)
+	NotifyAlerts(ctx context.Context, alerts []models.TriggeredAlert) error
+	NotifyForecastUpdated(ctx context.Context, city models.City) error
+	DeliverPending(ctx context.Context, limit int) (int, error)
+}
+
+// ErrCollectorNotRunning is returned when a run is triggered in a process
+// without a running data collector.
+var ErrCollectorNotRunning = errors.New("data collector is not running in this process")
+
+type CollectorService interface {
+	StartRun(ctx context.Context, triggeredBy string) (models.CollectorRun, error)
+	FinishRun(ctx context.Context, run models.CollectorRun) error
+	GetRuns(ctx context.Context, limit int) ([]models.CollectorRun, error)
+	GetStatus(ctx context.Context) (models.CollectorStatus, error)
+	Trigger(ctx context.Context) error
+	AttachCollector() (<-chan struct{}, func())
+	RecordCityFailure(ctx context.Context, cityId int, reason error) (bool, error)
+	ClearCityFailures(ctx context.Context, cityId int) error
+	GetDeadLetters(ctx context.Context) ([]models.DeadLetter, error)
+	PruneRuns(ctx context.Context, before time.Time, limit int) (int, error)
+}
+
+type HealthService interface {
+	Ready(ctx context.Context) models.Readiness
 }
 
 type Service struct {
 	UserService
 	CityService
 	ForecastService
+	AlertService
+	WebhookService
+	CollectorService
+	HealthService
 }
 
-func NewService(userService UserService, cityService CityService, forecastService ForecastService) *Service {
+func NewService(userService UserService, cityService CityService, forecastService ForecastService, alertService AlertService, webhookService WebhookService, collectorService CollectorService, healthService HealthService) *Service {
 	return &Service{
-		UserService:     userService,
-		CityService:     cityService,
-		ForecastService: forecastService,
+		UserService:      userService,
+		CityService:      cityService,
+		ForecastService:  forecastService,
+		AlertService:     alertService,
+		WebhookService:   webhookService,
+		CollectorService: collectorService,
+		HealthService:    healthService,
 	}
 }
@@ -0,0 +1,215 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+	"weather-app/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultAttemptsLimit = 20
+	maxAttemptsLimit     = 100
+)
+
+// LoginPolicy protects sign-in from password guessing. Failures are counted
+// per login and per IP address; failures older than Lockout are forgotten.
+//
+// The delays and lockout of a login do not apply to addresses it signed in
+// from before, as recorded in the sign-in attempts, or anyone could lock a
+// user out by failing to sign in as them. The price is that guesses from such
+// an address, say behind the same NAT, are only limited by MaxIPFailures.
+type LoginPolicy struct {
+	// MaxFailures locks a login out for Lockout after as many failures in a
+	// row. Zero disables the lockout.
+	MaxFailures int
+	// MaxIPFailures locks an address out the same way, whichever logins it
+	// tried.
+	MaxIPFailures int
+	Lockout       time.Duration
+	// Delay is how long a login has to wait after a failure before it may
+	// try again. It doubles with every further failure, up to MaxDelay. Zero
+	// disables the delays.
+	Delay    time.Duration
+	MaxDelay time.Duration
+}
+
+func loginSubject(login string) string {
+	return "login:" + login
+}
+
+func ipSubject(ip string) string {
+	return "ip:" + ip
+}
+
+// blocked returns why a subject with failure may not try to sign in at now,
+// or nil if it may. Only logins are delayed, so that users sharing an address
+// are not held up by each other's typos.
+func (p LoginPolicy) blocked(failure models.LoginFailure, delayed bool, now time.Time) *service.SignInBlockedError {
+	if failure.LockedUntil != nil && now.Before(*failure.LockedUntil) {
+		return &service.SignInBlockedError{Locked: true, RetryAfter: failure.LockedUntil.Sub(now)}
+	}
+	if !delayed || failure.Failures == 0 || p.Delay <= 0 || failure.LastFailedAt.Before(now.Add(-p.Lockout)) {
+		return nil
+	}
+	if retry := failure.LastFailedAt.Add(p.delay(failure.Failures)).Sub(now); retry > 0 {
+		return &service.SignInBlockedError{RetryAfter: retry}
+	}
+	return nil
+}
+
+// delay returns the wait after failures failures in a row.
+func (p LoginPolicy) delay(failures int) time.Duration {
+	delay := p.Delay
+	for i := 1; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// checkBlocked returns an error if the login or the address may not sign in
+// at now.
+func (s *UserService) checkBlocked(ctx context.Context, login, ip string, now time.Time) error {
+	subjects := []struct {
+		subject string
+		delayed bool
+	}{
+		{loginSubject(login), true},
+		{ipSubject(ip), false},
+	}
+	for _, subject := range subjects {
+		failure, err := s.userRep.GetLoginFailure(ctx, subject.subject)
+		if err != nil {
+			return err
+		}
+		blocked := s.policy.blocked(failure, subject.delayed, now)
+		if blocked == nil {
+			continue
+		}
+		if subject.delayed {
+			known, err := s.userRep.HasSignedInFrom(ctx, login, ip)
+			if err != nil {
+				return err
+			}
+			if known {
+				continue
+			}
+		}
+		return blocked
+	}
+	return nil
+}
+
+// recordFailure counts a failed sign-in against the login and the address and
+// locks out those that reached their limit.
+func (s *UserService) recordFailure(ctx context.Context, login, ip string, now time.Time) error {
+	subjects := []struct {
+		subject     string
+		maxFailures int
+	}{
+		{loginSubject(login), s.policy.MaxFailures},
+		{ipSubject(ip), s.policy.MaxIPFailures},
+	}
+	for _, subject := range subjects {
+		failure, err := s.userRep.RecordLoginFailure(ctx, subject.subject, now, now.Add(-s.policy.Lockout))
+		if err != nil {
+			return err
+		}
+		if subject.maxFailures > 0 && failure.Failures >= subject.maxFailures {
+			if err := s.userRep.LockLogin(ctx, subject.subject, now.Add(s.policy.Lockout)); err != nil {
+				return err
+			}
+			logrus.Warnf("Sign-in locked for %s after %d failures", subject.subject, failure.Failures)
+		}
+	}
+	return nil
+}
+
+// audit records a sign-in attempt. A failure to record it does not affect the
+// sign-in.
+func (s *UserService) audit(ctx context.Context, attempt models.SignInAttempt) {
+	if _, err := s.userRep.CreateSignInAttempt(ctx, attempt); err != nil {
+		logrus.Errorf("Failed to record sign-in attempt of %s: %v", attempt.Login, err)
+	}
+}
+
+// UnlockUser lifts the lockout of the user's login and forgets its failures.
+func (s *UserService) UnlockUser(ctx context.Context, userId int) error {
+	user, err := s.userRep.GetUserById(ctx, userId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("user %d: %w", userId, repository.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	return s.userRep.ClearLoginFailures(ctx, loginSubject(user.Login))
+}
+
+// ClearLockout lifts the lockout of subject, a login or an address as listed
+// by GetLockouts, and forgets its failures. Addresses can only be unlocked
+// this way.
+func (s *UserService) ClearLockout(ctx context.Context, subject string) error {
+	if !strings.HasPrefix(subject, loginSubject("")) && !strings.HasPrefix(subject, ipSubject("")) {
+		return &service.ValidationError{Message: fmt.Sprintf("subject %q must start with login: or ip:", subject)}
+	}
+	failure, err := s.userRep.GetLoginFailure(ctx, subject)
+	if err != nil {
+		return err
+	}
+	if failure.Failures == 0 {
+		return fmt.Errorf("failed sign-ins of %s: %w", subject, repository.ErrNotFound)
+	}
+	return s.userRep.ClearLoginFailures(ctx, subject)
+}
+
+// GetLockouts returns the logins and addresses locked out now.
+func (s *UserService) GetLockouts(ctx context.Context) ([]models.LoginFailure, error) {
+	lockouts, err := s.userRep.GetLockedLogins(ctx, s.now())
+	if err != nil {
+		return nil, err
+	}
+	if lockouts == nil {
+		lockouts = []models.LoginFailure{}
+	}
+	return lockouts, nil
+}
+
+// GetSignInAttempts returns the latest sign-in attempts, of login only unless
+// it is empty.
+func (s *UserService) GetSignInAttempts(ctx context.Context, login string, limit int) ([]models.SignInAttempt, error) {
+	if limit <= 0 {
+		limit = defaultAttemptsLimit
+	}
+	if limit > maxAttemptsLimit {
+		limit = maxAttemptsLimit
+	}
+	attempts, err := s.userRep.GetSignInAttempts(ctx, login, limit)
+	if err != nil {
+		return nil, err
+	}
+	if attempts == nil {
+		attempts = []models.SignInAttempt{}
+	}
+	return attempts, nil
+}
+
+// PruneLoginFailures deletes a batch of failure records of logins and
+// addresses that last failed before before, unless still locked out, and
+// returns its size. Anyone can add such records by failing to sign in, so
+// they have to go once they no longer count.
+func (s *UserService) PruneLoginFailures(ctx context.Context, before time.Time, limit int) (int, error) {
+	return s.userRep.PruneLoginFailures(ctx, before, limit)
+}
+
+// PruneSignInAttempts deletes a batch of sign-in attempts made before before
+// and returns its size.
+func (s *UserService) PruneSignInAttempts(ctx context.Context, before time.Time, limit int) (int, error) {
+	return s.userRep.PruneSignInAttempts(ctx, before, limit)
+}
@@ -0,0 +1,178 @@
+package userservice
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+	"weather-app/internal/repository/memory"
+	"weather-app/internal/service"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// LoginTestSuite runs the sign-in protection against the in-memory storage
+// backend with a fake clock.
+type LoginTestSuite struct {
+	suite.Suite
+	service *UserService
+	userId  int
+	now     time.Time
+}
+
+func (suite *LoginTestSuite) SetupTest() {
+	suite.service = NewUserService(new(MockCityService), memory.NewUserRepository(memory.NewStorage()), LoginPolicy{
+		MaxFailures:   3,
+		MaxIPFailures: 5,
+		Lockout:       15 * time.Minute,
+		Delay:         time.Second,
+		MaxDelay:      3 * time.Second,
+	})
+	suite.now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	suite.service.now = func() time.Time { return suite.now }
+
+	userId, err := suite.service.CreateUser(context.Background(), models.User{Login: "alice", Password: "secret"})
+	suite.Require().NoError(err)
+	suite.userId = userId
+}
+
+func (suite *LoginTestSuite) signIn(login, password, ip string) error {
+	_, err := suite.service.GenerateToken(context.Background(), login, password, ip)
+	return err
+}
+
+// blocked returns the reason err refused the sign-in, failing if it did not.
+func (suite *LoginTestSuite) blocked(err error) *service.SignInBlockedError {
+	var blocked *service.SignInBlockedError
+	suite.Require().True(errors.As(err, &blocked), "sign-in was not blocked: %v", err)
+	return blocked
+}
+
+func (suite *LoginTestSuite) TestDelayDoublesAfterEachFailure() {
+	suite.ErrorIs(suite.signIn("alice", "wrong", "10.0.0.1"), sql.ErrNoRows)
+
+	blocked := suite.blocked(suite.signIn("alice", "secret", "10.0.0.1"))
+	suite.False(blocked.Locked)
+	suite.Equal(time.Second, blocked.RetryAfter)
+
+	suite.now = suite.now.Add(time.Second)
+	suite.ErrorIs(suite.signIn("alice", "wrong", "10.0.0.1"), sql.ErrNoRows)
+	suite.Equal(2*time.Second, suite.blocked(suite.signIn("alice", "secret", "10.0.0.1")).RetryAfter)
+
+	// Other logins from the same address are not delayed.
+	suite.ErrorIs(suite.signIn("bob", "wrong", "10.0.0.1"), sql.ErrNoRows)
+}
+
+func (suite *LoginTestSuite) TestLockoutAfterMaxFailures() {
+	for i := 0; i < 3; i++ {
+		suite.ErrorIs(suite.signIn("alice", "wrong", "10.0.0.1"), sql.ErrNoRows)
+		suite.now = suite.now.Add(time.Minute)
+	}
+
+	blocked := suite.blocked(suite.signIn("alice", "secret", "10.0.0.2"))
+	suite.True(blocked.Locked)
+	suite.Equal(14*time.Minute, blocked.RetryAfter)
+
+	lockouts, err := suite.service.GetLockouts(context.Background())
+	suite.Require().NoError(err)
+	suite.Require().Len(lockouts, 1)
+	suite.Equal("login:alice", lockouts[0].Subject)
+
+	suite.now = suite.now.Add(14 * time.Minute)
+	suite.NoError(suite.signIn("alice", "secret", "10.0.0.2"))
+}
+
+func (suite *LoginTestSuite) TestIPLockout() {
+	for _, login := range []string{"a", "b", "c", "d", "e"} {
+		suite.ErrorIs(suite.signIn(login, "wrong", "10.0.0.1"), sql.ErrNoRows)
+	}
+
+	suite.True(suite.blocked(suite.signIn("alice", "secret", "10.0.0.1")).Locked)
+	suite.NoError(suite.signIn("alice", "secret", "10.0.0.2"))
+}
+
+func (suite *LoginTestSuite) TestSuccessClearsFailures() {
+	suite.ErrorIs(suite.signIn("alice", "wrong", "10.0.0.1"), sql.ErrNoRows)
+	suite.now = suite.now.Add(time.Second)
+	suite.NoError(suite.signIn("alice", "secret", "10.0.0.1"))
+	suite.ErrorIs(suite.signIn("alice", "wrong", "10.0.0.2"), sql.ErrNoRows)
+
+	// The failure before the sign-in is forgotten, so the delay starts over.
+	suite.Equal(time.Second, suite.blocked(suite.signIn("alice", "secret", "10.0.0.2")).RetryAfter)
+}
+
+func (suite *LoginTestSuite) TestKnownAddressIsNotLockedOut() {
+	suite.NoError(suite.signIn("alice", "secret", "10.0.0.1"))
+	for i := 0; i < 3; i++ {
+		suite.ErrorIs(suite.signIn("alice", "wrong", "10.0.0.66"), sql.ErrNoRows)
+		suite.now = suite.now.Add(time.Minute)
+	}
+
+	suite.True(suite.blocked(suite.signIn("alice", "secret", "10.0.0.2")).Locked)
+	suite.NoError(suite.signIn("alice", "secret", "10.0.0.1"), "alice signed in from here before")
+}
+
+func (suite *LoginTestSuite) TestUnlockUser() {
+	for i := 0; i < 3; i++ {
+		suite.ErrorIs(suite.signIn("alice", "wrong", "10.0.0.1"), sql.ErrNoRows)
+		suite.now = suite.now.Add(time.Minute)
+	}
+	suite.True(suite.blocked(suite.signIn("alice", "secret", "10.0.0.2")).Locked)
+
+	suite.Require().NoError(suite.service.UnlockUser(context.Background(), suite.userId))
+	suite.NoError(suite.signIn("alice", "secret", "10.0.0.2"))
+
+	lockouts, err := suite.service.GetLockouts(context.Background())
+	suite.Require().NoError(err)
+	suite.Empty(lockouts)
+	suite.NotNil(lockouts)
+}
+
+func (suite *LoginTestSuite) TestUnlockUnknownUser() {
+	suite.ErrorIs(suite.service.UnlockUser(context.Background(), suite.userId+1), repository.ErrNotFound)
+}
+
+func (suite *LoginTestSuite) TestClearLockout() {
+	for _, login := range []string{"a", "b", "c", "d", "e"} {
+		suite.ErrorIs(suite.signIn(login, "wrong", "10.0.0.1"), sql.ErrNoRows)
+	}
+	suite.True(suite.blocked(suite.signIn("alice", "secret", "10.0.0.1")).Locked)
+
+	suite.Require().NoError(suite.service.ClearLockout(context.Background(), "ip:10.0.0.1"))
+	suite.NoError(suite.signIn("alice", "secret", "10.0.0.1"))
+
+	suite.ErrorIs(suite.service.ClearLockout(context.Background(), "ip:10.0.0.1"), repository.ErrNotFound)
+	var invalid *service.ValidationError
+	suite.ErrorAs(suite.service.ClearLockout(context.Background(), "10.0.0.1"), &invalid)
+}
+
+func (suite *LoginTestSuite) TestAttemptsAreAudited() {
+	suite.ErrorIs(suite.signIn("alice", "wrong", "10.0.0.1"), sql.ErrNoRows)
+	suite.Error(suite.signIn("alice", "secret", "10.0.0.1"))
+	suite.now = suite.now.Add(time.Second)
+	suite.NoError(suite.signIn("alice", "secret", "10.0.0.1"))
+	suite.ErrorIs(suite.signIn("bob", "wrong", "10.0.0.2"), sql.ErrNoRows)
+
+	attempts, err := suite.service.GetSignInAttempts(context.Background(), "alice", 0)
+	suite.Require().NoError(err)
+	suite.Require().Len(attempts, 3)
+	suite.Equal(models.SignInSucceeded, attempts[0].Outcome)
+	suite.Require().NotNil(attempts[0].UserId)
+	suite.Equal(suite.userId, *attempts[0].UserId)
+	suite.Equal(models.SignInThrottled, attempts[1].Outcome)
+	suite.Equal(models.SignInFailed, attempts[2].Outcome)
+	suite.Equal("10.0.0.1", attempts[2].IP)
+	suite.Nil(attempts[2].UserId)
+
+	attempts, err = suite.service.GetSignInAttempts(context.Background(), "", 1)
+	suite.Require().NoError(err)
+	suite.Require().Len(attempts, 1)
+	suite.Equal("bob", attempts[0].Login)
+}
+
+func TestLoginTestSuite(t *testing.T) {
+	suite.Run(t, new(LoginTestSuite))
+}
@@ -1,7 +1,9 @@
 package userservice
 
 import (
+	"context"
 	"crypto/sha1"
+	"database/sql"
 	"errors"
 	"fmt"
 	"time"
@@ -10,6 +12,7 @@ import (
 	"weather-app/internal/service"
 
 	"github.com/dgrijalva/jwt-go"
+	"github.com/sirupsen/logrus"
 )
 
 const (
@@ -26,25 +29,66 @@ type tokenClaims struct {
 type UserService struct {
 	cityService service.CityService
 	userRep     repository.UserRepository
+	policy      LoginPolicy
+	now         func() time.Time
 }
 
-func NewUserService(cityService service.CityService, userRep repository.UserRepository) *UserService {
+// NewUserService creates the user service. Sign-ins are protected from
+// password guessing by policy.
+func NewUserService(cityService service.CityService, userRep repository.UserRepository, policy LoginPolicy) *UserService {
 	return &UserService{
 		cityService: cityService,
 		userRep:     userRep,
+		policy:      policy,
+		now:         time.Now,
 	}
 }
 
-func (s *UserService) CreateUser(user models.User) (int, error) {
+// CreateUser stores the user with a hashed password. Users get the regular
+// role unless another one is set explicitly.
+func (s *UserService) CreateUser(ctx context.Context, user models.User) (int, error) {
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
 	user.Password = generatePasswordHash(user.Password)
-	return s.userRep.CreateUser(user)
+	return s.userRep.CreateUser(ctx, user)
 }
 
-func (s *UserService) GenerateToken(login, password string) (string, error) {
-	user, err := s.userRep.GetUser(login, generatePasswordHash(password))
+// GenerateToken signs the user in from the ip address. Sign-ins are refused
+// while the login or the address is locked out or has to wait after a recent
+// failure; every attempt is recorded for audit.
+func (s *UserService) GenerateToken(ctx context.Context, login, password, ip string) (string, error) {
+	now := s.now()
+	attempt := models.SignInAttempt{Login: login, IP: ip, AttemptedAt: now}
+	if err := s.checkBlocked(ctx, login, ip, now); err != nil {
+		var blocked *service.SignInBlockedError
+		if errors.As(err, &blocked) {
+			attempt.Outcome = models.SignInThrottled
+			if blocked.Locked {
+				attempt.Outcome = models.SignInLocked
+			}
+			s.audit(ctx, attempt)
+		}
+		return "", err
+	}
+
+	user, err := s.userRep.GetUser(ctx, login, generatePasswordHash(password))
+	if errors.Is(err, sql.ErrNoRows) {
+		attempt.Outcome = models.SignInFailed
+		s.audit(ctx, attempt)
+		if recordErr := s.recordFailure(ctx, login, ip, now); recordErr != nil {
+			logrus.Errorf("Failed to record failed sign-in of %s: %v", login, recordErr)
+		}
+		return "", err
+	}
 	if err != nil {
 		return "", err
 	}
+	if err := s.userRep.ClearLoginFailures(ctx, loginSubject(login)); err != nil {
+		logrus.Errorf("Failed to clear failed sign-ins of %s: %v", login, err)
+	}
+	attempt.Outcome, attempt.UserId = models.SignInSucceeded, &user.Id
+	s.audit(ctx, attempt)
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, &tokenClaims{
 		jwt.StandardClaims{
 			ExpiresAt: time.Now().Add(tokenTTL).Unix(),
@@ -55,7 +99,7 @@ func (s *UserService) GenerateToken(login, password string) (string, error) {
 	return token.SignedString([]byte(signingKey))
 }
 
-func (s *UserService) ParseToken(accessToken string) (int, error) {
+func (s *UserService) ParseToken(ctx context.Context, accessToken string) (int, error) {
 	token, err := jwt.ParseWithClaims(accessToken, &tokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
@@ -63,7 +107,7 @@ func (s *UserService) ParseToken(accessToken string) (int, error) {
 		return []byte(signingKey), nil
 	})
 	if err != nil {
-		return 0, nil
+		return 0, err
 	}
 	claims, ok := token.Claims.(*tokenClaims)
 	if !ok {
@@ -72,20 +116,24 @@ func (s *UserService) ParseToken(accessToken string) (int, error) {
 	return claims.UserId, nil
 }
 
+func (s *UserService) GetUserById(ctx context.Context, userId int) (models.User, error) {
+	return s.userRep.GetUserById(ctx, userId)
+}
+
 func generatePasswordHash(password string) string {
 	hash := sha1.New()
 	hash.Write([]byte(password))
 	return fmt.Sprintf("%x", hash.Sum([]byte(salt)))
 }
 
-func (s *UserService) GetFavorites(userId int) ([]int, error) {
-	return s.userRep.GetFavorites(userId)
+func (s *UserService) GetFavorites(ctx context.Context, userId int) ([]int, error) {
+	return s.userRep.GetFavorites(ctx, userId)
 }
 
-func (s *UserService) AddFavorite(userId int, cityId int) (int, error) {
-	return s.userRep.AddFavorite(userId, cityId)
+func (s *UserService) AddFavorite(ctx context.Context, userId int, cityId int) (int, error) {
+	return s.userRep.AddFavorite(ctx, userId, cityId)
 }
 
-func (s *UserService) DeleteFavorite(userId int, cityId int) error {
-	return s.userRep.DeleteFavorite(userId, cityId)
+func (s *UserService) DeleteFavorite(ctx context.Context, userId int, cityId int) error {
+	return s.userRep.DeleteFavorite(ctx, userId, cityId)
 }
@@ -1,8 +1,10 @@
 package userservice
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 	"weather-app/internal/models"
 
 	"github.com/dgrijalva/jwt-go"
@@ -15,55 +17,120 @@ type MockCityService struct {
 	mock.Mock
 }
 
-func (m *MockCityService) CreateCity(city models.City) (int, error) {
+func (m *MockCityService) CreateCity(ctx context.Context, city models.City) (int, error) {
 	args := m.Called(city)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockCityService) GetCities() ([]models.City, error) {
+func (m *MockCityService) GetCities(ctx context.Context) ([]models.City, error) {
 	args := m.Called()
 	return args.Get(0).([]models.City), args.Error(1)
 }
 
-func (m *MockCityService) GetCity(cityId int) (models.City, error) {
+func (m *MockCityService) GetCity(ctx context.Context, cityId int) (models.City, error) {
 	args := m.Called(cityId)
 	return args.Get(0).(models.City), args.Error(1)
 }
 
-func (m *MockCityService) FetchCityData(cityName string, openWeatherAPIKey string) (models.City, error) {
+func (m *MockCityService) FetchCityData(ctx context.Context, cityName string, openWeatherAPIKey string) (models.City, error) {
 	args := m.Called(cityName, openWeatherAPIKey)
 	return args.Get(0).(models.City), args.Error(1)
 }
 
+func (m *MockCityService) GetCitySchedules(ctx context.Context) ([]models.CitySchedule, error) {
+	args := m.Called()
+	return args.Get(0).([]models.CitySchedule), args.Error(1)
+}
+
+func (m *MockCityService) UpdateCitySchedule(ctx context.Context, cityId int, interval int) error {
+	args := m.Called(cityId, interval)
+	return args.Error(0)
+}
+
 type MockUserRepository struct {
 	mock.Mock
 }
 
-func (m *MockUserRepository) CreateUser(user models.User) (int, error) {
+func (m *MockUserRepository) CreateUser(ctx context.Context, user models.User) (int, error) {
 	args := m.Called(user)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockUserRepository) GetUser(login, password string) (models.User, error) {
+func (m *MockUserRepository) GetUser(ctx context.Context, login, password string) (models.User, error) {
 	args := m.Called(login, password)
 	return args.Get(0).(models.User), args.Error(1)
 }
 
-func (m *MockUserRepository) GetFavorites(userId int) ([]int, error) {
+func (m *MockUserRepository) GetUserById(ctx context.Context, userId int) (models.User, error) {
+	args := m.Called(userId)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetFavorites(ctx context.Context, userId int) ([]int, error) {
 	args := m.Called(userId)
 	return args.Get(0).([]int), args.Error(1)
 }
 
-func (m *MockUserRepository) AddFavorite(userId int, cityId int) (int, error) {
+func (m *MockUserRepository) AddFavorite(ctx context.Context, userId int, cityId int) (int, error) {
 	args := m.Called(userId, cityId)
 	return args.Int(0), args.Error(1)
 }
 
-func (m *MockUserRepository) DeleteFavorite(userId int, cityId int) error {
+func (m *MockUserRepository) DeleteFavorite(ctx context.Context, userId int, cityId int) error {
 	args := m.Called(userId, cityId)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) RecordLoginFailure(ctx context.Context, subject string, failedAt time.Time, resetBefore time.Time) (models.LoginFailure, error) {
+	args := m.Called(subject, failedAt, resetBefore)
+	return args.Get(0).(models.LoginFailure), args.Error(1)
+}
+
+func (m *MockUserRepository) GetLoginFailure(ctx context.Context, subject string) (models.LoginFailure, error) {
+	args := m.Called(subject)
+	return args.Get(0).(models.LoginFailure), args.Error(1)
+}
+
+func (m *MockUserRepository) LockLogin(ctx context.Context, subject string, until time.Time) error {
+	args := m.Called(subject, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ClearLoginFailures(ctx context.Context, subject string) error {
+	args := m.Called(subject)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) GetLockedLogins(ctx context.Context, now time.Time) ([]models.LoginFailure, error) {
+	args := m.Called(now)
+	return args.Get(0).([]models.LoginFailure), args.Error(1)
+}
+
+func (m *MockUserRepository) CreateSignInAttempt(ctx context.Context, attempt models.SignInAttempt) (int, error) {
+	args := m.Called(attempt)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) GetSignInAttempts(ctx context.Context, login string, limit int) ([]models.SignInAttempt, error) {
+	args := m.Called(login, limit)
+	return args.Get(0).([]models.SignInAttempt), args.Error(1)
+}
+
+func (m *MockUserRepository) HasSignedInFrom(ctx context.Context, login, ip string) (bool, error) {
+	args := m.Called(login, ip)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) PruneLoginFailures(ctx context.Context, before time.Time, limit int) (int, error) {
+	args := m.Called(before, limit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) PruneSignInAttempts(ctx context.Context, before time.Time, limit int) (int, error) {
+	args := m.Called(before, limit)
+	return args.Int(0), args.Error(1)
+}
+
 type UserServiceTestSuite struct {
 	suite.Suite
 	service     *UserService
@@ -74,7 +141,14 @@ type UserServiceTestSuite struct {
 func (suite *UserServiceTestSuite) SetupTest() {
 	suite.mockCitySvc = new(MockCityService)
 	suite.mockUserRep = new(MockUserRepository)
-	suite.service = NewUserService(suite.mockCitySvc, suite.mockUserRep)
+	suite.service = NewUserService(suite.mockCitySvc, suite.mockUserRep, LoginPolicy{})
+}
+
+// allowSignIn stubs the bookkeeping of sign-ins by users without failures.
+func (suite *UserServiceTestSuite) allowSignIn() {
+	suite.mockUserRep.On("GetLoginFailure", mock.Anything).Return(models.LoginFailure{}, nil)
+	suite.mockUserRep.On("ClearLoginFailures", mock.Anything).Return(nil)
+	suite.mockUserRep.On("CreateSignInAttempt", mock.Anything).Return(1, nil)
 }
 
 func (suite *UserServiceTestSuite) TestCreateUser() {
@@ -86,10 +160,27 @@ func (suite *UserServiceTestSuite) TestCreateUser() {
 
 	hashedPassword := generatePasswordHash(user.Password)
 	suite.mockUserRep.On("CreateUser", mock.MatchedBy(func(u models.User) bool {
-		return u.Login == user.Login && u.Email == user.Email && u.Password == hashedPassword
+		return u.Login == user.Login && u.Email == user.Email && u.Password == hashedPassword && u.Role == models.RoleUser
 	})).Return(1, nil)
 
-	id, err := suite.service.CreateUser(user)
+	id, err := suite.service.CreateUser(context.Background(), user)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, id)
+	suite.mockUserRep.AssertExpectations(suite.T())
+}
+
+func (suite *UserServiceTestSuite) TestCreateUserKeepsRole() {
+	user := models.User{
+		Login:    "admin",
+		Password: "password",
+		Role:     models.RoleAdmin,
+	}
+
+	suite.mockUserRep.On("CreateUser", mock.MatchedBy(func(u models.User) bool {
+		return u.Login == user.Login && u.Role == models.RoleAdmin
+	})).Return(1, nil)
+
+	id, err := suite.service.CreateUser(context.Background(), user)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 1, id)
 	suite.mockUserRep.AssertExpectations(suite.T())
@@ -104,7 +195,7 @@ func (suite *UserServiceTestSuite) TestCreateUserError() {
 
 	suite.mockUserRep.On("CreateUser", mock.Anything).Return(0, errors.New("create user error"))
 
-	id, err := suite.service.CreateUser(user)
+	id, err := suite.service.CreateUser(context.Background(), user)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), 0, id)
 	suite.mockUserRep.AssertExpectations(suite.T())
@@ -117,9 +208,10 @@ func (suite *UserServiceTestSuite) TestGenerateToken() {
 		Password: generatePasswordHash("password"),
 	}
 
+	suite.allowSignIn()
 	suite.mockUserRep.On("GetUser", user.Login, user.Password).Return(user, nil)
 
-	token, err := suite.service.GenerateToken(user.Login, "password")
+	token, err := suite.service.GenerateToken(context.Background(), user.Login, "password", "127.0.0.1")
 	assert.NoError(suite.T(), err)
 
 	claims := &tokenClaims{}
@@ -132,9 +224,10 @@ func (suite *UserServiceTestSuite) TestGenerateToken() {
 }
 
 func (suite *UserServiceTestSuite) TestGenerateTokenError() {
+	suite.allowSignIn()
 	suite.mockUserRep.On("GetUser", "wronguser", generatePasswordHash("password")).Return(models.User{}, assert.AnError)
 
-	token, err := suite.service.GenerateToken("wronguser", "password")
+	token, err := suite.service.GenerateToken(context.Background(), "wronguser", "password", "127.0.0.1")
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), "", token)
 }
@@ -146,14 +239,19 @@ func (suite *UserServiceTestSuite) TestParseToken() {
 		Password: generatePasswordHash("password"),
 	}
 
+	suite.allowSignIn()
 	suite.mockUserRep.On("GetUser", user.Login, user.Password).Return(user, nil)
 
-	token, err := suite.service.GenerateToken(user.Login, "password")
+	token, err := suite.service.GenerateToken(context.Background(), user.Login, "password", "127.0.0.1")
 	assert.NoError(suite.T(), err)
 
-	userId, err := suite.service.ParseToken(token)
+	userId, err := suite.service.ParseToken(context.Background(), token)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), user.Id, userId)
+
+	userId, err = suite.service.ParseToken(context.Background(), "made-up")
+	assert.Error(suite.T(), err)
+	assert.Zero(suite.T(), userId)
 }
 
 func (suite *UserServiceTestSuite) TestGetFavorites() {
@@ -162,7 +260,7 @@ func (suite *UserServiceTestSuite) TestGetFavorites() {
 
 	suite.mockUserRep.On("GetFavorites", userId).Return(favorites, nil)
 
-	result, err := suite.service.GetFavorites(userId)
+	result, err := suite.service.GetFavorites(context.Background(), userId)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), favorites, result)
 	suite.mockUserRep.AssertExpectations(suite.T())
@@ -174,7 +272,7 @@ func (suite *UserServiceTestSuite) TestAddFavorite() {
 
 	suite.mockUserRep.On("AddFavorite", userId, cityId).Return(1, nil)
 
-	id, err := suite.service.AddFavorite(userId, cityId)
+	id, err := suite.service.AddFavorite(context.Background(), userId, cityId)
 	assert.NoError(suite.T(), err)
 	assert.Equal(suite.T(), 1, id)
 	suite.mockUserRep.AssertExpectations(suite.T())
@@ -186,7 +284,7 @@ func (suite *UserServiceTestSuite) TestAddFavoriteError() {
 
 	suite.mockUserRep.On("AddFavorite", userId, cityId).Return(0, errors.New("add favorite error"))
 
-	id, err := suite.service.AddFavorite(userId, cityId)
+	id, err := suite.service.AddFavorite(context.Background(), userId, cityId)
 	assert.Error(suite.T(), err)
 	assert.Equal(suite.T(), 0, id)
 	suite.mockUserRep.AssertExpectations(suite.T())
@@ -198,7 +296,7 @@ func (suite *UserServiceTestSuite) TestDeleteFavorite() {
 
 	suite.mockUserRep.On("DeleteFavorite", userId, cityId).Return(nil)
 
-	err := suite.service.DeleteFavorite(userId, cityId)
+	err := suite.service.DeleteFavorite(context.Background(), userId, cityId)
 	assert.NoError(suite.T(), err)
 	suite.mockUserRep.AssertExpectations(suite.T())
 }
@@ -209,7 +307,7 @@ func (suite *UserServiceTestSuite) TestDeleteFavoriteError() {
 
 	suite.mockUserRep.On("DeleteFavorite", userId, cityId).Return(errors.New("delete favorite error"))
 
-	err := suite.service.DeleteFavorite(userId, cityId)
+	err := suite.service.DeleteFavorite(context.Background(), userId, cityId)
 	assert.Error(suite.T(), err)
 	suite.mockUserRep.AssertExpectations(suite.T())
 }
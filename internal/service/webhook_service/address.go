@@ -0,0 +1,80 @@
+package webhookservice
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+	"weather-app/internal/service"
+)
+
+// DeliveryPolicy controls where webhooks may point.
+type DeliveryPolicy struct {
+	// AllowPrivateNetworks lets webhooks target loopback, private and
+	// link-local addresses. Otherwise users could make the server call its
+	// own network, such as the cloud metadata service, and read the outcome
+	// from the delivery log. Only tests and closed installations need it.
+	AllowPrivateNetworks bool
+}
+
+// sharedAddressSpace is the carrier-grade NAT range, which is as internal as
+// the private ranges.
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// blockedIP reports whether ip belongs to the server's own or private
+// networks.
+func blockedIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || sharedAddressSpace.Contains(ip)
+}
+
+// checkHost rejects hosts that resolve to a blocked address. It only gives
+// users an early error: the name may resolve differently by the time of a
+// delivery, which is why the dialer checks the address again.
+func (s *WebhookService) checkHost(ctx context.Context, host string) error {
+	if s.policy.AllowPrivateNetworks {
+		return nil
+	}
+	addrs, err := s.lookupIP(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return &service.ValidationError{Message: fmt.Sprintf("webhook host %s cannot be resolved", host)}
+	}
+	for _, addr := range addrs {
+		if blockedIP(addr.IP) {
+			return &service.ValidationError{Message: fmt.Sprintf("webhook host %s resolves to a private address", host)}
+		}
+	}
+	return nil
+}
+
+// newClient returns the HTTP client of deliveries. Unless policy allows
+// private networks, it refuses to connect to them whatever the host name
+// resolves to at the time, including after redirects. Proxies are not used,
+// since the proxy would make the connection instead.
+func newClient(policy DeliveryPolicy) *http.Client {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if !policy.AllowPrivateNetworks {
+		dialer.Control = refusePrivate
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: 10 * time.Second, Transport: transport}
+}
+
+// refusePrivate is a net.Dialer Control function, which sees the resolved
+// address being connected to.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || blockedIP(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+package webhookservice
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/repository"
+	"weather-app/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+
+	defaultMaxAttempts = 6
+	defaultBaseBackoff = 30 * time.Second
+	maxBackoff         = time.Hour
+	deliveryLease      = time.Minute
+	deliveryLogLimit   = 50
+)
+
+type WebhookService struct {
+	webhookRep  repository.WebhookRepository
+	client      *http.Client
+	policy      DeliveryPolicy
+	lookupIP    func(ctx context.Context, host string) ([]net.IPAddr, error)
+	maxAttempts int
+	baseBackoff time.Duration
+}
+
+// NewWebhookService creates the webhook service. Policy decides whether
+// webhooks may point to private networks.
+func NewWebhookService(webhookRep repository.WebhookRepository, policy DeliveryPolicy) *WebhookService {
+	return &WebhookService{
+		webhookRep:  webhookRep,
+		client:      newClient(policy),
+		policy:      policy,
+		lookupIP:    net.DefaultResolver.LookupIPAddr,
+		maxAttempts: defaultMaxAttempts,
+		baseBackoff: defaultBaseBackoff,
+	}
+}
+
+type eventPayload struct {
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+type forecastUpdatedData struct {
+	City models.City `json:"city"`
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of "<timestamp>.<body>". Receivers
+// compare it with the X-Webhook-Signature header (without the "sha256=" prefix).
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func (s *WebhookService) validateURL(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return &service.ValidationError{Message: fmt.Sprintf("invalid webhook url: %v", err)}
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return &service.ValidationError{Message: "webhook url must be an absolute http(s) url"}
+	}
+	return s.checkHost(ctx, u.Hostname())
+}
+
+func (s *WebhookService) CreateWebhook(ctx context.Context, webhook models.Webhook) (models.Webhook, error) {
+	if err := s.validateURL(ctx, webhook.URL); err != nil {
+		return models.Webhook{}, err
+	}
+	secret, err := generateSecret()
+	if err != nil {
+		return models.Webhook{}, fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	webhook.Secret = secret
+	webhook.CreatedAt = time.Now()
+	webhook.Id, err = s.webhookRep.CreateWebhook(ctx, webhook)
+	if err != nil {
+		return models.Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (s *WebhookService) GetWebhooks(ctx context.Context, userId int) ([]models.Webhook, error) {
+	webhooks, err := s.webhookRep.GetWebhooks(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	for i := range webhooks {
+		webhooks[i].Secret = ""
+	}
+	return webhooks, nil
+}
+
+func (s *WebhookService) DeleteWebhook(ctx context.Context, userId int, webhookId int) error {
+	return s.webhookRep.DeleteWebhook(ctx, userId, webhookId)
+}
+
+func (s *WebhookService) GetDeliveries(ctx context.Context, userId int, webhookId int) ([]models.WebhookDelivery, error) {
+	return s.webhookRep.GetDeliveries(ctx, userId, webhookId, deliveryLogLimit)
+}
+
+func (s *WebhookService) enqueue(ctx context.Context, webhooks []models.Webhook, event string, data any) error {
+	if len(webhooks) == 0 {
+		return nil
+	}
+	now := time.Now()
+	payload, err := json.Marshal(eventPayload{Event: event, CreatedAt: now, Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	for _, webhook := range webhooks {
+		_, err := s.webhookRep.CreateDelivery(ctx, models.WebhookDelivery{
+			WebhookId:     webhook.Id,
+			Event:         event,
+			Payload:       payload,
+			Status:        models.DeliveryStatusPending,
+			NextAttemptAt: now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyAlerts queues an alert.triggered delivery to every webhook of the alert owners.
+func (s *WebhookService) NotifyAlerts(ctx context.Context, alerts []models.TriggeredAlert) error {
+	for _, alert := range alerts {
+		webhooks, err := s.webhookRep.GetWebhooks(ctx, alert.UserId)
+		if err != nil {
+			return err
+		}
+		if err := s.enqueue(ctx, webhooks, models.WebhookEventAlertTriggered, alert); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NotifyForecastUpdated queues a forecast.updated delivery to the webhooks of
+// every user who has the city in favorites.
+func (s *WebhookService) NotifyForecastUpdated(ctx context.Context, city models.City) error {
+	webhooks, err := s.webhookRep.GetCityWebhooks(ctx, city.Id)
+	if err != nil {
+		return err
+	}
+	return s.enqueue(ctx, webhooks, models.WebhookEventForecastUpdated, forecastUpdatedData{City: city})
+}
+
+func (s *WebhookService) backoff(attempts int) time.Duration {
+	delay := s.baseBackoff << (attempts - 1)
+	if delay <= 0 || delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+func (s *WebhookService) send(ctx context.Context, webhook models.Webhook, delivery models.WebhookDelivery) (int, error) {
+	timestamp := time.Now().Unix()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(delivery.Id))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, "sha256="+Sign(webhook.Secret, timestamp, delivery.Payload))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// attempt performs one delivery attempt and updates the delivery state. Failed
+// deliveries are rescheduled with exponential backoff until maxAttempts is reached.
+func (s *WebhookService) attempt(ctx context.Context, webhook models.Webhook, delivery *models.WebhookDelivery, retry bool) {
+	status, err := s.send(ctx, webhook, *delivery)
+	now := time.Now()
+	delivery.Attempts++
+	delivery.ResponseStatus = status
+	delivery.NextAttemptAt = now
+	if err == nil {
+		delivery.Status = models.DeliveryStatusDelivered
+		delivery.LastError = ""
+		delivery.DeliveredAt = &now
+		return
+	}
+	delivery.LastError = err.Error()
+	if !retry || delivery.Attempts >= s.maxAttempts {
+		delivery.Status = models.DeliveryStatusFailed
+		return
+	}
+	delivery.Status = models.DeliveryStatusPending
+	delivery.NextAttemptAt = now.Add(s.backoff(delivery.Attempts))
+}
+
+// DeliverPending sends up to limit due deliveries and returns how many succeeded.
+func (s *WebhookService) DeliverPending(ctx context.Context, limit int) (int, error) {
+	deliveries, err := s.webhookRep.ClaimPendingDeliveries(ctx, limit, time.Now().Add(deliveryLease))
+	if err != nil {
+		return 0, err
+	}
+	delivered := 0
+	for _, delivery := range deliveries {
+		webhook, err := s.webhookRep.GetWebhookById(ctx, delivery.WebhookId)
+		if err != nil {
+			logrus.Errorf("Failed to load webhook %d for delivery %d: %v", delivery.WebhookId, delivery.Id, err)
+			continue
+		}
+		s.attempt(ctx, webhook, &delivery, true)
+		if err := s.webhookRep.UpdateDelivery(ctx, delivery); err != nil {
+			return delivered, err
+		}
+		if delivery.Status == models.DeliveryStatusDelivered {
+			delivered++
+		}
+	}
+	return delivered, nil
+}
+
+// TestWebhook synchronously sends a ping event and records the result in the delivery log.
+func (s *WebhookService) TestWebhook(ctx context.Context, userId int, webhookId int) (models.WebhookDelivery, error) {
+	webhook, err := s.webhookRep.GetWebhook(ctx, userId, webhookId)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	now := time.Now()
+	payload, err := json.Marshal(eventPayload{
+		Event:     models.WebhookEventPing,
+		CreatedAt: now,
+		Data:      map[string]int{"webhook_id": webhook.Id},
+	})
+	if err != nil {
+		return models.WebhookDelivery{}, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	delivery := models.WebhookDelivery{
+		WebhookId: webhook.Id,
+		Event:     models.WebhookEventPing,
+		Payload:   payload,
+		CreatedAt: now,
+	}
+	s.attempt(ctx, webhook, &delivery, false)
+	delivery.Id, err = s.webhookRep.CreateDelivery(ctx, delivery)
+	if err != nil {
+		return models.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,308 @@
+package webhookservice
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/service"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) CreateWebhook(ctx context.Context, webhook models.Webhook) (int, error) {
+	args := m.Called(webhook)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetWebhooks(ctx context.Context, userId int) ([]models.Webhook, error) {
+	args := m.Called(userId)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetWebhook(ctx context.Context, userId int, webhookId int) (models.Webhook, error) {
+	args := m.Called(userId, webhookId)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetWebhookById(ctx context.Context, webhookId int) (models.Webhook, error) {
+	args := m.Called(webhookId)
+	return args.Get(0).(models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) GetCityWebhooks(ctx context.Context, cityId int) ([]models.Webhook, error) {
+	args := m.Called(cityId)
+	return args.Get(0).([]models.Webhook), args.Error(1)
+}
+
+func (m *MockWebhookRepository) DeleteWebhook(ctx context.Context, userId int, webhookId int) error {
+	args := m.Called(userId, webhookId)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) CreateDelivery(ctx context.Context, delivery models.WebhookDelivery) (int, error) {
+	args := m.Called(delivery)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ClaimPendingDeliveries(ctx context.Context, limit int, leaseUntil time.Time) ([]models.WebhookDelivery, error) {
+	args := m.Called(limit, leaseUntil)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) UpdateDelivery(ctx context.Context, delivery models.WebhookDelivery) error {
+	args := m.Called(delivery)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) GetDeliveries(ctx context.Context, userId int, webhookId int, limit int) ([]models.WebhookDelivery, error) {
+	args := m.Called(userId, webhookId, limit)
+	return args.Get(0).([]models.WebhookDelivery), args.Error(1)
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+type WebhookServiceTestSuite struct {
+	suite.Suite
+	service  *WebhookService
+	mockRepo *MockWebhookRepository
+	receiver *httptest.Server
+	status   int
+	received chan receivedRequest
+}
+
+func (suite *WebhookServiceTestSuite) SetupTest() {
+	suite.mockRepo = new(MockWebhookRepository)
+	// The receiver listens on loopback.
+	suite.service = NewWebhookService(suite.mockRepo, DeliveryPolicy{AllowPrivateNetworks: true})
+	suite.status = http.StatusOK
+	suite.received = make(chan receivedRequest, 10)
+	suite.receiver = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		suite.received <- receivedRequest{header: r.Header.Clone(), body: body}
+		w.WriteHeader(suite.status)
+	}))
+}
+
+func (suite *WebhookServiceTestSuite) TearDownTest() {
+	suite.receiver.Close()
+}
+
+func (suite *WebhookServiceTestSuite) webhook() models.Webhook {
+	return models.Webhook{Id: 1, UserId: 1, URL: suite.receiver.URL, Secret: "top-secret"}
+}
+
+func (suite *WebhookServiceTestSuite) pendingDelivery(attempts int) models.WebhookDelivery {
+	return models.WebhookDelivery{
+		Id:        7,
+		WebhookId: 1,
+		Event:     models.WebhookEventForecastUpdated,
+		Payload:   []byte(`{"event":"forecast.updated"}`),
+		Status:    models.DeliveryStatusPending,
+		Attempts:  attempts,
+	}
+}
+
+func (suite *WebhookServiceTestSuite) TestCreateWebhook() {
+	suite.mockRepo.On("CreateWebhook", mock.MatchedBy(func(w models.Webhook) bool {
+		return w.UserId == 1 && w.URL == "https://example.com/hook" && len(w.Secret) == 64
+	})).Return(3, nil)
+
+	webhook, err := suite.service.CreateWebhook(context.Background(), models.Webhook{UserId: 1, URL: "https://example.com/hook"})
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 3, webhook.Id)
+	assert.NotEmpty(suite.T(), webhook.Secret)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+func (suite *WebhookServiceTestSuite) TestCreateWebhookInvalidURL() {
+	_, err := suite.service.CreateWebhook(context.Background(), models.Webhook{UserId: 1, URL: "ftp://example.com"})
+	assert.Error(suite.T(), err)
+	suite.mockRepo.AssertNotCalled(suite.T(), "CreateWebhook", mock.Anything)
+}
+
+// guarded returns a service that refuses private networks and resolves every
+// host to ip.
+func (suite *WebhookServiceTestSuite) guarded(ip string) *WebhookService {
+	guarded := NewWebhookService(suite.mockRepo, DeliveryPolicy{})
+	guarded.lookupIP = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP(ip)}}, nil
+	}
+	return guarded
+}
+
+func (suite *WebhookServiceTestSuite) TestCreateWebhookPrivateAddress() {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "169.254.169.254", "::1", "fd00::1"} {
+		_, err := suite.guarded(ip).CreateWebhook(context.Background(), models.Webhook{UserId: 1, URL: "https://hooks.example.com/hook"})
+		var invalid *service.ValidationError
+		assert.ErrorAs(suite.T(), err, &invalid, ip)
+	}
+	suite.mockRepo.AssertNotCalled(suite.T(), "CreateWebhook", mock.Anything)
+
+	suite.mockRepo.On("CreateWebhook", mock.Anything).Return(3, nil)
+	_, err := suite.guarded("93.184.215.14").CreateWebhook(context.Background(), models.Webhook{UserId: 1, URL: "https://hooks.example.com/hook"})
+	assert.NoError(suite.T(), err)
+}
+
+func (suite *WebhookServiceTestSuite) TestTestWebhookRefusesPrivateAddress() {
+	// The host passed validation, but now resolves to the receiver on
+	// loopback.
+	suite.mockRepo.On("GetWebhook", 1, 1).Return(suite.webhook(), nil)
+	suite.mockRepo.On("CreateDelivery", mock.Anything).Return(14, nil)
+
+	delivery, err := suite.guarded("93.184.215.14").TestWebhook(context.Background(), 1, 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), models.DeliveryStatusFailed, delivery.Status)
+	assert.Equal(suite.T(), 0, delivery.ResponseStatus)
+	assert.Contains(suite.T(), delivery.LastError, "is not allowed")
+	assert.Empty(suite.T(), suite.received)
+}
+
+func (suite *WebhookServiceTestSuite) TestGetWebhooksHidesSecret() {
+	suite.mockRepo.On("GetWebhooks", 1).Return([]models.Webhook{suite.webhook()}, nil)
+
+	webhooks, err := suite.service.GetWebhooks(context.Background(), 1)
+	assert.NoError(suite.T(), err)
+	assert.Empty(suite.T(), webhooks[0].Secret)
+}
+
+func (suite *WebhookServiceTestSuite) TestNotifyForecastUpdated() {
+	city := models.City{Id: 5, Name: "London", Country: "GB"}
+	webhooks := []models.Webhook{{Id: 1, UserId: 1}, {Id: 2, UserId: 3}}
+
+	suite.mockRepo.On("GetCityWebhooks", 5).Return(webhooks, nil)
+	suite.mockRepo.On("CreateDelivery", mock.MatchedBy(func(d models.WebhookDelivery) bool {
+		var payload eventPayload
+		return json.Unmarshal(d.Payload, &payload) == nil &&
+			payload.Event == models.WebhookEventForecastUpdated &&
+			d.Status == models.DeliveryStatusPending
+	})).Return(1, nil)
+
+	err := suite.service.NotifyForecastUpdated(context.Background(), city)
+	assert.NoError(suite.T(), err)
+	suite.mockRepo.AssertNumberOfCalls(suite.T(), "CreateDelivery", 2)
+}
+
+func (suite *WebhookServiceTestSuite) TestNotifyAlerts() {
+	alerts := []models.TriggeredAlert{{Id: 1, RuleId: 1, UserId: 4}}
+
+	suite.mockRepo.On("GetWebhooks", 4).Return([]models.Webhook{{Id: 9, UserId: 4}}, nil)
+	suite.mockRepo.On("CreateDelivery", mock.MatchedBy(func(d models.WebhookDelivery) bool {
+		return d.WebhookId == 9 && d.Event == models.WebhookEventAlertTriggered
+	})).Return(1, nil)
+
+	err := suite.service.NotifyAlerts(context.Background(), alerts)
+	assert.NoError(suite.T(), err)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+func (suite *WebhookServiceTestSuite) TestDeliverPendingSignsPayload() {
+	delivery := suite.pendingDelivery(0)
+
+	suite.mockRepo.On("ClaimPendingDeliveries", 10, mock.Anything).Return([]models.WebhookDelivery{delivery}, nil)
+	suite.mockRepo.On("GetWebhookById", 1).Return(suite.webhook(), nil)
+	suite.mockRepo.On("UpdateDelivery", mock.MatchedBy(func(d models.WebhookDelivery) bool {
+		return d.Status == models.DeliveryStatusDelivered && d.Attempts == 1 && d.ResponseStatus == 200 && d.DeliveredAt != nil
+	})).Return(nil)
+
+	delivered, err := suite.service.DeliverPending(context.Background(), 10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 1, delivered)
+
+	req := <-suite.received
+	assert.Equal(suite.T(), delivery.Payload, json.RawMessage(req.body))
+	assert.Equal(suite.T(), models.WebhookEventForecastUpdated, req.header.Get(EventHeader))
+	timestamp, err := strconv.ParseInt(req.header.Get(TimestampHeader), 10, 64)
+	assert.NoError(suite.T(), err)
+	signature := strings.TrimPrefix(req.header.Get(SignatureHeader), "sha256=")
+	assert.Equal(suite.T(), Sign("top-secret", timestamp, req.body), signature)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+func (suite *WebhookServiceTestSuite) TestDeliverPendingRetriesWithBackoff() {
+	suite.status = http.StatusServiceUnavailable
+	before := time.Now()
+
+	suite.mockRepo.On("ClaimPendingDeliveries", 10, mock.Anything).Return([]models.WebhookDelivery{suite.pendingDelivery(2)}, nil)
+	suite.mockRepo.On("GetWebhookById", 1).Return(suite.webhook(), nil)
+	suite.mockRepo.On("UpdateDelivery", mock.MatchedBy(func(d models.WebhookDelivery) bool {
+		return d.Status == models.DeliveryStatusPending && d.Attempts == 3 && d.ResponseStatus == 503 &&
+			!d.NextAttemptAt.Before(before.Add(4*defaultBaseBackoff))
+	})).Return(nil)
+
+	delivered, err := suite.service.DeliverPending(context.Background(), 10)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 0, delivered)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+func (suite *WebhookServiceTestSuite) TestDeliverPendingGivesUp() {
+	suite.status = http.StatusInternalServerError
+
+	suite.mockRepo.On("ClaimPendingDeliveries", 10, mock.Anything).Return([]models.WebhookDelivery{suite.pendingDelivery(defaultMaxAttempts - 1)}, nil)
+	suite.mockRepo.On("GetWebhookById", 1).Return(suite.webhook(), nil)
+	suite.mockRepo.On("UpdateDelivery", mock.MatchedBy(func(d models.WebhookDelivery) bool {
+		return d.Status == models.DeliveryStatusFailed && d.Attempts == defaultMaxAttempts && d.LastError != ""
+	})).Return(nil)
+
+	_, err := suite.service.DeliverPending(context.Background(), 10)
+	assert.NoError(suite.T(), err)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+func (suite *WebhookServiceTestSuite) TestDeliverPendingClaimError() {
+	suite.mockRepo.On("ClaimPendingDeliveries", 10, mock.Anything).Return([]models.WebhookDelivery(nil), errors.New("query error"))
+
+	_, err := suite.service.DeliverPending(context.Background(), 10)
+	assert.Error(suite.T(), err)
+}
+
+func (suite *WebhookServiceTestSuite) TestTestWebhook() {
+	suite.mockRepo.On("GetWebhook", 1, 1).Return(suite.webhook(), nil)
+	suite.mockRepo.On("CreateDelivery", mock.MatchedBy(func(d models.WebhookDelivery) bool {
+		return d.Event == models.WebhookEventPing && d.Status == models.DeliveryStatusDelivered
+	})).Return(12, nil)
+
+	delivery, err := suite.service.TestWebhook(context.Background(), 1, 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), 12, delivery.Id)
+	assert.Equal(suite.T(), http.StatusOK, delivery.ResponseStatus)
+
+	req := <-suite.received
+	assert.Equal(suite.T(), models.WebhookEventPing, req.header.Get(EventHeader))
+}
+
+func (suite *WebhookServiceTestSuite) TestTestWebhookFailureIsNotRetried() {
+	suite.status = http.StatusBadGateway
+
+	suite.mockRepo.On("GetWebhook", 1, 1).Return(suite.webhook(), nil)
+	suite.mockRepo.On("CreateDelivery", mock.MatchedBy(func(d models.WebhookDelivery) bool {
+		return d.Status == models.DeliveryStatusFailed && d.Attempts == 1
+	})).Return(13, nil)
+
+	delivery, err := suite.service.TestWebhook(context.Background(), 1, 1)
+	assert.NoError(suite.T(), err)
+	assert.Equal(suite.T(), http.StatusBadGateway, delivery.ResponseStatus)
+	suite.mockRepo.AssertExpectations(suite.T())
+}
+
+func TestWebhookServiceTestSuite(t *testing.T) {
+	suite.Run(t, new(WebhookServiceTestSuite))
+}
@@ -0,0 +1,101 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/suite"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	_ "modernc.org/sqlite"
+)
+
+// TracingTestSuite records the spans of the global tracer provider.
+type TracingTestSuite struct {
+	suite.Suite
+	recorder *tracetest.SpanRecorder
+	provider trace.TracerProvider
+}
+
+func (suite *TracingTestSuite) SetupTest() {
+	suite.recorder = tracetest.NewSpanRecorder()
+	suite.provider = otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(suite.recorder)))
+}
+
+func (suite *TracingTestSuite) TearDownTest() {
+	otel.SetTracerProvider(suite.provider)
+}
+
+func (suite *TracingTestSuite) TestEnd() {
+	_, span := Start(context.Background(), "failing")
+	End(span, errors.New("boom"))
+
+	spans := suite.recorder.Ended()
+	suite.Require().Len(spans, 1)
+	suite.Equal(codes.Error, spans[0].Status().Code)
+	suite.Equal("boom", spans[0].Status().Description)
+}
+
+func (suite *TracingTestSuite) TestTraceID() {
+	suite.Empty(TraceID(context.Background()))
+
+	ctx, span := Start(context.Background(), "request")
+	defer span.End()
+	suite.Equal(span.SpanContext().TraceID().String(), TraceID(ctx))
+}
+
+func (suite *TracingTestSuite) TestLogHook() {
+	var out bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&out)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(LogHook{})
+
+	logger.Info("untraced")
+	suite.NotContains(out.String(), "trace_id")
+
+	ctx, span := Start(context.Background(), "request")
+	defer span.End()
+	logger.WithContext(ctx).Info("traced")
+	suite.Contains(out.String(), `"trace_id":"`+span.SpanContext().TraceID().String()+`"`)
+	suite.Contains(out.String(), `"span_id":"`+span.SpanContext().SpanID().String()+`"`)
+}
+
+func (suite *TracingTestSuite) TestOpenDB() {
+	db, err := OpenDB("sqlite", ":memory:", "sqlite")
+	suite.Require().NoError(err)
+	defer db.Close()
+
+	_, err = db.ExecContext(context.Background(), "create table cities (id integer)")
+	suite.Require().NoError(err)
+	suite.Empty(suite.recorder.Ended(), "queries outside of a trace are not traced")
+
+	ctx, span := Start(context.Background(), "request")
+	_, err = db.ExecContext(ctx, "insert into cities (id) values (1)")
+	suite.Require().NoError(err)
+	span.End()
+
+	var traced bool
+	for _, ended := range suite.recorder.Ended() {
+		if ended.Parent().SpanID() == span.SpanContext().SpanID() {
+			traced = true
+		}
+	}
+	suite.True(traced, "the query is a child of the request")
+}
+
+func (suite *TracingTestSuite) TestSetupRejectsUnknownExporter() {
+	_, err := Setup(context.Background(), Config{Exporter: "jaeger", SampleRatio: 1})
+	suite.ErrorContains(err, `unknown trace exporter "jaeger"`)
+}
+
+func TestTracingTestSuite(t *testing.T) {
+	suite.Run(t, new(TracingTestSuite))
+}
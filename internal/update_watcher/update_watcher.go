@@ -0,0 +1,139 @@
+package updatewatcher
+
+import (
+	"context"
+	"time"
+	"weather-app/internal/lifecycle"
+	"weather-app/internal/models"
+	"weather-app/internal/pubsub"
+	"weather-app/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+// alertPageSize bounds the alerts loaded by one query.
+const alertPageSize = 100
+
+// UpdateWatcher publishes the forecasts and alerts stored by a collector
+// running in another process, which has no way to reach this process's
+// broker. It polls the forecast freshness and the latest alert id, so updates
+// arrive up to an interval late.
+type UpdateWatcher struct {
+	services *service.Service
+	broker   *pubsub.Broker
+	interval time.Duration
+	runner   *lifecycle.Runner
+
+	started     bool
+	updatedAt   map[int]time.Time
+	lastAlertId int
+}
+
+func NewUpdateWatcher(services *service.Service, broker *pubsub.Broker, interval time.Duration) *UpdateWatcher {
+	return &UpdateWatcher{
+		services: services,
+		broker:   broker,
+		interval: interval,
+		runner:   lifecycle.NewRunner(),
+	}
+}
+
+// Start polls for updates every interval until Stop is called or ctx is
+// canceled. Only updates stored after the first poll are published. It
+// blocks, so run it in a goroutine.
+func (w *UpdateWatcher) Start(ctx context.Context) error {
+	return w.runner.Run(ctx, w.run)
+}
+
+func (w *UpdateWatcher) Stop(ctx context.Context) error {
+	return w.runner.Stop(ctx)
+}
+
+func (w *UpdateWatcher) run(ctx context.Context) error {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.poll(ctx); err != nil {
+			logrus.Errorf("Failed to check for stored updates: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-w.runner.Stopping():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll publishes what was stored since the previous poll. The first one only
+// remembers where the storage stands.
+func (w *UpdateWatcher) poll(ctx context.Context) error {
+	if !w.started {
+		return w.baseline(ctx)
+	}
+	if err := w.publishForecasts(ctx); err != nil {
+		return err
+	}
+	return w.publishAlerts(ctx)
+}
+
+func (w *UpdateWatcher) baseline(ctx context.Context) error {
+	freshness, err := w.services.ForecastService.GetAllForecastFreshness(ctx)
+	if err != nil {
+		return err
+	}
+	lastAlertId, err := w.services.AlertService.GetLastTriggeredAlertId(ctx)
+	if err != nil {
+		return err
+	}
+	w.updatedAt = make(map[int]time.Time, len(freshness))
+	for _, f := range freshness {
+		w.updatedAt[f.CityId] = f.UpdatedAt
+	}
+	w.lastAlertId = lastAlertId
+	w.started = true
+	return nil
+}
+
+func (w *UpdateWatcher) publishForecasts(ctx context.Context) error {
+	freshness, err := w.services.ForecastService.GetAllForecastFreshness(ctx)
+	if err != nil {
+		return err
+	}
+	for _, f := range freshness {
+		if f.UpdatedAt.IsZero() || !f.UpdatedAt.After(w.updatedAt[f.CityId]) {
+			continue
+		}
+		summary, err := w.services.ForecastService.GetShortForecast(ctx, f.CityId)
+		if err != nil {
+			// Retried at the next poll.
+			logrus.Errorf("Failed to load forecast summary for city %d: %v", f.CityId, err)
+			continue
+		}
+		w.updatedAt[f.CityId] = f.UpdatedAt
+		w.broker.Publish(pubsub.ForecastUpdated(models.ForecastUpdate{
+			CityId:    f.CityId,
+			UpdatedAt: f.UpdatedAt,
+			Forecast:  summary,
+		}))
+	}
+	return nil
+}
+
+func (w *UpdateWatcher) publishAlerts(ctx context.Context) error {
+	for {
+		alerts, err := w.services.AlertService.GetTriggeredAlertsAfter(ctx, w.lastAlertId, alertPageSize)
+		if err != nil {
+			return err
+		}
+		for _, alert := range alerts {
+			w.broker.Publish(pubsub.AlertTriggered(alert))
+			w.lastAlertId = alert.Id
+		}
+		if len(alerts) < alertPageSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package updatewatcher
+
+import (
+	"context"
+	"testing"
+	"time"
+	"weather-app/internal/models"
+	"weather-app/internal/provider"
+	"weather-app/internal/pubsub"
+	"weather-app/internal/repository"
+	"weather-app/internal/repository/memory"
+	"weather-app/internal/service"
+	alertservice "weather-app/internal/service/alert_service"
+	cityservice "weather-app/internal/service/city_service"
+	collectorservice "weather-app/internal/service/collector_service"
+	forecastservice "weather-app/internal/service/forecast_service"
+	healthservice "weather-app/internal/service/health_service"
+	userservice "weather-app/internal/service/user_service"
+	webhookservice "weather-app/internal/service/webhook_service"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// UpdateWatcherTestSuite plays the collector of another process by writing
+// to the in-memory storage backend directly.
+type UpdateWatcherTestSuite struct {
+	suite.Suite
+	services *service.Service
+	alerts   repository.AlertRepository
+	broker   *pubsub.Broker
+	watcher  *UpdateWatcher
+	cityId   int
+	ruleId   int
+	userId   int
+}
+
+func (suite *UpdateWatcherTestSuite) SetupTest() {
+	storage := memory.NewStorage()
+	client := provider.NewClient(provider.Config{})
+	cityServ := cityservice.NewCityService(memory.NewCityRepository(storage), client)
+	forecastServ := forecastservice.NewForecastService(cityServ, memory.NewForecastRepository(storage), client, nil)
+	userServ := userservice.NewUserService(cityServ, memory.NewUserRepository(storage), userservice.LoginPolicy{})
+	suite.alerts = memory.NewAlertRepository(storage)
+	alertServ := alertservice.NewAlertService(cityServ, suite.alerts)
+	webhookServ := webhookservice.NewWebhookService(memory.NewWebhookRepository(storage), webhookservice.DeliveryPolicy{})
+	collectorServ := collectorservice.NewCollectorService(memory.NewCollectorRunRepository(storage), client)
+	healthServ := healthservice.NewHealthService(memory.NewHealthRepository(storage), memory.NewCityRepository(storage), memory.NewForecastRepository(storage), healthservice.ReadinessPolicy{})
+	suite.services = service.NewService(userServ, cityServ, forecastServ, alertServ, webhookServ, collectorServ, healthServ)
+
+	suite.broker = pubsub.NewBroker()
+	suite.watcher = NewUpdateWatcher(suite.services, suite.broker, time.Minute)
+
+	var err error
+	suite.cityId, err = suite.services.CityService.CreateCity(context.Background(), models.City{Name: "London", Country: "GB"})
+	suite.Require().NoError(err)
+	suite.userId, err = memory.NewUserRepository(storage).CreateUser(context.Background(), models.User{Login: "alice", Password: "hash", Email: "alice@example.com"})
+	suite.Require().NoError(err)
+	suite.ruleId, err = suite.alerts.CreateAlertRule(context.Background(), models.AlertRule{UserId: suite.userId, CityId: suite.cityId, Metric: models.AlertMetricTempBelow, WithinHours: 24})
+	suite.Require().NoError(err)
+}
+
+func (suite *UpdateWatcherTestSuite) TearDownTest() {
+	suite.broker.Close()
+}
+
+func (suite *UpdateWatcherTestSuite) storeForecasts(updatedAt time.Time) {
+	err := suite.services.ForecastService.SetForecastFreshness(context.Background(), models.ForecastFreshness{
+		CityId:     suite.cityId,
+		UpdatedAt:  updatedAt,
+		NextUpdate: updatedAt.Add(time.Hour),
+	})
+	suite.Require().NoError(err)
+}
+
+func (suite *UpdateWatcherTestSuite) triggerAlert() int {
+	id, err := suite.alerts.CreateTriggeredAlert(context.Background(), models.TriggeredAlert{RuleId: suite.ruleId, ForecastDate: time.Now(), Value: -1})
+	suite.Require().NoError(err)
+	return id
+}
+
+func (suite *UpdateWatcherTestSuite) received(sub *pubsub.Subscription) []pubsub.Event {
+	var events []pubsub.Event
+	for {
+		select {
+		case event := <-sub.Events():
+			events = append(events, event)
+		default:
+			return events
+		}
+	}
+}
+
+func (suite *UpdateWatcherTestSuite) TestPublishesUpdatesStoredAfterTheFirstPoll() {
+	sub := suite.broker.Subscribe(pubsub.ForecastTopic(suite.cityId), pubsub.AlertTopic(suite.userId))
+	defer sub.Close()
+	updatedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	suite.storeForecasts(updatedAt)
+	suite.triggerAlert()
+
+	suite.Require().NoError(suite.watcher.poll(context.Background()))
+	suite.Empty(suite.received(sub), "updates stored before the start are not replayed")
+
+	suite.storeForecasts(updatedAt.Add(time.Hour))
+	alertId := suite.triggerAlert()
+	suite.Require().NoError(suite.watcher.poll(context.Background()))
+
+	events := suite.received(sub)
+	suite.Require().Len(events, 2)
+	suite.Equal("forecast", events[0].Type)
+	update := events[0].Data.(models.ForecastUpdate)
+	suite.Equal(suite.cityId, update.CityId)
+	suite.Equal("London", update.Forecast.City)
+	suite.True(update.UpdatedAt.Equal(updatedAt.Add(time.Hour)))
+	suite.Equal("alert", events[1].Type)
+	suite.Equal(alertId, events[1].Data.(models.TriggeredAlert).Id)
+
+	suite.Require().NoError(suite.watcher.poll(context.Background()))
+	suite.Empty(suite.received(sub), "each update is published once")
+}
+
+func (suite *UpdateWatcherTestSuite) TestPublishesAlertsPageByPage() {
+	sub := suite.broker.Subscribe(pubsub.AlertTopic(suite.userId))
+	defer sub.Close()
+	suite.Require().NoError(suite.watcher.poll(context.Background()))
+
+	for i := 0; i < alertPageSize+20; i++ {
+		suite.triggerAlert()
+	}
+	suite.Require().NoError(suite.watcher.poll(context.Background()))
+	// The subscription buffer holds fewer events than were published, so
+	// check where the watcher stopped instead.
+	suite.NotEmpty(suite.received(sub))
+	last, err := suite.alerts.GetLastTriggeredAlertId(context.Background())
+	suite.Require().NoError(err)
+	suite.Equal(last, suite.watcher.lastAlertId)
+}
+
+func TestUpdateWatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(UpdateWatcherTestSuite))
+}
@@ -0,0 +1,61 @@
+package webhookdispatcher
+
+import (
+	"context"
+	"time"
+	"weather-app/internal/lifecycle"
+	"weather-app/internal/service"
+
+	"github.com/sirupsen/logrus"
+)
+
+type WebhookDispatcher struct {
+	services  *service.Service
+	interval  time.Duration
+	batchSize int
+	runner    *lifecycle.Runner
+}
+
+func NewWebhookDispatcher(services *service.Service, interval time.Duration, batchSize int) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		services:  services,
+		interval:  interval,
+		batchSize: batchSize,
+		runner:    lifecycle.NewRunner(),
+	}
+}
+
+// Start polls the delivery queue every interval until Stop is called or ctx
+// is canceled. It blocks, so run it in a goroutine.
+func (d *WebhookDispatcher) Start(ctx context.Context) error {
+	return d.runner.Run(ctx, d.run)
+}
+
+// Stop waits for the batch being delivered. Deliveries still running when ctx
+// expires are canceled and retried later.
+func (d *WebhookDispatcher) Stop(ctx context.Context) error {
+	return d.runner.Stop(ctx)
+}
+
+func (d *WebhookDispatcher) run(ctx context.Context) error {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-d.runner.Stopping():
+			return nil
+		case <-ticker.C:
+		}
+		delivered, err := d.services.WebhookService.DeliverPending(ctx, d.batchSize)
+		if err != nil {
+			logrus.Errorf("Failed to deliver webhooks: %v", err)
+			continue
+		}
+		if delivered > 0 {
+			logrus.Printf("%d webhooks were delivered", delivered)
+		}
+	}
+}
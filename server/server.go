@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 )
@@ -10,18 +11,29 @@ type Server struct {
 	httpServer *http.Server
 }
 
-func (s *Server) Run(port string, handler http.Handler) error {
-	s.httpServer = &http.Server{
-		Addr:           ":" + port,
-		Handler:        handler,
-		MaxHeaderBytes: 1 << 28,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
+func NewServer(port string, handler http.Handler) *Server {
+	return &Server{
+		httpServer: &http.Server{
+			Addr:           ":" + port,
+			Handler:        handler,
+			MaxHeaderBytes: 1 << 28,
+			ReadTimeout:    10 * time.Second,
+			WriteTimeout:   10 * time.Second,
+		},
 	}
+}
 
-	return s.httpServer.ListenAndServe()
+// Run serves requests until Shutdown is called.
+func (s *Server) Run() error {
+	err := s.httpServer.ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
 }
 
+// Shutdown stops accepting connections and waits for the active requests to
+// finish until ctx expires.
 func (s *Server) Shutdown(ctx context.Context) error {
 	return s.httpServer.Shutdown(ctx)
 }